@@ -0,0 +1,397 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+)
+
+// defaultServerAddr 默认服务器地址
+const defaultServerAddr = "localhost:50051"
+
+// command 客户端子命令
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(cmd *command, args []string, logger *logrus.Logger) error
+}
+
+// commands 所有子命令，第一个为默认命令
+var commands = []command{
+	{
+		name:    "convert",
+		usage:   "convert [选项] <ppt文件路径> [输出目录] [宽度] [高度]",
+		summary: "转换PPT并下载所有图片 (默认命令)",
+		run:     runConvert,
+	},
+	{
+		name:    "status",
+		usage:   "status [选项] <转换ID>",
+		summary: "查询转换状态",
+		run:     runStatus,
+	},
+	{
+		name:    "download",
+		usage:   "download [选项] <下载ID>",
+		summary: "下载单张图片",
+		run:     runDownload,
+	},
+	{
+		name:    "info",
+		usage:   "info [选项] <转换ID>",
+		summary: "列出转换生成的图片",
+		run:     runInfo,
+	},
+	{
+		name:    "archive",
+		usage:   "archive [选项] <转换ID>",
+		summary: "把转换的所有图片下载为一个压缩包",
+		run:     runArchive,
+	},
+	{
+		name:    "delete",
+		usage:   "delete [选项] <转换ID>",
+		summary: "删除转换的输出",
+		run:     runDelete,
+	},
+}
+
+// findCommand 按名称查找子命令
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// printUsage 打印所有子命令的用法
+func printUsage() {
+	fmt.Println("用法: client <命令> [选项] [参数]")
+	fmt.Println()
+	fmt.Println("命令:")
+	for _, cmd := range commands {
+		fmt.Printf("  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Println()
+	fmt.Println("省略命令时执行 convert，例如: client example.pptx ./output 1920 1080")
+	fmt.Println("使用 client <命令> -h 查看命令的选项")
+}
+
+// connectOptions 所有命令共用的连接选项
+type connectOptions struct {
+	server          string
+	retry           RetryOptions
+	compress        bool
+	maxMessageSize  int
+	downloadRetries int
+	keepalive       keepalive.ClientParameters
+	quiet           bool
+}
+
+// newFlagSet 创建子命令的参数集，包含所有命令共用的连接选项
+func newFlagSet(cmd *command, opts *connectOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	opts.retry = DefaultRetryOptions
+	fs.StringVar(&opts.server, "server", defaultServerAddr, "服务器地址")
+	fs.IntVar(&opts.retry.MaxAttempts, "retries", DefaultRetryOptions.MaxAttempts, "连接和下载的最大尝试次数")
+	fs.DurationVar(&opts.retry.DialTimeout, "timeout", DefaultRetryOptions.DialTimeout, "每次连接服务器的超时时间")
+	fs.BoolVar(&opts.compress, "compress", false, "使用gzip压缩gRPC消息")
+	fs.IntVar(&opts.maxMessageSize, "max-message-size", 128<<20, "gRPC单条消息大小上限 (字节)，应与服务器一致")
+	fs.DurationVar(&opts.keepalive.Time, "keepalive-time", 60*time.Second, "连接空闲该时间后发送keepalive ping，不能小于服务器的 -keepalive-min-time")
+	fs.DurationVar(&opts.keepalive.Timeout, "keepalive-timeout", 20*time.Second, "keepalive ping 等待响应的时间，超时后关闭连接")
+	fs.BoolVar(&opts.keepalive.PermitWithoutStream, "keepalive-permit-without-stream", true, "没有进行中的调用时也发送keepalive ping")
+	fs.BoolVar(&opts.quiet, "quiet", false, "不输出大文件下载过程中的进度")
+	fs.IntVar(&opts.downloadRetries, "download-retries", 2, "下载的图片与服务器提供的SHA-256不一致时重新下载的次数")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: client %s\n", cmd.usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// connect 连接服务器
+func connect(opts connectOptions, logger *logrus.Logger) (*PPTClient, error) {
+	logger.Infof("服务器地址: %s", opts.server)
+
+	extra := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(opts.maxMessageSize),
+			grpc.MaxCallSendMsgSize(opts.maxMessageSize),
+		),
+		grpc.WithKeepaliveParams(opts.keepalive),
+	}
+	if opts.compress {
+		extra = append(extra, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+
+	client, err := NewPPTClient(opts.server, opts.retry, logger, extra...)
+	if err != nil {
+		return nil, fmt.Errorf("创建客户端失败: %v", err)
+	}
+	client.maxMessageSize = opts.maxMessageSize
+	client.downloadRetries = opts.downloadRetries
+	if !opts.quiet {
+		client.DownloadProgress = logDownloadProgress(logger)
+	}
+	return client, nil
+}
+
+// runConvert 转换PPT并下载所有图片
+func runConvert(cmd *command, args []string, logger *logrus.Logger) error {
+	var opts connectOptions
+	fs := newFlagSet(cmd, &opts)
+	outputDir := fs.String("output", "./output", "输出目录")
+	width := fs.Int("width", 1920, "输出宽度")
+	height := fs.Int("height", 1080, "输出高度")
+	format := fs.String("format", "PNG", "输出格式 (PNG, JPEG, AUTO)")
+	concurrency := fs.Int("download-concurrency", 4, "同时下载的图片数量")
+	sortImages := fs.Bool("sort", true, "按幻灯片编号顺序下载图片")
+	fonts := fs.String("fonts", "", "随PPT上传的字体文件，多个文件用逗号分隔 (仅LibreOffice后端)")
+	singleCall := fs.Bool("single-call", false, "通过 ConvertAndStream 在一次调用中上传并接收所有图片")
+	inline := fs.Bool("inline", false, "请求服务器在转换响应中直接返回图片数据，超出服务器内联上限的图片仍单独下载")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("缺少PPT文件路径")
+	}
+
+	// 兼容旧的位置参数: <ppt文件路径> [输出目录] [宽度] [高度]
+	pptPath := fs.Arg(0)
+	if fs.NArg() > 1 {
+		*outputDir = fs.Arg(1)
+	}
+	if fs.NArg() > 2 {
+		if w, err := strconv.Atoi(fs.Arg(2)); err == nil {
+			*width = w
+		} else {
+			logger.Warnf("无效的宽度参数，使用默认值: %d", *width)
+		}
+	}
+	if fs.NArg() > 3 {
+		if h, err := strconv.Atoi(fs.Arg(3)); err == nil {
+			*height = h
+		} else {
+			logger.Warnf("无效的高度参数，使用默认值: %d", *height)
+		}
+	}
+
+	// 检查PPT文件是否存在
+	if _, err := os.Stat(pptPath); os.IsNotExist(err) {
+		return fmt.Errorf("PPT文件不存在: %s", pptPath)
+	}
+
+	logger.Infof("PPT文件: %s", pptPath)
+	logger.Infof("输出目录: %s", *outputDir)
+	logger.Infof("输出尺寸: %dx%d", *width, *height)
+
+	client, err := connect(opts, logger)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	convertOptions := ConvertOptions{
+		Width:               int32(*width),
+		Height:              int32(*height),
+		Format:              *format,
+		DownloadConcurrency: *concurrency,
+		SortBySlide:         *sortImages,
+		Fonts:               splitList(*fonts),
+		InlineImages:        *inline,
+	}
+
+	startTime := time.Now()
+	if *singleCall {
+		err = client.ConvertAndStream(pptPath, *outputDir, convertOptions)
+	} else {
+		err = client.ConvertPPT(pptPath, *outputDir, convertOptions)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("转换完成，耗时: %v", time.Since(startTime))
+	return nil
+}
+
+// splitList 解析逗号分隔的参数，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// runStatus 查询转换状态
+func runStatus(cmd *command, args []string, logger *logrus.Logger) error {
+	var opts connectOptions
+	fs := newFlagSet(cmd, &opts)
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("缺少转换ID")
+	}
+
+	client, err := connect(opts, logger)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	resp, err := client.GetConversionStatus(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	status := resp.Status
+	fmt.Printf("状态: %s\n", status.Status)
+	fmt.Printf("进度: %d%%\n", status.Progress)
+	fmt.Printf("消息: %s\n", status.Message)
+	fmt.Printf("幻灯片: %d/%d\n", status.ProcessedSlides, status.TotalSlides)
+
+	if result := resp.Result; result != nil {
+		fmt.Printf("结果: success=%v, %s\n", result.Success, result.Message)
+		if result.Error != "" {
+			fmt.Printf("错误: %s\n", result.Error)
+		}
+	}
+	return nil
+}
+
+// runDownload 下载单张图片
+func runDownload(cmd *command, args []string, logger *logrus.Logger) error {
+	var opts connectOptions
+	fs := newFlagSet(cmd, &opts)
+	output := fs.String("o", "", "输出文件路径 (默认为当前目录下的下载ID)")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("缺少下载ID")
+	}
+
+	downloadID := fs.Arg(0)
+	outputPath := *output
+	if outputPath == "" {
+		outputPath = filepath.Base(downloadID)
+	}
+
+	client, err := connect(opts, logger)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.downloadImageWithRetry(downloadID, outputPath); err != nil {
+		return err
+	}
+
+	logger.Infof("图片已保存到: %s", outputPath)
+	return nil
+}
+
+// runInfo 列出转换生成的图片
+func runInfo(cmd *command, args []string, logger *logrus.Logger) error {
+	var opts connectOptions
+	fs := newFlagSet(cmd, &opts)
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("缺少转换ID")
+	}
+
+	client, err := connect(opts, logger)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	images, err := client.ListImages(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-8s %-24s %-10s %-8s %s\n", "幻灯片", "文件名", "大小", "格式", "下载ID")
+	for _, image := range images {
+		fmt.Printf("%-8d %-24s %-10d %-8s %s\n", image.SlideNumber, image.Filename, image.FileSize, image.Format, image.DownloadId)
+	}
+	return nil
+}
+
+// runArchive 把转换的所有图片下载为一个压缩包
+func runArchive(cmd *command, args []string, logger *logrus.Logger) error {
+	var opts connectOptions
+	fs := newFlagSet(cmd, &opts)
+	format := fs.String("format", "zip", "压缩包格式 (zip, tar.gz)")
+	output := fs.String("o", "", "输出文件路径 (默认为当前目录下的 <转换ID>.<格式>)")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("缺少转换ID")
+	}
+
+	conversionID := fs.Arg(0)
+	outputPath := *output
+	if outputPath == "" {
+		outputPath = filepath.Base(conversionID) + "." + *format
+	}
+
+	client, err := connect(opts, logger)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	written, err := client.DownloadArchive(conversionID, *format, outputPath)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("压缩包已保存到: %s (%d 字节)", outputPath, written)
+	return nil
+}
+
+// runDelete 删除转换的输出目录和会话
+func runDelete(cmd *command, args []string, logger *logrus.Logger) error {
+	var opts connectOptions
+	fs := newFlagSet(cmd, &opts)
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("缺少转换ID")
+	}
+
+	client, err := connect(opts, logger)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	deleted, err := client.DeleteConversion(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("已删除转换 %s (%d 张图片)", fs.Arg(0), deleted)
+	return nil
+}
@@ -2,15 +2,22 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
 	"ppt-to-images-service/proto"
 )
@@ -19,14 +26,42 @@ import (
 type PPTClient struct {
 	conn   *grpc.ClientConn
 	client proto.PPTToImagesServiceClient
+	retry  RetryOptions
 	logger *logrus.Logger
+
+	maxMessageSize  int // gRPC单条消息大小上限，0表示使用gRPC默认值
+	downloadRetries int // 下载的文件校验失败时重新下载的次数
+
+	// DownloadProgress 下载图片和压缩包时定期调用，为nil时只在开始和结束时记录日志
+	DownloadProgress DownloadProgress
 }
 
-// NewPPTClient 创建新的PPT客户端
-func NewPPTClient(serverAddr string, logger *logrus.Logger) (*PPTClient, error) {
-	conn, err := grpc.Dial(serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return nil, fmt.Errorf("连接服务器失败: %v", err)
+// NewPPTClient 创建新的PPT客户端，连接失败时按退避策略重试
+// extra 为附加的连接选项 (如压缩)
+func NewPPTClient(serverAddr string, retry RetryOptions, logger *logrus.Logger, extra ...grpc.DialOption) (*PPTClient, error) {
+	var conn *grpc.ClientConn
+	var err error
+
+	dialOptions := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(retry.serviceConfig()),
+		grpc.WithBlock(),
+	}, extra...)
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), retry.DialTimeout)
+		conn, err = grpc.DialContext(ctx, serverAddr, dialOptions...)
+		cancel()
+		if err == nil {
+			break
+		}
+		if attempt >= retry.MaxAttempts {
+			return nil, fmt.Errorf("连接服务器失败 (已尝试 %d 次): %v", attempt, err)
+		}
+
+		wait := retry.backoff(attempt)
+		logger.Warnf("连接服务器失败，%v 后重试 (%d/%d): %v", wait, attempt, retry.MaxAttempts, err)
+		time.Sleep(wait)
 	}
 
 	client := proto.NewPPTToImagesServiceClient(conn)
@@ -34,6 +69,7 @@ func NewPPTClient(serverAddr string, logger *logrus.Logger) (*PPTClient, error)
 	return &PPTClient{
 		conn:   conn,
 		client: client,
+		retry:  retry,
 		logger: logger,
 	}, nil
 }
@@ -43,8 +79,19 @@ func (c *PPTClient) Close() error {
 	return c.conn.Close()
 }
 
+// ConvertOptions 转换选项
+type ConvertOptions struct {
+	Width               int32
+	Height              int32
+	Format              string
+	DownloadConcurrency int      // 同时下载的图片数量
+	SortBySlide         bool     // 下载前按幻灯片编号排序，不依赖服务器的发送顺序
+	Fonts               []string // 随PPT上传的字体文件路径
+	InlineImages        bool     // 请求服务器直接返回图片数据，省去单独下载
+}
+
 // ConvertPPT 转换PPT文件
-func (c *PPTClient) ConvertPPT(pptPath string, outputDir string, width, height int32) error {
+func (c *PPTClient) ConvertPPT(pptPath string, outputDir string, options ConvertOptions) error {
 	// 读取PPT文件
 	pptData, err := os.ReadFile(pptPath)
 	if err != nil {
@@ -58,9 +105,28 @@ func (c *PPTClient) ConvertPPT(pptPath string, outputDir string, width, height i
 	req := &proto.ConvertPPTRequest{
 		Filename:     filename,
 		PptData:      pptData,
-		Width:        width,
-		Height:       height,
-		OutputFormat: "PNG",
+		Width:        options.Width,
+		Height:       options.Height,
+		OutputFormat: options.Format,
+		InlineImages: options.InlineImages,
+	}
+
+	uploadSize := len(pptData)
+	for _, fontPath := range options.Fonts {
+		fontData, err := os.ReadFile(fontPath)
+		if err != nil {
+			return fmt.Errorf("读取字体文件失败: %v", err)
+		}
+		req.Fonts = append(req.Fonts, &proto.FontFile{
+			Filename: filepath.Base(fontPath),
+			Data:     fontData,
+		})
+		uploadSize += len(fontData)
+	}
+
+	// 整个文件放在一条消息中上传，超过上限时直接给出明确的提示
+	if c.maxMessageSize > 0 && uploadSize > c.maxMessageSize {
+		return fmt.Errorf("文件大小 %d 字节超过gRPC消息大小上限 %d 字节，请使用 -max-message-size 调大 (服务器也需相应调整)", uploadSize, c.maxMessageSize)
 	}
 
 	// 调用转换服务
@@ -76,6 +142,9 @@ func (c *PPTClient) ConvertPPT(pptPath string, outputDir string, width, height i
 		if err == io.EOF {
 			break
 		}
+		if status.Code(err) == codes.ResourceExhausted {
+			return fmt.Errorf("消息大小超过服务器上限，请调大服务器的 -max-message-size: %v", err)
+		}
 		if err != nil {
 			return fmt.Errorf("接收响应失败: %v", err)
 		}
@@ -98,6 +167,9 @@ func (c *PPTClient) ConvertPPT(pptPath string, outputDir string, width, height i
 				response.ImageInfo.SlideNumber,
 				response.ImageInfo.Filename,
 				response.ImageInfo.FileSize)
+			if response.ImageInfo.LowConfidence {
+				c.logger.Warnf("幻灯片 %d 几乎为空白，可能有图表或SmartArt未能渲染，请检查", response.ImageInfo.SlideNumber)
+			}
 
 		case *proto.ConvertPPTResponse_Result:
 			// 处理最终结果
@@ -107,7 +179,12 @@ func (c *PPTClient) ConvertPPT(pptPath string, outputDir string, width, height i
 				c.logger.Infof("总共转换了 %d/%d 张幻灯片", result.ConvertedSlides, result.TotalSlides)
 				
 				// 下载所有图片
-				if err := c.downloadAllImages(images, outputDir); err != nil {
+				if options.SortBySlide {
+					sort.SliceStable(images, func(i, j int) bool {
+						return images[i].SlideNumber < images[j].SlideNumber
+					})
+				}
+				if err := c.downloadAllImages(images, outputDir, options.DownloadConcurrency); err != nil {
 					c.logger.Errorf("下载图片失败: %v", err)
 					return err
 				}
@@ -120,39 +197,84 @@ func (c *PPTClient) ConvertPPT(pptPath string, outputDir string, width, height i
 	return nil
 }
 
-// downloadAllImages 下载所有图片
-func (c *PPTClient) downloadAllImages(images []*proto.ImageInfo, outputDir string) error {
+// downloadAllImages 最多 concurrency 张图片同时下载，单张失败时记录日志并继续
+func (c *PPTClient) downloadAllImages(images []*proto.ImageInfo, outputDir string, concurrency int) error {
 	// 确保输出目录存在
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("创建输出目录失败: %v", err)
 	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
 
-	c.logger.Infof("开始下载 %d 张图片到目录: %s", len(images), outputDir)
+	c.logger.Infof("开始下载 %d 张图片到目录: %s (并发数: %d)", len(images), outputDir, concurrency)
+
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		finished int
+		failed   int
+	)
+
+	jobs := make(chan *proto.ImageInfo)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for image := range jobs {
+				// 服务器已内联返回的图片直接写入文件
+				var err error
+				if len(image.Data) > 0 {
+					err = os.WriteFile(filepath.Join(outputDir, image.Filename), image.Data, 0644)
+				} else {
+					err = c.downloadImageWithRetry(image.DownloadId, filepath.Join(outputDir, image.Filename))
+				}
 
-	for i, image := range images {
-		c.logger.Infof("下载图片 %d/%d: %s", i+1, len(images), image.Filename)
-		
-		if err := c.downloadImage(image.DownloadId, filepath.Join(outputDir, image.Filename)); err != nil {
-			c.logger.Errorf("下载图片失败 %s: %v", image.Filename, err)
-			continue
-		}
+				// 完成顺序不固定，按完成数量输出进度
+				mutex.Lock()
+				finished++
+				if err != nil {
+					failed++
+					c.logger.Errorf("下载图片失败 %d/%d %s: %v", finished, len(images), image.Filename, err)
+				} else {
+					c.logger.Infof("下载图片 %d/%d: %s", finished, len(images), image.Filename)
+				}
+				mutex.Unlock()
+			}
+		}()
 	}
 
+	for _, image := range images {
+		jobs <- image
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failed > 0 {
+		c.logger.Warnf("图片下载完成，其中 %d 张失败", failed)
+		return nil
+	}
 	c.logger.Infof("所有图片下载完成")
 	return nil
 }
 
-// downloadImage 下载单张图片
-func (c *PPTClient) downloadImage(downloadID, outputPath string) error {
-	req := &proto.DownloadRequest{
-		DownloadId: downloadID,
-	}
+// errChecksumMismatch 下载的文件与服务器提供的SHA-256不一致
+var errChecksumMismatch = errors.New("文件校验失败")
 
-	stream, err := c.client.DownloadImage(context.Background(), req)
-	if err != nil {
-		return fmt.Errorf("调用下载服务失败: %v", err)
+// downloadImageWithRetry 下载单张图片，校验失败时重新创建文件完整下载，最多重试 downloadRetries 次
+func (c *PPTClient) downloadImageWithRetry(downloadID, outputPath string) error {
+	for attempt := 1; ; attempt++ {
+		err := c.downloadImage(downloadID, outputPath)
+		if !errors.Is(err, errChecksumMismatch) || attempt > c.downloadRetries {
+			return err
+		}
+		c.logger.Warnf("%s 校验失败，重新下载 (%d/%d): %v", filepath.Base(outputPath), attempt, c.downloadRetries, err)
 	}
+}
 
+// downloadImage 下载单张图片，连接中断时从已写入的位置续传
+// 服务器提供了SHA-256时下载完成后校验整个文件，不一致时返回 errChecksumMismatch
+func (c *PPTClient) downloadImage(downloadID, outputPath string) error {
 	// 创建输出文件
 	file, err := os.Create(outputPath)
 	if err != nil {
@@ -160,8 +282,58 @@ func (c *PPTClient) downloadImage(downloadID, outputPath string) error {
 	}
 	defer file.Close()
 
+	var written int64
+	for attempt := 1; ; attempt++ {
+		n, checksum, err := c.downloadFrom(downloadID, file, written)
+		written += n
+		if err == nil {
+			return verifyChecksum(file, checksum)
+		}
+		if !isTransient(err) || attempt >= c.retry.MaxAttempts {
+			return err
+		}
+
+		wait := c.retry.backoff(attempt)
+		c.logger.Warnf("下载中断，%v 后从 %d 字节处续传 (%d/%d): %v", wait, written, attempt, c.retry.MaxAttempts, err)
+		time.Sleep(wait)
+	}
+}
+
+// verifyChecksum 比较文件内容与服务器提供的SHA-256，旧版本服务器不提供时跳过
+func verifyChecksum(file *os.File, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("读取已下载的文件失败: %v", err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("读取已下载的文件失败: %v", err)
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return fmt.Errorf("%w: SHA-256 为 %s，应为 %s", errChecksumMismatch, actual, expected)
+	}
+	return nil
+}
+
+// downloadFrom 从指定偏移开始下载并写入文件，返回本次写入的字节数和服务器提供的整个文件的SHA-256
+func (c *PPTClient) downloadFrom(downloadID string, file *os.File, offset int64) (int64, string, error) {
+	req := &proto.DownloadRequest{
+		DownloadId: downloadID,
+		Offset:     offset,
+	}
+
+	stream, err := c.client.DownloadImage(context.Background(), req)
+	if err != nil {
+		return 0, "", fmt.Errorf("调用下载服务失败: %w", err)
+	}
+
 	var fileSize int64
 	var filename string
+	var checksum string
+	var written int64
+	progress := newProgressReporter(c.DownloadProgress, offset)
 
 	// 处理流式响应
 	for {
@@ -170,7 +342,7 @@ func (c *PPTClient) downloadImage(downloadID, outputPath string) error {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("接收下载响应失败: %v", err)
+			return written, checksum, fmt.Errorf("接收下载响应失败: %w", err)
 		}
 
 		switch response := resp.Response.(type) {
@@ -179,19 +351,24 @@ func (c *PPTClient) downloadImage(downloadID, outputPath string) error {
 			info := response.Info
 			fileSize = info.FileSize
 			filename = info.Filename
+			checksum = info.Sha256
+			progress.info(info.Filename, info.FileSize)
 			c.logger.Debugf("下载文件信息: %s (大小: %d 字节, 类型: %s)", 
 				info.Filename, info.FileSize, info.ContentType)
 
 		case *proto.DownloadResponse_Chunk:
 			// 数据块
-			if _, err := file.Write(response.Chunk); err != nil {
-				return fmt.Errorf("写入文件失败: %v", err)
+			n, err := file.Write(response.Chunk)
+			written += int64(n)
+			if err != nil {
+				return written, checksum, fmt.Errorf("写入文件失败: %v", err)
 			}
+			progress.add(n)
 		}
 	}
 
-	c.logger.Infof("图片下载完成: %s (大小: %d 字节)", filename, fileSize)
-	return nil
+	c.logger.Debugf("图片下载完成: %s (大小: %d 字节)", filename, fileSize)
+	return written, checksum, nil
 }
 
 // GetConversionStatus 获取转换状态
@@ -208,64 +385,123 @@ func (c *PPTClient) GetConversionStatus(conversionID string) (*proto.StatusRespo
 	return resp, nil
 }
 
-func main() {
-	// 设置日志
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
-	logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-	})
+// ListImages 列出转换生成的图片
+func (c *PPTClient) ListImages(conversionID string) ([]*proto.ImageInfo, error) {
+	req := &proto.ListImagesRequest{
+		ConversionId: conversionID,
+	}
 
-	// 检查命令行参数
-	if len(os.Args) < 2 {
-		fmt.Println("用法: go run main.go <ppt文件路径> [输出目录] [宽度] [高度]")
-		fmt.Println("示例: go run main.go example.pptx ./output 1920 1080")
-		os.Exit(1)
+	resp, err := c.client.ListImages(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("获取图片列表失败: %v", err)
 	}
 
-	pptPath := os.Args[1]
-	outputDir := "./output"
-	width := int32(1920)
-	height := int32(1080)
+	return resp.Images, nil
+}
 
-	if len(os.Args) > 2 {
-		outputDir = os.Args[2]
+// DownloadArchive 把转换的所有输出下载为一个压缩包 (zip 或 tar.gz)，返回写入的字节数
+func (c *PPTClient) DownloadArchive(conversionID, format, outputPath string) (int64, error) {
+	req := &proto.DownloadArchiveRequest{
+		ConversionId: conversionID,
+		Format:       format,
 	}
-	if len(os.Args) > 3 {
-		if w, err := fmt.Sscanf(os.Args[3], "%d", &width); err != nil || w != 1 {
-			logger.Warnf("无效的宽度参数，使用默认值: %d", width)
-		}
+
+	stream, err := c.client.DownloadArchive(context.Background(), req)
+	if err != nil {
+		return 0, fmt.Errorf("调用压缩包下载服务失败: %v", err)
 	}
-	if len(os.Args) > 4 {
-		if h, err := fmt.Sscanf(os.Args[4], "%d", &height); err != nil || h != 1 {
-			logger.Warnf("无效的高度参数，使用默认值: %d", height)
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return 0, fmt.Errorf("创建输出文件失败: %v", err)
+	}
+	defer file.Close()
+
+	var written int64
+	progress := newProgressReporter(c.DownloadProgress, 0)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			os.Remove(outputPath)
+			return written, fmt.Errorf("接收压缩包失败: %v", err)
+		}
+
+		switch response := resp.Response.(type) {
+		case *proto.DownloadResponse_Info:
+			progress.info(response.Info.Filename, response.Info.FileSize)
+		case *proto.DownloadResponse_Chunk:
+			n, err := file.Write(response.Chunk)
+			written += int64(n)
+			if err != nil {
+				return written, fmt.Errorf("写入文件失败: %v", err)
+			}
+			progress.add(n)
 		}
 	}
+	return written, nil
+}
 
-	// 检查PPT文件是否存在
-	if _, err := os.Stat(pptPath); os.IsNotExist(err) {
-		logger.Fatalf("PPT文件不存在: %s", pptPath)
+// DeleteConversion 删除转换的输出，返回删除的图片数量
+func (c *PPTClient) DeleteConversion(conversionID string) (int32, error) {
+	req := &proto.DeleteRequest{
+		ConversionId: conversionID,
 	}
 
-	logger.Infof("PPT转换客户端启动")
-	logger.Infof("服务器地址: localhost:50051")
-	logger.Infof("PPT文件: %s", pptPath)
-	logger.Infof("输出目录: %s", outputDir)
-	logger.Infof("输出尺寸: %dx%d", width, height)
+	resp, err := c.client.DeleteConversion(context.Background(), req)
+	if err != nil {
+		return 0, fmt.Errorf("删除转换失败: %v", err)
+	}
 
-	// 创建客户端
-	client, err := NewPPTClient("localhost:50051", logger)
+	return resp.DeletedImages, nil
+}
+
+// RetrySlide 重新渲染已结束转换中的单张幻灯片，options 为空时使用原转换的选项，返回新图片的信息
+func (c *PPTClient) RetrySlide(conversionID string, slideNumber int32, options *proto.ConvertPPTRequest) (*proto.ImageInfo, error) {
+	req := &proto.RetrySlideRequest{
+		ConversionId: conversionID,
+		SlideNumber:  slideNumber,
+		Options:      options,
+	}
+
+	info, err := c.client.RetrySlide(context.Background(), req)
 	if err != nil {
-		logger.Fatalf("创建客户端失败: %v", err)
+		return nil, fmt.Errorf("重新渲染幻灯片失败: %v", err)
 	}
-	defer client.Close()
 
-	// 执行转换
-	startTime := time.Now()
-	if err := client.ConvertPPT(pptPath, outputDir, width, height); err != nil {
-		logger.Fatalf("转换失败: %v", err)
+	return info, nil
+}
+
+func main() {
+	// 设置日志
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
 	}
 
-	duration := time.Since(startTime)
-	logger.Infof("转换完成，耗时: %v", duration)
+	// 第一个参数不是子命令时按 convert 处理，兼容旧的用法
+	args := os.Args[1:]
+	cmd := findCommand(args[0])
+	switch {
+	case cmd != nil:
+		args = args[1:]
+	case args[0] == "-h" || args[0] == "--help" || args[0] == "help":
+		printUsage()
+		return
+	default:
+		cmd = &commands[0]
+	}
+
+	logger.Infof("PPT转换客户端启动")
+	if err := cmd.run(cmd, args, logger); err != nil {
+		logger.Fatalf("%s失败: %v", cmd.name, err)
+	}
 }
@@ -0,0 +1,50 @@
+package main
+
+import "github.com/sirupsen/logrus"
+
+// downloadProgressInterval 两次报告下载进度之间至少接收的字节数
+const downloadProgressInterval = 4 << 20
+
+// DownloadProgress 下载进度回调，received 为已接收的字节数 (包括续传前已写入的部分)，
+// total 为 DownloadInfo 中的文件大小，未知时 (如压缩包) 为0
+type DownloadProgress func(filename string, received, total int64)
+
+// logDownloadProgress 以Info级别记录下载进度的回调
+func logDownloadProgress(logger *logrus.Logger) DownloadProgress {
+	return func(filename string, received, total int64) {
+		if total > 0 {
+			logger.Infof("下载 %s: %d/%d 字节 (%d%%)", filename, received, total, received*100/total)
+			return
+		}
+		logger.Infof("下载 %s: 已接收 %d 字节", filename, received)
+	}
+}
+
+// progressReporter 累计一次下载接收的字节数，每接收 downloadProgressInterval 字节调用一次回调
+type progressReporter struct {
+	callback DownloadProgress
+	filename string
+	total    int64
+	received int64
+	reported int64
+}
+
+// newProgressReporter 从 offset (续传时已写入的字节数) 开始累计
+func newProgressReporter(callback DownloadProgress, offset int64) *progressReporter {
+	return &progressReporter{callback: callback, received: offset, reported: offset}
+}
+
+// info 记录 DownloadInfo 中的文件名和大小
+func (p *progressReporter) info(filename string, total int64) {
+	p.filename, p.total = filename, total
+}
+
+// add 累计接收的字节数，需要时报告进度
+func (p *progressReporter) add(n int) {
+	p.received += int64(n)
+	if p.callback == nil || p.received-p.reported < downloadProgressInterval {
+		return
+	}
+	p.reported = p.received
+	p.callback(p.filename, p.received, p.total)
+}
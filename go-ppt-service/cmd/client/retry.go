@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryOptions 连接和下载的重试选项
+type RetryOptions struct {
+	MaxAttempts    int           // 最大尝试次数 (包括第一次)
+	DialTimeout    time.Duration // 每次连接的超时时间
+	InitialBackoff time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff     time.Duration // 最长等待时间
+}
+
+// DefaultRetryOptions 默认重试选项
+var DefaultRetryOptions = RetryOptions{
+	MaxAttempts:    5,
+	DialTimeout:    10 * time.Second,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+}
+
+// backoff 第 attempt 次重试前的等待时间 (attempt 从1开始)
+func (r RetryOptions) backoff(attempt int) time.Duration {
+	d := r.InitialBackoff
+	for i := 1; i < attempt && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	return d
+}
+
+// serviceConfig gRPC内置重试策略，对一元调用和尚未收到响应的流在 UNAVAILABLE 时自动重试
+func (r RetryOptions) serviceConfig() string {
+	attempts := r.MaxAttempts
+	// gRPC限制 maxAttempts 在2到5之间
+	if attempts < 2 {
+		attempts = 2
+	}
+	if attempts > 5 {
+		attempts = 5
+	}
+
+	return fmt.Sprintf(`{
+	"methodConfig": [{
+		"name": [{"service": "ppt_service.PPTToImagesService"}],
+		"retryPolicy": {
+			"maxAttempts": %d,
+			"initialBackoff": "%.3fs",
+			"maxBackoff": "%.3fs",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`, attempts, r.InitialBackoff.Seconds(), r.MaxBackoff.Seconds())
+}
+
+// isTransient 是否为可重试的临时错误
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"ppt-to-images-service/proto"
+)
+
+// uploadChunkSize ConvertAndStream 上传PPT的分块大小
+const uploadChunkSize = 64 * 1024
+
+// ConvertAndStream 在一次调用中上传PPT并直接接收所有图片，不需要单独下载
+func (c *PPTClient) ConvertAndStream(pptPath string, outputDir string, options ConvertOptions) error {
+	pptData, err := os.ReadFile(pptPath)
+	if err != nil {
+		return fmt.Errorf("读取PPT文件失败: %v", err)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %v", err)
+	}
+
+	filename := filepath.Base(pptPath)
+	c.logger.Infof("开始转换PPT文件 (单次调用): %s", filename)
+
+	header := &proto.ConvertPPTRequest{
+		Filename:     filename,
+		Width:        options.Width,
+		Height:       options.Height,
+		OutputFormat: options.Format,
+	}
+	for _, fontPath := range options.Fonts {
+		fontData, err := os.ReadFile(fontPath)
+		if err != nil {
+			return fmt.Errorf("读取字体文件失败: %v", err)
+		}
+		header.Fonts = append(header.Fonts, &proto.FontFile{
+			Filename: filepath.Base(fontPath),
+			Data:     fontData,
+		})
+	}
+
+	stream, err := c.client.ConvertAndStream(context.Background())
+	if err != nil {
+		return fmt.Errorf("调用转换服务失败: %v", err)
+	}
+
+	// 先发送header，再分块发送文件数据，最后关闭发送方向
+	if err := stream.Send(&proto.ConvertAndStreamRequest{
+		Request: &proto.ConvertAndStreamRequest_Header{Header: header},
+	}); err != nil {
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+	for offset := 0; offset < len(pptData); offset += uploadChunkSize {
+		end := offset + uploadChunkSize
+		if end > len(pptData) {
+			end = len(pptData)
+		}
+		if err := stream.Send(&proto.ConvertAndStreamRequest{
+			Request: &proto.ConvertAndStreamRequest_Chunk{Chunk: pptData[offset:end]},
+		}); err != nil {
+			return fmt.Errorf("上传文件失败: %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return fmt.Errorf("上传文件失败: %v", err)
+	}
+
+	var file *os.File
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("接收响应失败: %v", err)
+		}
+
+		switch response := resp.Response.(type) {
+		case *proto.ConvertAndStreamResponse_Status:
+			status := response.Status
+			c.logger.Infof("[%s] %s (%d%%) - %d/%d",
+				status.Status,
+				status.Message,
+				status.Progress,
+				status.ProcessedSlides,
+				status.TotalSlides)
+
+		case *proto.ConvertAndStreamResponse_ImageInfo:
+			// 每张图片的数据块紧跟在它的图片信息之后
+			file, err = os.Create(filepath.Join(outputDir, response.ImageInfo.Filename))
+			if err != nil {
+				return fmt.Errorf("创建输出文件失败: %v", err)
+			}
+
+		case *proto.ConvertAndStreamResponse_ImageChunk:
+			chunk := response.ImageChunk
+			if file == nil {
+				return fmt.Errorf("收到没有图片信息的数据块: %s", chunk.Filename)
+			}
+			if _, err := file.Write(chunk.Data); err != nil {
+				return fmt.Errorf("写入文件失败: %v", err)
+			}
+			if chunk.Last {
+				if err := file.Close(); err != nil {
+					return fmt.Errorf("写入文件失败: %v", err)
+				}
+				file = nil
+				c.logger.Infof("已接收图片: 幻灯片 %d - %s", chunk.SlideNumber, chunk.Filename)
+			}
+
+		case *proto.ConvertAndStreamResponse_Result:
+			result := response.Result
+			if !result.Success {
+				return fmt.Errorf("转换失败: %s", result.Error)
+			}
+			c.logger.Infof("转换成功: %s", result.Message)
+			c.logger.Infof("总共转换了 %d/%d 张幻灯片", result.ConvertedSlides, result.TotalSlides)
+		}
+	}
+}
@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"math"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip" // 注册gzip压缩，客户端可选择启用
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 
+	"ppt-to-images-service/internal/converter"
 	"ppt-to-images-service/internal/server"
 	"ppt-to-images-service/proto"
 )
@@ -21,8 +30,55 @@ func main() {
 	var (
 		port      = flag.String("port", "50051", "gRPC服务端口")
 		outputDir = flag.String("output", "./output", "输出目录")
+		defWidth  = flag.Int("default-width", 1920, "请求不指定尺寸时的输出宽度")
+		defHeight = flag.Int("default-height", 1080, "请求不指定尺寸时的输出高度")
+		defFormat = flag.String("default-format", "PNG", "请求不指定格式时的输出格式 (PNG, JPEG, AUTO)")
 		tempDir   = flag.String("temp", "./temp", "临时目录")
 		logLevel  = flag.String("log-level", "info", "日志级别 (debug, info, warn, error)")
+		poolSize  = flag.Int("ppt-pool-size", 2, "常驻PowerPoint实例数量 (仅Windows, 0表示每次转换启动新进程)")
+		poolUses  = flag.Int("ppt-pool-max-uses", 20, "单个PowerPoint实例最多处理的转换次数，超过后回收重建 (仅Windows)")
+		loProfile = flag.String("lo-profile-dir", "", "LibreOffice临时用户配置的父目录 (默认使用系统临时目录)")
+		renderJob = flag.Int("render-workers", 0, "单个PPT并行渲染的页数 (仅LibreOffice, 0表示使用CPU核数)")
+		encodeJob = flag.Int("encode-workers", 0, "所有转换同时进行图片后处理 (合成、编码、压缩等) 的协程数量 (0表示使用CPU核数)")
+		maxProcs  = flag.Int("max-render-procs", 0, "所有转换同时运行的外部渲染进程 (soffice、pdftoppm等) 数量上限，超过时等待 (0表示不限制)")
+		fontsDir  = flag.String("fonts-dir", "", "渲染时额外加载的字体目录 (仅LibreOffice)")
+		autoColor = flag.Int("auto-color-count", 4096, "AUTO格式: 采样颜色数达到该值才可能选择JPEG")
+		autoEntr  = flag.Float64("auto-entropy", 6.5, "AUTO格式: 亮度熵 (0-8) 达到该值才可能选择JPEG")
+		maxUpload = flag.Int64("max-upload-size", 100<<20, "上传文件大小上限 (字节, 0表示不限制)")
+		maxMsg    = flag.Int("max-message-size", 128<<20, "gRPC单条消息大小上限 (字节)，需大于上传文件大小上限")
+		maxSlides = flag.Int("max-slides", 0, "单个演示文稿的最大幻灯片数量 (0表示不限制)")
+		maxPixels = flag.Int64("max-pixels", 7680*4320, "单张输出图片的最大像素数 (宽*高, 0表示不限制)")
+		httpPort  = flag.String("http-port", "", "HTTP网关端口 (为空时不启用)")
+		maxConv   = flag.Int("max-conversions", runtime.NumCPU(), "同时执行的转换数量上限 (0表示不限制)")
+		queueSize = flag.Int("queue-size", 100, "异步转换队列长度，队列满时拒绝新的提交")
+		queueAge  = flag.Duration("queue-aging", 30*time.Second, "排队任务每等待该时间有效优先级提高一级，避免低优先级任务一直等待")
+		workers   = flag.Int("workers", 2, "处理异步转换任务的协程数量")
+		webhook   = flag.String("webhook-url", "", "转换结束时POST JSON事件的地址 (为空时不发送)")
+		hookKey   = flag.String("webhook-secret", "", "webhook签名密钥，设置后在 X-PPT-Signature 头中附带 HMAC-SHA256 签名")
+		drainWait = flag.Duration("shutdown-timeout", 60*time.Second, "关闭时取消进行中的转换后等待其结束的最长时间，超时后强制结束残留的外部进程")
+		layout    = flag.String("output-layout", "", "输出子目录模板，如 {tenant}/{date}/{conversion_id} (为空时使用 session_<时间戳>)")
+		outputTTL = flag.Duration("output-ttl", 0, "输出目录保留时间，超过后自动删除 (0表示不清理)")
+		dlCleanup = flag.Bool("cleanup-after-download", false, "成功转换的图片全部下载后立即删除输出目录 (没有全部下载的由 -output-ttl 清理)")
+		maxMemory = flag.Int64("max-memory-bytes", 0, "同时进行的转换估算内存占用上限 (字节, 0表示不限制)，超出时新的转换等待")
+		fetchHost = flag.String("allowed-fetch-hosts", "", "允许通过 source_url 下载演示文稿的主机，逗号分隔，支持 *.example.com (为空时不允许)")
+		fetchWait = flag.Duration("fetch-timeout", 30*time.Second, "下载 source_url 的超时时间")
+		blankRate = flag.Float64("blank-threshold", 0.995, "同一颜色的采样像素占比达到该值时把幻灯片标记为 low_confidence (0表示不检查)")
+		minImage  = flag.Int64("min-image-size", 100, "渲染出的幻灯片图片的最小字节数，更小或无法解码的图片按该幻灯片渲染失败处理")
+		fallbacks = flag.String("fallback-backends", "", "主渲染后端失败或幻灯片空白 (见 -blank-threshold) 时依次尝试的备用后端，逗号分隔 (如 placeholder)")
+		watermark = flag.String("placeholder-watermark", converter.DefaultPlaceholderWatermark, "未安装LibreOffice时占位图片中央绘制的文字 (只支持拉丁字符)")
+		iccFile   = flag.String("icc-profile", "", "请求设置 embed_color_profile 时写入图片的ICC配置文件，如sRGB配置文件 (为空时不支持)")
+		maxOutput = flag.Int64("max-output-bytes", 0, "输出目录的总大小上限 (字节)，预计超过时拒绝新的转换并返回 ResourceExhausted (0表示不限制)")
+		convWait  = flag.Duration("conversion-timeout", 0, "单次转换的时间上限，超过后结束外部进程并返回 DeadlineExceeded (0表示不限制)")
+		dlIdle    = flag.Duration("download-idle-timeout", 2*time.Minute, "下载时客户端读取一块数据的最长等待时间，超过后中止下载并关闭文件 (0表示不限制)")
+		maxInline = flag.Int64("max-inline-size", 4<<20, "请求设置 inline_images 时一次转换内联返回的图片数据总大小上限 (字节)")
+		keepUp    = flag.Bool("keep-uploads", false, "转换结束后把上传的PPT保留在 <output>/uploads/<转换ID>/ 中，便于排查问题")
+		kaTime    = flag.Duration("keepalive-time", 60*time.Second, "连接空闲该时间后服务器发送keepalive ping，避免NAT/防火墙断开空闲连接")
+		kaWait    = flag.Duration("keepalive-timeout", 20*time.Second, "keepalive ping 等待响应的时间，超时后关闭连接")
+		kaMinTime = flag.Duration("keepalive-min-time", 30*time.Second, "允许客户端发送keepalive ping的最小间隔，更频繁时服务器断开连接")
+		kaIdle    = flag.Bool("keepalive-permit-without-stream", true, "允许客户端在没有进行中的调用时发送keepalive ping")
+		maxStream = flag.Uint("max-concurrent-streams", 0, "每个HTTP/2连接上同时进行的gRPC调用 (流) 数量上限，超出的调用在客户端等待 (0表示不限制)")
+		accessLog = flag.Bool("access-log", false, "记录每次gRPC调用的方法、客户端地址、租户、耗时和状态码 (错误按严重程度记为warn或error)")
+		selfTest  = flag.Bool("self-test", false, "用内置的示例演示文稿走一遍转换、下载流程后退出，报告每一步的结果和耗时 (失败时退出码为1)")
 	)
 	flag.Parse()
 
@@ -52,11 +108,133 @@ func main() {
 	logger.Infof("临时目录: %s", *tempDir)
 	logger.Infof("日志级别: %s", *logLevel)
 
+	if err := server.ValidateOutputDefaults(*defWidth, *defHeight, *defFormat, *maxPixels); err != nil {
+		logger.Fatalf("无效的默认输出设置: %v", err)
+	}
+	logger.Infof("默认输出: %dx%d %s", *defWidth, *defHeight, strings.ToUpper(*defFormat))
+
+	if err := server.ValidateOutputLayout(*layout); err != nil {
+		logger.Fatalf("无效的 -output-layout: %v", err)
+	}
+	if *layout != "" {
+		logger.Infof("输出目录模板: %s", *layout)
+	}
+	if *outputTTL > 0 {
+		logger.Infof("输出目录保留时间: %v", *outputTTL)
+	}
+	if *convWait > 0 {
+		logger.Infof("转换时间上限: %v", *convWait)
+	}
+	if err := server.PrepareDirs(*outputDir, *tempDir); err != nil {
+		logger.Fatalf("%v", err)
+	}
+	if *iccFile != "" {
+		if err := converter.ValidateColorProfile(*iccFile); err != nil {
+			logger.Fatalf("无效的 -icc-profile: %v", err)
+		}
+		logger.Infof("ICC配置文件: %s", *iccFile)
+	}
+
+	if *maxStream > math.MaxUint32 {
+		logger.Fatalf("无效的 -max-concurrent-streams: %d", *maxStream)
+	}
+
 	// 创建gRPC服务器
-	grpcServer := grpc.NewServer()
+	serverOptions := []grpc.ServerOption{
+		grpc.MaxConcurrentStreams(uint32(*maxStream)),
+		grpc.MaxRecvMsgSize(*maxMsg),
+		grpc.MaxSendMsgSize(*maxMsg),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    *kaTime,
+			Timeout: *kaWait,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             *kaMinTime,
+			PermitWithoutStream: *kaIdle,
+		}),
+	}
+	// 访问日志在外层，记录的是panic恢复后返回的状态码
+	unaryInterceptors := []grpc.UnaryServerInterceptor{server.UnaryRecovery(logger)}
+	streamInterceptors := []grpc.StreamServerInterceptor{server.StreamRecovery(logger)}
+	if *accessLog {
+		unaryInterceptors = append([]grpc.UnaryServerInterceptor{server.UnaryAccessLog(logger)}, unaryInterceptors...)
+		streamInterceptors = append([]grpc.StreamServerInterceptor{server.StreamAccessLog(logger)}, streamInterceptors...)
+	}
+	serverOptions = append(serverOptions,
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+	grpcServer := grpc.NewServer(serverOptions...)
+	logger.Infof("gRPC消息大小上限: %d 字节", *maxMsg)
+	logger.Infof("keepalive: 空闲 %v 后ping，超时 %v，客户端ping最小间隔 %v", *kaTime, *kaWait, *kaMinTime)
+	if *maxStream > 0 {
+		logger.Infof("每个连接的并发流上限: %d", *maxStream)
+	} else {
+		logger.Info("每个连接的并发流上限: 不限制")
+	}
+	if *maxUpload <= 0 || *maxUpload > int64(*maxMsg) {
+		logger.Warnf("上传文件大小上限 (%d) 超过gRPC消息大小上限，超出部分的gRPC上传将返回 ResourceExhausted", *maxUpload)
+	}
 	
+	if *dlCleanup && *outputTTL <= 0 {
+		logger.Warn("启用了 -cleanup-after-download 但没有设置 -output-ttl，没有全部下载的转换输出不会被清理")
+	}
+
+	// 自检时不发送webhook事件
+	if *selfTest {
+		*webhook = ""
+	}
+
 	// 创建PPT服务
-	pptService := server.NewGRPCServer(*outputDir, *tempDir, logger)
+	pptService := server.NewGRPCServer(server.Config{
+		OutputDir:            *outputDir,
+		TempDir:              *tempDir,
+		MaxUploadSize:        *maxUpload,
+		QueueSize:            *queueSize,
+		Workers:              *workers,
+		QueueAging:           *queueAge,
+		WebhookURL:           *webhook,
+		MaxConversions:       *maxConv,
+		OutputLayout:         *layout,
+		OutputTTL:            *outputTTL,
+		CleanupAfterDownload: *dlCleanup,
+		KeepUploads:          *keepUp,
+		MaxMemory:            *maxMemory,
+		AllowedFetchHosts:    server.ParseFetchHosts(*fetchHost),
+		FetchTimeout:         *fetchWait,
+		MaxInlineSize:        *maxInline,
+		ConversionTimeout:    *convWait,
+		MaxOutputBytes:       *maxOutput,
+		DownloadIdleTimeout:  *dlIdle,
+		WebhookSecret:        *hookKey,
+		DefaultWidth:         *defWidth,
+		DefaultHeight:        *defHeight,
+		DefaultFormat:        *defFormat,
+		Converter: converter.Options{
+			PoolSize:    *poolSize,
+			PoolMaxUses: *poolUses,
+
+			LibreOfficeProfileDir: *loProfile,
+			RenderWorkers:         *renderJob,
+			EncodeWorkers:         *encodeJob,
+			MaxRenderProcs:        *maxProcs,
+			FontsDir:              *fontsDir,
+			MaxPixels:             *maxPixels,
+			MaxSlides:             *maxSlides,
+			ICCProfile:            *iccFile,
+			BlankThreshold:        *blankRate,
+			PlaceholderWatermark:  *watermark,
+			FallbackBackends:      strings.Split(*fallbacks, ","),
+			MinImageSize:          *minImage,
+			AutoFormat: converter.AutoFormatThresholds{
+				ColorCount: *autoColor,
+				Entropy:    *autoEntr,
+			},
+		},
+	}, logger)
+	if *selfTest {
+		os.Exit(runSelfTest(pptService))
+	}
 	proto.RegisterPPTToImagesServiceServer(grpcServer, pptService)
 	
 	// 启用gRPC反射 (用于调试和测试)
@@ -77,6 +255,22 @@ func main() {
 		}
 	}()
 
+	// 启动HTTP网关
+	var httpServer *http.Server
+	if *httpPort != "" {
+		httpServer = &http.Server{
+			Addr:    ":" + *httpPort,
+			Handler: server.NewHTTPGateway(pptService, logger).Handler(),
+		}
+
+		go func() {
+			logger.Infof("HTTP网关启动在端口 %s", *httpPort)
+			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatalf("HTTP网关启动失败: %v", err)
+			}
+		}()
+	}
+
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -84,7 +278,48 @@ func main() {
 
 	logger.Info("收到停止信号，正在关闭服务器...")
 
-	// 优雅关闭
+	// 先取消进行中的转换并等待其结束 (超时后强制结束外部进程)，再关闭HTTP网关和gRPC服务
+	ctx, cancel := context.WithTimeout(context.Background(), *drainWait)
+	if err := pptService.Shutdown(ctx); err != nil {
+		logger.Warnf("等待转换结束失败: %v", err)
+	}
+	cancel()
+	if httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := httpServer.Shutdown(ctx); err != nil {
+			logger.Warnf("关闭HTTP网关失败: %v", err)
+		}
+		cancel()
+	}
 	grpcServer.GracefulStop()
+	if err := pptService.Close(); err != nil {
+		logger.Warnf("释放转换器资源失败: %v", err)
+	}
 	logger.Info("服务器已关闭")
 }
+
+// runSelfTest 执行自检并输出每一步的结果和耗时，返回进程退出码
+func runSelfTest(pptService *server.GRPCServer) int {
+	start := time.Now()
+	steps := pptService.SelfTest(context.Background())
+	failed := false
+	for _, step := range steps {
+		if step.Err != nil {
+			failed = true
+			fmt.Printf("FAIL  %-8s %8v  %v\n", step.Name, step.Duration.Round(time.Millisecond), step.Err)
+		} else {
+			fmt.Printf("PASS  %-8s %8v\n", step.Name, step.Duration.Round(time.Millisecond))
+		}
+	}
+	if err := pptService.Close(); err != nil {
+		fmt.Printf("释放转换器资源失败: %v\n", err)
+	}
+
+	// 某一步失败时后面的步骤不会执行，完整的自检包括转换、检查图片、下载和清理4步
+	if failed || len(steps) < 4 {
+		fmt.Printf("自检失败 (共 %v)\n", time.Since(start).Round(time.Millisecond))
+		return 1
+	}
+	fmt.Printf("自检通过 (共 %v)\n", time.Since(start).Round(time.Millisecond))
+	return 0
+}
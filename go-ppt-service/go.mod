@@ -5,12 +5,12 @@ go 1.21
 require (
 	github.com/disintegration/imaging v1.6.2
 	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/image v0.13.0
 	google.golang.org/grpc v1.59.0
 )
 
 require (
 	github.com/golang/protobuf v1.5.3 // indirect
-	golang.org/x/image v0.13.0 // indirect
 	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
@@ -0,0 +1,21 @@
+package converter
+
+// 动画的渲染方式
+// LibreOffice导出PDF时不执行动画，只支持 AnimationFinal，其他模式会退回为 AnimationFinal
+const (
+	// AnimationFinal 渲染所有动画播放完成后的样子 (默认)
+	AnimationFinal = "final"
+	// AnimationFirst 渲染第一次单击之前的样子
+	AnimationFirst = "first"
+	// AnimationAllBuilds 每个构建步骤输出一张图片，文件名为 slide_001_b00.png 格式
+	AnimationAllBuilds = "all_builds"
+)
+
+// slideCount 统计图片列表中不同幻灯片的数量，同一张幻灯片可能有多个构建步骤或分块
+func slideCount(images []ImageInfo) int {
+	slides := make(map[int]bool, len(images))
+	for _, image := range images {
+		slides[image.SlideNumber] = true
+	}
+	return len(slides)
+}
@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// DefaultAutoCropTolerance 自动裁剪的默认颜色容差 (每通道 0-255)
+	DefaultAutoCropTolerance = 8
+	// autoCropMinAreaRatio 裁剪后保留的面积低于原图的该比例时放弃裁剪，避免把纯色幻灯片裁成一小块
+	autoCropMinAreaRatio = 0.5
+)
+
+// AutoCropOptions 自动裁剪四周纯色边距的选项
+type AutoCropOptions struct {
+	Tolerance int // 与边距颜色的每通道差值不超过该值视为同一颜色，0使用默认值
+}
+
+// CropBox 自动裁剪保留的区域，坐标相对于渲染出的幻灯片 (旋转和加边框之前)
+type CropBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// autoCrop 裁掉与左上角颜色相同的四周边距，没有边距或裁剪过多时返回原图和nil
+func (c *PPTConverter) autoCrop(img image.Image, slideNumber int, options *AutoCropOptions) (image.Image, *CropBox) {
+	if options == nil {
+		return img, nil
+	}
+	tolerance := options.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultAutoCropTolerance
+	}
+
+	src := imaging.Clone(img)
+	bounds := src.Bounds()
+	content := uniformMargins(src, tolerance)
+	if content == bounds {
+		return img, nil
+	}
+
+	area := float64(content.Dx()*content.Dy()) / float64(bounds.Dx()*bounds.Dy())
+	if area < autoCropMinAreaRatio {
+		c.logger.Warnf("第 %d 张幻灯片自动裁剪后只剩 %.0f%% 的面积，可能是纯色幻灯片，不裁剪", slideNumber, area*100)
+		return img, nil
+	}
+
+	c.logger.Debugf("第 %d 张幻灯片自动裁剪: %dx%d -> %dx%d", slideNumber, bounds.Dx(), bounds.Dy(), content.Dx(), content.Dy())
+	return imaging.Crop(src, content), &CropBox{
+		X:      content.Min.X - bounds.Min.X,
+		Y:      content.Min.Y - bounds.Min.Y,
+		Width:  content.Dx(),
+		Height: content.Dy(),
+	}
+}
+
+// uniformMargins 以左上角像素为边距颜色，从四边向内跳过全部为该颜色的行和列，返回剩余区域
+// 整张图片都是同一颜色时返回空区域
+func uniformMargins(img *image.NRGBA, tolerance int) image.Rectangle {
+	bounds := img.Bounds()
+	ref := img.Pix[0:4]
+
+	matches := func(x, y int) bool {
+		offset := img.PixOffset(x, y)
+		for i := 0; i < 4; i++ {
+			diff := int(img.Pix[offset+i]) - int(ref[i])
+			if diff < -tolerance || diff > tolerance {
+				return false
+			}
+		}
+		return true
+	}
+	rowUniform := func(y, minX, maxX int) bool {
+		for x := minX; x < maxX; x++ {
+			if !matches(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+	columnUniform := func(x, minY, maxY int) bool {
+		for y := minY; y < maxY; y++ {
+			if !matches(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+
+	top := bounds.Min.Y
+	for top < bounds.Max.Y && rowUniform(top, bounds.Min.X, bounds.Max.X) {
+		top++
+	}
+	if top == bounds.Max.Y {
+		return image.Rectangle{}
+	}
+	bottom := bounds.Max.Y
+	for rowUniform(bottom-1, bounds.Min.X, bounds.Max.X) {
+		bottom--
+	}
+	left := bounds.Min.X
+	for columnUniform(left, top, bottom) {
+		left++
+	}
+	right := bounds.Max.X
+	for columnUniform(right-1, top, bottom) {
+		right--
+	}
+
+	return image.Rect(left, top, right, bottom)
+}
@@ -0,0 +1,157 @@
+package converter
+
+import (
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// AutoFormat 按每张幻灯片的内容自动选择PNG或JPEG
+const AutoFormat = "AUTO"
+
+// autoFormatSampleSize 分析图片时每条边最多采样的像素数
+const autoFormatSampleSize = 256
+
+// AutoFormatThresholds AUTO格式的判定阈值，两项都达到时输出JPEG，否则输出PNG
+type AutoFormatThresholds struct {
+	// ColorCount 采样像素中不同颜色的数量 (每通道量化为5位)
+	ColorCount int
+	// Entropy 亮度直方图的熵 (bit，最大为8)
+	Entropy float64
+}
+
+// defaultAutoFormatThresholds 默认阈值
+// 文字和矢量图形的幻灯片颜色少、亮度集中在少数几个值上；照片颜色多且亮度分布均匀
+var defaultAutoFormatThresholds = AutoFormatThresholds{
+	ColorCount: 4096,
+	Entropy:    6.5,
+}
+
+// withDefaults 未设置的阈值使用默认值
+func (t AutoFormatThresholds) withDefaults() AutoFormatThresholds {
+	if t.ColorCount <= 0 {
+		t.ColorCount = defaultAutoFormatThresholds.ColorCount
+	}
+	if t.Entropy <= 0 {
+		t.Entropy = defaultAutoFormatThresholds.Entropy
+	}
+	return t
+}
+
+// chooseImageFormat 根据图片内容选择输出格式
+func (c *PPTConverter) chooseImageFormat(img image.Image) string {
+	colors, entropy := analyzeImage(img)
+	format := "PNG"
+	if colors >= c.autoFormat.ColorCount && entropy >= c.autoFormat.Entropy {
+		format = "JPEG"
+	}
+
+	c.logger.Debugf("AUTO格式: 颜色数 %d, 亮度熵 %.2f -> %s", colors, entropy, format)
+	return format
+}
+
+// analyzeImage 按固定步长采样，统计量化后的颜色数和亮度直方图的熵
+func analyzeImage(img image.Image) (int, float64) {
+	bounds := img.Bounds()
+	step := bounds.Dx()
+	if bounds.Dy() > step {
+		step = bounds.Dy()
+	}
+	step /= autoFormatSampleSize
+	if step < 1 {
+		step = 1
+	}
+
+	var colors [1 << 15]bool
+	var histogram [256]int
+	colorCount, total := 0, 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			r, g, b, _ := img.At(x, y).RGBA()
+
+			key := (r>>11)<<10 | (g>>11)<<5 | b>>11
+			if !colors[key] {
+				colors[key] = true
+				colorCount++
+			}
+
+			luma := (299*r + 587*g + 114*b) / 1000 >> 8
+			histogram[luma]++
+			total++
+		}
+	}
+
+	entropy := 0.0
+	for _, count := range histogram {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+
+	return colorCount, entropy
+}
+
+// applyImageFormats AUTO格式或单独指定了格式时，外部工具先以PNG渲染 (见 renderFormat)，
+// 再逐张确定格式，需要时转存为JPEG并删除PNG
+func (c *PPTConverter) applyImageFormats(images []ImageInfo, options ConversionOptions) {
+	if options.renderFormat() != "PNG" {
+		return
+	}
+
+	for i := range images {
+		format := options.slideFormat(images[i].SlideNumber - options.NumberOffset)
+		if imageExtension(format) == "png" && format != AutoFormat {
+			continue
+		}
+
+		img, err := imaging.Open(images[i].FilePath)
+		if err != nil {
+			c.logger.Warnf("读取第 %d 张幻灯片失败，保留PNG: %v", images[i].SlideNumber, err)
+			continue
+		}
+
+		if format == AutoFormat {
+			format = c.chooseImageFormat(img)
+		}
+		if imageExtension(format) != "jpg" {
+			continue
+		}
+
+		jpegPath := strings.TrimSuffix(images[i].FilePath, filepath.Ext(images[i].FilePath)) + ".jpg"
+		if err := c.saveImage(img, jpegPath, "JPEG", options.JPEGSubsampling, nil); err != nil {
+			os.Remove(jpegPath)
+			c.logger.Warnf("第 %d 张幻灯片转存JPEG失败，保留PNG: %v", images[i].SlideNumber, err)
+			continue
+		}
+
+		fileInfo, err := os.Stat(jpegPath)
+		if err != nil {
+			os.Remove(jpegPath)
+			c.logger.Warnf("获取文件信息失败: %v", err)
+			continue
+		}
+
+		os.Remove(images[i].FilePath)
+		images[i].Filename = filepath.Base(jpegPath)
+		images[i].FilePath = jpegPath
+		images[i].FileSize = fileInfo.Size()
+		images[i].Format = "JPEG"
+	}
+}
+
+// formatFromExtension 文件扩展名对应的图片格式
+func formatFromExtension(ext string) string {
+	switch strings.ToLower(strings.TrimPrefix(ext, ".")) {
+	case "jpg", "jpeg":
+		return "JPEG"
+	default:
+		return "PNG"
+	}
+}
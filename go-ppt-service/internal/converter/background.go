@@ -0,0 +1,521 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+)
+
+// 版式到母版、母版到主题的关系类型
+const (
+	slideMasterRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster"
+	themeRelType       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"
+)
+
+// backgroundSuffix 背景图片文件名在幻灯片编号之后的后缀: slide_001_bg.png
+const backgroundSuffix = "_bg"
+
+// pptxColorValue 颜色元素 (a:srgbClr、a:schemeClr、a:sysClr) 的值和亮度调整
+type pptxColorValue struct {
+	Val     string `xml:"val,attr"`
+	LastClr string `xml:"lastClr,attr"` // a:sysClr 最后计算出的颜色
+	LumMod  *struct {
+		Val int `xml:"val,attr"`
+	} `xml:"lumMod"`
+	LumOff *struct {
+		Val int `xml:"val,attr"`
+	} `xml:"lumOff"`
+}
+
+// pptxColor 包含一个颜色元素的元素，如 a:solidFill、a:gs、p:bgRef
+type pptxColor struct {
+	RGB    *pptxColorValue `xml:"srgbClr"`
+	Scheme *pptxColorValue `xml:"schemeClr"`
+	System *pptxColorValue `xml:"sysClr"`
+}
+
+// pptxBackground 幻灯片、版式或母版的背景 (p:cSld/p:bg)
+type pptxBackground struct {
+	Properties *struct {
+		Solid    *pptxColor `xml:"solidFill"`
+		Gradient *struct {
+			Stops []struct {
+				Position int `xml:"pos,attr"` // 千分之一百分比
+				pptxColor
+			} `xml:"gsLst>gs"`
+			Linear *struct {
+				Angle int `xml:"ang,attr"` // 六万分之一度，0为从左到右
+			} `xml:"lin"`
+		} `xml:"gradFill"`
+		Blip *struct {
+			Embed string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships embed,attr"`
+		} `xml:"blipFill>blip"`
+	} `xml:"bgPr"`
+	Reference *pptxColor `xml:"bgRef"`
+}
+
+// pptxBackgroundPart 幻灯片、版式和母版XML中与背景有关的部分
+type pptxBackgroundPart struct {
+	Background *pptxBackground `xml:"cSld>bg"`
+	// ColorMap 母版的颜色映射 (如 bg1="lt1")，幻灯片和版式中为空
+	ColorMap struct {
+		Attrs []xml.Attr `xml:",any,attr"`
+	} `xml:"clrMap"`
+}
+
+// gradientStop 渐变中的一个颜色点，Position 为0到1
+type gradientStop struct {
+	Position float64
+	Color    color.RGBA
+}
+
+// slideBackground 从PPTX读取的一张幻灯片的背景，Image、Stops、Fill 按顺序取第一个设置的
+type slideBackground struct {
+	// Image 图片填充的背景，拉伸到整张幻灯片
+	Image image.Image
+	// Stops/Angle 线性渐变的颜色点和方向 (弧度，0为从左到右，顺时针)
+	Stops []gradientStop
+	Angle float64
+	// Fill 纯色背景
+	Fill color.RGBA
+}
+
+// render 按指定尺寸绘制背景
+func (b *slideBackground) render(width, height int) image.Image {
+	if b.Image != nil {
+		return imaging.Resize(b.Image, width, height, imaging.Lanczos)
+	}
+	if len(b.Stops) == 0 {
+		return imaging.New(width, height, b.Fill)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	dx, dy := math.Cos(b.Angle), math.Sin(b.Angle)
+	// 画布四角在渐变方向上的投影范围对应0到1
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, corner := range [][2]float64{{0, 0}, {float64(width), 0}, {0, float64(height)}, {float64(width), float64(height)}} {
+		p := corner[0]*dx + corner[1]*dy
+		low, high = math.Min(low, p), math.Max(high, p)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			t := 0.0
+			if high > low {
+				t = ((float64(x)+0.5)*dx + (float64(y)+0.5)*dy - low) / (high - low)
+			}
+			canvas.SetRGBA(x, y, gradientColor(b.Stops, t))
+		}
+	}
+	return canvas
+}
+
+// gradientColor 渐变在位置 t 的颜色，颜色点按位置排好序
+func gradientColor(stops []gradientStop, t float64) color.RGBA {
+	if t <= stops[0].Position {
+		return stops[0].Color
+	}
+	for i := 1; i < len(stops); i++ {
+		if t > stops[i].Position {
+			continue
+		}
+		from, to := stops[i-1], stops[i]
+		f := 0.0
+		if to.Position > from.Position {
+			f = (t - from.Position) / (to.Position - from.Position)
+		}
+		mix := func(a, b uint8) uint8 {
+			return uint8(math.Round(float64(a) + (float64(b)-float64(a))*f))
+		}
+		return color.RGBA{R: mix(from.Color.R, to.Color.R), G: mix(from.Color.G, to.Color.G), B: mix(from.Color.B, to.Color.B), A: 0xff}
+	}
+	return stops[len(stops)-1].Color
+}
+
+// backgroundReader 读取PPTX中的背景，同一母版的颜色映射和主题只解析一次
+type backgroundReader struct {
+	reader  *zip.Reader
+	schemes map[string]map[string]color.RGBA // 母版路径 -> 颜色映射后的主题颜色 (bg1、accent1 等)
+}
+
+// relatedPart 按关系类型找到部件引用的第一个部件，没有时返回空字符串
+func (r *backgroundReader) relatedPart(partPath, relType string) (string, error) {
+	dir, file := path.Split(partPath)
+
+	var rels struct {
+		Relationships []struct {
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := readZipXML(r.reader, path.Join(dir, "_rels", file+".rels"), &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.Type == relType {
+			return path.Join(dir, rel.Target), nil
+		}
+	}
+	return "", nil
+}
+
+// colorScheme 母版的主题颜色，按母版的 clrMap 加上 bg1、tx1 等别名
+func (r *backgroundReader) colorScheme(masterPath string, master pptxBackgroundPart) (map[string]color.RGBA, error) {
+	if scheme, ok := r.schemes[masterPath]; ok {
+		return scheme, nil
+	}
+
+	scheme := make(map[string]color.RGBA)
+	if masterPath == "" {
+		return scheme, nil
+	}
+	themePath, err := r.relatedPart(masterPath, themeRelType)
+	if err != nil {
+		return nil, err
+	}
+	if themePath != "" {
+		var theme struct {
+			Scheme struct {
+				Colors []struct {
+					XMLName xml.Name
+					pptxColor
+				} `xml:",any"`
+			} `xml:"themeElements>clrScheme"`
+		}
+		if err := readZipXML(r.reader, themePath, &theme); err != nil {
+			return nil, err
+		}
+		for _, entry := range theme.Scheme.Colors {
+			if c, ok := resolveColor(entry.pptxColor, nil); ok {
+				scheme[entry.XMLName.Local] = c
+			}
+		}
+	}
+	for _, attr := range master.ColorMap.Attrs {
+		if c, ok := scheme[attr.Value]; ok {
+			scheme[attr.Name.Local] = c
+		}
+	}
+
+	r.schemes[masterPath] = scheme
+	return scheme, nil
+}
+
+// read 读取幻灯片的背景: 幻灯片没有设置时依次使用版式和母版的背景，都没有时为白色
+// 返回 nil 表示背景无法提取 (如图案填充、无法解码的图片)
+func (r *backgroundReader) read(slidePath string) (*slideBackground, error) {
+	layoutPath, err := r.relatedPart(slidePath, slideLayoutRelType)
+	if err != nil {
+		return nil, err
+	}
+	var masterPath string
+	if layoutPath != "" {
+		if masterPath, err = r.relatedPart(layoutPath, slideMasterRelType); err != nil {
+			return nil, err
+		}
+	}
+
+	var master pptxBackgroundPart
+	if masterPath != "" {
+		if err := readZipXML(r.reader, masterPath, &master); err != nil {
+			return nil, err
+		}
+	}
+	scheme, err := r.colorScheme(masterPath, master)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, partPath := range []string{slidePath, layoutPath, masterPath} {
+		if partPath == "" {
+			continue
+		}
+		part := master
+		if partPath != masterPath {
+			part = pptxBackgroundPart{}
+			if err := readZipXML(r.reader, partPath, &part); err != nil {
+				return nil, err
+			}
+		}
+		if part.Background != nil {
+			return r.resolve(partPath, part.Background, scheme)
+		}
+	}
+	return &slideBackground{Fill: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}}, nil
+}
+
+// resolve 把背景元素换算为可以绘制的背景，partPath 为定义背景的部件，用于找到背景图片
+// p:bgRef 引用主题中的背景填充样式，按其颜色的纯色近似
+func (r *backgroundReader) resolve(partPath string, background *pptxBackground, scheme map[string]color.RGBA) (*slideBackground, error) {
+	if background.Reference != nil {
+		if c, ok := resolveColor(*background.Reference, scheme); ok {
+			return &slideBackground{Fill: c}, nil
+		}
+		return nil, nil
+	}
+
+	properties := background.Properties
+	switch {
+	case properties == nil:
+		return nil, nil
+	case properties.Solid != nil:
+		if c, ok := resolveColor(*properties.Solid, scheme); ok {
+			return &slideBackground{Fill: c}, nil
+		}
+	case properties.Gradient != nil:
+		// 路径 (射线、矩形) 渐变按从上到下的线性渐变近似
+		result := &slideBackground{Angle: math.Pi / 2}
+		if properties.Gradient.Linear != nil {
+			result.Angle = float64(properties.Gradient.Linear.Angle) / 60000 * math.Pi / 180
+		}
+		for _, stop := range properties.Gradient.Stops {
+			c, ok := resolveColor(stop.pptxColor, scheme)
+			if !ok {
+				return nil, nil
+			}
+			result.Stops = append(result.Stops, gradientStop{Position: float64(stop.Position) / 100000, Color: c})
+		}
+		if len(result.Stops) == 0 {
+			return nil, nil
+		}
+		sort.SliceStable(result.Stops, func(i, j int) bool { return result.Stops[i].Position < result.Stops[j].Position })
+		return result, nil
+	case properties.Blip != nil:
+		targets, err := slideRelationships(r.reader, partPath)
+		if err != nil {
+			return nil, err
+		}
+		if target, ok := targets[properties.Blip.Embed]; ok {
+			if img := readZipImage(r.reader, target); img != nil {
+				return &slideBackground{Image: img}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// resolveColor 计算颜色元素的颜色，主题颜色通过 scheme 查找，找不到 (如主题样式中的 phClr) 时返回 false
+// 只处理亮度调整 (lumMod、lumOff)，其他调整 (tint、shade、alpha 等) 忽略
+func resolveColor(element pptxColor, scheme map[string]color.RGBA) (color.RGBA, bool) {
+	var (
+		value *pptxColorValue
+		c     color.RGBA
+		ok    bool
+	)
+	switch {
+	case element.RGB != nil:
+		value = element.RGB
+		c, ok = parseHexColor(value.Val)
+	case element.System != nil:
+		value = element.System
+		c, ok = parseHexColor(value.LastClr)
+	case element.Scheme != nil:
+		value = element.Scheme
+		c, ok = scheme[value.Val]
+	}
+	if !ok {
+		return color.RGBA{}, false
+	}
+
+	if value.LumMod != nil || value.LumOff != nil {
+		h, s, l := rgbToHSL(c)
+		if value.LumMod != nil {
+			l *= float64(value.LumMod.Val) / 100000
+		}
+		if value.LumOff != nil {
+			l += float64(value.LumOff.Val) / 100000
+		}
+		c = hslToRGB(h, s, math.Max(0, math.Min(1, l)))
+	}
+	return c, true
+}
+
+// parseHexColor 解析 RRGGBB 形式的颜色
+func parseHexColor(value string) (color.RGBA, bool) {
+	if len(value) != 6 {
+		return color.RGBA{}, false
+	}
+	rgb, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, true
+}
+
+// rgbToHSL 把颜色转换为色相、饱和度和亮度，都在0到1之间
+func rgbToHSL(c color.RGBA) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l = (max + min) / 2
+	if max == min {
+		return 0, 0, l
+	}
+
+	d := max - min
+	if l > 0.5 {
+		s = d / (2 - max - min)
+	} else {
+		s = d / (max + min)
+	}
+	switch max {
+	case r:
+		h = (g - b) / d
+		if g < b {
+			h += 6
+		}
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h / 6, s, l
+}
+
+// hslToRGB rgbToHSL 的逆运算
+func hslToRGB(h, s, l float64) color.RGBA {
+	if s == 0 {
+		v := uint8(math.Round(l * 255))
+		return color.RGBA{R: v, G: v, B: v, A: 0xff}
+	}
+
+	q := l * (1 + s)
+	if l >= 0.5 {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	channel := func(t float64) uint8 {
+		t -= math.Floor(t)
+		var v float64
+		switch {
+		case t < 1.0/6:
+			v = p + (q-p)*6*t
+		case t < 0.5:
+			v = q
+		case t < 2.0/3:
+			v = p + (q-p)*(2.0/3-t)*6
+		default:
+			v = p
+		}
+		return uint8(math.Round(v * 255))
+	}
+	return color.RGBA{R: channel(h + 1.0/3), G: channel(h), B: channel(h - 1.0/3), A: 0xff}
+}
+
+// readSlideBackgrounds 读取每张幻灯片 (原始编号) 的背景，无法提取的幻灯片对应 nil
+// 不是PPTX (如PPT、ODP) 时返回nil
+func readSlideBackgrounds(pptData []byte) (map[int]*slideBackground, error) {
+	if !bytes.HasPrefix(pptData, []byte("PK\x03\x04")) {
+		return nil, nil
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(pptData), int64(len(pptData)))
+	if err != nil {
+		return nil, fmt.Errorf("打开PPTX失败: %v", err)
+	}
+	if !isPPTXPackage(reader) {
+		return nil, nil
+	}
+
+	paths, err := pptxSlidePaths(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	backgrounds := &backgroundReader{reader: reader, schemes: make(map[string]map[string]color.RGBA)}
+	result := make(map[int]*slideBackground, len(paths))
+	for i, slidePath := range paths {
+		if slidePath == "" {
+			continue
+		}
+		background, err := backgrounds.read(slidePath)
+		if err != nil {
+			return nil, err
+		}
+		result[i+1] = background
+	}
+	return result, nil
+}
+
+// appendBackgrounds 请求设置 ExtractBackground 时为每张转换了的幻灯片输出只有背景的 slide_NNN_bg.png，失败时只记录日志
+// 渲染后端都不能单独导出背景，背景从PPTX的XML (幻灯片、版式、母版和主题) 中读取后按幻灯片的渲染尺寸绘制；
+// 不是PPTX或背景无法提取 (如图案填充) 的幻灯片跳过
+func (c *PPTConverter) appendBackgrounds(images []ImageInfo, outputPath string, pptData []byte, options ConversionOptions) []ImageInfo {
+	if !options.ExtractBackground {
+		return images
+	}
+
+	backgrounds, err := readSlideBackgrounds(pptData)
+	if err != nil {
+		c.logger.Warnf("读取幻灯片背景失败，不输出背景图片: %v", err)
+		return images
+	}
+	if backgrounds == nil {
+		c.logger.Infof("只能从PPTX文件读取幻灯片背景，不输出背景图片")
+		return images
+	}
+
+	done := make(map[int]bool)
+	var result []ImageInfo
+	for _, image := range images {
+		if image.SlideNumber <= 0 || done[image.SlideNumber] {
+			continue
+		}
+		done[image.SlideNumber] = true
+
+		slideNumber := image.SlideNumber - options.NumberOffset
+		background := backgrounds[slideNumber]
+		if background == nil {
+			c.logger.Infof("第 %d 张幻灯片的背景无法提取 (如图案填充)，跳过", slideNumber)
+			continue
+		}
+
+		width, height := options.Width, options.Height
+		if size, ok := options.SlideSizes[slideNumber]; ok {
+			width, height = size.Width, size.Height
+		}
+		if err := c.checkPixels(width, height); err != nil {
+			c.logger.Warnf("第 %d 张幻灯片的背景: %v", slideNumber, err)
+			continue
+		}
+
+		filename := slideFileStem(image.SlideNumber, -1) + backgroundSuffix + ".png"
+		filePath := filepath.Join(outputPath, filename)
+		if err := imaging.Save(background.render(width, height), filePath); err != nil {
+			c.logger.Warnf("保存 %s 失败: %v", filename, err)
+			continue
+		}
+		stat, err := os.Stat(filePath)
+		if err != nil {
+			c.logger.Warnf("读取 %s 失败: %v", filename, err)
+			continue
+		}
+
+		result = append(result, ImageInfo{
+			SlideNumber: image.SlideNumber,
+			Filename:    filename,
+			FilePath:    filePath,
+			FileSize:    stat.Size(),
+			DownloadID:  generateDownloadID(),
+			Format:      "PNG",
+			Width:       width,
+			Height:      height,
+			SourceHash:  image.SourceHash,
+			Background:  true,
+		})
+	}
+
+	if len(result) > 0 {
+		c.logger.Infof("输出 %d 张幻灯片的背景图片", len(result))
+	}
+	return append(images, result...)
+}
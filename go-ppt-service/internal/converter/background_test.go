@@ -0,0 +1,103 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// backgroundPPTX 生成三张幻灯片的PPTX: 第1张为红色纯色背景，第2张沿用母版的主题颜色背景 (bg2 -> lt2 绿色)，第3张为图案填充
+func backgroundPPTX(t *testing.T) []byte {
+	t.Helper()
+
+	const p = `xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"`
+	backgrounds := []string{
+		`<p:bg><p:bgPr><a:solidFill><a:srgbClr val="FF0000"/></a:solidFill></p:bgPr></p:bg>`,
+		``,
+		`<p:bg><p:bgPr><a:pattFill prst="pct5"/></p:bgPr></p:bg>`,
+	}
+
+	files := map[string][]byte{
+		"ppt/slideLayouts/slideLayout1.xml": []byte(`<p:sldLayout ` + p + `><p:cSld><p:spTree/></p:cSld></p:sldLayout>`),
+		"ppt/slideLayouts/_rels/slideLayout1.xml.rels": []byte(fmt.Sprintf(`<Relationships><Relationship Id="rId1" Type="%s" Target="../slideMasters/slideMaster1.xml"/></Relationships>`,
+			slideMasterRelType)),
+		"ppt/slideMasters/slideMaster1.xml": []byte(`<p:sldMaster ` + p + `><p:cSld><p:bg><p:bgPr><a:solidFill><a:schemeClr val="bg2"/></a:solidFill></p:bgPr></p:bg><p:spTree/></p:cSld>` +
+			`<p:clrMap bg1="lt1" tx1="dk1" bg2="lt2" tx2="dk2"/></p:sldMaster>`),
+		"ppt/slideMasters/_rels/slideMaster1.xml.rels": []byte(fmt.Sprintf(`<Relationships><Relationship Id="rId1" Type="%s" Target="../theme/theme1.xml"/></Relationships>`,
+			themeRelType)),
+		"ppt/theme/theme1.xml": []byte(`<a:theme xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><a:themeElements><a:clrScheme name="Test">` +
+			`<a:dk1><a:sysClr val="windowText" lastClr="000000"/></a:dk1><a:lt1><a:sysClr val="window" lastClr="FFFFFF"/></a:lt1>` +
+			`<a:dk2><a:srgbClr val="000080"/></a:dk2><a:lt2><a:srgbClr val="00FF00"/></a:lt2></a:clrScheme></a:themeElements></a:theme>`),
+	}
+	var slideIDs, rels strings.Builder
+	for i, background := range backgrounds {
+		fmt.Fprintf(&slideIDs, `<p:sldId id="%d" r:id="rId%d"/>`, 256+i, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Target="slides/slide%d.xml"/>`, i+1, i+1)
+		files[fmt.Sprintf("ppt/slides/slide%d.xml", i+1)] = []byte(`<p:sld ` + p + `><p:cSld>` + background + `<p:spTree/></p:cSld></p:sld>`)
+		files[fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", i+1)] = []byte(fmt.Sprintf(`<Relationships><Relationship Id="rId1" Type="%s" Target="../slideLayouts/slideLayout1.xml"/></Relationships>`,
+			slideLayoutRelType))
+	}
+	files["ppt/presentation.xml"] = []byte(`<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:sldIdLst>` +
+		slideIDs.String() + `</p:sldIdLst></p:presentation>`)
+	files["ppt/_rels/presentation.xml.rels"] = []byte("<Relationships>" + rels.String() + "</Relationships>")
+	return zipPackage(t, files)
+}
+
+func TestConvertPPTExtractBackground(t *testing.T) {
+	c, _ := newTestConverter(t, &MockRenderer{Slides: 3}, Options{})
+
+	result, err := c.ConvertPPT(context.Background(), backgroundPPTX(t), "deck.pptx", ConversionOptions{
+		Width:             320,
+		Height:            180,
+		ExtractBackground: true,
+		NumberOffset:      10,
+	}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+
+	// 3张幻灯片图片之后是第1、2张的背景，图案填充的第3张跳过
+	if len(result.Images) != 5 {
+		t.Fatalf("返回了 %d 个文件，应为5个", len(result.Images))
+	}
+	want := []struct {
+		filename string
+		color    color.NRGBA
+	}{
+		{"slide_011_bg.png", color.NRGBA{R: 0xff, A: 0xff}},
+		{"slide_012_bg.png", color.NRGBA{G: 0xff, A: 0xff}},
+	}
+	for i, w := range want {
+		image := result.Images[3+i]
+		if image.Filename != w.filename || !image.Background || image.SlideNumber != 11+i || image.Width != 320 || image.Height != 180 {
+			t.Errorf("第 %d 张背景为 %+v", i+1, image)
+			continue
+		}
+		img, err := imaging.Open(image.FilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := color.NRGBAModel.Convert(img.At(160, 90)); got != w.color {
+			t.Errorf("%s 的颜色为 %v，应为 %v", w.filename, got, w.color)
+		}
+	}
+	if result.ConvertedSlides != 3 {
+		t.Errorf("ConvertedSlides 为 %d，背景图片不应计入", result.ConvertedSlides)
+	}
+}
+
+func TestResolveColorLuminance(t *testing.T) {
+	// 白色的 lumMod 75% 为 #BFBFBF (PowerPoint "白色，背景1，深色25%")
+	element := pptxColor{Scheme: &pptxColorValue{Val: "bg1"}}
+	element.Scheme.LumMod = &struct {
+		Val int `xml:"val,attr"`
+	}{Val: 75000}
+	c, ok := resolveColor(element, map[string]color.RGBA{"bg1": {R: 0xff, G: 0xff, B: 0xff, A: 0xff}})
+	if !ok || c != (color.RGBA{R: 0xbf, G: 0xbf, B: 0xbf, A: 0xff}) {
+		t.Errorf("颜色为 %v (%v)", c, ok)
+	}
+}
@@ -0,0 +1,67 @@
+package converter
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// dominantColorRatio 按与 analyzeImage 相同的步长采样，返回出现最多的颜色 (每通道量化为5位) 所占比例
+func dominantColorRatio(img image.Image) float64 {
+	bounds := img.Bounds()
+	step := max(bounds.Dx(), bounds.Dy()) / autoFormatSampleSize
+	if step < 1 {
+		step = 1
+	}
+
+	var counts [1 << 15]int
+	dominant, total := 0, 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			r, g, b, _ := img.At(x, y).RGBA()
+			key := (r>>11)<<10 | (g>>11)<<5 | b>>11
+			counts[key]++
+			if counts[key] > dominant {
+				dominant = counts[key]
+			}
+			total++
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(dominant) / float64(total)
+}
+
+// isSuspiciouslyBlank 判断渲染结果是否几乎只有一种颜色，图表、SmartArt等未能渲染时常出现空白幻灯片
+// 阈值为0时不检查，检查应在叠加边框、编号之前进行
+func (c *PPTConverter) isSuspiciouslyBlank(img image.Image, slideNumber int) bool {
+	if c.blankLimit <= 0 {
+		return false
+	}
+
+	ratio := dominantColorRatio(img)
+	if ratio < c.blankLimit {
+		return false
+	}
+
+	c.logger.Warnf("第 %d 张幻灯片 %.1f%% 的像素为同一颜色，可能有图表或SmartArt未能渲染，请检查输出", slideNumber, ratio*100)
+	return true
+}
+
+// markBlankSlides 检查外部工具生成的图片，几乎为单一颜色时设置 LowConfidence
+func (c *PPTConverter) markBlankSlides(images []ImageInfo) {
+	if c.blankLimit <= 0 {
+		return
+	}
+
+	for i := range images {
+		img, err := imaging.Open(images[i].FilePath)
+		if err != nil {
+			c.logger.Warnf("读取第 %d 张幻灯片失败，跳过空白检查: %v", images[i].SlideNumber, err)
+			continue
+		}
+		images[i].LowConfidence = c.isSuspiciouslyBlank(img, images[i].SlideNumber)
+	}
+}
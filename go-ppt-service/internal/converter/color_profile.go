@@ -0,0 +1,140 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// iccProfileName PNG iCCP块中的配置文件名称
+const iccProfileName = "ICC profile"
+
+// jpegICCChunkSize JPEG单个APP2段最多容纳的配置文件字节数 (65535 - 长度2字节 - 标识14字节)
+const jpegICCChunkSize = 65519
+
+// loadColorProfile 读取ICC配置文件，至少包含128字节的文件头和 "acsp" 签名
+func loadColorProfile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 128 || string(data[36:40]) != "acsp" {
+		return nil, fmt.Errorf("不是有效的ICC配置文件: %s", path)
+	}
+	return data, nil
+}
+
+// ValidateColorProfile 检查ICC配置文件是否可读且格式有效，用于启动时检查 -icc-profile
+func ValidateColorProfile(path string) error {
+	_, err := loadColorProfile(path)
+	return err
+}
+
+// embedColorProfiles 在所有图片中写入服务器配置的ICC颜色配置文件，失败时只记录日志
+func (c *PPTConverter) embedColorProfiles(images []ImageInfo) {
+	if len(c.colorProfile) == 0 {
+		c.logger.Warnf("服务器未配置ICC配置文件，跳过写入颜色配置")
+		return
+	}
+
+	for i := range images {
+		data, err := os.ReadFile(images[i].FilePath)
+		if err == nil {
+			data, err = addColorProfile(data, c.colorProfile)
+		}
+		if err == nil {
+			err = os.WriteFile(images[i].FilePath, data, 0644)
+		}
+		if err != nil {
+			c.logger.Warnf("写入第 %d 张幻灯片颜色配置失败: %v", images[i].SlideNumber, err)
+			continue
+		}
+
+		images[i].FileSize = int64(len(data))
+	}
+}
+
+// addColorProfile 根据文件头判断格式，PNG写入iCCP块，JPEG写入ICC_PROFILE (APP2) 段
+func addColorProfile(data, profile []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		return addPNGColorProfile(data, profile)
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xD8:
+		return addJPEGColorProfile(data, profile)
+	default:
+		return nil, fmt.Errorf("不支持的图片格式")
+	}
+}
+
+// addPNGColorProfile 在IHDR块之后插入iCCP块: 名称\0 压缩方法(0) zlib压缩的配置文件
+// iCCP必须位于PLTE和IDAT之前，Go的PNG编码器紧跟IHDR写入PLTE或IDAT
+func addPNGColorProfile(data, profile []byte) ([]byte, error) {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if len(data) < ihdrEnd || string(data[12:16]) != "IHDR" {
+		return nil, fmt.Errorf("无效的PNG文件")
+	}
+
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(profile)
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("压缩ICC配置文件失败: %v", err)
+	}
+
+	payload := append([]byte(iccProfileName), 0, 0)
+	payload = append(payload, compressed.Bytes()...)
+
+	var chunk bytes.Buffer
+	writePNGChunk(&chunk, "iCCP", payload)
+
+	result := make([]byte, 0, len(data)+chunk.Len())
+	result = append(result, data[:ihdrEnd]...)
+	result = append(result, chunk.Bytes()...)
+	result = append(result, data[ihdrEnd:]...)
+	return result, nil
+}
+
+// addJPEGColorProfile 在SOI和已有的APP0/APP1段之后插入ICC_PROFILE段，配置文件过大时按顺序拆分为多个APP2段
+// 每段: "ICC_PROFILE\0" 序号(从1开始) 总段数 数据
+func addJPEGColorProfile(data, profile []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("无效的JPEG文件")
+	}
+
+	count := (len(profile) + jpegICCChunkSize - 1) / jpegICCChunkSize
+	if count > 255 {
+		return nil, fmt.Errorf("ICC配置文件过大")
+	}
+
+	var segments bytes.Buffer
+	for i := 0; i < count; i++ {
+		end := (i + 1) * jpegICCChunkSize
+		if end > len(profile) {
+			end = len(profile)
+		}
+		part := profile[i*jpegICCChunkSize : end]
+
+		segments.Write([]byte{0xFF, 0xE2})
+		binary.Write(&segments, binary.BigEndian, uint16(2+14+len(part)))
+		segments.WriteString("ICC_PROFILE\x00")
+		segments.Write([]byte{byte(i + 1), byte(count)})
+		segments.Write(part)
+	}
+
+	// JFIF (APP0) 和EXIF (APP1) 按惯例紧跟SOI，ICC段放在它们之后
+	offset := 2
+	for offset+4 <= len(data) && data[offset] == 0xFF && (data[offset+1] == 0xE0 || data[offset+1] == 0xE1) {
+		offset += 2 + int(binary.BigEndian.Uint16(data[offset+2:]))
+	}
+	if offset > len(data) {
+		return nil, fmt.Errorf("无效的JPEG文件")
+	}
+
+	result := make([]byte, 0, len(data)+segments.Len())
+	result = append(result, data[:offset]...)
+	result = append(result, segments.Bytes()...)
+	result = append(result, data[offset:]...)
+	return result, nil
+}
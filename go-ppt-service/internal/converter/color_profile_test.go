@@ -0,0 +1,150 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"image"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestProfile 写入指定大小的ICC配置文件: 文件头带有 "acsp" 签名，其余为随机数据
+func writeTestProfile(t *testing.T, size int) (string, []byte) {
+	t.Helper()
+
+	profile := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(profile)
+	copy(profile[36:], "acsp")
+	path := filepath.Join(t.TempDir(), "test.icc")
+	if err := os.WriteFile(path, profile, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, profile
+}
+
+// pngColorProfile 读取PNG iCCP块中的配置文件并解压
+func pngColorProfile(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	for _, chunk := range readPNGChunks(t, data) {
+		if chunk.Type != "iCCP" {
+			continue
+		}
+		// 名称\0 压缩方法 zlib数据
+		name, rest, _ := bytes.Cut(chunk.Data, []byte{0})
+		if string(name) != iccProfileName || rest[0] != 0 {
+			t.Fatalf("iCCP块的名称为 %q，压缩方法为 %d", name, rest[0])
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(rest[1:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		profile, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return profile
+	}
+	t.Fatal("没有iCCP块")
+	return nil
+}
+
+// jpegColorProfile 按序号拼接JPEG所有ICC_PROFILE (APP2) 段中的配置文件，并返回这些段在标记段中的位置
+func jpegColorProfile(t *testing.T, data []byte) ([]byte, []int) {
+	t.Helper()
+
+	var parts [][]byte
+	var positions []int
+	for i, segment := range readJPEGSegments(t, data) {
+		if segment.Marker != 0xE2 || !bytes.HasPrefix(segment.Data, []byte("ICC_PROFILE\x00")) {
+			continue
+		}
+		sequence, count := int(segment.Data[12]), int(segment.Data[13])
+		if parts == nil {
+			parts = make([][]byte, count)
+		}
+		if sequence < 1 || sequence > len(parts) || count != len(parts) {
+			t.Fatalf("ICC_PROFILE段的序号为 %d/%d", sequence, count)
+		}
+		parts[sequence-1] = segment.Data[14:]
+		positions = append(positions, i)
+	}
+	if len(positions) != len(parts) {
+		t.Fatalf("找到 %d 个ICC_PROFILE段，应为 %d 个", len(positions), len(parts))
+	}
+	return bytes.Join(parts, nil), positions
+}
+
+func TestConvertPPTEmbedColorProfile(t *testing.T) {
+	tests := []struct {
+		format   string
+		size     int
+		segments int // JPEG的APP2段数量
+	}{
+		{"PNG", 3144, 0},
+		{"JPEG", 3144, 1},
+		{"PNG", 2*jpegICCChunkSize + 100, 0},
+		{"JPEG", 2*jpegICCChunkSize + 100, 3}, // 超过单段容量，拆分为多段
+	}
+
+	for _, tt := range tests {
+		path, profile := writeTestProfile(t, tt.size)
+		c, _ := newTestConverter(t, &MockRenderer{Slides: 1}, Options{ICCProfile: path})
+		result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{
+			OutputFormat:      tt.format,
+			EmbedColorProfile: true,
+			EmbedMetadata:     true,
+		}, nil)
+		if err != nil {
+			t.Fatalf("%s %d 字节: ConvertPPT 失败: %v", tt.format, tt.size, err)
+		}
+
+		data, err := os.ReadFile(result.Images[0].FilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info := result.Images[0]; info.FileSize != int64(len(data)) {
+			t.Errorf("%s %d 字节: 记录的文件大小为 %d，实际为 %d", tt.format, tt.size, info.FileSize, len(data))
+		}
+		if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+			t.Errorf("%s %d 字节: 写入配置文件后无法解码: %v", tt.format, tt.size, err)
+		}
+
+		if tt.format == "PNG" {
+			if embedded := pngColorProfile(t, data); !bytes.Equal(embedded, profile) {
+				t.Errorf("%s %d 字节: iCCP块中的配置文件 (%d 字节) 与加载的不一致", tt.format, tt.size, len(embedded))
+			}
+			continue
+		}
+
+		embedded, positions := jpegColorProfile(t, data)
+		if !bytes.Equal(embedded, profile) {
+			t.Errorf("%s %d 字节: APP2段中的配置文件 (%d 字节) 与加载的不一致", tt.format, tt.size, len(embedded))
+		}
+		if len(positions) != tt.segments {
+			t.Errorf("%s %d 字节: 写入了 %d 个APP2段，应为 %d 个", tt.format, tt.size, len(positions), tt.segments)
+		}
+		// ICC段位于元数据 (EXIF APP1) 之后，且连续排列
+		segments := readJPEGSegments(t, data)
+		if positions[0] == 0 || segments[positions[0]-1].Marker != 0xE1 || positions[len(positions)-1]-positions[0] != len(positions)-1 {
+			t.Errorf("%s %d 字节: APP2段的位置为 %v", tt.format, tt.size, positions)
+		}
+	}
+}
+
+func TestLoadColorProfileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.icc")
+	if err := os.WriteFile(path, make([]byte, 200), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateColorProfile(path); err == nil {
+		t.Error("没有 acsp 签名的文件应返回错误")
+	}
+	if err := ValidateColorProfile(filepath.Join(t.TempDir(), "missing.icc")); err == nil {
+		t.Error("不存在的文件应返回错误")
+	}
+}
@@ -0,0 +1,127 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/disintegration/imaging"
+
+	"ppt-to-images-service/internal/jpegenc"
+)
+
+const (
+	// 总览图单元格默认尺寸 (16:9)
+	defaultContactSheetCellWidth  = 320
+	defaultContactSheetCellHeight = 180
+)
+
+// ContactSheetOptions 幻灯片总览图选项
+type ContactSheetOptions struct {
+	Columns    int // 列数，0表示自动
+	Rows       int // 行数，0表示自动
+	CellWidth  int // 单元格宽度，0使用默认值
+	CellHeight int // 单元格高度，0使用默认值
+	Gutter     int // 单元格间距及外边距
+}
+
+// appendContactSheet 按选项生成总览图并追加到图片列表，失败时只记录日志
+func (c *PPTConverter) appendContactSheet(images []ImageInfo, outputPath string, options ConversionOptions) []ImageInfo {
+	if options.ContactSheet == nil || len(images) == 0 {
+		return images
+	}
+
+	sheet, err := c.createContactSheet(summaryImages(images, options), outputPath, options.OutputFormat, options.JPEGSubsampling, options.resampleFilter(), *options.ContactSheet)
+	if err != nil {
+		c.logger.Warnf("生成总览图失败: %v", err)
+		return images
+	}
+
+	c.logger.Infof("生成总览图: %s", sheet.Filename)
+	return append(images, *sheet)
+}
+
+// createContactSheet 将已渲染的幻灯片缩放后按网格拼接为一张总览图
+func (c *PPTConverter) createContactSheet(images []ImageInfo, outputPath, format string, subsampling jpegenc.Subsampling, filter imaging.ResampleFilter, options ContactSheetOptions) (*ImageInfo, error) {
+	columns, rows := contactSheetLayout(len(images), options.Columns, options.Rows)
+
+	cellWidth := options.CellWidth
+	if cellWidth <= 0 {
+		cellWidth = defaultContactSheetCellWidth
+	}
+	cellHeight := options.CellHeight
+	if cellHeight <= 0 {
+		cellHeight = defaultContactSheetCellHeight
+	}
+	gutter := options.Gutter
+	if gutter < 0 {
+		gutter = 0
+	}
+
+	width := columns*cellWidth + (columns+1)*gutter
+	height := rows*cellHeight + (rows+1)*gutter
+	if err := c.checkPixels(width, height); err != nil {
+		return nil, err
+	}
+	sheet := imaging.New(width, height, color.White)
+
+	for i, info := range images {
+		img, err := imaging.Open(info.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("读取第 %d 张幻灯片失败: %v", info.SlideNumber, err)
+		}
+
+		// 保持宽高比缩放到单元格内并居中
+		thumb := imaging.Fit(img, cellWidth, cellHeight, filter)
+		column, row := i%columns, i/columns
+		x := gutter + column*(cellWidth+gutter) + (cellWidth-thumb.Bounds().Dx())/2
+		y := gutter + row*(cellHeight+gutter) + (cellHeight-thumb.Bounds().Dy())/2
+		sheet = imaging.Paste(sheet, thumb, image.Pt(x, y))
+	}
+
+	filename := "contact_sheet." + imageExtension(format)
+	filePath := filepath.Join(outputPath, filename)
+	if err := c.saveImage(sheet, filePath, format, subsampling, nil); err != nil {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("保存总览图失败: %v", err)
+	}
+
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+
+	return &ImageInfo{
+		SlideNumber: 0,
+		Filename:    filename,
+		FilePath:    filePath,
+		FileSize:    fileInfo.Size(),
+		DownloadID:  generateDownloadID(),
+		Format:      formatFromExtension(filepath.Ext(filename)),
+		Width:       width,
+		Height:      height,
+	}, nil
+}
+
+// contactSheetLayout 计算网格行列数
+// 只指定一边时另一边按数量推算，都未指定时尽量排成正方形；指定的格子不足时增加行数
+func contactSheetLayout(count, columns, rows int) (int, int) {
+	switch {
+	case columns > 0 && rows > 0:
+	case columns > 0:
+		rows = (count + columns - 1) / columns
+	case rows > 0:
+		columns = (count + rows - 1) / rows
+	default:
+		columns = int(math.Ceil(math.Sqrt(float64(count))))
+		rows = (count + columns - 1) / columns
+	}
+
+	if columns*rows < count {
+		rows = (count + columns - 1) / columns
+	}
+	return columns, rows
+}
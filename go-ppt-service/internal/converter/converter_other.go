@@ -0,0 +1,42 @@
+//go:build !windows
+// +build !windows
+
+package converter
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NewPlatformConverter 创建当前平台的PPT转换器
+// 非Windows平台优先使用LibreOffice渲染，未安装时退回到占位渲染器
+func NewPlatformConverter(outputDir, tempDir string, width, height int, outputFormat string, options Options, logger *logrus.Logger) Converter {
+	options.processLimiter = newProcessLimiter(options.MaxRenderProcs, logger)
+	var renderer Renderer
+	libreOffice, err := NewLibreOfficeRenderer(options, logger)
+	if err != nil {
+		logger.Warnf("LibreOffice不可用，使用占位渲染器: %v", err)
+		renderer = NewPlaceholderRenderer(options, logger)
+	} else {
+		renderer = libreOffice
+	}
+	converter := NewPPTConverter(renderer, outputDir, tempDir, width, height, outputFormat, options, logger)
+	converter.fallbacks = newFallbackRenderers(renderer.Backend(), tempDir, options, logger)
+	return converter
+}
+
+// newBackendRenderer 按名称创建备用渲染器，非Windows平台支持 libreoffice 和 placeholder
+func newBackendRenderer(name, tempDir string, options Options, logger *logrus.Logger) (Renderer, error) {
+	switch name {
+	case "libreoffice":
+		renderer, err := NewLibreOfficeRenderer(options, logger)
+		if err != nil {
+			return nil, err
+		}
+		return renderer, nil
+	case "placeholder":
+		return NewPlaceholderRenderer(options, logger), nil
+	}
+	return nil, fmt.Errorf("当前平台不支持渲染后端: %s", name)
+}
@@ -0,0 +1,30 @@
+//go:build windows
+// +build windows
+
+package converter
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NewPlatformConverter 创建当前平台的PPT转换器 (Windows使用PowerPoint COM接口渲染)
+func NewPlatformConverter(outputDir, tempDir string, width, height int, outputFormat string, options Options, logger *logrus.Logger) Converter {
+	options.processLimiter = newProcessLimiter(options.MaxRenderProcs, logger)
+	renderer := NewPowerPointRenderer(tempDir, options, logger)
+	converter := NewPPTConverter(renderer, outputDir, tempDir, width, height, outputFormat, options, logger)
+	converter.fallbacks = newFallbackRenderers(renderer.Backend(), tempDir, options, logger)
+	return converter
+}
+
+// newBackendRenderer 按名称创建备用渲染器，Windows平台支持 powerpoint 和 placeholder
+func newBackendRenderer(name, tempDir string, options Options, logger *logrus.Logger) (Renderer, error) {
+	switch name {
+	case "powerpoint":
+		return NewPowerPointRenderer(tempDir, options, logger), nil
+	case "placeholder":
+		return NewPlaceholderRenderer(options, logger), nil
+	}
+	return nil, fmt.Errorf("当前平台不支持渲染后端: %s", name)
+}
@@ -0,0 +1,34 @@
+package converter
+
+import (
+	"runtime"
+	"sync"
+)
+
+// encodePool 所有转换共用的幻灯片后处理 (合成、格式转换、编码、压缩等) 工作协程上限
+// 渲染器提交一张幻灯片后即可继续渲染下一张，后处理在工作协程中与渲染重叠进行
+type encodePool struct {
+	slots chan struct{}
+}
+
+// newEncodePool 创建后处理工作协程上限，workers 不大于0时使用CPU核数
+func newEncodePool(workers int) *encodePool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &encodePool{slots: make(chan struct{}, workers)}
+}
+
+// submit 在新协程中执行 task，所有工作协程都忙时等待其中一个完成，使渲染不会远远领先于后处理
+// pending 记录尚未完成的任务
+func (p *encodePool) submit(pending *sync.WaitGroup, task func()) {
+	p.slots <- struct{}{}
+	pending.Add(1)
+	go func() {
+		defer func() {
+			<-p.slots
+			pending.Done()
+		}()
+		task()
+	}()
+}
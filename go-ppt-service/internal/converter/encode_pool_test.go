@@ -0,0 +1,37 @@
+package converter
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEncodePoolLimit(t *testing.T) {
+	pool := newEncodePool(2)
+
+	var pending sync.WaitGroup
+	var running, peak, finished int32
+	for i := 0; i < 6; i++ {
+		pool.submit(&pending, func() {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&finished, 1)
+		})
+	}
+	pending.Wait()
+
+	if finished != 6 {
+		t.Errorf("完成了 %d 个任务，应为6个", finished)
+	}
+	if peak > 2 {
+		t.Errorf("同时运行了 %d 个任务，上限为2", peak)
+	}
+}
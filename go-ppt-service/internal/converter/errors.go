@@ -0,0 +1,39 @@
+package converter
+
+import "errors"
+
+// 转换器返回的错误类型，调用方通过 errors.Is 判断
+var (
+	// ErrUnsupportedFormat 不支持的输出格式
+	ErrUnsupportedFormat = errors.New("不支持的格式")
+	// ErrCorruptFile 文件损坏或无法被转换后端打开
+	ErrCorruptFile = errors.New("文件损坏或无法打开")
+	// ErrBackendUnavailable 转换后端 (PowerPoint、LibreOffice) 不可用，可稍后重试
+	ErrBackendUnavailable = errors.New("转换后端不可用")
+	// ErrNoSlides 演示文稿中没有幻灯片
+	ErrNoSlides = errors.New("没有幻灯片")
+	// ErrSlideOutOfRange 指定的幻灯片编号超出范围
+	ErrSlideOutOfRange = errors.New("幻灯片编号超出范围")
+	// ErrImageTooLarge 输出图片像素数超过上限
+	ErrImageTooLarge = errors.New("输出图片尺寸过大")
+	// ErrNoMatchingSlides 没有与版式或标题筛选条件匹配的幻灯片
+	ErrNoMatchingSlides = errors.New("没有匹配的幻灯片")
+	// ErrTooManySlides 演示文稿的幻灯片数量超过上限
+	ErrTooManySlides = errors.New("幻灯片数量超过上限")
+	// ErrCancelled 转换被取消 (如服务关闭)，外部进程已被结束
+	ErrCancelled = errors.New("转换已取消")
+	// ErrTimeout 转换超过整体时间上限，外部进程已被结束
+	ErrTimeout = errors.New("转换超时")
+	// ErrNoThumbnail 演示文稿中没有内嵌的预览缩略图
+	ErrNoThumbnail = errors.New("没有内嵌缩略图")
+	// ErrInvalidImage 渲染器写出的图片为空、过小或无法解码
+	ErrInvalidImage = errors.New("渲染结果无效")
+	// ErrSlideMissing 渲染器没有输出该幻灯片 (如LibreOffice导出的PDF页数少于幻灯片数)
+	ErrSlideMissing = errors.New("渲染器没有输出该幻灯片")
+	// ErrInvalidOrder 指定的排列顺序无效或没有恰好覆盖需要转换的幻灯片
+	ErrInvalidOrder = errors.New("无效的排列顺序")
+	// ErrInvalidSlideSize 单独指定的幻灯片尺寸无效
+	ErrInvalidSlideSize = errors.New("无效的幻灯片尺寸")
+	// ErrFilenameCollision 不同的输出文件得到了相同的文件名 (仅 StrictFilenames)
+	ErrFilenameCollision = errors.New("输出文件名冲突")
+)
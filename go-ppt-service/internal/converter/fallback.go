@@ -0,0 +1,163 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newFallbackRenderers 按 Options.FallbackBackends 的顺序创建备用渲染器
+// 与主渲染器相同、重复或当前平台不可用的后端记录警告后跳过
+func newFallbackRenderers(primary, tempDir string, options Options, logger *logrus.Logger) []Renderer {
+	seen := map[string]bool{primary: true}
+	var renderers []Renderer
+	for _, name := range options.FallbackBackends {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			logger.Warnf("备用渲染后端 %s 与主后端或之前的备用后端重复，已忽略", name)
+			continue
+		}
+		seen[name] = true
+
+		renderer, err := newBackendRenderer(name, tempDir, options, logger)
+		if err != nil {
+			logger.Warnf("备用渲染后端 %s 不可用: %v", name, err)
+			continue
+		}
+		renderers = append(renderers, renderer)
+	}
+	return renderers
+}
+
+// fallbackable 渲染错误是否可以换用备用后端重试
+// 取消、超时以及由请求或演示文稿本身决定的错误 (幻灯片编号、数量、尺寸等) 换用其他后端也不会成功
+func fallbackable(err error) bool {
+	for _, target := range []error{
+		ErrCancelled, ErrTimeout, ErrNoSlides, ErrSlideOutOfRange, ErrNoMatchingSlides,
+		ErrTooManySlides, ErrImageTooLarge, ErrUnsupportedFormat, ErrInvalidOrder,
+		ErrInvalidSlideSize,
+	} {
+		if errors.Is(err, target) {
+			return false
+		}
+	}
+	return true
+}
+
+// needsFallback 幻灯片是否需要由备用后端重新渲染: 没有图片 (渲染失败) 或结果几乎空白
+func needsFallback(images []ImageInfo) bool {
+	if len(images) == 0 {
+		return true
+	}
+	for _, image := range images {
+		if image.LowConfidence {
+			return true
+		}
+	}
+	return false
+}
+
+// renderFallbacks 依次用备用后端重新渲染主后端失败或空白的幻灯片，renderErr 为主后端 Render 返回的错误
+// 主后端整体失败时备用后端渲染缺少的 (或全部) 幻灯片；没有备用后端成功完成渲染时返回 renderErr
+func (c *PPTConverter) renderFallbacks(ctx context.Context, job *renderJob, pptPath, workDir string, renderErr error) error {
+	recovered := renderErr == nil
+	for i, renderer := range c.fallbacks {
+		retry, counted := job.retrySlides()
+		if counted && len(retry) == 0 || ctx.Err() != nil {
+			break
+		}
+
+		if counted {
+			c.logger.Warnf("%s 未能正常渲染 %d 张幻灯片 %v，改用 %s 重新渲染", job.backend, len(retry), retry, renderer.Backend())
+		} else {
+			c.logger.Warnf("%s 渲染失败，改用 %s: %v", job.backend, renderer.Backend(), renderErr)
+		}
+
+		if err := c.renderWith(ctx, renderer, job, retry, pptPath, workDir, i); err != nil {
+			c.logger.Warnf("备用后端 %s 渲染失败: %v", renderer.Backend(), err)
+			if !fallbackable(err) {
+				return err
+			}
+			continue
+		}
+		recovered = true
+	}
+
+	if !recovered {
+		return renderErr
+	}
+	return nil
+}
+
+// renderWith 用 renderer 渲染 slides 指定的幻灯片 (为空时渲染请求的全部幻灯片)，结果更好的幻灯片替换主任务中的结果
+func (c *PPTConverter) renderWith(ctx context.Context, renderer Renderer, job *renderJob, slides []int, pptPath, workDir string, attempt int) error {
+	options := job.options
+	if len(slides) > 0 {
+		options.SlideIndices = slides
+	}
+	// 通知由主任务在合并后按顺序进行，结果的顺序也由主任务决定
+	options.OnImageReady = nil
+	options.Order = OrderAscending
+	options.OrderIndices = nil
+	options.notesPDFPath = ""
+
+	// 备用后端的中间文件和输出都放在单独的目录中，合并时只移动被采用的图片
+	outputPath := filepath.Join(job.outputPath, fmt.Sprintf(".fallback%d", attempt+1))
+	renderPath := filepath.Join(outputPath, ".render")
+	if err := os.MkdirAll(renderPath, 0755); err != nil {
+		return fmt.Errorf("创建备用输出目录失败: %v", err)
+	}
+	defer os.RemoveAll(outputPath)
+	workDir = filepath.Join(workDir, fmt.Sprintf("fallback%d", attempt+1))
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("创建临时目录失败: %v", err)
+	}
+
+	sub := job.subJob(options, outputPath, renderer.Backend())
+	// 返回 (包括出错) 之前等待已提交的幻灯片处理完，之后才删除输出目录
+	defer sub.wait()
+	rendered, err := renderer.Render(ctx, pptPath, RenderOptions{
+		ConversionOptions: options,
+		WorkDir:           workDir,
+		OutputPath:        renderPath,
+		Format:            options.renderFormat(),
+		Hidden:            job.hidden,
+		job:               sub,
+	})
+	if err != nil {
+		return err
+	}
+	if err := sub.finish(rendered); err != nil {
+		return err
+	}
+	return job.merge(sub, false)
+}
+
+// logBackends 记录每个渲染后端最终生成了哪些幻灯片
+func (c *PPTConverter) logBackends(filename string, images []ImageInfo) {
+	slides := make(map[string][]int)
+	var backends []string
+	seen := make(map[int]bool)
+	for _, image := range images {
+		// 同一张幻灯片的构建步骤、分块和多分辨率图片由同一个后端生成
+		if image.Backend == "" || seen[image.SlideNumber] {
+			continue
+		}
+		seen[image.SlideNumber] = true
+		if _, ok := slides[image.Backend]; !ok {
+			backends = append(backends, image.Backend)
+		}
+		slides[image.Backend] = append(slides[image.Backend], image.SlideNumber)
+	}
+	for _, backend := range backends {
+		c.logger.Infof("%s: 渲染后端 %s 生成了 %d 张幻灯片 %v", filename, backend, len(slides[backend]), slides[backend])
+	}
+}
@@ -0,0 +1,72 @@
+package converter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// imageBackends 每张图片的渲染后端
+func imageBackends(images []ImageInfo) []string {
+	var backends []string
+	for _, image := range images {
+		backends = append(backends, image.Backend)
+	}
+	return backends
+}
+
+func TestConvertPPTFallbackOnError(t *testing.T) {
+	primary := &MockRenderer{Slides: 3, FailOn: 2, Name: "primary"}
+	fallback := &MockRenderer{Slides: 3, Name: "fallback"}
+	c, _ := newTestConverter(t, primary, Options{})
+	c.fallbacks = []Renderer{fallback}
+
+	result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+	if !result.Success || !reflect.DeepEqual(slideNumbers(result.Images), []int{1, 2, 3}) {
+		t.Fatalf("结果 %+v", result)
+	}
+	// 主后端出错时没有返回任何图片，全部由备用后端渲染
+	if got := imageBackends(result.Images); !reflect.DeepEqual(got, []string{"fallback", "fallback", "fallback"}) {
+		t.Errorf("渲染后端为 %v", got)
+	}
+}
+
+func TestConvertPPTFallbackOnBlank(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		// 主后端的纯色图片被标记为空白，备用后端只重新渲染这些幻灯片
+		primary := &MockRenderer{Slides: 3, Photos: map[int]bool{1: true, 3: true}, Stream: stream, Name: "primary"}
+		fallback := &MockRenderer{Slides: 3, Photos: map[int]bool{1: true, 2: true, 3: true}, Name: "fallback"}
+		c, outputDir := newTestConverter(t, primary, Options{BlankThreshold: 0.9})
+		c.fallbacks = []Renderer{fallback}
+
+		var ready []int
+		result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{
+			OutputSubdir: "out",
+			OnImageReady: func(image ImageInfo) {
+				ready = append(ready, image.SlideNumber)
+			},
+		}, nil)
+		if err != nil {
+			t.Fatalf("stream=%v: ConvertPPT 失败: %v", stream, err)
+		}
+		if got := imageBackends(result.Images); !reflect.DeepEqual(got, []string{"primary", "fallback", "primary"}) {
+			t.Errorf("stream=%v: 渲染后端为 %v", stream, got)
+		}
+		if got := fallback.renderedSlides(); !reflect.DeepEqual(got, []int{2}) {
+			t.Errorf("stream=%v: 备用后端渲染了 %v", stream, got)
+		}
+		if result.Images[1].LowConfidence {
+			t.Errorf("stream=%v: 替换后的幻灯片仍被标记为空白", stream)
+		}
+		// 空白的幻灯片等备用后端的结果出来后才通知，顺序不变
+		if !reflect.DeepEqual(ready, []int{1, 2, 3}) {
+			t.Errorf("stream=%v: OnImageReady 通知了 %v", stream, ready)
+		}
+		if files := outputFiles(t, outputDir+"/out"); !reflect.DeepEqual(files, []string{"slide_001.png", "slide_002.png", "slide_003.png"}) {
+			t.Errorf("stream=%v: 输出目录中的文件为 %v", stream, files)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// filenameCollisions 一次转换中与已有输出重名的文件名，各幻灯片的后处理在多个协程中同时记录
+type filenameCollisions struct {
+	mutex sync.Mutex
+	names []string
+}
+
+// add 记录重名的文件名，nil 时忽略
+func (f *filenameCollisions) add(name string) {
+	if f == nil {
+		return
+	}
+	f.mutex.Lock()
+	f.names = append(f.names, name)
+	f.mutex.Unlock()
+}
+
+// list 已记录的文件名
+func (f *filenameCollisions) list() []string {
+	if f == nil {
+		return nil
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.names...)
+}
+
+// outputFilename 为即将写入 dir 的输出文件取得不重名的文件名
+// 文件名由分辨率后缀和切分序号拼接而成，可能与同一张或其他幻灯片的输出相同 (如后缀 _t02 与第2块分块)，
+// 此时在扩展名前加 _2、_3 等后缀并记录警告，而不是覆盖已有文件；StrictFilenames 时转换结束后以 ErrFilenameCollision 失败
+// 通过独占创建空文件占用名称，并行处理的幻灯片不会取得同一个名称
+func (c *PPTConverter) outputFilename(dir, filename string, options ConversionOptions) string {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+
+	name := filename
+	for n := 2; ; n++ {
+		file, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			file.Close()
+			break
+		}
+		if !os.IsExist(err) {
+			// 其他错误由随后的写入报告
+			return name
+		}
+		name = fmt.Sprintf("%s_%d%s", base, n, ext)
+	}
+
+	if name != filename {
+		c.logger.Warnf("输出文件名 %s 已被占用，改为 %s", filename, name)
+		options.collisions.add(filename)
+	}
+	return name
+}
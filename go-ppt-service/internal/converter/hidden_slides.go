@@ -0,0 +1,56 @@
+package converter
+
+// hiddenSlides 解析PPTX，返回被隐藏 (<p:sld show="0">) 的幻灯片编号 (从1开始)
+// PPT、ODP等非PPTX格式无法解析，返回空集合
+func hiddenSlides(pptData []byte) (map[int]bool, error) {
+	slides, err := readPPTXSlides(pptData)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden := make(map[int]bool)
+	for i, slide := range slides {
+		if slide.Show == "0" || slide.Show == "false" {
+			hidden[i+1] = true
+		}
+	}
+	return hidden, nil
+}
+
+// skippedSlides 返回本次转换需要跳过的隐藏幻灯片，IncludeHidden 时返回空集合
+// 解析失败时记录日志并按没有隐藏幻灯片处理
+func (c *PPTConverter) skippedSlides(pptData []byte, options ConversionOptions) map[int]bool {
+	if options.IncludeHidden {
+		return map[int]bool{}
+	}
+
+	hidden, err := hiddenSlides(pptData)
+	if err != nil {
+		c.logger.Warnf("无法识别隐藏的幻灯片: %v", err)
+		return map[int]bool{}
+	}
+	if len(hidden) > 0 {
+		c.logger.Infof("跳过 %d 张隐藏的幻灯片", len(hidden))
+	}
+	return hidden
+}
+
+// coverSlide 第一张不被跳过的幻灯片的编号
+func coverSlide(hidden map[int]bool) int {
+	slide := 1
+	for hidden[slide] {
+		slide++
+	}
+	return slide
+}
+
+// pageSlideNumbers 导出的PDF不含隐藏幻灯片时，计算每一页对应的幻灯片编号 (下标为页码-1)
+func pageSlideNumbers(pageCount int, hidden map[int]bool) []int {
+	numbers := make([]int, 0, pageCount)
+	for slide := 1; len(numbers) < pageCount; slide++ {
+		if !hidden[slide] {
+			numbers = append(numbers, slide)
+		}
+	}
+	return numbers
+}
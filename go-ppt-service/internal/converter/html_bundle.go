@@ -0,0 +1,132 @@
+package converter
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+// htmlBundleFilename HTML幻灯片页面的文件名，与图片位于同一目录
+const htmlBundleFilename = "index.html"
+
+// htmlBundleTemplate 简单的幻灯片轮播页面，图片使用相对路径，整个目录可直接拷贝到网站中
+var htmlBundleTemplate = template.Must(template.New("bundle").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; background: #222; color: #eee; font-family: sans-serif; }
+.slides { position: relative; max-width: 1280px; margin: 24px auto; }
+.slide { display: none; margin: 0; text-align: center; }
+.slide.active { display: block; }
+.slide img { max-width: 100%; box-shadow: 0 2px 12px rgba(0, 0, 0, 0.6); }
+.slide figcaption { margin-top: 12px; }
+.controls { text-align: center; margin-bottom: 24px; }
+.controls button { font-size: 16px; padding: 6px 18px; margin: 0 8px; }
+</style>
+</head>
+<body>
+<div class="slides">
+{{- range $i, $slide := .Slides}}
+<figure class="slide{{if eq $i 0}} active{{end}}">
+<img src="{{$slide.Src}}" alt="{{$slide.Alt}}">
+<figcaption>{{$slide.Position}} / {{$.Total}}{{if $slide.Caption}} - {{$slide.Caption}}{{end}}</figcaption>
+</figure>
+{{- end}}
+</div>
+<div class="controls">
+<button id="prev">上一张</button>
+<button id="next">下一张</button>
+</div>
+<script>
+(function () {
+  var slides = document.querySelectorAll(".slide");
+  var current = 0;
+  function show(index) {
+    slides[current].classList.remove("active");
+    current = (index + slides.length) % slides.length;
+    slides[current].classList.add("active");
+  }
+  document.getElementById("prev").onclick = function () { show(current - 1); };
+  document.getElementById("next").onclick = function () { show(current + 1); };
+  document.addEventListener("keydown", function (e) {
+    if (e.key === "ArrowLeft") show(current - 1);
+    if (e.key === "ArrowRight" || e.key === " ") show(current + 1);
+  });
+})();
+</script>
+</body>
+</html>
+`))
+
+// htmlBundleSlide 页面中的一张幻灯片
+type htmlBundleSlide struct {
+	Src      string
+	Alt      string
+	Position int // 在页面中的序号 (从1开始)
+	Caption  string
+}
+
+// appendHTMLBundle 生成引用幻灯片图片的 index.html 并追加到图片列表，失败时只记录日志
+// PPTX中幻灯片的标题作为图片说明，总览图不放入页面，多分辨率输出时只使用最大尺寸
+func (c *PPTConverter) appendHTMLBundle(images []ImageInfo, outputPath, filename string, pptData []byte, options ConversionOptions) []ImageInfo {
+	if !options.HTMLBundle || len(images) == 0 {
+		return images
+	}
+
+	slides, err := readPPTXSlides(pptData)
+	if err != nil {
+		c.logger.Warnf("读取幻灯片标题失败: %v", err)
+	}
+
+	data := struct {
+		Title  string
+		Total  int
+		Slides []htmlBundleSlide
+	}{Title: filename}
+
+	for _, image := range summaryImages(images, options) {
+		if image.SlideNumber <= 0 {
+			continue
+		}
+
+		slide := htmlBundleSlide{
+			Src:      image.Filename,
+			Alt:      image.Filename,
+			Position: len(data.Slides) + 1,
+		}
+		if index := image.SlideNumber - options.NumberOffset - 1; index >= 0 && index < len(slides) {
+			slide.Caption = slides[index].title()
+			if slide.Caption != "" {
+				slide.Alt = slide.Caption
+			}
+		}
+		data.Slides = append(data.Slides, slide)
+	}
+	data.Total = len(data.Slides)
+
+	var buf bytes.Buffer
+	if err := htmlBundleTemplate.Execute(&buf, data); err != nil {
+		c.logger.Warnf("生成HTML页面失败: %v", err)
+		return images
+	}
+
+	filePath := filepath.Join(outputPath, htmlBundleFilename)
+	if err := os.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+		c.logger.Warnf("生成HTML页面失败: %v", err)
+		return images
+	}
+
+	c.logger.Infof("生成HTML页面: %s", htmlBundleFilename)
+	return append(images, ImageInfo{
+		SlideNumber: 0,
+		Filename:    htmlBundleFilename,
+		FilePath:    filePath,
+		FileSize:    int64(buf.Len()),
+		DownloadID:  generateDownloadID(),
+		Format:      "HTML",
+	})
+}
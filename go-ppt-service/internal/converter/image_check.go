@@ -0,0 +1,37 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"os"
+)
+
+// SlideError 一张幻灯片的渲染失败原因，该幻灯片 (或它的某个构建步骤) 没有输出图片
+type SlideError struct {
+	// SlideNumber 加上编号偏移后的编号，与 ImageInfo.SlideNumber 一致
+	SlideNumber int    `json:"slide_number"`
+	Error       string `json:"error"`
+}
+
+// checkRenderedImage 检查渲染器写出的图片: 文件不小于 minSize 字节且能完整解码
+// 外部工具偶尔会"成功"退出却只写出空文件或截断的图片，这里把它们当作渲染失败
+func checkRenderedImage(filePath string, minSize int64) error {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	if fileInfo.Size() == 0 || fileInfo.Size() < minSize {
+		return fmt.Errorf("%w: 文件只有 %d 字节", ErrInvalidImage, fileInfo.Size())
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, _, err := image.Decode(file); err != nil {
+		return fmt.Errorf("%w: 无法解码: %v", ErrInvalidImage, err)
+	}
+	return nil
+}
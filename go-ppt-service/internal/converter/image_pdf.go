@@ -0,0 +1,208 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// imagePDFFilename 只包含幻灯片图片的PDF的文件名，与图片位于同一目录
+const imagePDFFilename = "slides.pdf"
+
+// imagePDFPointsPerPixel 页面尺寸按96DPI把像素换算为PDF的点 (1/72英寸)
+const imagePDFPointsPerPixel = 72.0 / 96.0
+
+// appendImagePDF 把幻灯片图片按顺序逐页放入 slides.pdf 并追加到图片列表，失败时只记录日志
+// PDF中只有图片，没有可以选中或提取的文字；JPEG原样嵌入，PNG解码后以无损的Flate压缩存储 (透明区域按白色合成)
+// 总览图和HTML页面不放入PDF，多分辨率输出时只使用最大尺寸，切分的图片每块一页
+func (c *PPTConverter) appendImagePDF(images []ImageInfo, outputPath string, options ConversionOptions) []ImageInfo {
+	if !options.PDFImageOnly || len(images) == 0 {
+		return images
+	}
+
+	var pages []ImageInfo
+	for _, image := range summaryImages(images, options) {
+		if image.SlideNumber > 0 {
+			pages = append(pages, image)
+		}
+	}
+	if len(pages) == 0 {
+		return images
+	}
+
+	filePath := filepath.Join(outputPath, imagePDFFilename)
+	size, err := writeImagePDF(filePath, pages)
+	if err != nil {
+		os.Remove(filePath)
+		c.logger.Warnf("生成PDF失败: %v", err)
+		return images
+	}
+
+	c.logger.Infof("生成PDF: %s (%d 页)", imagePDFFilename, len(pages))
+	return append(images, ImageInfo{
+		SlideNumber: 0,
+		Filename:    imagePDFFilename,
+		FilePath:    filePath,
+		FileSize:    size,
+		DownloadID:  generateDownloadID(),
+		Format:      "PDF",
+	})
+}
+
+// pdfImage 嵌入PDF的一张图片
+type pdfImage struct {
+	width, height int
+	colorSpace    string // DeviceRGB 或 DeviceGray
+	filter        string // DCTDecode (JPEG原样嵌入) 或 FlateDecode
+	data          []byte
+}
+
+// loadPDFImage 读取图片文件并转换为可以嵌入PDF的数据
+func loadPDFImage(path string) (*pdfImage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", filepath.Base(path), err)
+	}
+	if format == "jpeg" {
+		switch config.ColorModel {
+		case color.YCbCrModel, color.RGBAModel:
+			return &pdfImage{width: config.Width, height: config.Height, colorSpace: "DeviceRGB", filter: "DCTDecode", data: data}, nil
+		case color.GrayModel:
+			return &pdfImage{width: config.Width, height: config.Height, colorSpace: "DeviceGray", filter: "DCTDecode", data: data}, nil
+		}
+		// CMYK等其他颜色模型的JPEG解码后重新存储
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码 %s 失败: %v", filepath.Base(path), err)
+	}
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(canvas, canvas.Bounds(), img, bounds.Min, draw.Over)
+
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	row := make([]byte, bounds.Dx()*3)
+	for y := 0; y < bounds.Dy(); y++ {
+		pixels := canvas.Pix[y*canvas.Stride:]
+		for x := 0; x < bounds.Dx(); x++ {
+			copy(row[x*3:x*3+3], pixels[x*4:x*4+3])
+		}
+		if _, err := writer.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return &pdfImage{width: bounds.Dx(), height: bounds.Dy(), colorSpace: "DeviceRGB", filter: "FlateDecode", data: compressed.Bytes()}, nil
+}
+
+// writeImagePDF 生成每页一张图片、页面与图片同样大小的PDF，返回文件大小
+// 图片逐页读取后写入，不会同时把所有图片放在内存中
+func writeImagePDF(filePath string, pages []ImageInfo) (int64, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	w := &pdfWriter{w: bufio.NewWriter(file)}
+	w.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	// 对象编号: 1 目录，2 页面树，之后每页依次为页面、内容流和图片
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+3*i)
+	}
+	w.object("<< /Type /Catalog /Pages 2 0 R >>")
+	w.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+
+	for i, page := range pages {
+		img, err := loadPDFImage(page.FilePath)
+		if err != nil {
+			return 0, err
+		}
+		id := 3 + 3*i
+		width := float64(img.width) * imagePDFPointsPerPixel
+		height := float64(img.height) * imagePDFPointsPerPixel
+
+		w.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			width, height, id+2, id+1))
+		w.stream("", []byte(fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", width, height)))
+		w.stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s ",
+			img.width, img.height, img.colorSpace, img.filter), img.data)
+	}
+
+	if err := w.finish(); err != nil {
+		return 0, err
+	}
+	return w.offset, file.Close()
+}
+
+// pdfWriter 顺序写出PDF对象并记录每个对象的偏移，第一次写入出错后忽略之后的写入
+// 对象按写出的顺序从1开始编号，1号对象必须是目录
+type pdfWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	offsets []int64
+	err     error
+}
+
+func (w *pdfWriter) write(data []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(data)
+	w.offset += int64(n)
+	w.err = err
+}
+
+func (w *pdfWriter) printf(format string, args ...interface{}) {
+	w.write([]byte(fmt.Sprintf(format, args...)))
+}
+
+// object 写出下一个编号的对象
+func (w *pdfWriter) object(body string) {
+	w.offsets = append(w.offsets, w.offset)
+	w.printf("%d 0 obj\n%s\nendobj\n", len(w.offsets), body)
+}
+
+// stream 写出下一个编号的流对象，dict 为 /Length 之外的字典内容
+func (w *pdfWriter) stream(dict string, data []byte) {
+	w.offsets = append(w.offsets, w.offset)
+	w.printf("%d 0 obj\n<< %s/Length %d >>\nstream\n", len(w.offsets), dict, len(data))
+	w.write(data)
+	w.printf("\nendstream\nendobj\n")
+}
+
+// finish 写出交叉引用表和文件尾并刷新缓冲，返回写入过程中的第一个错误
+func (w *pdfWriter) finish() error {
+	xref := w.offset
+	w.printf("xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		w.printf("%010d 00000 n \n", offset)
+	}
+	w.printf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, xref)
+
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
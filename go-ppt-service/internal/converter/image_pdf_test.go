@@ -0,0 +1,54 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+)
+
+func TestConvertPPTImagePDF(t *testing.T) {
+	for _, format := range []string{"PNG", "JPEG"} {
+		c, _ := newTestConverter(t, &MockRenderer{Slides: 3}, Options{})
+
+		result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{
+			OutputFormat: format,
+			PDFImageOnly: true,
+			HTMLBundle:   true,
+		}, nil)
+		if err != nil {
+			t.Fatalf("%s: ConvertPPT 失败: %v", format, err)
+		}
+
+		// 3张幻灯片、index.html 和 slides.pdf
+		if len(result.Images) != 5 {
+			t.Fatalf("%s: 返回了 %d 个文件，应为5个", format, len(result.Images))
+		}
+		pdf := result.Images[4]
+		if pdf.Filename != imagePDFFilename || pdf.Format != "PDF" || pdf.SlideNumber != 0 {
+			t.Fatalf("%s: 最后一个文件为 %+v", format, pdf)
+		}
+
+		data, err := os.ReadFile(pdf.FilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(data)) != pdf.FileSize || !bytes.HasPrefix(data, []byte("%PDF-")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+			t.Errorf("%s: PDF文件不完整 (%d 字节，记录为 %d)", format, len(data), pdf.FileSize)
+		}
+		if !bytes.Contains(data, []byte("/Count 3")) || bytes.Count(data, []byte("/Subtype /Image")) != 3 {
+			t.Errorf("%s: PDF中应有3页图片", format)
+		}
+		// 只有图片，没有字体和文字
+		if bytes.Contains(data, []byte("/Font")) {
+			t.Errorf("%s: PDF中包含字体", format)
+		}
+		filter := []byte("/Filter /FlateDecode")
+		if format == "JPEG" {
+			filter = []byte("/Filter /DCTDecode")
+		}
+		if !bytes.Contains(data, filter) {
+			t.Errorf("%s: 图片没有使用 %s", format, filter)
+		}
+	}
+}
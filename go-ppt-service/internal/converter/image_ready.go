@@ -0,0 +1,54 @@
+package converter
+
+import "sync"
+
+// notify 依次通知已完成的图片，回调为空时什么都不做
+func (callback ImageReadyCallback) notify(images []ImageInfo) {
+	if callback == nil {
+		return
+	}
+	for _, image := range images {
+		callback(image)
+	}
+}
+
+// imageReadyQueue 并行渲染时按顺序串行通知 ImageReadyCallback
+// 先完成的幻灯片暂存，直到排在它前面的幻灯片都已完成 (或失败) 后才通知
+type imageReadyQueue struct {
+	mutex    sync.Mutex
+	callback ImageReadyCallback
+	order    []int
+	next     int
+	pending  map[int][]ImageInfo
+}
+
+// newImageReadyQueue 创建通知队列，order 为通知的顺序 (如PDF页码)
+func newImageReadyQueue(callback ImageReadyCallback, order []int) *imageReadyQueue {
+	return &imageReadyQueue{
+		callback: callback,
+		order:    order,
+		pending:  make(map[int][]ImageInfo),
+	}
+}
+
+// done 记录 key 对应的图片 (失败时为空)，并通知所有已经轮到的图片
+// 回调在持有锁时调用，因此不会并发，且顺序与 order 一致
+func (q *imageReadyQueue) done(key int, images []ImageInfo) {
+	if q.callback == nil {
+		return
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.pending[key] = images
+	for q.next < len(q.order) {
+		ready, ok := q.pending[q.order[q.next]]
+		if !ok {
+			return
+		}
+		delete(q.pending, q.order[q.next])
+		q.callback.notify(ready)
+		q.next++
+	}
+}
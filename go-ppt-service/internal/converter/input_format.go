@@ -0,0 +1,15 @@
+package converter
+
+// 转换后端名称
+const (
+	BackendPlaceholder = "placeholder"
+	BackendLibreOffice = "libreoffice"
+	BackendPowerPoint  = "powerpoint"
+)
+
+var (
+	// powerPointExtensions PowerPoint和占位渲染器支持的输入文件扩展名
+	powerPointExtensions = []string{".pptx", ".ppt"}
+	// libreOfficeExtensions LibreOffice支持的输入文件扩展名，Keynote通过LibreOffice的libetonyek导入
+	libreOfficeExtensions = []string{".pptx", ".ppt", ".odp", ".key"}
+)
@@ -0,0 +1,85 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// slideLayoutRelType 幻灯片到版式的关系类型
+const slideLayoutRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout"
+
+// pptxLayout 幻灯片版式XML中用到的部分
+type pptxLayout struct {
+	Type string `xml:"type,attr"` // 版式类型，如 title、secHead、obj
+	CSld struct {
+		Name string `xml:"name,attr"` // 版式名称，如 "Section Header"、"节标题"
+	} `xml:"cSld"`
+}
+
+// SlidesWithLayout 返回版式名称或类型与 filter 匹配 (忽略大小写) 的幻灯片编号，以及幻灯片总数
+// 版式通过幻灯片的 slideLayout 关系找到，名称取自版式XML的 cSld name 属性，类型取自 sldLayout type 属性
+func SlidesWithLayout(pptData []byte, filter string) ([]int, int, error) {
+	if !bytes.HasPrefix(pptData, []byte("PK\x03\x04")) {
+		return nil, 0, fmt.Errorf("%w: 按版式筛选只支持PPTX文件", ErrUnsupportedFormat)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(pptData), int64(len(pptData)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrCorruptFile, err)
+	}
+	if !isPPTXPackage(reader) {
+		return nil, 0, fmt.Errorf("%w: 按版式筛选只支持PPTX文件", ErrUnsupportedFormat)
+	}
+
+	paths, err := pptxSlidePaths(reader)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrCorruptFile, err)
+	}
+
+	filter = strings.TrimSpace(filter)
+	var matches []int
+	for i, slidePath := range paths {
+		if slidePath == "" {
+			continue
+		}
+		layout, err := readSlideLayout(reader, slidePath)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrCorruptFile, err)
+		}
+		if strings.EqualFold(layout.CSld.Name, filter) || strings.EqualFold(layout.Type, filter) {
+			matches = append(matches, i+1)
+		}
+	}
+
+	return matches, len(paths), nil
+}
+
+// readSlideLayout 通过幻灯片的关系文件读取它使用的版式
+func readSlideLayout(reader *zip.Reader, slidePath string) (pptxLayout, error) {
+	dir, file := path.Split(slidePath)
+
+	var rels struct {
+		Relationships []struct {
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := readZipXML(reader, path.Join(dir, "_rels", file+".rels"), &rels); err != nil {
+		return pptxLayout{}, err
+	}
+
+	var layout pptxLayout
+	for _, rel := range rels.Relationships {
+		if rel.Type != slideLayoutRelType {
+			continue
+		}
+		if err := readZipXML(reader, path.Join(dir, rel.Target), &layout); err != nil {
+			return pptxLayout{}, err
+		}
+		break
+	}
+	return layout, nil
+}
@@ -0,0 +1,71 @@
+//go:build !windows
+// +build !windows
+
+package converter
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fontConfigTemplate 每次转换使用的fontconfig配置，在系统配置的基础上追加字体目录
+const fontConfigTemplate = `<?xml version="1.0"?>
+<!DOCTYPE fontconfig SYSTEM "fonts.dtd">
+<fontconfig>
+  <include ignore_missing="yes">/etc/fonts/fonts.conf</include>
+%s  <cachedir>%s</cachedir>
+</fontconfig>
+`
+
+// fontEnv 生成本次转换的fontconfig配置，返回需要传给soffice的环境变量
+// 没有配置字体目录也没有上传字体时返回nil，使用系统默认配置
+func (r *LibreOfficeRenderer) fontEnv(workDir string, fonts []FontFile) ([]string, error) {
+	var dirs []string
+	if r.fontsDir != "" {
+		dirs = append(dirs, r.fontsDir)
+	}
+
+	// 随请求上传的字体只对本次转换生效，放在临时目录中随转换结束删除
+	if len(fonts) > 0 {
+		uploadDir, err := filepath.Abs(filepath.Join(workDir, "fonts"))
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(uploadDir, 0755); err != nil {
+			return nil, fmt.Errorf("创建字体目录失败: %v", err)
+		}
+		for _, font := range fonts {
+			name := filepath.Base(font.Filename)
+			if err := os.WriteFile(filepath.Join(uploadDir, name), font.Data, 0644); err != nil {
+				return nil, fmt.Errorf("写入字体文件失败: %v", err)
+			}
+		}
+		dirs = append(dirs, uploadDir)
+	}
+
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+
+	// fontconfig要求使用绝对路径
+	workDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirElements strings.Builder
+	for _, dir := range dirs {
+		fmt.Fprintf(&dirElements, "  <dir>%s</dir>\n", html.EscapeString(dir))
+	}
+	config := fmt.Sprintf(fontConfigTemplate, dirElements.String(), html.EscapeString(filepath.Join(workDir, "fontcache")))
+
+	configFile := filepath.Join(workDir, "fonts.conf")
+	if err := os.WriteFile(configFile, []byte(config), 0644); err != nil {
+		return nil, fmt.Errorf("创建字体配置失败: %v", err)
+	}
+
+	return append(os.Environ(), "FONTCONFIG_FILE="+configFile), nil
+}
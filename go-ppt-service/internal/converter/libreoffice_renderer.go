@@ -0,0 +1,428 @@
+//go:build !windows
+// +build !windows
+
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LibreOfficeRenderer 使用LibreOffice的渲染器 (Linux/macOS)
+// 先用soffice将PPT导出为PDF，再用pdftoppm将每一页渲染为图片
+type LibreOfficeRenderer struct {
+	sofficePath   string
+	pdftoppmPath  string
+	pdfinfoPath   string
+	profileDir    string
+	fontsDir      string
+	renderWorkers int
+	processes     *processTracker
+	logger        *logrus.Logger
+}
+
+// NewLibreOfficeRenderer 创建LibreOffice渲染器，未安装soffice或pdftoppm时返回 ErrBackendUnavailable
+func NewLibreOfficeRenderer(options Options, logger *logrus.Logger) (*LibreOfficeRenderer, error) {
+	sofficePath, err := exec.LookPath("soffice")
+	if err != nil {
+		return nil, fmt.Errorf("%w: 未找到soffice: %v", ErrBackendUnavailable, err)
+	}
+	pdftoppmPath, err := exec.LookPath("pdftoppm")
+	if err != nil {
+		return nil, fmt.Errorf("%w: 未找到pdftoppm: %v", ErrBackendUnavailable, err)
+	}
+
+	// pdfinfo用于获取页数以便逐页并行渲染，缺失时整份PDF一次渲染
+	pdfinfoPath, err := exec.LookPath("pdfinfo")
+	if err != nil {
+		logger.Warnf("未找到pdfinfo，不启用并行渲染: %v", err)
+		pdfinfoPath = ""
+	}
+
+	renderWorkers := options.RenderWorkers
+	if renderWorkers <= 0 {
+		renderWorkers = runtime.NumCPU()
+	}
+
+	// 每次转换的用户配置目录都创建在该目录下
+	profileDir := options.LibreOfficeProfileDir
+	if profileDir == "" {
+		profileDir = os.TempDir()
+	}
+	profileDir, err = filepath.Abs(profileDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(profileDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建LibreOffice配置目录失败: %v", err)
+	}
+
+	// 字体目录通过每次转换的fontconfig配置加载，目录不存在时只记录警告
+	fontsDir := options.FontsDir
+	if fontsDir != "" {
+		fontsDir, err = filepath.Abs(fontsDir)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(fontsDir); err != nil {
+			logger.Warnf("字体目录不可用: %v", err)
+		}
+	}
+
+	return &LibreOfficeRenderer{
+		sofficePath:   sofficePath,
+		pdftoppmPath:  pdftoppmPath,
+		pdfinfoPath:   pdfinfoPath,
+		profileDir:    profileDir,
+		fontsDir:      fontsDir,
+		renderWorkers: renderWorkers,
+		processes:     newProcessTracker(options.processLimiter),
+		logger:        logger,
+	}, nil
+}
+
+// Backend 转换后端名称
+func (r *LibreOfficeRenderer) Backend() string {
+	return BackendLibreOffice
+}
+
+// SupportedExtensions 支持的输入文件扩展名，除PowerPoint格式外还支持ODP和Keynote
+func (r *LibreOfficeRenderer) SupportedExtensions() []string {
+	return libreOfficeExtensions
+}
+
+// KillProcesses 强制结束仍在运行的soffice、pdfinfo和pdftoppm进程，返回结束的数量
+func (r *LibreOfficeRenderer) KillProcesses() int {
+	return r.processes.killAll()
+}
+
+// Close LibreOffice每次转换启动新进程，不持有资源
+func (r *LibreOfficeRenderer) Close() error {
+	return nil
+}
+
+// Render 将PPT导出为PDF后渲染需要的页
+func (r *LibreOfficeRenderer) Render(ctx context.Context, pptPath string, opts RenderOptions) ([]RenderedSlide, error) {
+	if opts.AnimationMode != AnimationFinal {
+		r.logger.Warnf("LibreOffice不支持动画模式 %s，按 %s 渲染", opts.AnimationMode, AnimationFinal)
+	}
+	if opts.ThemeVariant > 0 {
+		r.logger.Warnf("LibreOffice不支持主题变体，忽略 theme_variant=%d", opts.ThemeVariant)
+	}
+	opts.Report(20, "正在使用LibreOffice转换PPT...")
+
+	env, err := r.fontEnv(opts.WorkDir, opts.Fonts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 只需要封面且已知幻灯片总数时只导出第一页 (隐藏的幻灯片不导出，第一页就是封面)
+	pageRange := ""
+	if opts.coverTotal > 0 {
+		pageRange = "1"
+	}
+	pdfFile, err := r.exportPDF(ctx, pptPath, opts.WorkDir, env, opts.IncludeHidden, false, pageRange)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Report(60, "正在将PDF渲染为图片...")
+	var slides []RenderedSlide
+	if pageRange != "" {
+		slides, err = r.renderCover(ctx, pdfFile, opts)
+	} else {
+		slides, err = r.renderPages(ctx, pdfFile, opts)
+	}
+	if err == nil && opts.notesPDFPath != "" {
+		r.exportNotesPDF(ctx, pptPath, env, opts)
+	}
+	return slides, err
+}
+
+// exportNotesPDF 再次调用soffice只导出备注页，失败时只记录日志，由转换器用幻灯片图片生成备注页
+func (r *LibreOfficeRenderer) exportNotesPDF(ctx context.Context, pptPath string, env []string, opts RenderOptions) {
+	notesDir := filepath.Join(opts.WorkDir, "notes")
+	if err := os.MkdirAll(notesDir, 0755); err != nil {
+		r.logger.Warnf("导出备注页失败: %v", err)
+		return
+	}
+	notesFile, err := r.exportPDF(ctx, pptPath, notesDir, env, opts.IncludeHidden, true, "")
+	if err == nil {
+		err = os.Rename(notesFile, opts.notesPDFPath)
+	}
+	if err != nil {
+		r.logger.Warnf("导出备注页失败: %v", err)
+	}
+}
+
+// exportPDF 调用soffice将PPT导出为PDF，notesPages 为true时只导出备注页，pageRange 不为空时只导出其中的页 (如 "1")
+// 每次调用使用独立的用户配置目录，多个LibreOffice实例才能并行运行
+// env 不为空时作为soffice的环境变量 (用于指定字体配置)
+func (r *LibreOfficeRenderer) exportPDF(ctx context.Context, inputFile, workDir string, env []string, includeHidden, notesPages bool, pageRange string) (string, error) {
+	profile := filepath.Join(r.profileDir, fmt.Sprintf("lo_%d", time.Now().UnixNano()))
+	defer os.RemoveAll(profile)
+
+	profileURL := url.URL{Scheme: "file", Path: filepath.ToSlash(profile)}
+
+	// 导出隐藏的幻灯片、备注页和页码范围需要通过JSON形式的过滤器参数指定 (LibreOffice 7.4及以上)
+	var filterOptions []string
+	if includeHidden {
+		filterOptions = append(filterOptions, `"ExportHiddenSlides":{"type":"boolean","value":"true"}`)
+	}
+	if notesPages {
+		filterOptions = append(filterOptions,
+			`"ExportNotesPages":{"type":"boolean","value":"true"}`,
+			`"ExportOnlyNotesPages":{"type":"boolean","value":"true"}`)
+	}
+	if pageRange != "" {
+		filterOptions = append(filterOptions, `"PageRange":{"type":"string","value":"`+pageRange+`"}`)
+	}
+	target := "pdf"
+	if len(filterOptions) > 0 {
+		target = "pdf:impress_pdf_Export:{" + strings.Join(filterOptions, ",") + "}"
+	}
+
+	cmd := command(ctx, r.sofficePath,
+		"-env:UserInstallation="+profileURL.String(),
+		"--headless",
+		"--convert-to", target,
+		"--outdir", workDir,
+		inputFile,
+	)
+	cmd.Env = env
+	output, err := r.processes.run(ctx, cmd)
+	var execErr *exec.Error
+	if errors.As(err, &execErr) {
+		return "", fmt.Errorf("%w: %v", ErrBackendUnavailable, err)
+	}
+	if errors.Is(err, ErrCancelled) {
+		return "", err
+	}
+	if err != nil {
+		r.logger.Errorf("LibreOffice执行失败: %v", err)
+		r.logger.Errorf("输出: %s", string(output))
+		return "", fmt.Errorf("LibreOffice执行失败: %v", err)
+	}
+
+	r.logger.Debugf("LibreOffice输出: %s", string(output))
+
+	base := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+	pdfFile := filepath.Join(workDir, base+".pdf")
+	if _, err := os.Stat(pdfFile); err != nil {
+		// soffice无法打开文件时通常仍以0退出，只是不生成PDF
+		return "", fmt.Errorf("%w: LibreOffice未生成PDF文件", ErrCorruptFile)
+	}
+
+	return pdfFile, nil
+}
+
+// renderPages 将PDF中需要的页渲染为图片
+// 能获取页数时逐页并行调用pdftoppm，每页完成后立即提交；否则一次渲染整份PDF，不需要的页由 PPTConverter 删除
+// LibreOffice默认不导出隐藏的幻灯片，PDF页码需要换算回幻灯片编号
+func (r *LibreOfficeRenderer) renderPages(ctx context.Context, pdfFile string, opts RenderOptions) ([]RenderedSlide, error) {
+	if r.pdfinfoPath != "" && (r.renderWorkers > 1 || len(opts.SlideIndices) > 0) {
+		pageCount, err := r.pageCount(ctx, pdfFile)
+		if err == nil {
+			if err := opts.Counted(pageCount + len(opts.Hidden)); err != nil {
+				return nil, err
+			}
+
+			slideNumbers := pageSlideNumbers(pageCount, opts.Hidden)
+			var pages []int
+			for page := 1; page <= pageCount; page++ {
+				if opts.Selected(slideNumbers[page-1]) {
+					pages = append(pages, page)
+				}
+			}
+			return r.renderPagesParallel(ctx, pdfFile, pages, slideNumbers, opts)
+		}
+		r.logger.Warnf("获取PDF页数失败，改为整份渲染: %v", err)
+	}
+
+	if err := r.renderAllPages(ctx, pdfFile, opts); err != nil {
+		return nil, err
+	}
+
+	slides, err := scanRenderedSlides(opts.OutputPath, opts.Format)
+	if err != nil {
+		return nil, fmt.Errorf("扫描输出目录失败: %v", err)
+	}
+	if err := opts.Counted(len(slides) + len(opts.Hidden)); err != nil {
+		return nil, err
+	}
+	return slides, nil
+}
+
+// renderCover 渲染只包含封面一页的PDF，幻灯片总数使用转换器从PPTX读取的数量
+func (r *LibreOfficeRenderer) renderCover(ctx context.Context, pdfFile string, opts RenderOptions) ([]RenderedSlide, error) {
+	if err := opts.Counted(opts.coverTotal); err != nil {
+		return nil, err
+	}
+	return r.renderPagesParallel(ctx, pdfFile, []int{1}, opts.SlideIndices, opts)
+}
+
+// renderPagesParallel 最多 renderWorkers 个pdftoppm进程同时渲染，每个进程只渲染一页，完成后立即提交
+// slideNumbers 为每一页对应的幻灯片编号，用于生成文件名
+func (r *LibreOfficeRenderer) renderPagesParallel(ctx context.Context, pdfFile string, pages, slideNumbers []int, opts RenderOptions) ([]RenderedSlide, error) {
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		firstErr error
+	)
+	rendered := make(map[int]RenderedSlide, len(pages))
+
+	workers := r.renderWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	semaphore := make(chan struct{}, workers)
+	for _, page := range pages {
+		semaphore <- struct{}{}
+		// 取消后不再启动新的页面，已启动的pdftoppm随 ctx 一起结束
+		if ctx.Err() != nil {
+			<-semaphore
+			break
+		}
+		wg.Add(1)
+
+		go func(page int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			slide := RenderedSlide{SlideNumber: slideNumbers[page-1], BuildIndex: -1}
+			stem := filepath.Join(opts.OutputPath, slideFileStem(slide.SlideNumber, -1))
+			slide.FilePath = stem + "." + imageExtension(opts.Format)
+
+			width, height := opts.SizeFor(slide.SlideNumber)
+			args := r.pdftoppmArgs(opts.Format, width, height)
+			args = append(args, "-f", strconv.Itoa(page), "-l", strconv.Itoa(page), "-singlefile", pdfFile, stem)
+			err := r.runPdftoppm(ctx, args)
+			if err == nil {
+				opts.Rendered([]RenderedSlide{slide})
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("渲染第 %d 页失败: %v", page, err)
+				}
+				return
+			}
+			rendered[page] = slide
+		}(page)
+	}
+	wg.Wait()
+
+	if err := checkCancelled(ctx); err != nil {
+		return nil, err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	slides := make([]RenderedSlide, 0, len(rendered))
+	for _, page := range pages {
+		slides = append(slides, rendered[page])
+	}
+	return slides, nil
+}
+
+// pageCount 通过pdfinfo获取PDF页数
+func (r *LibreOfficeRenderer) pageCount(ctx context.Context, pdfFile string) (int, error) {
+	// 只解析标准输出，pdfinfo对不规范的PDF会在标准错误输出警告
+	cmd := command(ctx, r.pdfinfoPath, pdfFile)
+	cmd.Stderr = io.Discard
+	output, err := r.processes.run(ctx, cmd)
+	if err != nil {
+		return 0, fmt.Errorf("pdfinfo执行失败: %v", err)
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if !strings.HasPrefix(line, "Pages:") {
+			continue
+		}
+		pages, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Pages:")))
+		if err != nil {
+			return 0, fmt.Errorf("无法解析页数: %v", err)
+		}
+		return pages, nil
+	}
+
+	return 0, fmt.Errorf("pdfinfo输出中没有页数")
+}
+
+// pdftoppmArgs 输出格式和尺寸相关的pdftoppm参数
+func (r *LibreOfficeRenderer) pdftoppmArgs(format string, width, height int) []string {
+	args := []string{"-png"}
+	if imageExtension(format) == "jpg" {
+		args = []string{"-jpeg"}
+	}
+	if width > 0 && height > 0 {
+		args = append(args, "-scale-to-x", strconv.Itoa(width), "-scale-to-y", strconv.Itoa(height))
+	}
+	return args
+}
+
+// runPdftoppm 执行pdftoppm
+func (r *LibreOfficeRenderer) runPdftoppm(ctx context.Context, args []string) error {
+	output, err := r.processes.run(ctx, command(ctx, r.pdftoppmPath, args...))
+	if errors.Is(err, ErrCancelled) {
+		return err
+	}
+	if err != nil {
+		r.logger.Errorf("pdftoppm执行失败: %v", err)
+		r.logger.Errorf("输出: %s", string(output))
+		return fmt.Errorf("pdftoppm执行失败: %v", err)
+	}
+	return nil
+}
+
+// renderAllPages 调用pdftoppm一次渲染整份PDF，并按幻灯片编号重命名为 slide_001.png 格式
+func (r *LibreOfficeRenderer) renderAllPages(ctx context.Context, pdfFile string, opts RenderOptions) error {
+	ext := imageExtension(opts.Format)
+	outputPath := opts.OutputPath
+
+	args := append(r.pdftoppmArgs(opts.Format, opts.Width, opts.Height), pdfFile, filepath.Join(outputPath, "page"))
+
+	if err := r.runPdftoppm(ctx, args); err != nil {
+		return err
+	}
+
+	// pdftoppm按页数决定编号位数 (page-1.png 或 page-01.png)，统一重命名
+	matches, err := filepath.Glob(filepath.Join(outputPath, "page-*."+ext))
+	if err != nil {
+		return err
+	}
+	slideNumbers := pageSlideNumbers(len(matches), opts.Hidden)
+	for _, match := range matches {
+		number := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), "page-"), "."+ext)
+		pageNumber, err := strconv.Atoi(number)
+		if err != nil || pageNumber < 1 || pageNumber > len(slideNumbers) {
+			r.logger.Warnf("无法识别的页面文件: %s", match)
+			continue
+		}
+
+		target := filepath.Join(outputPath, slideFilename(slideNumbers[pageNumber-1], -1, ext))
+		if err := os.Rename(match, target); err != nil {
+			return fmt.Errorf("重命名页面文件失败: %v", err)
+		}
+	}
+
+	return nil
+}
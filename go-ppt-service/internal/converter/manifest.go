@@ -0,0 +1,127 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// manifestFilename 输出清单的文件名，与图片位于同一目录
+const manifestFilename = "manifest.json"
+
+// ManifestSchemaVersion 清单格式的版本，删除字段或改变字段含义时递增，只增加字段时不变
+const ManifestSchemaVersion = 1
+
+// Manifest 描述一次转换全部输出文件的清单 (manifest.json)
+type Manifest struct {
+	SchemaVersion   int            `json:"schema_version"`
+	ConversionID    string         `json:"conversion_id"`
+	SourceFile      string         `json:"source_file"`
+	CreatedAt       time.Time      `json:"created_at"`
+	TotalSlides     int            `json:"total_slides"`
+	ConvertedSlides int            `json:"converted_slides"`
+	HiddenSlides    int            `json:"hidden_slides"`
+	SlideSize       SlideSize      `json:"slide_size"`
+	Files           []ManifestFile `json:"files"`
+}
+
+// ManifestFile 清单中的一个输出文件，字段含义与 ImageInfo 相同
+type ManifestFile struct {
+	SlideNumber int    `json:"slide_number"` // 总览图和HTML页面为0
+	BuildIndex  int    `json:"build_index"`
+	TileIndex   int    `json:"tile_index"`
+	Resolution  string `json:"resolution"`
+	Filename    string `json:"filename"`
+	Format      string `json:"format"`
+	FileSize    int64  `json:"file_size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	DownloadID  string `json:"download_id"`
+	SHA256      string `json:"sha256"`                // 文件内容的SHA-256 (十六进制)
+	Placeholder bool   `json:"placeholder,omitempty"` // 占位渲染器生成的图片，不是幻灯片的真实内容
+	Backend     string `json:"backend,omitempty"`     // 生成该图片的渲染后端
+}
+
+// appendManifest 生成描述 result 中所有文件的 manifest.json，追加到图片列表并记录它的下载ID
+// 在所有文件都不再改动之后调用，失败时只记录日志
+func (c *PPTConverter) appendManifest(result *ConversionResult, outputPath, filename string, options ConversionOptions) {
+	manifest := Manifest{
+		SchemaVersion:   ManifestSchemaVersion,
+		ConversionID:    options.ConversionID,
+		SourceFile:      filepath.Base(filename),
+		CreatedAt:       time.Now(),
+		TotalSlides:     result.TotalSlides,
+		ConvertedSlides: result.ConvertedSlides,
+		HiddenSlides:    result.HiddenSlides,
+		SlideSize:       result.SlideSize,
+		Files:           make([]ManifestFile, 0, len(result.Images)),
+	}
+
+	for _, image := range result.Images {
+		checksum, err := fileSHA256(image.FilePath)
+		if err != nil {
+			c.logger.Warnf("生成输出清单失败: %v", err)
+			return
+		}
+		manifest.Files = append(manifest.Files, ManifestFile{
+			SlideNumber: image.SlideNumber,
+			BuildIndex:  image.BuildIndex,
+			TileIndex:   image.TileIndex,
+			Resolution:  image.Resolution,
+			Filename:    image.Filename,
+			Format:      image.Format,
+			FileSize:    image.FileSize,
+			Width:       image.Width,
+			Height:      image.Height,
+			// 与服务器登记的下载ID一致
+			DownloadID:  NamespacedDownloadID(options.ConversionID, image.DownloadID),
+			SHA256:      checksum,
+			Placeholder: image.Placeholder,
+			Backend:     image.Backend,
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		c.logger.Warnf("生成输出清单失败: %v", err)
+		return
+	}
+
+	filePath := filepath.Join(outputPath, manifestFilename)
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		c.logger.Warnf("生成输出清单失败: %v", err)
+		return
+	}
+
+	c.logger.Infof("生成输出清单: %s (%d 个文件)", manifestFilename, len(manifest.Files))
+	info := ImageInfo{
+		SlideNumber: 0,
+		Filename:    manifestFilename,
+		FilePath:    filePath,
+		FileSize:    int64(len(data)),
+		DownloadID:  generateDownloadID(),
+		Format:      "JSON",
+	}
+	result.Images = append(result.Images, info)
+	result.ManifestDownloadID = info.DownloadID
+}
+
+// fileSHA256 计算文件内容的SHA-256，返回十六进制字符串
+func fileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, file); err != nil {
+		return "", fmt.Errorf("读取 %s 失败: %v", filepath.Base(filePath), err)
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
@@ -0,0 +1,76 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertPPTManifest(t *testing.T) {
+	c, _ := newTestConverter(t, &MockRenderer{Slides: 3}, Options{})
+
+	result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{
+		ConversionID:     "job",
+		GenerateManifest: true,
+		HTMLBundle:       true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+
+	// 3张幻灯片、index.html 和 manifest.json
+	if len(result.Images) != 5 {
+		t.Fatalf("返回了 %d 个文件，应为5个", len(result.Images))
+	}
+	last := result.Images[len(result.Images)-1]
+	if last.Filename != manifestFilename || last.Format != "JSON" || last.DownloadID != result.ManifestDownloadID {
+		t.Fatalf("最后一个文件为 %+v，清单下载ID为 %q", last, result.ManifestDownloadID)
+	}
+
+	data, err := os.ReadFile(last.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("解析清单失败: %v", err)
+	}
+
+	if manifest.SchemaVersion != ManifestSchemaVersion || manifest.ConversionID != "job" || manifest.SourceFile != "deck.ppt" {
+		t.Errorf("清单头部为 %+v", manifest)
+	}
+	if manifest.TotalSlides != 3 || manifest.ConvertedSlides != 3 {
+		t.Errorf("清单中总数 %d，转换 %d", manifest.TotalSlides, manifest.ConvertedSlides)
+	}
+	if len(manifest.Files) != 4 {
+		t.Fatalf("清单中有 %d 个文件，应为4个 (不包括清单自身)", len(manifest.Files))
+	}
+	for i, file := range manifest.Files {
+		image := result.Images[i]
+		checksum, err := fileSHA256(image.FilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Filename != image.Filename || file.DownloadID != NamespacedDownloadID("job", image.DownloadID) || file.FileSize != image.FileSize ||
+			file.Width != image.Width || file.Height != image.Height || file.SHA256 != checksum {
+			t.Errorf("清单第 %d 项 %+v 与 %+v 不一致", i, file, image)
+		}
+	}
+}
+
+func TestConvertPPTWithoutManifest(t *testing.T) {
+	c, outputDir := newTestConverter(t, &MockRenderer{Slides: 1}, Options{})
+
+	result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{OutputSubdir: "job"}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+	if result.ManifestDownloadID != "" || len(result.Images) != 1 {
+		t.Errorf("没有设置 GenerateManifest 时生成了清单: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "job", manifestFilename)); !os.IsNotExist(err) {
+		t.Errorf("输出目录中有 %s", manifestFilename)
+	}
+}
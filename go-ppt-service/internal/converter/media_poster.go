@@ -0,0 +1,275 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"path"
+
+	"github.com/disintegration/imaging"
+)
+
+var (
+	// posterBackground 没有封面的视频区域的底色
+	posterBackground = color.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xff}
+	// posterPlayButton 播放按钮的颜色
+	posterPlayButton = color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+)
+
+// mediaPoster 幻灯片上的一个视频，位置和尺寸为占幻灯片宽高的比例
+type mediaPoster struct {
+	X, Y, Width, Height float64
+	// Image 视频的封面帧，PPTX中没有封面或无法解码时为nil，此时绘制播放按钮
+	Image image.Image
+}
+
+// pptxPicture 幻灯片中图片元素 (视频也以图片元素保存，blip为封面帧) 用到的部分
+type pptxPicture struct {
+	VideoFile  *struct{} `xml:"nvPicPr>nvPr>videoFile"`
+	Extensions []struct {
+		Media *struct{} `xml:"media"`
+	} `xml:"nvPicPr>nvPr>extLst>ext"`
+	Blip struct {
+		Embed string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships embed,attr"`
+	} `xml:"blipFill>blip"`
+	Offset struct {
+		X int64 `xml:"x,attr"`
+		Y int64 `xml:"y,attr"`
+	} `xml:"spPr>xfrm>off"`
+	Extent struct {
+		CX int64 `xml:"cx,attr"`
+		CY int64 `xml:"cy,attr"`
+	} `xml:"spPr>xfrm>ext"`
+}
+
+// isVideo 图片元素是否为嵌入或链接的视频 (a:videoFile 或 PowerPoint 2010 的 p14:media)
+func (p pptxPicture) isVideo() bool {
+	if p.VideoFile != nil {
+		return true
+	}
+	for _, ext := range p.Extensions {
+		if ext.Media != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readMediaPosters 读取每张幻灯片 (原始编号) 上的视频及其封面帧
+// 只处理直接位于幻灯片上的视频，组合中的视频和旋转、翻转不处理；不是PPTX (如PPT、ODP) 时返回nil
+func readMediaPosters(pptData []byte) (map[int][]mediaPoster, error) {
+	if !bytes.HasPrefix(pptData, []byte("PK\x03\x04")) {
+		return nil, nil
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(pptData), int64(len(pptData)))
+	if err != nil {
+		return nil, fmt.Errorf("打开PPTX失败: %v", err)
+	}
+	if !isPPTXPackage(reader) {
+		return nil, nil
+	}
+
+	var presentation struct {
+		SlideSize struct {
+			CX int64 `xml:"cx,attr"`
+			CY int64 `xml:"cy,attr"`
+		} `xml:"sldSz"`
+	}
+	if err := readZipXML(reader, "ppt/presentation.xml", &presentation); err != nil {
+		return nil, err
+	}
+	slideWidth, slideHeight := presentation.SlideSize.CX, presentation.SlideSize.CY
+	if slideWidth <= 0 || slideHeight <= 0 {
+		return nil, nil
+	}
+
+	paths, err := pptxSlidePaths(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	posters := make(map[int][]mediaPoster)
+	for i, slidePath := range paths {
+		if slidePath == "" {
+			continue
+		}
+
+		var slide struct {
+			Pictures []pptxPicture `xml:"cSld>spTree>pic"`
+		}
+		if err := readZipXML(reader, slidePath, &slide); err != nil {
+			return nil, err
+		}
+
+		var targets map[string]string
+		for _, picture := range slide.Pictures {
+			if !picture.isVideo() || picture.Extent.CX <= 0 || picture.Extent.CY <= 0 {
+				continue
+			}
+			if targets == nil {
+				if targets, err = slideRelationships(reader, slidePath); err != nil {
+					return nil, err
+				}
+			}
+
+			poster := mediaPoster{
+				X:      float64(picture.Offset.X) / float64(slideWidth),
+				Y:      float64(picture.Offset.Y) / float64(slideHeight),
+				Width:  float64(picture.Extent.CX) / float64(slideWidth),
+				Height: float64(picture.Extent.CY) / float64(slideHeight),
+			}
+			if target, ok := targets[picture.Blip.Embed]; ok {
+				poster.Image = readZipImage(reader, target)
+			}
+			posters[i+1] = append(posters[i+1], poster)
+		}
+	}
+	return posters, nil
+}
+
+// slideRelationships 幻灯片关系ID到ZIP中文件路径的映射，外部链接 (TargetMode="External") 不包括在内
+func slideRelationships(reader *zip.Reader, slidePath string) (map[string]string, error) {
+	var rels struct {
+		Relationships []struct {
+			ID         string `xml:"Id,attr"`
+			Target     string `xml:"Target,attr"`
+			TargetMode string `xml:"TargetMode,attr"`
+		} `xml:"Relationship"`
+	}
+	relsPath := path.Join(path.Dir(slidePath), "_rels", path.Base(slidePath)+".rels")
+	if err := readZipXML(reader, relsPath, &rels); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if rel.TargetMode != "External" {
+			targets[rel.ID] = path.Join(path.Dir(slidePath), rel.Target)
+		}
+	}
+	return targets, nil
+}
+
+// readZipImage 读取并解码ZIP中的图片，不存在或无法解码 (如EMF封面) 时返回nil
+func readZipImage(reader *zip.Reader, name string) image.Image {
+	file, err := reader.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+// withMediaPosters 请求设置 RenderMediaPosters 时读取视频封面并记录在选项中，失败时只记录日志
+func (c *PPTConverter) withMediaPosters(pptData []byte, options ConversionOptions) ConversionOptions {
+	if !options.RenderMediaPosters {
+		return options
+	}
+	posters, err := readMediaPosters(pptData)
+	if err != nil {
+		c.logger.Warnf("读取视频封面失败: %v", err)
+	}
+	if len(posters) == 0 {
+		c.logger.Debugf("演示文稿中没有视频，不合成视频封面")
+	}
+	options.mediaPosters = posters
+	return options
+}
+
+// drawMediaPostersToFiles 把视频封面 (没有时为播放按钮) 合成到有视频的幻灯片图片上，失败时只记录日志
+// 在空白检查、自动裁剪和叠加内容之前调用，此时图片与幻灯片一一对应，按比例即可换算位置
+func (c *PPTConverter) drawMediaPostersToFiles(images []ImageInfo, options ConversionOptions) {
+	if len(options.mediaPosters) == 0 {
+		return
+	}
+
+	filter := options.resampleFilter()
+	for i := range images {
+		posters := options.mediaPosters[images[i].SlideNumber-options.NumberOffset]
+		if len(posters) == 0 {
+			continue
+		}
+		err := c.rewriteImage(&images[i], options.JPEGSubsampling, func(img image.Image) (image.Image, error) {
+			return drawMediaPosters(img, posters, filter), nil
+		})
+		if err != nil {
+			c.logger.Warnf("第 %d 张幻灯片合成视频封面失败: %v", images[i].SlideNumber, err)
+		}
+	}
+}
+
+// drawMediaPosters 在图片上按位置绘制每个视频的封面
+func drawMediaPosters(img image.Image, posters []mediaPoster, filter imaging.ResampleFilter) image.Image {
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), img, bounds.Min, draw.Src)
+
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	for _, poster := range posters {
+		area := image.Rect(
+			int(poster.X*width+0.5), int(poster.Y*height+0.5),
+			int((poster.X+poster.Width)*width+0.5), int((poster.Y+poster.Height)*height+0.5),
+		)
+		visible := area.Intersect(canvas.Bounds())
+		if visible.Empty() {
+			continue
+		}
+
+		// 超出幻灯片的视频按完整区域缩放封面，只绘制可见的部分
+		if poster.Image != nil {
+			frame := imaging.Resize(poster.Image, area.Dx(), area.Dy(), filter)
+			draw.Draw(canvas, area, frame, image.Point{}, draw.Over)
+		} else {
+			drawPlayButton(canvas, visible)
+		}
+	}
+	return canvas
+}
+
+// drawPlayButton 用深色填充区域并在中央绘制圆形播放按钮
+func drawPlayButton(canvas *image.RGBA, rect image.Rectangle) {
+	draw.Draw(canvas, rect, image.NewUniform(posterBackground), image.Point{}, draw.Src)
+
+	radius := min(rect.Dx(), rect.Dy()) / 5
+	if radius < 2 {
+		return
+	}
+	cx, cy := rect.Min.X+rect.Dx()/2, rect.Min.Y+rect.Dy()/2
+
+	// 圆环
+	outer, inner := radius*radius, (radius*9/10)*(radius*9/10)
+	for y := -radius; y <= radius; y++ {
+		for x := -radius; x <= radius; x++ {
+			if d := x*x + y*y; d <= outer && d >= inner {
+				canvas.Set(cx+x, cy+y, posterPlayButton)
+			}
+		}
+	}
+
+	// 指向右侧的三角形，重心略向右移使视觉上居中
+	half := radius / 2
+	left, right := cx-half*2/3, cx+half*4/3
+	for x := left; x <= right; x++ {
+		extent := half * (right - x) / (right - left)
+		for y := cy - extent; y <= cy+extent; y++ {
+			canvas.Set(x, y, posterPlayButton)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestMediaPosters(t *testing.T) {
+	poster := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range poster.Pix {
+		poster.Pix[i] = 0xff
+		if i%4 == 1 || i%4 == 2 {
+			poster.Pix[i] = 0 // 红色
+		}
+	}
+	var posterPNG bytes.Buffer
+	if err := png.Encode(&posterPNG, poster); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第1张幻灯片有一个带封面的视频和一张普通图片，第2张幻灯片有一个没有封面的视频
+	pptx := zipPackage(t, map[string][]byte{
+		"ppt/presentation.xml": []byte(`<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<p:sldIdLst><p:sldId id="256" r:id="rId1"/><p:sldId id="257" r:id="rId2"/></p:sldIdLst><p:sldSz cx="1000" cy="500"/></p:presentation>`),
+		"ppt/_rels/presentation.xml.rels": []byte(`<Relationships><Relationship Id="rId1" Target="slides/slide1.xml"/><Relationship Id="rId2" Target="slides/slide2.xml"/></Relationships>`),
+		"ppt/slides/slide1.xml": []byte(`<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:cSld><p:spTree>
+<p:pic><p:nvPicPr><p:nvPr/></p:nvPicPr><p:blipFill><a:blip r:embed="rId2"/></p:blipFill><p:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="100" cy="100"/></a:xfrm></p:spPr></p:pic>
+<p:pic><p:nvPicPr><p:nvPr><a:videoFile r:link="rId1"/></p:nvPr></p:nvPicPr><p:blipFill><a:blip r:embed="rId2"/></p:blipFill><p:spPr><a:xfrm><a:off x="500" y="250"/><a:ext cx="500" cy="250"/></a:xfrm></p:spPr></p:pic>
+</p:spTree></p:cSld></p:sld>`),
+		"ppt/slides/_rels/slide1.xml.rels": []byte(`<Relationships><Relationship Id="rId1" Target="../media/media1.mp4"/><Relationship Id="rId2" Target="../media/image1.png"/></Relationships>`),
+		"ppt/slides/slide2.xml": []byte(`<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:p14="http://schemas.microsoft.com/office/powerpoint/2010/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:cSld><p:spTree>
+<p:pic><p:nvPicPr><p:nvPr><p:extLst><p:ext uri="{DAA4B4D4-6D71-4841-9C94-3DE7FCFB9230}"><p14:media r:embed="rId1"/></p:ext></p:extLst></p:nvPr></p:nvPicPr><p:spPr><a:xfrm xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><a:off x="0" y="0"/><a:ext cx="1000" cy="500"/></a:xfrm></p:spPr></p:pic>
+</p:spTree></p:cSld></p:sld>`),
+		"ppt/slides/_rels/slide2.xml.rels": []byte(`<Relationships><Relationship Id="rId1" Target="../media/media2.mp4"/></Relationships>`),
+		"ppt/media/image1.png":             posterPNG.Bytes(),
+	})
+
+	posters, err := readMediaPosters(pptx)
+	if err != nil {
+		t.Fatalf("readMediaPosters 失败: %v", err)
+	}
+	if len(posters) != 2 || len(posters[1]) != 1 || len(posters[2]) != 1 {
+		t.Fatalf("视频为 %+v，应为每张幻灯片各一个", posters)
+	}
+	if p := posters[1][0]; p.X != 0.5 || p.Y != 0.5 || p.Width != 0.5 || p.Height != 0.5 || p.Image == nil {
+		t.Errorf("第1张幻灯片的视频为 %+v", p)
+	}
+	if posters[2][0].Image != nil {
+		t.Error("没有封面的视频不应有封面图片")
+	}
+
+	white := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for i := range white.Pix {
+		white.Pix[i] = 0xff
+	}
+	drawn := drawMediaPosters(white, posters[1], imaging.Lanczos)
+	if got := color.RGBAModel.Convert(drawn.At(150, 75)).(color.RGBA); got != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Errorf("视频区域的颜色为 %v，应为封面的红色", got)
+	}
+	if got := color.RGBAModel.Convert(drawn.At(50, 25)).(color.RGBA); got != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Errorf("视频区域之外的颜色为 %v，不应改变", got)
+	}
+
+	drawn = drawMediaPosters(white, posters[2], imaging.Lanczos)
+	if got := color.RGBAModel.Convert(drawn.At(2, 2)).(color.RGBA); got != posterBackground {
+		t.Errorf("没有封面时视频区域的颜色为 %v，应为 %v", got, posterBackground)
+	}
+	if got := color.RGBAModel.Convert(drawn.At(100, 50)).(color.RGBA); got != posterPlayButton {
+		t.Errorf("没有封面时中央的颜色为 %v，应为播放按钮", got)
+	}
+
+	if posters, err := readMediaPosters([]byte("not a zip")); err != nil || posters != nil {
+		t.Errorf("非PPTX: %v, %v", posters, err)
+	}
+}
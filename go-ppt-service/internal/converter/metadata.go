@@ -0,0 +1,186 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"strconv"
+	"unicode/utf16"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// imageMetadata 写入输出图片的元数据
+type imageMetadata struct {
+	SourceFile  string // 源PPT文件名
+	SlideNumber int    // 幻灯片编号
+}
+
+// embedImageMetadata 为外部工具生成的图片文件补写元数据，并更新文件大小
+func (c *PPTConverter) embedImageMetadata(images []ImageInfo, sourceFile string) {
+	for i := range images {
+		meta := &imageMetadata{SourceFile: sourceFile, SlideNumber: images[i].SlideNumber}
+
+		data, err := os.ReadFile(images[i].FilePath)
+		if err == nil {
+			data, err = addImageMetadata(data, meta)
+		}
+		if err == nil {
+			err = os.WriteFile(images[i].FilePath, data, 0644)
+		}
+		if err != nil {
+			c.logger.Warnf("写入第 %d 张幻灯片元数据失败: %v", images[i].SlideNumber, err)
+			continue
+		}
+
+		images[i].FileSize = int64(len(data))
+	}
+}
+
+// addImageMetadata 根据文件头判断格式，PNG写入文本块，JPEG写入EXIF UserComment
+func addImageMetadata(data []byte, meta *imageMetadata) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		return addPNGTextChunks(data, [][2]string{
+			{"SourceFile", meta.SourceFile},
+			{"SlideNumber", strconv.Itoa(meta.SlideNumber)},
+		})
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xD8:
+		comment := fmt.Sprintf("SourceFile=%s; SlideNumber=%d", meta.SourceFile, meta.SlideNumber)
+		return addJPEGUserComment(data, comment)
+	default:
+		return nil, fmt.Errorf("不支持的图片格式")
+	}
+}
+
+// addPNGTextChunks 在IHDR块之后插入文本块
+// 文本可以用Latin-1表示时写入tEXt块，否则写入UTF-8编码的iTXt块
+func addPNGTextChunks(data []byte, pairs [][2]string) ([]byte, error) {
+	// 签名(8) + IHDR块: 长度(4) + 类型(4) + 数据(13) + CRC(4)
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if len(data) < ihdrEnd || string(data[12:16]) != "IHDR" {
+		return nil, fmt.Errorf("无效的PNG文件")
+	}
+
+	var chunks bytes.Buffer
+	for _, pair := range pairs {
+		if latin1, ok := toLatin1(pair[1]); ok {
+			payload := append([]byte(pair[0]), 0)
+			payload = append(payload, latin1...)
+			writePNGChunk(&chunks, "tEXt", payload)
+			continue
+		}
+
+		// iTXt: 关键字\0 压缩标志 压缩方法 语言标签\0 翻译关键字\0 文本
+		payload := append([]byte(pair[0]), 0, 0, 0, 0, 0)
+		payload = append(payload, pair[1]...)
+		writePNGChunk(&chunks, "iTXt", payload)
+	}
+
+	result := make([]byte, 0, len(data)+chunks.Len())
+	result = append(result, data[:ihdrEnd]...)
+	result = append(result, chunks.Bytes()...)
+	result = append(result, data[ihdrEnd:]...)
+	return result, nil
+}
+
+// writePNGChunk 写入一个PNG数据块
+func writePNGChunk(buf *bytes.Buffer, chunkType string, payload []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(payload)))
+	buf.WriteString(chunkType)
+	buf.Write(payload)
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(payload)
+	binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
+
+// toLatin1 将字符串转换为Latin-1字节，存在无法表示的字符时返回false
+func toLatin1(s string) ([]byte, bool) {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			return nil, false
+		}
+		out = append(out, byte(r))
+	}
+	return out, true
+}
+
+// addJPEGUserComment 在SOI之后插入只包含UserComment的EXIF (APP1) 段
+func addJPEGUserComment(data []byte, comment string) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("无效的JPEG文件")
+	}
+
+	// UserComment前8字节为字符集标识
+	var userComment []byte
+	if isASCII(comment) {
+		userComment = append([]byte("ASCII\x00\x00\x00"), comment...)
+	} else {
+		userComment = []byte("UNICODE\x00")
+		for _, u := range utf16.Encode([]rune(comment)) {
+			userComment = binary.BigEndian.AppendUint16(userComment, u)
+		}
+	}
+
+	// TIFF结构 (大端): 文件头(8) + IFD0(18) + Exif IFD(18) + UserComment数据
+	const (
+		ifd0Offset = 8
+		exifOffset = ifd0Offset + 18
+		dataOffset = exifOffset + 18
+	)
+
+	var tiff bytes.Buffer
+	tiff.Write([]byte{'M', 'M', 0x00, 0x2A})
+	binary.Write(&tiff, binary.BigEndian, uint32(ifd0Offset))
+
+	// IFD0: 只有一个指向Exif IFD的条目
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	writeIFDEntry(&tiff, 0x8769, 4, 1, exifOffset) // ExifIFDPointer, LONG
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+
+	// Exif IFD: UserComment
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	writeIFDEntry(&tiff, 0x9286, 7, uint32(len(userComment)), dataOffset) // UserComment, UNDEFINED
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+
+	tiff.Write(userComment)
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	if len(payload)+2 > 0xFFFF {
+		return nil, fmt.Errorf("EXIF数据过大")
+	}
+
+	var segment bytes.Buffer
+	segment.Write([]byte{0xFF, 0xE1})
+	binary.Write(&segment, binary.BigEndian, uint16(len(payload)+2))
+	segment.Write(payload)
+
+	result := make([]byte, 0, len(data)+segment.Len())
+	result = append(result, data[:2]...)
+	result = append(result, segment.Bytes()...)
+	result = append(result, data[2:]...)
+	return result, nil
+}
+
+// writeIFDEntry 写入一个12字节的IFD条目
+func writeIFDEntry(buf *bytes.Buffer, tag, fieldType uint16, count, value uint32) {
+	binary.Write(buf, binary.BigEndian, tag)
+	binary.Write(buf, binary.BigEndian, fieldType)
+	binary.Write(buf, binary.BigEndian, count)
+	binary.Write(buf, binary.BigEndian, value)
+}
+
+// isASCII 字符串是否只包含ASCII字符
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > 0x7F {
+			return false
+		}
+	}
+	return true
+}
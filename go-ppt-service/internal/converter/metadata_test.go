@@ -0,0 +1,245 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// pngChunk PNG文件中的一个数据块
+type pngChunk struct {
+	Type string
+	Data []byte
+}
+
+// readPNGChunks 按顺序读取PNG文件的所有数据块，并检查每块的CRC
+func readPNGChunks(t *testing.T, data []byte) []pngChunk {
+	t.Helper()
+
+	if !bytes.HasPrefix(data, pngSignature) {
+		t.Fatal("不是PNG文件")
+	}
+	var chunks []pngChunk
+	for offset := len(pngSignature); offset < len(data); {
+		if offset+12 > len(data) {
+			t.Fatalf("偏移 %d 处的数据块被截断", offset)
+		}
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		end := offset + 8 + length + 4
+		if end > len(data) {
+			t.Fatalf("偏移 %d 处的数据块被截断", offset)
+		}
+		chunk := pngChunk{Type: string(data[offset+4 : offset+8]), Data: data[offset+8 : offset+8+length]}
+		if crc := crc32.ChecksumIEEE(data[offset+4 : offset+8+length]); crc != binary.BigEndian.Uint32(data[end-4:]) {
+			t.Fatalf("%s 块的CRC错误", chunk.Type)
+		}
+		chunks = append(chunks, chunk)
+		offset = end
+	}
+	return chunks
+}
+
+// jpegSegment JPEG文件中SOS之前的一个标记段
+type jpegSegment struct {
+	Marker byte
+	Data   []byte
+}
+
+// readJPEGSegments 读取JPEG文件从SOI到SOS之前的所有标记段
+func readJPEGSegments(t *testing.T, data []byte) []jpegSegment {
+	t.Helper()
+
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		t.Fatal("不是JPEG文件")
+	}
+	var segments []jpegSegment
+	for offset := 2; ; {
+		if offset+4 > len(data) || data[offset] != 0xFF {
+			t.Fatalf("偏移 %d 处不是有效的标记段", offset)
+		}
+		marker := data[offset+1]
+		if marker == 0xDA {
+			return segments
+		}
+		end := offset + 2 + int(binary.BigEndian.Uint16(data[offset+2:]))
+		if end > len(data) {
+			t.Fatalf("偏移 %d 处的标记段被截断", offset)
+		}
+		segments = append(segments, jpegSegment{Marker: marker, Data: data[offset+4 : end]})
+		offset = end
+	}
+}
+
+// pngText 读取PNG的tEXt (Latin-1) 和iTXt (UTF-8，未压缩) 块
+func pngText(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	text := make(map[string]string)
+	for _, chunk := range readPNGChunks(t, data) {
+		switch chunk.Type {
+		case "tEXt":
+			keyword, value, _ := bytes.Cut(chunk.Data, []byte{0})
+			runes := make([]rune, len(value))
+			for i, b := range value {
+				runes[i] = rune(b)
+			}
+			text[string(keyword)] = string(runes)
+		case "iTXt":
+			// 关键字\0 压缩标志 压缩方法 语言标签\0 翻译关键字\0 文本
+			keyword, rest, _ := bytes.Cut(chunk.Data, []byte{0})
+			if rest[0] != 0 {
+				t.Fatalf("iTXt块 %s 被压缩", keyword)
+			}
+			_, rest, _ = bytes.Cut(rest[2:], []byte{0})
+			_, value, _ := bytes.Cut(rest, []byte{0})
+			text[string(keyword)] = string(value)
+		}
+	}
+	return text
+}
+
+// jpegUserComment 从JPEG的EXIF (APP1) 段中读取UserComment
+func jpegUserComment(t *testing.T, data []byte) string {
+	t.Helper()
+
+	for _, segment := range readJPEGSegments(t, data) {
+		if segment.Marker != 0xE1 || !bytes.HasPrefix(segment.Data, []byte("Exif\x00\x00")) {
+			continue
+		}
+		tiff := segment.Data[6:]
+		if string(tiff[:4]) != "MM\x00\x2A" {
+			t.Fatalf("TIFF文件头为 %q", tiff[:4])
+		}
+
+		exifOffset, ok := ifdValue(tiff, binary.BigEndian.Uint32(tiff[4:]), 0x8769)
+		if !ok {
+			t.Fatal("IFD0中没有ExifIFDPointer")
+		}
+		entry, ok := ifdEntry(tiff, exifOffset, 0x9286)
+		if !ok {
+			t.Fatal("Exif IFD中没有UserComment")
+		}
+		count := binary.BigEndian.Uint32(entry[4:])
+		offset := binary.BigEndian.Uint32(entry[8:])
+		comment := tiff[offset : offset+count]
+
+		switch charset := string(comment[:8]); charset {
+		case "ASCII\x00\x00\x00":
+			return string(comment[8:])
+		case "UNICODE\x00":
+			units := make([]uint16, 0, (len(comment)-8)/2)
+			for i := 8; i+1 < len(comment); i += 2 {
+				units = append(units, binary.BigEndian.Uint16(comment[i:]))
+			}
+			return string(utf16.Decode(units))
+		default:
+			t.Fatalf("UserComment的字符集为 %q", charset)
+		}
+	}
+	t.Fatal("没有EXIF段")
+	return ""
+}
+
+// ifdEntry 在大端TIFF数据中查找IFD条目，返回12字节的条目
+func ifdEntry(tiff []byte, ifdOffset uint32, tag uint16) ([]byte, bool) {
+	count := int(binary.BigEndian.Uint16(tiff[ifdOffset:]))
+	for i := 0; i < count; i++ {
+		entry := tiff[int(ifdOffset)+2+i*12:][:12]
+		if binary.BigEndian.Uint16(entry) == tag {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// ifdValue IFD条目中的LONG值
+func ifdValue(tiff []byte, ifdOffset uint32, tag uint16) (uint32, bool) {
+	entry, ok := ifdEntry(tiff, ifdOffset, tag)
+	if !ok {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(entry[8:]), true
+}
+
+// encodeTestImage 编码一张纯色的小图片
+func encodeTestImage(t *testing.T, format string) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 16, 9))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	var buf bytes.Buffer
+	var err error
+	if format == "PNG" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAddImageMetadata(t *testing.T) {
+	tests := []struct {
+		format     string
+		sourceFile string
+		chunk      string // PNG文本写入的块类型
+	}{
+		{"PNG", "deck.pptx", "tEXt"},
+		{"PNG", "café.pptx", "tEXt"},
+		{"PNG", "年度汇报.pptx", "iTXt"}, // 无法用Latin-1表示，改用UTF-8
+		{"JPEG", "deck.pptx", ""},
+		{"JPEG", "年度汇报.pptx", ""},
+	}
+
+	for _, tt := range tests {
+		data, err := addImageMetadata(encodeTestImage(t, tt.format), &imageMetadata{SourceFile: tt.sourceFile, SlideNumber: 12})
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.format, tt.sourceFile, err)
+		}
+
+		// 写入元数据后图片仍然可以解码
+		if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+			t.Errorf("%s %s: 写入元数据后无法解码: %v", tt.format, tt.sourceFile, err)
+		}
+
+		if tt.format == "PNG" {
+			text := pngText(t, data)
+			if text["SourceFile"] != tt.sourceFile || text["SlideNumber"] != "12" {
+				t.Errorf("%s: 读取的文本为 %v", tt.sourceFile, text)
+			}
+			var types []string
+			for _, chunk := range readPNGChunks(t, data) {
+				types = append(types, chunk.Type)
+			}
+			// 文本块紧跟IHDR，位于IDAT之前
+			if len(types) < 2 || types[0] != "IHDR" || types[1] != tt.chunk {
+				t.Errorf("%s: 数据块顺序为 %v，IHDR之后应为 %s", tt.sourceFile, types, tt.chunk)
+			}
+			continue
+		}
+
+		comment := jpegUserComment(t, data)
+		if want := "SourceFile=" + tt.sourceFile + "; SlideNumber=12"; comment != want {
+			t.Errorf("%s: UserComment为 %q，应为 %q", tt.sourceFile, comment, want)
+		}
+	}
+}
+
+func TestAddImageMetadataUnsupportedFormat(t *testing.T) {
+	if _, err := addImageMetadata([]byte("GIF89a"), &imageMetadata{SourceFile: "deck.pptx", SlideNumber: 1}); err == nil {
+		t.Error("不支持的格式应返回错误")
+	}
+	if _, err := addPNGTextChunks(append(append([]byte{}, pngSignature...), strings.Repeat("x", 40)...), nil); err == nil {
+		t.Error("没有IHDR块的PNG应返回错误")
+	}
+}
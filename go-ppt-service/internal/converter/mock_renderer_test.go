@@ -0,0 +1,191 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"ppt-to-images-service/internal/jpegenc"
+)
+
+// errMockRender MockRenderer 在 FailOn 指定的幻灯片上返回的错误
+var errMockRender = errors.New("模拟渲染失败")
+
+// MockRenderer 返回预设图片的渲染器，不依赖LibreOffice或PowerPoint即可测试 PPTConverter 的完整流程
+// 每张幻灯片渲染为 opts.SizeFor 尺寸的纯色图片，Photos 中的幻灯片渲染为随机噪点 (AUTO格式会选择JPEG)
+type MockRenderer struct {
+	// Slides 演示文稿的幻灯片总数 (包括隐藏的)
+	Slides int
+	// Photos 渲染为噪点图片的幻灯片
+	Photos map[int]bool
+	// FailOn 渲染到该幻灯片时出错，0表示不出错
+	FailOn int
+	// SkipFailed 出错的幻灯片只跳过而不中止渲染 (与占位渲染器相同)，否则 Render 返回 errMockRender
+	SkipFailed bool
+	// Stream 每张幻灯片写好后立即通过 opts.Rendered 提交，否则全部在 Render 返回后处理
+	Stream bool
+	// Placeholder 把生成的图片标记为占位图片
+	Placeholder bool
+	// HangOn 渲染到该幻灯片时一直等到 ctx 结束，用于测试超时，0表示不等待
+	HangOn int
+	// Name 后端名称，为空时为 mock
+	Name string
+	// Empty 写出空文件的幻灯片，模拟外部工具静默失败
+	Empty map[int]bool
+	// WholeDeck 所有幻灯片都按 opts.Width x opts.Height 渲染，模拟一次导出全部幻灯片、不支持单独尺寸的后端
+	WholeDeck bool
+
+	mutex    sync.Mutex
+	rendered []int
+	closed   bool
+}
+
+// Backend 转换后端名称
+func (m *MockRenderer) Backend() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return "mock"
+}
+
+// SupportedExtensions 支持的输入文件扩展名
+func (m *MockRenderer) SupportedExtensions() []string {
+	return powerPointExtensions
+}
+
+// Close 记录渲染器已关闭
+func (m *MockRenderer) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.closed = true
+	return nil
+}
+
+// Render 按编号依次生成需要的幻灯片
+func (m *MockRenderer) Render(ctx context.Context, pptPath string, opts RenderOptions) ([]RenderedSlide, error) {
+	if err := opts.Counted(m.Slides); err != nil {
+		return nil, err
+	}
+	opts.Report(20, "正在使用模拟渲染器转换PPT...")
+
+	var slides []RenderedSlide
+	for slideNumber := 1; slideNumber <= m.Slides; slideNumber++ {
+		if !opts.Selected(slideNumber) {
+			continue
+		}
+		if err := checkCancelled(ctx); err != nil {
+			return nil, err
+		}
+		if slideNumber == m.HangOn {
+			<-ctx.Done()
+			return nil, checkCancelled(ctx)
+		}
+		if slideNumber == m.FailOn {
+			if m.SkipFailed {
+				continue
+			}
+			return nil, fmt.Errorf("%w: 第 %d 张幻灯片", errMockRender, slideNumber)
+		}
+
+		slide := RenderedSlide{
+			SlideNumber: slideNumber,
+			BuildIndex:  -1,
+			FilePath:    filepath.Join(opts.OutputPath, slideFilename(slideNumber, -1, imageExtension(opts.Format))),
+			Placeholder: m.Placeholder,
+		}
+		width, height := opts.SizeFor(slideNumber)
+		if m.WholeDeck {
+			width, height = opts.Width, opts.Height
+		}
+		if m.Empty[slideNumber] {
+			if err := os.WriteFile(slide.FilePath, nil, 0644); err != nil {
+				return nil, err
+			}
+		} else if err := writeImageFile(m.slideImage(slideNumber, width, height), slide.FilePath, opts.Format, jpegenc.Options{Quality: DefaultJPEGQuality}, nil); err != nil {
+			return nil, err
+		}
+
+		m.mutex.Lock()
+		m.rendered = append(m.rendered, slideNumber)
+		m.mutex.Unlock()
+
+		if m.Stream {
+			opts.Rendered([]RenderedSlide{slide})
+		}
+		slides = append(slides, slide)
+	}
+	return slides, nil
+}
+
+// slideImage 生成幻灯片的图片内容
+func (m *MockRenderer) slideImage(slideNumber, width, height int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	random := rand.New(rand.NewSource(int64(slideNumber)))
+	fill := color.NRGBA{R: uint8(slideNumber * 40), G: 128, B: 200, A: 255}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if m.Photos[slideNumber] {
+				fill = color.NRGBA{R: uint8(random.Intn(256)), G: uint8(random.Intn(256)), B: uint8(random.Intn(256)), A: 255}
+			}
+			img.SetNRGBA(x, y, fill)
+		}
+	}
+	return img
+}
+
+// renderedSlides 实际渲染过的幻灯片编号
+func (m *MockRenderer) renderedSlides() []int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]int(nil), m.rendered...)
+}
+
+// newTestConverter 创建使用指定渲染器、输出到测试临时目录的转换器，默认输出 160x90 的PNG
+func newTestConverter(t *testing.T, renderer Renderer, options Options) (*PPTConverter, string) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	outputDir := t.TempDir()
+	return NewPPTConverter(renderer, outputDir, t.TempDir(), 160, 90, "PNG", options, logger), outputDir
+}
+
+// testPPTX 生成只包含 presentation.xml 的最小PPTX，用于测试按幻灯片尺寸确定输出尺寸
+func testPPTX(t *testing.T, widthEMU, heightEMU int64) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	files := map[string]string{
+		"ppt/presentation.xml": fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"><p:sldSz cx="%d" cy="%d"/></p:presentation>`, widthEMU, heightEMU),
+		"ppt/_rels/presentation.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"></Relationships>`,
+	}
+	for name, content := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
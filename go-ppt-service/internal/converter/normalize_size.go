@@ -0,0 +1,46 @@
+package converter
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/disintegration/imaging"
+)
+
+// letterbox 把图片按比例缩放到恰好放入 width x height 的画布并居中，两侧或上下的空白填充白色
+// 尺寸已经相同时原样返回
+func letterbox(img image.Image, width, height int, filter imaging.ResampleFilter) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() == width && bounds.Dy() == height {
+		return img
+	}
+
+	fitted := imaging.Fit(img, width, height, filter)
+	if fitted.Bounds().Dx() < width && fitted.Bounds().Dy() < height {
+		// Fit 不放大图片，较小的图片 (如自动裁剪后) 按比例放大到画布边缘
+		fitted = imaging.Resize(img, width, 0, filter)
+		if fitted.Bounds().Dy() > height {
+			fitted = imaging.Resize(img, 0, height, filter)
+		}
+	}
+
+	canvas := imaging.New(width, height, color.White)
+	return imaging.PasteCenter(canvas, fitted)
+}
+
+// normalizedSize 设置 NormalizeSize 时每张幻灯片图片的统一尺寸 (包括旋转和边框)，多分辨率输出时为最大的尺寸
+// 没有设置时返回0
+func normalizedSize(options ConversionOptions) (width, height int) {
+	if !options.NormalizeSize {
+		return 0, 0
+	}
+	width, height = options.Width, options.Height
+	if options.Rotate == 90 || options.Rotate == 270 {
+		width, height = height, width
+	}
+	if options.Border != nil && options.Border.Width > 0 {
+		width += 2 * options.Border.Width
+		height += 2 * options.Border.Width
+	}
+	return width, height
+}
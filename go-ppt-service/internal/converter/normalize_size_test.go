@@ -0,0 +1,42 @@
+package converter
+
+import (
+	"context"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestLetterbox(t *testing.T) {
+	// 正方形图片放入16:9画布，两侧留白
+	square := imaging.New(40, 40, color.Black)
+	img := letterbox(square, 160, 90, imaging.Lanczos)
+	if img.Bounds().Dx() != 160 || img.Bounds().Dy() != 90 {
+		t.Fatalf("画布尺寸为 %v", img.Bounds())
+	}
+	if r, _, _, _ := img.At(80, 45).RGBA(); r != 0 {
+		t.Errorf("中央应为幻灯片内容")
+	}
+	if r, _, _, _ := img.At(5, 45).RGBA(); r != 0xffff {
+		t.Errorf("两侧应为白色")
+	}
+
+	c, _ := newTestConverter(t, &MockRenderer{Slides: 2}, Options{})
+	result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.pptx", ConversionOptions{
+		NormalizeSize: true,
+		Rotate:        90,
+		Border:        &BorderOptions{Width: 5},
+	}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+	if result.NormalizedWidth != 100 || result.NormalizedHeight != 170 {
+		t.Errorf("统一尺寸为 %dx%d，应为 100x170", result.NormalizedWidth, result.NormalizedHeight)
+	}
+	for _, image := range result.Images {
+		if image.Width != result.NormalizedWidth || image.Height != result.NormalizedHeight {
+			t.Errorf("%s 的尺寸为 %dx%d", image.Filename, image.Width, image.Height)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package converter
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// notesPDFFilename 备注页PDF的文件名，与图片位于同一目录
+const notesPDFFilename = "notes.pdf"
+
+// notesSlideRelType 幻灯片到备注页的关系类型
+const notesSlideRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide"
+
+// 自行生成的备注页版式 (单位为点): A4纵向，幻灯片在上，备注文字在下
+const (
+	notesPageWidth      = 595.0
+	notesPageHeight     = 842.0
+	notesMargin         = 48.0
+	notesSlideMaxHeight = 380.0 // 幻灯片图片的最大高度
+	notesGap            = 24.0  // 幻灯片与备注文字的间距
+	notesFontSize       = 11.0
+	notesLeading        = 16.0
+)
+
+// nativeNotesPages 渲染后端导出的备注页包含全部 (未隐藏的) 幻灯片且按放映顺序排列，
+// 只选择了部分幻灯片或改变了顺序时由转换器自行生成
+func nativeNotesPages(options ConversionOptions) bool {
+	return len(options.SlideIndices) == 0 && len(options.OrderIndices) == 0 && options.Order != OrderDescending
+}
+
+// appendNotesPDF 把备注页PDF追加到图片列表: 渲染后端已导出时直接使用，否则用幻灯片图片和PPTX中的备注文字生成，失败时只记录日志
+func (c *PPTConverter) appendNotesPDF(images []ImageInfo, outputPath string, pptData []byte, options ConversionOptions) []ImageInfo {
+	if !options.NotesPDF || len(images) == 0 {
+		return images
+	}
+
+	filePath := filepath.Join(outputPath, notesPDFFilename)
+	var size int64
+	if info, err := os.Stat(options.notesPDFPath); options.notesPDFPath != "" && err == nil && os.Rename(options.notesPDFPath, filePath) == nil {
+		size = info.Size()
+		c.logger.Infof("使用渲染后端导出的备注页PDF: %s", notesPDFFilename)
+	} else {
+		notes, err := slideNotes(pptData)
+		if err != nil {
+			c.logger.Warnf("读取演讲者备注失败，备注页中只有幻灯片: %v", err)
+		}
+		size, err = writeNotesPDF(filePath, notesSlideImages(images, options), notes, options.NumberOffset)
+		if err != nil {
+			os.Remove(filePath)
+			c.logger.Warnf("生成备注页PDF失败: %v", err)
+			return images
+		}
+		c.logger.Infof("生成备注页PDF: %s", notesPDFFilename)
+	}
+
+	return append(images, ImageInfo{
+		SlideNumber: 0,
+		Filename:    notesPDFFilename,
+		FilePath:    filePath,
+		FileSize:    size,
+		DownloadID:  generateDownloadID(),
+		Format:      "PDF",
+	})
+}
+
+// notesSlideImages 每张幻灯片在备注页中使用的一张图片，按结果中的顺序排列
+// 多个构建步骤时使用最后一步 (动画全部完成)，切分的图片只使用第一块
+func notesSlideImages(images []ImageInfo, options ConversionOptions) []ImageInfo {
+	var slides []ImageInfo
+	index := make(map[int]int)
+	for _, image := range summaryImages(images, options) {
+		if image.SlideNumber <= 0 || image.TileIndex > 1 {
+			continue
+		}
+		if i, ok := index[image.SlideNumber]; ok {
+			slides[i] = image
+			continue
+		}
+		index[image.SlideNumber] = len(slides)
+		slides = append(slides, image)
+	}
+	return slides
+}
+
+// slideNotes 按放映顺序返回每张幻灯片的演讲者备注 (备注页正文占位符中的文字，每个段落一行)，没有备注时为空字符串
+// 不是PPTX (如PPT、ODP) 时返回nil
+func slideNotes(pptData []byte) ([]string, error) {
+	if !bytes.HasPrefix(pptData, []byte("PK\x03\x04")) {
+		return nil, nil
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(pptData), int64(len(pptData)))
+	if err != nil {
+		return nil, fmt.Errorf("打开PPTX失败: %v", err)
+	}
+	if !isPPTXPackage(reader) {
+		return nil, nil
+	}
+
+	paths, err := pptxSlidePaths(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]string, len(paths))
+	for i, slidePath := range paths {
+		if slidePath == "" {
+			continue
+		}
+		notesPath, err := notesSlidePath(reader, slidePath)
+		if err != nil || notesPath == "" {
+			continue
+		}
+		var notesSlide pptxSlide
+		if err := readZipXML(reader, notesPath, &notesSlide); err != nil {
+			return nil, err
+		}
+		notes[i] = notesSlide.bodyText()
+	}
+	return notes, nil
+}
+
+// notesSlidePath 通过幻灯片的关系文件找到它的备注页，没有备注页时返回空字符串
+func notesSlidePath(reader *zip.Reader, slidePath string) (string, error) {
+	dir, file := path.Split(slidePath)
+
+	var rels struct {
+		Relationships []struct {
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := readZipXML(reader, path.Join(dir, "_rels", file+".rels"), &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.Type == notesSlideRelType {
+			return path.Join(dir, rel.Target), nil
+		}
+	}
+	return "", nil
+}
+
+// bodyText 正文占位符中的文字，每个段落一行
+func (s pptxSlide) bodyText() string {
+	var paragraphs []string
+	for _, shape := range s.Shapes {
+		if shape.Placeholder.Type != "body" {
+			continue
+		}
+		for _, paragraph := range shape.Paragraphs {
+			paragraphs = append(paragraphs, strings.Join(paragraph.Runs, ""))
+		}
+	}
+	return strings.TrimSpace(strings.Join(paragraphs, "\n"))
+}
+
+// notesPage 备注页PDF中的一页
+type notesPage struct {
+	image ImageInfo // 幻灯片图片，备注文字较长时的续页为零值
+	lines []string
+}
+
+// layoutNotesPages 把每张幻灯片的图片和折行后的备注排成页，一页放不下的备注放到续页中
+func layoutNotesPages(slides []ImageInfo, notes []string, numberOffset int) []notesPage {
+	textWidth := notesPageWidth - 2*notesMargin
+	textHeight := notesPageHeight - 2*notesMargin
+	fullPage := int(textHeight / notesLeading)
+
+	var pages []notesPage
+	for _, slide := range slides {
+		var text string
+		if index := slide.SlideNumber - numberOffset - 1; index >= 0 && index < len(notes) {
+			text = notes[index]
+		}
+		lines := wrapNotes(text, textWidth)
+
+		_, imageHeight := notesImageBox(slide)
+		capacity := int((textHeight - imageHeight - notesGap) / notesLeading)
+		if capacity > len(lines) {
+			capacity = len(lines)
+		}
+		pages = append(pages, notesPage{image: slide, lines: lines[:capacity]})
+		for rest := lines[capacity:]; len(rest) > 0; {
+			n := fullPage
+			if n > len(rest) {
+				n = len(rest)
+			}
+			pages = append(pages, notesPage{lines: rest[:n]})
+			rest = rest[n:]
+		}
+	}
+	return pages
+}
+
+// notesImageBox 幻灯片图片在页面上的尺寸: 占满文字宽度，过高时按最大高度缩小
+func notesImageBox(image ImageInfo) (float64, float64) {
+	width := notesPageWidth - 2*notesMargin
+	if image.Width <= 0 || image.Height <= 0 {
+		return width, width * 9 / 16
+	}
+	height := width * float64(image.Height) / float64(image.Width)
+	if height > notesSlideMaxHeight {
+		width *= notesSlideMaxHeight / height
+		height = notesSlideMaxHeight
+	}
+	return width, height
+}
+
+// wrapNotes 按估算的宽度 (ASCII字符半个字宽，其他字符一个字宽) 折行，英文尽量在空格处断开
+func wrapNotes(text string, width float64) []string {
+	if text == "" {
+		return nil
+	}
+
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := []rune{}
+		lineWidth := 0.0
+		for _, r := range strings.TrimRight(paragraph, " \t\r") {
+			advance := notesRuneWidth(r)
+			if lineWidth+advance > width && len(line) > 0 {
+				// 英文单词中间不断开，退回到最后一个空格
+				cut := len(line)
+				if r != ' ' && r < 0x80 {
+					if space := lastSpace(line); space > 0 {
+						cut = space + 1
+					}
+				}
+				lines = append(lines, strings.TrimRight(string(line[:cut]), " "))
+				line = append([]rune{}, line[cut:]...)
+				lineWidth = 0
+				for _, rest := range line {
+					lineWidth += notesRuneWidth(rest)
+				}
+				if r == ' ' && len(line) == 0 {
+					continue
+				}
+			}
+			line = append(line, r)
+			lineWidth += advance
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// notesRuneWidth 估算字符的宽度
+func notesRuneWidth(r rune) float64 {
+	if r < 0x80 {
+		return notesFontSize / 2
+	}
+	return notesFontSize
+}
+
+// lastSpace 最后一个空格的位置，没有时返回-1
+func lastSpace(line []rune) int {
+	for i := len(line) - 1; i >= 0; i-- {
+		if line[i] == ' ' {
+			return i
+		}
+	}
+	return -1
+}
+
+// notesFont 备注文字使用的字体对象: Adobe预定义的简体中文字体 STSong-Light (不嵌入，由阅读器提供)，
+// 以 UniGB-UCS2-H 编码按UCS-2书写，同时覆盖中文和ASCII字符
+const (
+	notesFontType0 = "<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light-UniGB-UCS2-H /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>"
+	notesFontCID   = "<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>"
+	notesFontDesc  = "<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>"
+)
+
+// writeNotesPDF 生成每张幻灯片一页 (备注较长时有续页) 的备注页PDF，返回文件大小
+func writeNotesPDF(filePath string, slides []ImageInfo, notes []string, numberOffset int) (int64, error) {
+	if len(slides) == 0 {
+		return 0, fmt.Errorf("没有可用的幻灯片图片")
+	}
+	pages := layoutNotesPages(slides, notes, numberOffset)
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	// 对象编号: 1 目录，2 页面树，3-5 字体，之后每页依次为页面、内容流和图片 (续页没有图片)
+	var kids []string
+	id := 6
+	for _, page := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", id))
+		id += 2
+		if page.image.FilePath != "" {
+			id++
+		}
+	}
+
+	w := &pdfWriter{w: bufio.NewWriter(file)}
+	w.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	w.object("<< /Type /Catalog /Pages 2 0 R >>")
+	w.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %.0f %.0f] >>",
+		strings.Join(kids, " "), len(pages), notesPageWidth, notesPageHeight))
+	w.object(notesFontType0)
+	w.object(notesFontCID)
+	w.object(notesFontDesc)
+
+	for _, page := range pages {
+		id := len(w.offsets) + 1
+		var content strings.Builder
+		top := notesPageHeight - notesMargin
+		var img *pdfImage
+		if page.image.FilePath != "" {
+			if img, err = loadPDFImage(page.image.FilePath); err != nil {
+				return 0, err
+			}
+			width, height := notesImageBox(page.image)
+			x := (notesPageWidth - width) / 2
+			y := top - height
+			fmt.Fprintf(&content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im0 Do Q\n", width, height, x, y)
+			fmt.Fprintf(&content, "0.5 w 0.6 G %.2f %.2f %.2f %.2f re S\n", x, y, width, height)
+			top = y - notesGap
+		}
+		if len(page.lines) > 0 {
+			fmt.Fprintf(&content, "BT /F1 %.1f Tf %.1f TL %.2f %.2f Td\n", notesFontSize, notesLeading, notesMargin, top-notesFontSize)
+			for _, line := range page.lines {
+				fmt.Fprintf(&content, "<%s> Tj T*\n", ucs2Hex(line))
+			}
+			content.WriteString("ET\n")
+		}
+
+		resources := "/Font << /F1 3 0 R >>"
+		if img != nil {
+			resources += fmt.Sprintf(" /XObject << /Im0 %d 0 R >>", id+2)
+		}
+		w.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Resources << %s >> /Contents %d 0 R >>", resources, id+1))
+		w.stream("", []byte(content.String()))
+		if img != nil {
+			w.stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s ",
+				img.width, img.height, img.colorSpace, img.filter), img.data)
+		}
+	}
+
+	if err := w.finish(); err != nil {
+		return 0, err
+	}
+	return w.offset, file.Close()
+}
+
+// ucs2Hex 把文字编码为UCS-2大端序的十六进制字符串，基本多文种平面之外的字符替换为问号
+func ucs2Hex(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r > math.MaxUint16 || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// notesPPTX 生成每张幻灯片带有指定备注 (为空时没有备注页) 的PPTX
+func notesPPTX(t *testing.T, notes []string) []byte {
+	t.Helper()
+
+	files := map[string][]byte{}
+	var slideIDs, rels strings.Builder
+	for i, text := range notes {
+		fmt.Fprintf(&slideIDs, `<p:sldId id="%d" r:id="rId%d"/>`, 256+i, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Target="slides/slide%d.xml"/>`, i+1, i+1)
+		files[fmt.Sprintf("ppt/slides/slide%d.xml", i+1)] = []byte(`<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"><p:cSld><p:spTree/></p:cSld></p:sld>`)
+
+		slideRels := "<Relationships>"
+		if text != "" {
+			slideRels += fmt.Sprintf(`<Relationship Id="rId2" Type="%s" Target="../notesSlides/notesSlide%d.xml"/>`, notesSlideRelType, i+1)
+			var paragraphs strings.Builder
+			for _, line := range strings.Split(text, "\n") {
+				paragraphs.WriteString(`<a:p><a:r><a:t>` + line + `</a:t></a:r></a:p>`)
+			}
+			files[fmt.Sprintf("ppt/notesSlides/notesSlide%d.xml", i+1)] = []byte(`<p:notes xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><p:cSld><p:spTree>` +
+				`<p:sp><p:nvSpPr><p:nvPr><p:ph type="sldImg"/></p:nvPr></p:nvSpPr></p:sp>` +
+				`<p:sp><p:nvSpPr><p:nvPr><p:ph type="body" idx="1"/></p:nvPr></p:nvSpPr><p:txBody>` + paragraphs.String() + `</p:txBody></p:sp>` +
+				`</p:spTree></p:cSld></p:notes>`)
+		}
+		files[fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", i+1)] = []byte(slideRels + "</Relationships>")
+	}
+	files["ppt/presentation.xml"] = []byte(`<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:sldIdLst>` +
+		slideIDs.String() + `</p:sldIdLst></p:presentation>`)
+	files["ppt/_rels/presentation.xml.rels"] = []byte("<Relationships>" + rels.String() + "</Relationships>")
+	return zipPackage(t, files)
+}
+
+func TestSlideNotes(t *testing.T) {
+	want := []string{"开场白\nWelcome", "", "总结"}
+	notes, err := slideNotes(notesPPTX(t, want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(notes, want) {
+		t.Errorf("备注为 %q，应为 %q", notes, want)
+	}
+
+	if notes, err := slideNotes([]byte("ppt")); notes != nil || err != nil {
+		t.Errorf("PPT文件的备注为 %q (%v)", notes, err)
+	}
+}
+
+func TestConvertPPTNotesPDF(t *testing.T) {
+	// 模拟渲染器不能导出备注页，由幻灯片图片和备注文字生成；最后一张幻灯片的备注需要续页
+	long := strings.Repeat("这是一段很长的备注。", 200)
+	c, _ := newTestConverter(t, &MockRenderer{Slides: 3}, Options{})
+
+	result, err := c.ConvertPPT(context.Background(), notesPPTX(t, []string{"开场白", "", long}), "deck.pptx", ConversionOptions{
+		NotesPDF: true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+
+	if len(result.Images) != 4 {
+		t.Fatalf("返回了 %d 个文件，应为4个", len(result.Images))
+	}
+	pdf := result.Images[3]
+	if pdf.Filename != notesPDFFilename || pdf.Format != "PDF" || pdf.SlideNumber != 0 {
+		t.Fatalf("最后一个文件为 %+v", pdf)
+	}
+
+	data, err := os.ReadFile(pdf.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(data)) != pdf.FileSize || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Errorf("PDF文件不完整 (%d 字节，记录为 %d)", len(data), pdf.FileSize)
+	}
+	if bytes.Count(data, []byte("/Subtype /Image")) != 3 || bytes.Contains(data, []byte("/Count 3 ")) {
+		t.Errorf("PDF中应有3张幻灯片图片和至少一页续页")
+	}
+	if !bytes.Contains(data, []byte("<"+ucs2Hex("开场白")+">")) {
+		t.Errorf("PDF中没有第1张幻灯片的备注")
+	}
+}
+
+func TestWrapNotes(t *testing.T) {
+	lines := wrapNotes("hello world again\n中文中文中文", notesFontSize*6)
+	want := []string{"hello world", "again", "中文中文中文"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("折行结果为 %q，应为 %q", lines, want)
+	}
+}
@@ -0,0 +1,70 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// imageOptimizers 各输出格式对应的无损优化工具及参数，文件路径追加在参数最后
+// oxipng 和 jpegoptim 默认保留文本块和EXIF，不影响已写入的元数据
+var imageOptimizers = map[string][]string{
+	"PNG":  {"oxipng", "-o", "2", "--quiet"},
+	"JPEG": {"jpegoptim", "--quiet"},
+}
+
+// optimizeImages 使用外部工具无损压缩图片并更新文件大小，工具不存在时记录警告并跳过该格式
+func (c *PPTConverter) optimizeImages(images []ImageInfo) {
+	tools := make(map[string]string)
+	for format, command := range imageOptimizers {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+		tools[format] = path
+	}
+
+	var originalSize, optimizedSize int64
+	skipped := make(map[string]bool)
+	for i := range images {
+		format := images[i].Format
+		path, ok := tools[format]
+		if !ok {
+			if !skipped[format] {
+				skipped[format] = true
+				c.logger.Warnf("未找到%s图片优化工具，跳过优化", format)
+			}
+			continue
+		}
+
+		size, err := c.optimizeImage(path, imageOptimizers[format][1:], images[i].FilePath)
+		if err != nil {
+			c.logger.Warnf("优化第 %d 张幻灯片图片失败: %v", images[i].SlideNumber, err)
+			continue
+		}
+
+		originalSize += images[i].FileSize
+		optimizedSize += size
+		images[i].FileSize = size
+	}
+
+	if originalSize > 0 {
+		c.logger.Infof("图片优化完成: %d 字节 -> %d 字节 (减少 %.1f%%)",
+			originalSize, optimizedSize, float64(originalSize-optimizedSize)*100/float64(originalSize))
+	}
+}
+
+// optimizeImage 原地优化单张图片，返回优化后的文件大小
+func (c *PPTConverter) optimizeImage(tool string, args []string, filePath string) (int64, error) {
+	args = append(append([]string{}, args...), filePath)
+	output, err := exec.Command(tool, args...).CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("%v: %s", err, string(output))
+	}
+
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return 0, err
+	}
+	return fileInfo.Size(), nil
+}
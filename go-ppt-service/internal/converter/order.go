@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 结果中图片的排列顺序，只影响 ImageInfo 在结果和 OnImageReady 中出现的顺序，文件名和幻灯片编号不变
+const (
+	// OrderAscending 按幻灯片编号从小到大 (默认)
+	OrderAscending = "ascending"
+	// OrderDescending 按幻灯片编号从大到小
+	OrderDescending = "descending"
+)
+
+// normalizeOrder 检查并规范化排列顺序，为空时为 OrderAscending
+func normalizeOrder(order string) (string, error) {
+	order = strings.ToLower(strings.TrimSpace(order))
+	switch order {
+	case "":
+		return OrderAscending, nil
+	case OrderAscending, OrderDescending:
+		return order, nil
+	default:
+		return "", fmt.Errorf("%w: 未知的排列顺序 %s (支持 %s, %s)", ErrInvalidOrder, order, OrderAscending, OrderDescending)
+	}
+}
+
+// orderPlan 按 options 重新排列需要渲染的幻灯片 (升序)
+// OrderIndices 不为空时优先使用，它必须恰好包含每张需要渲染的幻灯片一次 (原始编号，不包括隐藏和未选中的幻灯片)
+func orderPlan(plan []int, options ConversionOptions) ([]int, error) {
+	if len(options.OrderIndices) > 0 {
+		planned := make(map[int]bool, len(plan))
+		for _, slide := range plan {
+			planned[slide] = true
+		}
+		seen := make(map[int]bool, len(options.OrderIndices))
+		for _, slide := range options.OrderIndices {
+			if !planned[slide] {
+				return nil, fmt.Errorf("%w: 第 %d 张幻灯片不在需要转换的幻灯片 %v 中", ErrInvalidOrder, slide, plan)
+			}
+			if seen[slide] {
+				return nil, fmt.Errorf("%w: 第 %d 张幻灯片重复出现", ErrInvalidOrder, slide)
+			}
+			seen[slide] = true
+		}
+		if len(seen) != len(plan) {
+			return nil, fmt.Errorf("%w: 指定了 %d 张幻灯片的顺序，需要转换的幻灯片为 %v", ErrInvalidOrder, len(seen), plan)
+		}
+		return append([]int(nil), options.OrderIndices...), nil
+	}
+
+	if options.Order == OrderDescending {
+		ordered := make([]int, len(plan))
+		for i, slide := range plan {
+			ordered[len(plan)-1-i] = slide
+		}
+		return ordered, nil
+	}
+	return plan, nil
+}
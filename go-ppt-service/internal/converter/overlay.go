@@ -0,0 +1,279 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"ppt-to-images-service/internal/jpegenc"
+)
+
+// 幻灯片编号的位置
+const (
+	StampBottomRight = "bottom-right"
+	StampBottomLeft  = "bottom-left"
+	StampTopRight    = "top-right"
+	StampTopLeft     = "top-left"
+)
+
+// StampOptions 在图片角落绘制幻灯片编号的选项
+type StampOptions struct {
+	Position string      // 位置，为空时为右下角
+	FontSize float64     // 字号 (像素)，0表示按图片高度自动计算
+	Color    color.Color // 文字颜色，为空时为黑色
+}
+
+// BorderOptions 图片四周纯色边框的选项
+type BorderOptions struct {
+	Width int         // 边框宽度 (像素)
+	Color color.Color // 边框颜色，为空时为白色
+}
+
+// overlay 渲染后叠加到幻灯片图片上的内容，按顺序依次应用
+type overlay func(img image.Image, slideNumber int) (image.Image, error)
+
+var (
+	stampFont     *opentype.Font
+	stampFontErr  error
+	stampFontOnce sync.Once
+)
+
+// overlays 根据选项构建需要叠加的内容
+func (c *PPTConverter) overlays(options ConversionOptions) []overlay {
+	var result []overlay
+	// 先旋转，编号和边框按旋转后的方向绘制
+	if options.Rotate != 0 {
+		degrees := options.Rotate
+		result = append(result, func(img image.Image, slideNumber int) (image.Image, error) {
+			return rotateImage(img, degrees), nil
+		})
+	}
+	if options.Stamp != nil {
+		stamp := *options.Stamp
+		result = append(result, func(img image.Image, slideNumber int) (image.Image, error) {
+			return drawSlideNumber(img, slideNumber, stamp)
+		})
+	}
+	// 边框最后添加，编号仍绘制在幻灯片区域内
+	if options.Border != nil && options.Border.Width > 0 {
+		border := *options.Border
+		result = append(result, func(img image.Image, slideNumber int) (image.Image, error) {
+			return addBorder(img, border), nil
+		})
+	}
+	return result
+}
+
+// applyOverlays 依次应用叠加内容
+func applyOverlays(img image.Image, slideNumber int, overlays []overlay) (image.Image, error) {
+	for _, apply := range overlays {
+		var err error
+		if img, err = apply(img, slideNumber); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// applyOverlaysToFiles 为外部工具生成的图片文件自动裁剪、统一尺寸、叠加内容并覆盖原文件，失败时只记录日志
+func (c *PPTConverter) applyOverlaysToFiles(images []ImageInfo, options ConversionOptions) {
+	overlays := c.overlays(options)
+	if len(overlays) == 0 && options.AutoCrop == nil && !options.NormalizeSize {
+		return
+	}
+
+	for i := range images {
+		err := c.rewriteImage(&images[i], options.JPEGSubsampling, func(img image.Image) (image.Image, error) {
+			img, images[i].Crop = c.autoCrop(img, images[i].SlideNumber, options.AutoCrop)
+			if options.NormalizeSize {
+				img = letterbox(img, options.Width, options.Height, options.resampleFilter())
+			}
+			return applyOverlays(img, images[i].SlideNumber, overlays)
+		})
+		if err != nil {
+			c.logger.Warnf("第 %d 张幻灯片叠加内容失败: %v", images[i].SlideNumber, err)
+		}
+	}
+}
+
+// rewriteImage 读取图片、处理后按原格式写回，并更新文件大小
+func (c *PPTConverter) rewriteImage(info *ImageInfo, subsampling jpegenc.Subsampling, process func(img image.Image) (image.Image, error)) error {
+	img, err := imaging.Open(info.FilePath)
+	if err != nil {
+		return fmt.Errorf("读取图片失败: %v", err)
+	}
+
+	img, err = process(img)
+	if err != nil {
+		return err
+	}
+
+	if err := c.saveImage(img, info.FilePath, formatFromExtension(filepath.Ext(info.FilePath)), subsampling, nil); err != nil {
+		return fmt.Errorf("保存图片失败: %v", err)
+	}
+
+	fileInfo, err := os.Stat(info.FilePath)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	info.FileSize = fileInfo.Size()
+	info.Width, info.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	return nil
+}
+
+// rotateImage 按顺时针角度旋转图片 (imaging 的 Rotate90 等为逆时针)
+func rotateImage(img image.Image, degrees int) image.Image {
+	switch degrees {
+	case 90:
+		return imaging.Rotate270(img)
+	case 180:
+		return imaging.Rotate180(img)
+	case 270:
+		return imaging.Rotate90(img)
+	default:
+		return img
+	}
+}
+
+// addBorder 扩大画布并在四周填充边框颜色
+func addBorder(img image.Image, options BorderOptions) image.Image {
+	borderColor := options.Color
+	if borderColor == nil {
+		borderColor = color.White
+	}
+
+	bounds := img.Bounds()
+	canvas := imaging.New(bounds.Dx()+2*options.Width, bounds.Dy()+2*options.Width, borderColor)
+	return imaging.Paste(canvas, img, image.Pt(options.Width, options.Width))
+}
+
+// overlayFace 创建绘制叠加文字使用的字体 (Go Bold，只包含拉丁字符)，用完后需要关闭
+func overlayFace(size float64) (font.Face, error) {
+	stampFontOnce.Do(func() {
+		stampFont, stampFontErr = opentype.Parse(gobold.TTF)
+	})
+	if stampFontErr != nil {
+		return nil, fmt.Errorf("加载字体失败: %v", stampFontErr)
+	}
+
+	face, err := opentype.NewFace(stampFont, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建字体失败: %v", err)
+	}
+	return face, nil
+}
+
+// drawSlideNumber 在指定角落绘制幻灯片编号，文字下方垫一层半透明白底以便在深色背景上辨认
+func drawSlideNumber(img image.Image, slideNumber int, options StampOptions) (image.Image, error) {
+
+	bounds := img.Bounds()
+	size := options.FontSize
+	if size <= 0 {
+		size = float64(bounds.Dy()) / 30
+		if size < 12 {
+			size = 12
+		}
+	}
+
+	face, err := overlayFace(size)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	text := strconv.Itoa(slideNumber)
+	metrics := face.Metrics()
+	textWidth := font.MeasureString(face, text).Ceil()
+	textHeight := (metrics.Ascent + metrics.Descent).Ceil()
+	padding := int(size / 4)
+	margin := int(size / 2)
+	boxWidth := textWidth + 2*padding
+	boxHeight := textHeight + 2*padding
+
+	// 计算底框左上角位置
+	x := bounds.Max.X - margin - boxWidth
+	y := bounds.Max.Y - margin - boxHeight
+	switch options.Position {
+	case StampBottomLeft:
+		x = bounds.Min.X + margin
+	case StampTopRight:
+		y = bounds.Min.Y + margin
+	case StampTopLeft:
+		x = bounds.Min.X + margin
+		y = bounds.Min.Y + margin
+	}
+
+	textColor := options.Color
+	if textColor == nil {
+		textColor = color.Black
+	}
+
+	dst := imaging.Clone(img)
+	box := image.Rect(x, y, x+boxWidth, y+boxHeight)
+	draw.Draw(dst, box, image.NewUniform(color.NRGBA{R: 255, G: 255, B: 255, A: 180}), image.Point{}, draw.Over)
+
+	drawer := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(textColor),
+		Face: face,
+		Dot:  fixed.P(x+padding, y+padding+metrics.Ascent.Ceil()),
+	}
+	drawer.DrawString(text)
+
+	return dst, nil
+}
+
+// drawWatermark 在图片中央绘制一行深红色文字，文字所在的横条垫一层半透明白底
+// 字号按图片高度确定，过长的文字缩小到图片宽度的90%以内
+func drawWatermark(img image.Image, text string) (image.Image, error) {
+	bounds := img.Bounds()
+	size := max(12, float64(bounds.Dy())/10)
+
+	face, err := overlayFace(size)
+	if err != nil {
+		return nil, err
+	}
+	if textWidth := font.MeasureString(face, text).Ceil(); textWidth > bounds.Dx()*9/10 {
+		face.Close()
+		size = max(6, size*float64(bounds.Dx())*0.9/float64(textWidth))
+		if face, err = overlayFace(size); err != nil {
+			return nil, err
+		}
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	textWidth := font.MeasureString(face, text).Ceil()
+	textHeight := (metrics.Ascent + metrics.Descent).Ceil()
+	padding := int(size / 3)
+	y := bounds.Min.Y + (bounds.Dy()-textHeight)/2
+
+	dst := imaging.Clone(img)
+	band := image.Rect(bounds.Min.X, y-padding, bounds.Max.X, y+textHeight+padding)
+	draw.Draw(dst, band, image.NewUniform(color.NRGBA{R: 255, G: 255, B: 255, A: 200}), image.Point{}, draw.Over)
+
+	drawer := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(color.NRGBA{R: 180, G: 0, B: 0, A: 255}),
+		Face: face,
+		Dot:  fixed.P(bounds.Min.X+(bounds.Dx()-textWidth)/2, y+metrics.Ascent.Ceil()),
+	}
+	drawer.DrawString(text)
+
+	return dst, nil
+}
@@ -0,0 +1,55 @@
+package converter
+
+// finishImages 对外部工具生成的幻灯片图片依次执行全部后处理
+// 只处理传入的图片，不同幻灯片可以在多个协程中同时处理
+func (c *PPTConverter) finishImages(images []ImageInfo, filename string, options ConversionOptions) []ImageInfo {
+	// 视频区域在部分后端中渲染为空白，先合成封面再检查是否空白
+	c.drawMediaPostersToFiles(images, options)
+
+	// 在叠加边框和编号之前检查渲染结果是否几乎空白
+	c.markBlankSlides(images)
+
+	c.applyOverlaysToFiles(images, options)
+
+	// AUTO格式或单独指定了格式时先以PNG渲染，再逐张决定是否转为JPEG
+	c.applyImageFormats(images, options)
+
+	if options.EmbedMetadata {
+		c.embedImageMetadata(images, filename)
+	}
+
+	return c.completeImages(images, filename, options)
+}
+
+// completeImages 输出多分辨率、切分、写入颜色配置文件并压缩图片，之后文件不再改动，可以通知 OnImageReady
+func (c *PPTConverter) completeImages(images []ImageInfo, filename string, options ConversionOptions) []ImageInfo {
+	setSourceHashes(images, options)
+
+	images = c.splitResolutions(images, filename, options)
+	images = c.splitTiles(images, filename, options)
+	c.encodePNGDepths(images, filename, options)
+
+	if options.EmbedColorProfile {
+		c.embedColorProfiles(images)
+	}
+
+	if options.Optimize {
+		c.optimizeImages(images)
+	}
+	return images
+}
+
+// appendSummaries 在幻灯片图片之后追加总览图、HTML页面、只含图片的PDF、备注页PDF和背景图片，这些文件只随最终结果返回
+func (c *PPTConverter) appendSummaries(images []ImageInfo, outputPath, filename string, pptData []byte, options ConversionOptions) []ImageInfo {
+	slides := len(images)
+	images = c.appendContactSheet(images, outputPath, options)
+
+	if options.Optimize && len(images) > slides {
+		c.optimizeImages(images[slides:])
+	}
+
+	images = c.appendHTMLBundle(images, outputPath, filename, pptData, options)
+	images = c.appendImagePDF(images, outputPath, options)
+	images = c.appendNotesPDF(images, outputPath, pptData, options)
+	return c.appendBackgrounds(images, outputPath, pptData, options)
+}
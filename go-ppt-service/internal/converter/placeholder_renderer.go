@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"ppt-to-images-service/internal/jpegenc"
+)
+
+// DefaultPlaceholderWatermark 占位图片上默认绘制的文字
+const DefaultPlaceholderWatermark = "PLACEHOLDER - NOT RENDERED"
+
+// PlaceholderRenderer 没有可用的渲染工具时使用的渲染器，从PPTX的 presentation.xml 读取幻灯片数量，为每张幻灯片生成纯色占位图片
+// 图片中央绘制水印文字，结果中的 ImageInfo.Placeholder 为 true，避免被误认为真实的渲染结果
+type PlaceholderRenderer struct {
+	watermark string
+	logger    *logrus.Logger
+}
+
+// NewPlaceholderRenderer 创建占位渲染器，options.PlaceholderWatermark 为空时使用默认水印
+func NewPlaceholderRenderer(options Options, logger *logrus.Logger) *PlaceholderRenderer {
+	watermark := options.PlaceholderWatermark
+	if watermark == "" {
+		watermark = DefaultPlaceholderWatermark
+	}
+	return &PlaceholderRenderer{watermark: watermark, logger: logger}
+}
+
+// Backend 转换后端名称，只生成占位图片
+func (r *PlaceholderRenderer) Backend() string {
+	return BackendPlaceholder
+}
+
+// SupportedExtensions 支持的输入文件扩展名 (小写，带点)
+func (r *PlaceholderRenderer) SupportedExtensions() []string {
+	return powerPointExtensions
+}
+
+// Close 占位渲染器不持有资源
+func (r *PlaceholderRenderer) Close() error {
+	return nil
+}
+
+// Render 逐张生成占位图片，每张写好后立即提交，单张失败时记录日志并继续
+func (r *PlaceholderRenderer) Render(ctx context.Context, pptPath string, opts RenderOptions) ([]RenderedSlide, error) {
+	if opts.ThemeVariant > 0 {
+		r.logger.Warnf("占位渲染器不支持主题变体，忽略 theme_variant=%d", opts.ThemeVariant)
+	}
+	data, err := os.ReadFile(pptPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取PPT文件失败: %v", err)
+	}
+	totalSlides, ok := CountSlides(data)
+	if !ok {
+		return nil, fmt.Errorf("%w: 占位渲染器只能读取PPTX文件的幻灯片数量", ErrCorruptFile)
+	}
+	r.logger.Infof("PPT文件包含 %d 张幻灯片", totalSlides)
+	if err := opts.Counted(totalSlides); err != nil {
+		return nil, err
+	}
+	opts.Report(20, fmt.Sprintf("PPT解析完成，共 %d 张幻灯片", totalSlides))
+
+	var slides []RenderedSlide
+	for slideNumber := 1; slideNumber <= totalSlides; slideNumber++ {
+		if !opts.Selected(slideNumber) {
+			continue
+		}
+		if err := checkCancelled(ctx); err != nil {
+			return nil, err
+		}
+
+		filePath := filepath.Join(opts.OutputPath, slideFilename(slideNumber, -1, imageExtension(opts.Format)))
+		if err := r.renderSlide(slideNumber, filePath, opts); err != nil {
+			r.logger.Errorf("转换第 %d 张幻灯片失败: %v", slideNumber, err)
+			continue
+		}
+
+		slide := RenderedSlide{SlideNumber: slideNumber, BuildIndex: -1, FilePath: filePath, Placeholder: true}
+		opts.Rendered([]RenderedSlide{slide})
+		slides = append(slides, slide)
+	}
+	return slides, nil
+}
+
+// renderSlide 生成单张幻灯片的占位图片，叠加内容、格式和元数据由后处理完成
+func (r *PlaceholderRenderer) renderSlide(slideNumber int, filePath string, opts RenderOptions) error {
+	width, height := opts.SizeFor(slideNumber)
+	img, err := createPlaceholderImage(slideNumber, width, height, r.watermark)
+	if err != nil {
+		return err
+	}
+	if err := writeImageFile(img, filePath, opts.Format, jpegenc.Options{Quality: DefaultJPEGQuality}, nil); err != nil {
+		os.Remove(filePath)
+		return fmt.Errorf("保存图片失败: %v", err)
+	}
+	return nil
+}
+
+// createPlaceholderImage 创建占位图片，背景色随幻灯片编号变化，中央绘制水印文字
+func createPlaceholderImage(slideNumber, width, height int, watermark string) (image.Image, error) {
+	if width <= 0 {
+		width = 1920
+	}
+	if height <= 0 {
+		height = 1080
+	}
+
+	colors := []color.RGBA{
+		{255, 200, 200, 255}, // 浅红色
+		{200, 255, 200, 255}, // 浅绿色
+		{200, 200, 255, 255}, // 浅蓝色
+		{255, 255, 200, 255}, // 浅黄色
+		{255, 200, 255, 255}, // 浅紫色
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fill := colors[slideNumber%len(colors)]
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, fill)
+		}
+	}
+	return drawWatermark(img, watermark)
+}
@@ -0,0 +1,95 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCreatePlaceholderImageWatermark(t *testing.T) {
+	for _, size := range [][2]int{{1920, 1080}, {160, 90}} {
+		img, err := createPlaceholderImage(1, size[0], size[1], DefaultPlaceholderWatermark)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bounds := img.Bounds(); bounds.Dx() != size[0] || bounds.Dy() != size[1] {
+			t.Fatalf("图片尺寸为 %v，应为 %dx%d", bounds, size[0], size[1])
+		}
+
+		// 水印文字为深红色，背景为浅色
+		text := 0
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				if c.R > 120 && c.G < 80 && c.B < 80 {
+					text++
+				}
+			}
+		}
+		if text == 0 {
+			t.Errorf("%dx%d 的占位图片上没有水印", size[0], size[1])
+		}
+	}
+}
+
+func TestConvertPPTPlaceholderImages(t *testing.T) {
+	c, _ := newTestConverter(t, &MockRenderer{Slides: 2, Placeholder: true}, Options{})
+
+	result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, image := range result.Images {
+		if !image.Placeholder {
+			t.Errorf("%s 没有标记为占位图片", image.Filename)
+		}
+	}
+}
+
+func TestConvertPPTCancelledRemovesTempFiles(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	c, outputDir := newTestConverter(t, NewPlaceholderRenderer(Options{}, logger), Options{})
+
+	workDir := filepath.Join(c.tempDir, "conv_cancel")
+	tempFile := filepath.Join(workDir, "deck.pptx")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 渲染器读取幻灯片数量后报告进度，此时取消，占位渲染器在渲染下一张幻灯片之前发现
+	rendering := false
+	progress := func(status ConversionStatus) {
+		if status.TotalSlides == 0 || rendering {
+			return
+		}
+		rendering = true
+		if _, err := os.Stat(tempFile); err != nil {
+			t.Errorf("渲染时临时文件不存在: %v", err)
+		}
+		cancel()
+	}
+	_, err := c.ConvertPPT(ctx, backgroundPPTX(t), "deck.pptx", ConversionOptions{ConversionID: "conv_cancel"}, progress)
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("错误为 %v，应为 ErrCancelled", err)
+	}
+	if !rendering {
+		t.Fatal("取消之前没有开始渲染")
+	}
+
+	if _, err := os.Stat(workDir); !os.IsNotExist(err) {
+		t.Errorf("取消后临时目录仍然存在: %v", err)
+	}
+	if files := outputFiles(t, c.tempDir); len(files) != 0 {
+		t.Errorf("取消后临时目录中残留 %v", files)
+	}
+	if files := outputFiles(t, outputDir); len(files) != 0 {
+		t.Errorf("取消后输出目录中残留 %v", files)
+	}
+}
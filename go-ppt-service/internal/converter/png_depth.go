@@ -0,0 +1,284 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// paletteColors 调色板PNG最多使用的颜色数
+	paletteColors = 256
+	// paletteMaxError 量化后每通道的均方根误差 (0-255) 超过该值时放弃调色板，保持真彩色
+	// 文字和纯色为主的幻灯片通常在1以下，照片和渐变背景往往超过该值
+	paletteMaxError = 4.0
+)
+
+// CheckPNGDepth 检查PNG位深度选项: 位深度只能为 0 (保持渲染结果)、8 或 16，调色板只能是8位
+func CheckPNGDepth(bitDepth int, palette bool) error {
+	switch bitDepth {
+	case 0, 8, 16:
+	default:
+		return fmt.Errorf("无效的PNG位深度: %d (只支持 8, 16)", bitDepth)
+	}
+	if palette && bitDepth == 16 {
+		return fmt.Errorf("调色板PNG只能为8位，不能与16位深度同时使用")
+	}
+	return nil
+}
+
+// encodePNGDepths 按 PNGBitDepth/PNGPalette 重新编码PNG图片，JPEG图片跳过，失败时只记录日志
+// 在多分辨率和切分之后、写入颜色配置和压缩之前调用；请求写入元数据时重新写入
+func (c *PPTConverter) encodePNGDepths(images []ImageInfo, filename string, options ConversionOptions) {
+	if options.PNGBitDepth == 0 && !options.PNGPalette {
+		return
+	}
+
+	for i := range images {
+		if formatFromExtension(filepath.Ext(images[i].FilePath)) != "PNG" {
+			continue
+		}
+		var meta *imageMetadata
+		if options.EmbedMetadata {
+			meta = &imageMetadata{SourceFile: filename, SlideNumber: images[i].SlideNumber}
+		}
+		if err := c.encodePNGDepth(&images[i], options, meta); err != nil {
+			c.logger.Warnf("第 %d 张幻灯片重新编码PNG失败: %v", images[i].SlideNumber, err)
+		}
+	}
+}
+
+// encodePNGDepth 重新编码一张PNG图片并更新文件大小，8位真彩色的图片不需要改变时不重写文件
+func (c *PPTConverter) encodePNGDepth(info *ImageInfo, options ConversionOptions, meta *imageMetadata) error {
+	img, err := imaging.Open(info.FilePath)
+	if err != nil {
+		return fmt.Errorf("读取图片失败: %v", err)
+	}
+
+	var output image.Image
+	switch {
+	case options.PNGPalette:
+		paletted, rmse := quantizePalette(img)
+		if rmse <= paletteMaxError {
+			output = paletted
+			break
+		}
+		c.logger.Debugf("第 %d 张幻灯片量化误差 %.2f 超过 %.1f，保持真彩色", info.SlideNumber, rmse, paletteMaxError)
+		if is16Bit(img) {
+			output = imaging.Clone(img)
+		}
+	case options.PNGBitDepth == 16:
+		if !is16Bit(img) {
+			output = to16Bit(img)
+		}
+	case options.PNGBitDepth == 8:
+		if is16Bit(img) {
+			output = imaging.Clone(img)
+		}
+	}
+	if output == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, output); err != nil {
+		return fmt.Errorf("编码PNG失败: %v", err)
+	}
+	data := buf.Bytes()
+	if meta != nil {
+		if data, err = addImageMetadata(data, meta); err != nil {
+			return fmt.Errorf("写入元数据失败: %v", err)
+		}
+	}
+	if err := os.WriteFile(info.FilePath, data, 0644); err != nil {
+		return fmt.Errorf("保存图片失败: %v", err)
+	}
+	info.FileSize = int64(len(data))
+	return nil
+}
+
+// is16Bit 图片是否为每通道16位
+func is16Bit(img image.Image) bool {
+	switch img.(type) {
+	case *image.RGBA64, *image.NRGBA64, *image.Gray16:
+		return true
+	default:
+		return false
+	}
+}
+
+// to16Bit 把图片转换为每通道16位的 NRGBA64
+func to16Bit(img image.Image) *image.NRGBA64 {
+	bounds := img.Bounds()
+	result := image.NewNRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			result.Set(x-bounds.Min.X, y-bounds.Min.Y, img.At(x, y))
+		}
+	}
+	return result
+}
+
+// colorCount 直方图中的一种颜色及其像素数
+type colorCount struct {
+	color color.NRGBA
+	count int
+}
+
+// quantizePalette 用中位切分把图片量化为最多 paletteColors 种颜色，返回调色板图片和每通道的均方根误差
+// 颜色不超过 paletteColors 种时 (文字和纯色幻灯片) 调色板就是这些颜色，没有误差
+func quantizePalette(img image.Image) (*image.Paletted, float64) {
+	nrgba := imaging.Clone(img)
+	histogram := make(map[color.NRGBA]int)
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		histogram[color.NRGBA{R: nrgba.Pix[i], G: nrgba.Pix[i+1], B: nrgba.Pix[i+2], A: nrgba.Pix[i+3]}]++
+	}
+	colors := make([]colorCount, 0, len(histogram))
+	for c, count := range histogram {
+		colors = append(colors, colorCount{color: c, count: count})
+	}
+
+	palette := medianCut(colors, paletteColors)
+	paletted := image.NewPaletted(nrgba.Rect, palette)
+	indices := make(map[color.NRGBA]uint8, len(histogram))
+	var squared float64
+	for i, j := 0, 0; i < len(nrgba.Pix); i, j = i+4, j+1 {
+		c := color.NRGBA{R: nrgba.Pix[i], G: nrgba.Pix[i+1], B: nrgba.Pix[i+2], A: nrgba.Pix[i+3]}
+		index, ok := indices[c]
+		if !ok {
+			index = uint8(nearestColor(palette, c))
+			indices[c] = index
+		}
+		paletted.Pix[j] = index
+		squared += float64(colorDistance(palette[index].(color.NRGBA), c))
+	}
+
+	pixels := len(nrgba.Pix) / 4
+	if pixels == 0 {
+		return paletted, 0
+	}
+	return paletted, math.Sqrt(squared / float64(pixels*4))
+}
+
+// medianCut 反复把 (颜色范围 x 像素数) 最大的颜色盒沿最宽的通道在像素数的中位处一分为二，
+// 直到得到 maxColors 个盒子，每个盒子取按像素数加权的平均色
+func medianCut(colors []colorCount, maxColors int) color.Palette {
+	boxes := [][]colorCount{colors}
+	for len(boxes) < maxColors {
+		best, bestChannel, bestScore := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, spread := widestChannel(box)
+			if score := spread * boxCount(box); score > bestScore {
+				best, bestChannel, bestScore = i, channel, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(i, k int) bool {
+			return channelValue(box[i].color, bestChannel) < channelValue(box[k].color, bestChannel)
+		})
+		half, seen, split := boxCount(box)/2, 0, 1
+		for i := 0; i < len(box)-1; i++ {
+			seen += box[i].count
+			if seen >= half {
+				split = i + 1
+				break
+			}
+		}
+		boxes[best] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := make(color.Palette, 0, len(boxes))
+	for _, box := range boxes {
+		var r, g, b, a, total int
+		for _, c := range box {
+			r += int(c.color.R) * c.count
+			g += int(c.color.G) * c.count
+			b += int(c.color.B) * c.count
+			a += int(c.color.A) * c.count
+			total += c.count
+		}
+		if total == 0 {
+			continue
+		}
+		palette = append(palette, color.NRGBA{
+			R: uint8((r + total/2) / total), G: uint8((g + total/2) / total),
+			B: uint8((b + total/2) / total), A: uint8((a + total/2) / total),
+		})
+	}
+	return palette
+}
+
+// widestChannel 盒子中取值范围最大的通道 (0-3 为 R, G, B, A) 及其范围
+func widestChannel(box []colorCount) (channel, spread int) {
+	for ch := 0; ch < 4; ch++ {
+		low, high := 255, 0
+		for _, c := range box {
+			v := int(channelValue(c.color, ch))
+			low, high = min(low, v), max(high, v)
+		}
+		if high-low > spread {
+			channel, spread = ch, high-low
+		}
+	}
+	return channel, spread
+}
+
+// boxCount 盒子中的像素总数
+func boxCount(box []colorCount) int {
+	total := 0
+	for _, c := range box {
+		total += c.count
+	}
+	return total
+}
+
+// channelValue 颜色的第 ch 个通道
+func channelValue(c color.NRGBA, ch int) uint8 {
+	switch ch {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	case 2:
+		return c.B
+	default:
+		return c.A
+	}
+}
+
+// nearestColor 调色板中与 c 距离最近的颜色的下标
+func nearestColor(palette color.Palette, c color.NRGBA) int {
+	best, bestDistance := 0, math.MaxInt
+	for i, p := range palette {
+		if d := colorDistance(p.(color.NRGBA), c); d < bestDistance {
+			best, bestDistance = i, d
+			if d == 0 {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// colorDistance 两种颜色各通道差的平方和
+func colorDistance(a, b color.NRGBA) int {
+	dr, dg := int(a.R)-int(b.R), int(a.G)-int(b.G)
+	db, da := int(a.B)-int(b.B), int(a.A)-int(b.A)
+	return dr*dr + dg*dg + db*db + da*da
+}
@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestQuantizePalette(t *testing.T) {
+	// 只有几种颜色的幻灯片 (背景、文字、抗锯齿边缘) 量化后没有误差
+	slide := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	colors := []color.NRGBA{{255, 255, 255, 255}, {0, 0, 0, 255}, {128, 128, 128, 255}, {200, 30, 30, 255}}
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			slide.SetNRGBA(x, y, colors[(x/8+y/8)%len(colors)])
+		}
+	}
+	paletted, rmse := quantizePalette(slide)
+	if rmse != 0 || len(paletted.Palette) != len(colors) {
+		t.Errorf("%d 种颜色: 误差 %.2f，调色板 %d 种颜色", len(colors), rmse, len(paletted.Palette))
+	}
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			if got := paletted.At(x, y).(color.NRGBA); got != slide.NRGBAAt(x, y) {
+				t.Fatalf("(%d, %d) 为 %v，应为 %v", x, y, got, slide.NRGBAAt(x, y))
+			}
+		}
+	}
+
+	// 渐变只有少量误差，仍使用调色板
+	gradient := image.NewNRGBA(image.Rect(0, 0, 256, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 256; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y * 4), 128, 255})
+		}
+	}
+	if paletted, rmse = quantizePalette(gradient); rmse > paletteMaxError || len(paletted.Palette) > paletteColors {
+		t.Errorf("渐变: 误差 %.2f，调色板 %d 种颜色", rmse, len(paletted.Palette))
+	}
+
+	// 随机噪点 (照片) 的误差超过阈值，应保持真彩色
+	noise := image.NewNRGBA(image.Rect(0, 0, 128, 128))
+	random := rand.New(rand.NewSource(1))
+	random.Read(noise.Pix)
+	for i := 3; i < len(noise.Pix); i += 4 {
+		noise.Pix[i] = 255
+	}
+	if _, rmse = quantizePalette(noise); rmse <= paletteMaxError {
+		t.Errorf("噪点的量化误差 %.2f 不应低于阈值 %.1f", rmse, paletteMaxError)
+	}
+}
+
+func TestConvertPPTPNGPalette(t *testing.T) {
+	// 第2张幻灯片为噪点，误差过大保持真彩色
+	renderer := &MockRenderer{Slides: 2, Photos: map[int]bool{2: true}}
+	c, _ := newTestConverter(t, renderer, Options{})
+	result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{PNGPalette: true, EmbedMetadata: true}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+
+	for i, paletted := range []bool{true, false} {
+		file, err := os.Open(result.Images[i].FilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := png.DecodeConfig(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := config.ColorModel.(color.Palette); ok != paletted {
+			t.Errorf("第 %d 张幻灯片是否为调色板PNG: %v，应为 %v", i+1, ok, paletted)
+		}
+		if info, err := os.Stat(result.Images[i].FilePath); err != nil || info.Size() != result.Images[i].FileSize {
+			t.Errorf("第 %d 张幻灯片的文件大小没有更新", i+1)
+		}
+	}
+}
+
+func TestCheckPNGDepth(t *testing.T) {
+	for _, test := range []struct {
+		bitDepth int
+		palette  bool
+		ok       bool
+	}{
+		{0, false, true}, {8, true, true}, {16, false, true}, {0, true, true},
+		{16, true, false}, {4, false, false}, {24, false, false},
+	} {
+		if err := CheckPNGDepth(test.bitDepth, test.palette); (err == nil) != test.ok {
+			t.Errorf("位深度 %d，调色板 %v: %v", test.bitDepth, test.palette, err)
+		}
+	}
+}
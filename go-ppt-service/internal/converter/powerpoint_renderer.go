@@ -0,0 +1,232 @@
+//go:build windows
+// +build windows
+
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PowerPointRenderer 通过PowerShell和Office COM接口渲染的渲染器 (Windows)
+// PowerPoint总是导出全部幻灯片 (包括隐藏的)，不需要的图片由 PPTConverter 删除
+type PowerPointRenderer struct {
+	pool      *powerPointPool
+	processes *processTracker
+	logger    *logrus.Logger
+}
+
+// NewPowerPointRenderer 创建PowerPoint渲染器
+func NewPowerPointRenderer(tempDir string, options Options, logger *logrus.Logger) *PowerPointRenderer {
+	renderer := &PowerPointRenderer{
+		processes: newProcessTracker(options.processLimiter),
+		logger:    logger,
+	}
+
+	// 启用PowerPoint实例池，创建失败时退回到每次转换启动新进程
+	if options.PoolSize > 0 {
+		pool, err := newPowerPointPool(options.PoolSize, options.PoolMaxUses, tempDir, logger)
+		if err != nil {
+			logger.Warnf("创建PowerPoint实例池失败，将为每次转换启动新进程: %v", err)
+		} else {
+			renderer.pool = pool
+			logger.Infof("PowerPoint实例池已启用 (大小: %d, 实例最多使用: %d 次)", options.PoolSize, options.PoolMaxUses)
+		}
+	}
+
+	return renderer
+}
+
+// Backend 转换后端名称
+func (r *PowerPointRenderer) Backend() string {
+	return BackendPowerPoint
+}
+
+// SupportedExtensions 支持的输入文件扩展名 (小写，带点)
+func (r *PowerPointRenderer) SupportedExtensions() []string {
+	return powerPointExtensions
+}
+
+// KillProcesses 强制结束仍在运行的一次性PowerShell进程，返回结束的数量
+func (r *PowerPointRenderer) KillProcesses() int {
+	return r.processes.killAll()
+}
+
+// Close 关闭PowerPoint实例池并结束所有常驻进程
+func (r *PowerPointRenderer) Close() error {
+	if r.pool == nil {
+		return nil
+	}
+	return r.pool.close()
+}
+
+// Render 导出全部幻灯片后扫描输出目录
+func (r *PowerPointRenderer) Render(ctx context.Context, pptPath string, opts RenderOptions) ([]RenderedSlide, error) {
+	if len(opts.Fonts) > 0 {
+		r.logger.Warnf("PowerPoint后端不支持随请求上传字体，忽略 %d 个字体文件", len(opts.Fonts))
+	}
+	opts.Report(20, "正在使用PowerPoint转换PPT...")
+
+	var err error
+	if r.pool != nil {
+		slideName, buildName := dotNetSlideNameFormats()
+		err = r.pool.convert(ctx, powerPointRequest{
+			Input:     pptPath,
+			Output:    opts.OutputPath,
+			Width:     opts.Width,
+			Height:    opts.Height,
+			Filter:    powerPointExportFilter(opts.Format),
+			Extension: imageExtension(opts.Format),
+			SlideName: slideName,
+			BuildName: buildName,
+			Mode:      opts.AnimationMode,
+			Variant:   opts.ThemeVariant,
+			NotesPDF:  opts.notesPDFPath,
+			Hidden:    opts.IncludeHidden,
+		})
+	} else {
+		err = r.convertWithScript(ctx, pptPath, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slides, err := scanRenderedSlides(opts.OutputPath, opts.Format)
+	if err != nil {
+		return nil, fmt.Errorf("扫描输出目录失败: %v", err)
+	}
+	if err := opts.Counted(renderedSlideCount(slides)); err != nil {
+		return nil, err
+	}
+	return slides, nil
+}
+
+// convertWithScript 启动一次性的PowerShell脚本完成转换
+func (r *PowerPointRenderer) convertWithScript(ctx context.Context, tempFile string, opts RenderOptions) error {
+	// 创建PowerShell脚本
+	psScript := r.createPowerShellScript(tempFile, opts)
+	// 脚本与上传文件的副本放在同一个转换专用的临时目录中，随目录一起删除
+	scriptFile := filepath.Join(filepath.Dir(tempFile), "convert.ps1")
+
+	if err := os.WriteFile(scriptFile, []byte(psScript), 0644); err != nil {
+		return fmt.Errorf("创建PowerShell脚本失败: %v", err)
+	}
+	defer os.Remove(scriptFile)
+
+	// 执行PowerShell脚本
+	cmd := command(ctx, "powershell", "-ExecutionPolicy", "Bypass", "-File", scriptFile)
+	output, err := r.processes.run(ctx, cmd)
+	var execErr *exec.Error
+	if errors.As(err, &execErr) {
+		return fmt.Errorf("%w: %v", ErrBackendUnavailable, err)
+	}
+	if errors.Is(err, ErrCancelled) {
+		return err
+	}
+	if err != nil {
+		r.logger.Errorf("PowerShell脚本执行失败: %v", err)
+		r.logger.Errorf("输出: %s", string(output))
+		return fmt.Errorf("PowerShell脚本执行失败: %v", err)
+	}
+
+	r.logger.Debugf("PowerShell脚本输出: %s", string(output))
+	logPowerPointWarnings(r.logger, output)
+	return nil
+}
+
+// createPowerShellScript 创建PowerShell转换脚本
+func (r *PowerPointRenderer) createPowerShellScript(inputFile string, opts RenderOptions) string {
+	slideName, buildName := dotNetSlideNameFormats()
+	script := fmt.Sprintf(`
+# PowerPoint转换脚本
+%s
+
+try {
+    # 创建PowerPoint应用程序对象
+    $ppt = New-Object -ComObject PowerPoint.Application
+    $ppt.Visible = $false
+    
+    # 打开演示文稿
+    $presentation = $ppt.Presentations.Open("%s", $false, $false, $false)
+    Apply-ThemeVariant $presentation %d
+    
+    Write-Host "演示文稿包含 $($presentation.Slides.Count) 张幻灯片"
+    
+    # 遍历每张幻灯片
+    for ($i = 1; $i -le $presentation.Slides.Count; $i++) {
+        $slide = $presentation.Slides($i)
+        
+        Write-Host "正在导出第 $i 张幻灯片"
+        
+        # 导出幻灯片为图片
+        Export-Slide $slide $i "%s" "%s" "%s" "%s" "%s" %d %d "%s"
+        
+        Write-Host "第 $i 张幻灯片导出完成"
+    }
+    
+    # 导出备注页
+    Export-NotesPages $presentation "%s" $%s
+    
+    # 关闭演示文稿
+    $presentation.Close()
+    
+    # 退出PowerPoint
+    $ppt.Quit()
+    
+    # 释放COM对象
+    [System.Runtime.Interopservices.Marshal]::ReleaseComObject($presentation) | Out-Null
+    [System.Runtime.Interopservices.Marshal]::ReleaseComObject($ppt) | Out-Null
+    
+    Write-Host "转换完成"
+}
+catch {
+    Write-Error "转换过程中发生错误: $($_.Exception.Message)"
+    exit 1
+}
+finally {
+    # 确保PowerPoint进程被关闭
+    Get-Process -Name "POWERPNT" -ErrorAction SilentlyContinue | Stop-Process -Force -ErrorAction SilentlyContinue
+}
+`,
+		powerPointExportFunction,
+		strings.ReplaceAll(inputFile, "\\", "\\\\"),
+		opts.ThemeVariant,
+		strings.ReplaceAll(opts.OutputPath, "\\", "\\\\"),
+		slideName,
+		buildName,
+		imageExtension(opts.Format),
+		powerPointExportFilter(opts.Format),
+		opts.Width,
+		opts.Height,
+		opts.AnimationMode,
+		strings.ReplaceAll(opts.notesPDFPath, "\\", "\\\\"),
+		strconv.FormatBool(opts.IncludeHidden),
+	)
+
+	return script
+}
+
+// logPowerPointWarnings 以Warn级别记录一次性脚本输出中的警告 (powerPointWarnMarker 开头的行)
+func logPowerPointWarnings(logger *logrus.Logger, output []byte) {
+	for _, line := range strings.Split(string(output), "\n") {
+		if message, ok := strings.CutPrefix(strings.TrimSpace(line), powerPointWarnMarker); ok {
+			logger.Warnf("PowerPoint: %s", strings.TrimSpace(message))
+		}
+	}
+}
+
+// powerPointExportFilter 输出格式对应的PowerPoint导出过滤器名称
+func powerPointExportFilter(format string) string {
+	if imageExtension(format) == "jpg" {
+		return "JPG"
+	}
+	return "PNG"
+}
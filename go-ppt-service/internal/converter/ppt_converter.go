@@ -2,28 +2,44 @@ package converter
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"image"
-	"image/jpeg"
 	"image/png"
-	"io"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/disintegration/imaging"
 	"github.com/sirupsen/logrus"
+
+	"ppt-to-images-service/internal/jpegenc"
 )
 
 // ImageInfo 图片信息
 type ImageInfo struct {
-	SlideNumber int    `json:"slide_number"`
-	Filename    string `json:"filename"`
-	FilePath    string `json:"file_path"`
-	FileSize    int64  `json:"file_size"`
-	DownloadID  string `json:"download_id"`
+	SlideNumber   int      `json:"slide_number"`
+	Filename      string   `json:"filename"`
+	FilePath      string   `json:"file_path"`
+	FileSize      int64    `json:"file_size"`
+	DownloadID    string   `json:"download_id"`
+	Format        string   `json:"format"`         // 实际输出格式 (PNG, JPEG)
+	BuildIndex    int      `json:"build_index"`    // 动画构建步骤 (仅 all_builds 模式)，0为第一次单击之前
+	TileIndex     int      `json:"tile_index"`     // 超高幻灯片切分后的分块序号，从1开始，0表示未切分
+	Width         int      `json:"width"`          // 图片宽度 (包括边框)
+	Height        int      `json:"height"`         // 图片高度 (包括边框)
+	LowConfidence bool     `json:"low_confidence"` // 渲染结果几乎为单一颜色，可能有图表或SmartArt未能渲染
+	Crop          *CropBox `json:"crop,omitempty"` // 自动裁剪保留的区域，未裁剪时为空
+	SourceHash    string   `json:"source_hash"`    // 幻灯片源XML的SHA-256，用于检测幻灯片是否被修改，非PPTX时为空
+	Resolution    string   `json:"resolution"`     // 多分辨率输出时该图片对应尺寸的后缀
+	Placeholder   bool     `json:"placeholder"`    // 占位渲染器生成的图片，不是幻灯片的真实内容
+	Backend       string   `json:"backend"`        // 生成该图片的渲染后端，总览图等汇总文件为空
+	Background    bool     `json:"background"`     // 只有幻灯片背景的图片 (slide_NNN_bg.png)，见 ExtractBackground
 }
 
 // ConversionResult 转换结果
@@ -32,8 +48,26 @@ type ConversionResult struct {
 	Message         string      `json:"message"`
 	TotalSlides     int         `json:"total_slides"`
 	ConvertedSlides int         `json:"converted_slides"`
+	HiddenSlides    int       `json:"hidden_slides"` // 跳过的隐藏幻灯片数量
+	SlideSize       SlideSize `json:"slide_size"`    // 演示文稿的幻灯片尺寸，无法识别时为零值
+	AspectRatio     float64   `json:"aspect_ratio"`  // 幻灯片的宽高比 (宽/高)，无法识别尺寸时为0
+	// SlideSizeDefaulted 探测时演示文稿没有记录尺寸或无法读取，SlideSize 为 DefaultSlideSize
+	SlideSizeDefaulted bool        `json:"slide_size_defaulted,omitempty"`
 	Images          []ImageInfo `json:"images"`
 	Error           string      `json:"error,omitempty"`
+	// ManifestDownloadID 请求设置 GenerateManifest 时 manifest.json 的下载ID，该文件同时位于 Images 的末尾
+	ManifestDownloadID string `json:"manifest_download_id,omitempty"`
+	// Partial 转换超时后按 ReturnPartial 返回的部分结果，只包含超时前已完成的幻灯片
+	Partial bool `json:"partial,omitempty"`
+	// Timing 各阶段的耗时
+	Timing *ConversionTiming `json:"timing,omitempty"`
+	// SlideErrors 渲染结果为空、过小或无法解码而被丢弃的幻灯片
+	SlideErrors []SlideError `json:"slide_errors,omitempty"`
+	// MatchedSlides 按 layout_filter、title_pattern 筛选时匹配的幻灯片编号 (原始编号)
+	MatchedSlides []int `json:"matched_slides,omitempty"`
+	// NormalizedWidth/NormalizedHeight 设置 NormalizeSize 时所有幻灯片图片的统一尺寸 (包括旋转和边框)，多分辨率输出时为最大的尺寸
+	NormalizedWidth  int `json:"normalized_width,omitempty"`
+	NormalizedHeight int `json:"normalized_height,omitempty"`
 }
 
 // ConversionStatus 转换状态
@@ -48,38 +82,291 @@ type ConversionStatus struct {
 // ProgressCallback 进度回调函数
 type ProgressCallback func(status ConversionStatus)
 
-// PPTConverter PPT转换器
+// ImageReadyCallback 图片就绪回调函数，每张幻灯片图片的文件写好、不再改动后调用
+// 即使幻灯片并行渲染，调用也按幻灯片顺序依次进行，不会并发
+type ImageReadyCallback func(image ImageInfo)
+
+// ConversionOptions 单次转换请求的选项
+type ConversionOptions struct {
+	// Width/Height 输出图片尺寸，为0时使用转换器默认值
+	Width  int
+	Height int
+	// OutputFormat 输出格式 (PNG, JPEG, AUTO)，为空时使用转换器默认值
+	OutputFormat string
+	// SlideFormats 单独指定格式的幻灯片 (演示文稿内的原始编号 -> 格式)，其余幻灯片使用 OutputFormat
+	SlideFormats map[int]string
+	// SlideSizes 单独指定尺寸的幻灯片 (演示文稿内的原始编号 -> 尺寸)，其余幻灯片使用 Width/Height；不能与 Resolutions 同时使用
+	SlideSizes map[int]SizeOverride
+	// ContactSheet 不为空时额外生成所有幻灯片缩略图的总览图
+	ContactSheet *ContactSheetOptions
+	// EmbedMetadata 在图片中写入源文件名和幻灯片编号 (PNG文本块 / JPEG EXIF)
+	EmbedMetadata bool
+	// SlideIndices 只转换指定的幻灯片 (从1开始)，为空时转换全部
+	SlideIndices []int
+	// Stamp 不为空时在每张图片角落绘制幻灯片编号
+	Stamp *StampOptions
+	// Border 不为空时在每张图片四周加上纯色边框，图片尺寸相应增大
+	Border *BorderOptions
+	// Rotate 渲染后按顺时针旋转的角度 (0, 90, 180, 270)，90和270时图片宽高互换
+	Rotate int
+	// IncludeHidden 同时转换被隐藏的幻灯片，默认跳过
+	IncludeHidden bool
+	// NumberOffset 输出文件名和幻灯片编号从 1+NumberOffset 开始，SlideIndices 仍使用原始编号
+	NumberOffset int
+	// AnimationMode 动画的渲染方式 (final, first, all_builds)，为空时为 final
+	AnimationMode string
+	// Order 结果和 OnImageReady 中图片的顺序 (ascending, descending)，为空时为 ascending
+	Order string
+	// OrderIndices 不为空时按其中的幻灯片编号 (原始编号) 排列图片，必须恰好包含每张需要转换的幻灯片一次
+	OrderIndices []int
+	// Optimize 使用外部工具 (oxipng, jpegoptim) 无损压缩输出图片
+	Optimize bool
+	// Fonts 随请求上传、只在本次转换中使用的字体 (仅LibreOffice)
+	Fonts []FontFile
+	// HTMLBundle 额外生成引用所有图片的 index.html 幻灯片页面
+	HTMLBundle bool
+	// PDFImageOnly 额外生成每页一张幻灯片图片的 slides.pdf，PDF中没有可以提取的文字
+	PDFImageOnly bool
+	// NotesPDF 额外生成每张幻灯片一页、包含演讲者备注的 notes.pdf，渲染后端能导出备注页时直接使用其结果
+	NotesPDF bool
+	// StrictFilenames 输出文件名重名时返回 ErrFilenameCollision，而不是加上 _2 等后缀后继续
+	StrictFilenames bool
+	// CoverOnly 只转换第一张不被跳过的幻灯片 (封面)，忽略 SlideIndices；结果中的总数仍为整个演示文稿的幻灯片数
+	CoverOnly bool
+	// NormalizeSize 把每张幻灯片 (自动裁剪之后) 按比例放入 Width x Height 的画布并居中，空白填充白色，保证所有图片尺寸相同；
+	// 不能与 SlideSizes 同时使用
+	NormalizeSize bool
+	// OutputSubdir 输出目录下本次转换使用的子目录 (可以有多级)，为空时使用 session_<时间戳>
+	OutputSubdir string
+	// KeepUploadDir 不为空时转换结束后把上传的文件移动到该目录保留，而不是删除
+	KeepUploadDir string
+	// ConversionID 本次转换的ID，临时文件都放在临时目录下以它命名的子目录中，为空时自动生成
+	ConversionID string
+	// EmbedColorProfile 在图片中写入服务器配置的ICC颜色配置文件 (PNG iCCP / JPEG APP2)
+	EmbedColorProfile bool
+	// TileHeight 图片高度超过该值时从上到下切分为多张，0表示不切分
+	TileHeight int
+	// AutoCrop 不为空时在叠加内容之前裁掉渲染结果四周的纯色边距
+	AutoCrop *AutoCropOptions
+	// OnImageReady 每张幻灯片图片完成后立即调用，总览图和HTML页面不会触发
+	OnImageReady ImageReadyCallback
+	// JPEGSubsampling JPEG的色度抽样，零值为4:2:0；其他值时外部工具先渲染为PNG再由服务器编码
+	JPEGSubsampling jpegenc.Subsampling
+	// Resolutions 不为空时每张幻灯片以其中最大的尺寸渲染一次，再缩小输出其余尺寸，忽略 Width/Height
+	Resolutions []Resolution
+	// GenerateManifest 额外生成描述全部输出文件 (包括总览图和HTML页面) 的 manifest.json
+	GenerateManifest bool
+	// Timeout 整个转换的时间上限，超过后结束外部进程并返回 ErrTimeout，0表示不限制
+	Timeout time.Duration
+	// ResampleFilter 缩放图片 (多分辨率输出、总览图) 使用的算法 (lanczos, catmullrom, linear, box, nearest)，为空时为 lanczos
+	ResampleFilter string
+	// ReturnPartial 超时时同时返回已完成幻灯片的部分结果 (Partial 为 true)，并保留其输出文件
+	ReturnPartial bool
+	// PNGBitDepth PNG图片每通道的位数 (8, 16)，0表示保持渲染结果
+	PNGBitDepth int
+	// PNGPalette 把PNG图片量化为最多256色的调色板图片，量化误差过大 (照片、渐变) 时保持真彩色
+	PNGPalette bool
+	// RenderMediaPosters 把PPTX中视频的封面帧合成到幻灯片图片的视频区域，没有封面时绘制播放按钮
+	RenderMediaPosters bool
+	// ThemeVariant 渲染前应用的主题变体 (从1开始，对应PowerPoint设计选项卡中变体的顺序)，0表示保持原样；
+	// 只有PowerPoint后端支持，其他后端记录日志后忽略
+	ThemeVariant int
+	// ExtractBackground 额外为每张幻灯片输出只有背景的 slide_NNN_bg.png，背景从PPTX的XML读取；
+	// 不是PPTX或背景无法提取 (如图案填充) 时记录日志后跳过
+	ExtractBackground bool
+
+	// sourceHashes 每张幻灯片 (按放映顺序) 源XML的哈希，由转换器开始时从PPTX读取
+	sourceHashes []string
+	// mediaPosters 每张幻灯片 (原始编号) 上的视频，设置 RenderMediaPosters 时由转换器开始时从PPTX读取
+	mediaPosters map[int][]mediaPoster
+	// notesPDFPath 渲染后端导出备注页PDF的位置，为空时由转换器用幻灯片图片生成
+	notesPDFPath string
+	// collisions 本次转换中重名的输出文件名，由转换器开始时创建
+	collisions *filenameCollisions
+	// coverTotal CoverOnly 且能从PPTX读取幻灯片总数时为该总数，渲染器可以只导出封面而不必导出整个演示文稿
+	coverTotal int
+}
+
+// FontFile 字体文件
+type FontFile struct {
+	Filename string
+	Data     []byte
+}
+
+// Converter PPT转换器接口，由各平台的转换器实现
+type Converter interface {
+	// ConvertPPT ctx 取消时结束外部进程并返回 ErrCancelled，超过 options.Timeout 时返回 ErrTimeout
+	// 设置 options.ReturnPartial 时超时的转换同时返回部分结果
+	ConvertPPT(ctx context.Context, pptData []byte, filename string, options ConversionOptions, progressCallback ProgressCallback) (*ConversionResult, error)
+	// Backend 转换后端名称 (placeholder, libreoffice, powerpoint)
+	Backend() string
+	// SupportedExtensions 支持的输入文件扩展名 (小写，带点)
+	SupportedExtensions() []string
+	// KillProcesses 强制结束仍在运行的外部进程，返回结束的数量
+	KillProcesses() int
+	Close() error
+}
+
+// Options 转换器可选配置
+type Options struct {
+	// PoolSize Windows下常驻PowerPoint实例数量，0表示每次转换启动新进程
+	PoolSize int
+	// PoolMaxUses 单个PowerPoint实例最多处理的转换次数，超过后回收重建
+	PoolMaxUses int
+	// LibreOfficeProfileDir LibreOffice每次转换使用的独立用户配置目录的父目录，为空时使用系统临时目录
+	LibreOfficeProfileDir string
+	// RenderWorkers LibreOffice路径下并行渲染的页数，0表示使用CPU核数
+	RenderWorkers int
+	// EncodeWorkers 所有转换同时进行幻灯片后处理 (合成、格式转换、编码、压缩等) 的协程数量，0表示使用CPU核数
+	EncodeWorkers int
+	// FontsDir LibreOffice渲染时额外加载的字体目录，为空时只使用系统字体
+	FontsDir string
+	// AutoFormat AUTO输出格式的判定阈值，未设置的项使用默认值
+	AutoFormat AutoFormatThresholds
+	// MaxPixels 单张输出图片 (包括总览图) 的最大像素数，0表示不限制
+	MaxPixels int64
+	// ICCProfile 请求要求写入颜色配置时使用的ICC配置文件路径，为空时不支持写入
+	ICCProfile string
+	// BlankThreshold 同一颜色的采样像素占比达到该值时把幻灯片标记为 LowConfidence，0表示不检查
+	BlankThreshold float64
+	// MaxSlides 单个演示文稿的最大幻灯片数量，0表示不限制
+	MaxSlides int
+	// PlaceholderWatermark 占位渲染器在图片中央绘制的文字 (只支持拉丁字符)，为空时为 DefaultPlaceholderWatermark
+	PlaceholderWatermark string
+	// MinImageSize 渲染出的幻灯片图片的最小字节数，更小或无法解码的图片按该幻灯片渲染失败处理，0时只拒绝空文件和无法解码的图片
+	MinImageSize int64
+	// FallbackBackends 主渲染后端失败或幻灯片空白 (见 BlankThreshold) 时依次尝试的备用后端
+	// 非Windows平台支持 libreoffice、placeholder，Windows平台支持 placeholder
+	FallbackBackends []string
+	// MaxRenderProcs 所有转换同时运行的外部渲染进程 (soffice、pdfinfo、pdftoppm、一次性的PowerShell) 数量上限，
+	// 超过时新的进程等待，0表示不限制；PowerPoint实例池中常驻的进程不计入
+	MaxRenderProcs int
+
+	// processLimiter 由 NewPlatformConverter 按 MaxRenderProcs 创建，主渲染器和备用渲染器共用
+	processLimiter *processLimiter
+}
+
+// PPTConverter PPT转换器，负责各后端共用的流程，幻灯片的渲染交给 Renderer
 type PPTConverter struct {
+	renderer     Renderer
+	fallbacks    []Renderer // 主渲染器失败或结果空白时依次尝试的备用渲染器
 	outputDir    string
 	tempDir      string
 	width        int
 	height       int
 	outputFormat string
+	autoFormat   AutoFormatThresholds
+	maxPixels    int64
+	colorProfile []byte
+	blankLimit   float64 // 空白检查阈值，见 Options.BlankThreshold
+	maxSlides    int
+	minImageSize int64 // 见 Options.MinImageSize
+	encoder      *encodePool
 	logger       *logrus.Logger
 }
 
-// NewPPTConverter 创建新的PPT转换器
-func NewPPTConverter(outputDir, tempDir string, width, height int, outputFormat string, logger *logrus.Logger) *PPTConverter {
-	return &PPTConverter{
+// NewPPTConverter 创建使用指定渲染器的PPT转换器
+func NewPPTConverter(renderer Renderer, outputDir, tempDir string, width, height int, outputFormat string, options Options, logger *logrus.Logger) *PPTConverter {
+	c := &PPTConverter{
+		renderer:     renderer,
 		outputDir:    outputDir,
 		tempDir:      tempDir,
 		width:        width,
 		height:       height,
 		outputFormat: strings.ToUpper(outputFormat),
+		autoFormat:   defaultAutoFormatThresholds,
 		logger:       logger,
 	}
+	c.configure(options)
+	return c
+}
+
+// configure 应用转换器选项
+func (c *PPTConverter) configure(options Options) {
+	c.autoFormat = options.AutoFormat.withDefaults()
+	c.maxPixels = options.MaxPixels
+	c.blankLimit = options.BlankThreshold
+	c.maxSlides = options.MaxSlides
+	c.minImageSize = options.MinImageSize
+	c.encoder = newEncodePool(options.EncodeWorkers)
+	if options.ICCProfile != "" {
+		profile, err := loadColorProfile(options.ICCProfile)
+		if err != nil {
+			c.logger.Warnf("加载ICC配置文件失败: %v", err)
+			return
+		}
+		c.colorProfile = profile
+	}
+}
+
+// checkSlideCount 检查幻灯片数量是否超过上限
+func (c *PPTConverter) checkSlideCount(total int) error {
+	if c.maxSlides > 0 && total > c.maxSlides {
+		return fmt.Errorf("%w: 演示文稿有 %d 张幻灯片，上限为 %d", ErrTooManySlides, total, c.maxSlides)
+	}
+	return nil
+}
+
+// checkSlideLimit 渲染之前检查PPTX的幻灯片数量，其他格式无法快速统计，在渲染器报告页数后检查
+func (c *PPTConverter) checkSlideLimit(pptData []byte) error {
+	if c.maxSlides <= 0 {
+		return nil
+	}
+	if total, ok := CountSlides(pptData); ok {
+		return c.checkSlideCount(total)
+	}
+	return nil
+}
+
+// checkPixels 检查图片像素数是否超过上限，在分配图片内存之前调用
+func (c *PPTConverter) checkPixels(width, height int) error {
+	if c.maxPixels > 0 && int64(width)*int64(height) > c.maxPixels {
+		return fmt.Errorf("%w: %dx%d 超过 %d 像素", ErrImageTooLarge, width, height, c.maxPixels)
+	}
+	return nil
 }
 
-// ConvertPPT 转换PPT文件
-func (c *PPTConverter) ConvertPPT(pptData []byte, filename string, progressCallback ProgressCallback) (*ConversionResult, error) {
-	c.logger.Info("开始转换PPT文件: ", filename)
+// KillProcesses 强制结束渲染器启动的、仍在运行的外部进程，返回结束的数量
+func (c *PPTConverter) KillProcesses() int {
+	killed := 0
+	for _, renderer := range append([]Renderer{c.renderer}, c.fallbacks...) {
+		if killer, ok := renderer.(processKiller); ok {
+			killed += killer.KillProcesses()
+		}
+	}
+	return killed
+}
+
+// ConvertPPT 转换PPT文件：准备临时目录和选项，由渲染器生成图片，再逐张完成后处理并汇总结果
+func (c *PPTConverter) ConvertPPT(ctx context.Context, pptData []byte, filename string, options ConversionOptions, progressCallback ProgressCallback) (*ConversionResult, error) {
+	c.logger.Infof("开始转换PPT文件 (%s): %s", c.renderer.Backend(), filename)
+	start := time.Now()
+	timing := &ConversionTiming{}
+	options, slideSize := c.fitSlideSize(pptData, options)
+	options = c.withSourceHashes(pptData, options)
+	options = c.withMediaPosters(pptData, options)
+	options, err := c.resolveOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if options, err = c.resolveSlideSizes(options, slideSize); err != nil {
+		return nil, err
+	}
+	if err := c.checkSlideLimit(pptData); err != nil {
+		return nil, err
+	}
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, options.Timeout, ErrTimeout)
+		defer cancel()
+	}
 	
-	// 创建临时文件
-	tempFile, err := c.createTempFile(pptData, filename)
+	// 创建本次转换专用的临时目录和上传文件副本
+	workDir, tempFile, err := c.createWorkDir(pptData, filename, options)
 	if err != nil {
 		return nil, fmt.Errorf("创建临时文件失败: %v", err)
 	}
-	defer os.Remove(tempFile)
+	defer c.releaseWorkDir(workDir, tempFile, filename, options)
 
 	// 发送开始处理状态
 	if progressCallback != nil {
@@ -90,70 +377,114 @@ func (c *PPTConverter) ConvertPPT(pptData []byte, filename string, progressCallb
 		})
 	}
 
-	// 打开PPT文件
-	pres, err := presentation.Open(tempFile)
-	if err != nil {
-		return nil, fmt.Errorf("打开PPT文件失败: %v", err)
+	// 创建输出目录，渲染器先写入其中的临时子目录，处理时再按最终编号移出
+	outputPath := c.outputPathFor(options)
+	renderPath := filepath.Join(outputPath, ".render")
+	if err := os.MkdirAll(renderPath, 0755); err != nil {
+		return nil, fmt.Errorf("创建输出目录失败: %v", err)
 	}
-	defer pres.Close()
-
-	totalSlides := len(pres.Slides())
-	c.logger.Infof("PPT文件包含 %d 张幻灯片", totalSlides)
-
-	// 发送解析完成状态
-	if progressCallback != nil {
-		progressCallback(ConversionStatus{
-			Status:      "processing",
-			Progress:    20,
-			Message:     fmt.Sprintf("PPT解析完成，共 %d 张幻灯片", totalSlides),
-			TotalSlides: totalSlides,
-		})
+	defer os.RemoveAll(renderPath)
+	if options.NotesPDF && nativeNotesPages(options) {
+		options.notesPDFPath = filepath.Join(renderPath, notesPDFFilename)
 	}
-
-	// 创建输出目录
-	outputPath := filepath.Join(c.outputDir, generateSessionID())
-	if err := os.MkdirAll(outputPath, 0755); err != nil {
-		return nil, fmt.Errorf("创建输出目录失败: %v", err)
+	options.collisions = &filenameCollisions{}
+	success := false
+	defer func() {
+		// 转换失败时删除输出目录及其中已导出的部分图片
+		if !success {
+			os.RemoveAll(outputPath)
+		}
+	}()
+
+	// 各后端都按原始编号渲染，隐藏的幻灯片在渲染时或渲染后跳过
+	hidden := c.skippedSlides(pptData, options)
+	probed, _ := CountSlides(pptData)
+	if options.CoverOnly {
+		options.SlideIndices = []int{coverSlide(hidden)}
+		options.coverTotal = probed
 	}
-
-	var images []ImageInfo
-	convertedCount := 0
-
-	// 转换每张幻灯片
-	for i, slide := range pres.Slides() {
-		slideNumber := i + 1
-		
-		// 发送当前处理状态
-		if progressCallback != nil {
-			progress := 20 + int(float64(slideNumber)/float64(totalSlides)*70)
-			progressCallback(ConversionStatus{
-				Status:          "processing",
-				Progress:        progress,
-				Message:         fmt.Sprintf("正在转换第 %d/%d 张幻灯片", slideNumber, totalSlides),
-				TotalSlides:     totalSlides,
-				ProcessedSlides: slideNumber - 1,
-			})
+	job := &renderJob{
+		converter:  c,
+		filename:   filename,
+		options:    options,
+		outputPath: outputPath,
+		hidden:     hidden,
+		progress:   progressCallback,
+		backend:    c.renderer.Backend(),
+		deferBad:   len(c.fallbacks) > 0,
+		probed:     probed,
+		base:       20,
+		results:    make(map[int][]ImageInfo),
+		submitted:  make(map[int]bool),
+		deferred:   make(map[int]bool),
+		started:    time.Now(),
+
+		slideErrors: make(map[int]error),
+		sizes:       make(map[int]image.Point),
+	}
+	timing.Parse = job.started.Sub(start)
+	slides, err := c.renderer.Render(ctx, tempFile, RenderOptions{
+		ConversionOptions: options,
+		WorkDir:           workDir,
+		OutputPath:        renderPath,
+		Format:            options.renderFormat(),
+		Hidden:            hidden,
+		job:               job,
+	})
+	// 出错或超时时同样等待已提交的幻灯片处理完，部分结果和清理输出目录都在此之后
+	job.wait()
+	timing.Render = time.Since(job.started)
+	if err == nil {
+		err = job.finish(slides)
+	}
+	// 主后端失败或有空白的幻灯片时依次尝试备用后端
+	if len(c.fallbacks) > 0 && ctx.Err() == nil && (err == nil || fallbackable(err)) {
+		fallbackStart := time.Now()
+		err = c.renderFallbacks(ctx, job, tempFile, workDir, err)
+		timing.Render += time.Since(fallbackStart)
+	}
+	// 一次导出全部幻灯片的渲染器没有按单独指定的尺寸渲染时，按指定尺寸重新渲染这些幻灯片
+	if len(options.SlideSizes) > 0 && ctx.Err() == nil && err == nil {
+		sizeStart := time.Now()
+		c.renderSlideSizes(ctx, job, tempFile, workDir)
+		timing.Render += time.Since(sizeStart)
+	}
+	job.notifyDeferred()
+	if err != nil {
+		// 渲染器只知道 ctx 被取消，超时由这里区分 (调用方的截止时间仍按取消处理)
+		if !errors.Is(context.Cause(ctx), ErrTimeout) {
+			return nil, err
 		}
-
-		// 转换幻灯片为图片
-		imageInfo, err := c.convertSlide(slide, slideNumber, outputPath)
-		if err != nil {
-			c.logger.Errorf("转换第 %d 张幻灯片失败: %v", slideNumber, err)
-			continue
+		err = fmt.Errorf("%w: 超过 %v", ErrTimeout, options.Timeout)
+		c.logger.Warnf("PPT转换超时: %s: %v", filename, err)
+		if !options.ReturnPartial {
+			return nil, err
 		}
+		result := c.partialResult(job, outputPath, filename, pptData, slideSize, err)
+		success = result.ConvertedSlides > 0
+		c.finishTiming(result, job, timing, start, filename)
+		return result, err
+	}
+	job.recordMissing()
+	images := job.completed()
+	if names := options.collisions.list(); options.StrictFilenames && len(names) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrFilenameCollision, strings.Join(names, ", "))
+	}
+	convertedCount := slideCount(images)
+	job.fillTiming(timing)
 
-		images = append(images, *imageInfo)
-		convertedCount++
+	summaryStart := time.Now()
+	images = c.appendSummaries(images, outputPath, filename, pptData, options)
 
-		c.logger.Infof("成功转换第 %d 张幻灯片: %s", slideNumber, imageInfo.Filename)
-	}
+	// 隐藏的幻灯片不计入总数
+	totalSlides := job.total - len(hidden)
 
 	// 发送完成状态
 	if progressCallback != nil {
 		progressCallback(ConversionStatus{
 			Status:          "completed",
 			Progress:        100,
-			Message:         fmt.Sprintf("转换完成，成功转换 %d/%d 张幻灯片", convertedCount, totalSlides),
+			Message:         fmt.Sprintf("转换完成，成功转换 %d 张幻灯片", convertedCount),
 			TotalSlides:     totalSlides,
 			ProcessedSlides: convertedCount,
 		})
@@ -161,135 +492,344 @@ func (c *PPTConverter) ConvertPPT(pptData []byte, filename string, progressCallb
 
 	result := &ConversionResult{
 		Success:         convertedCount > 0,
-		Message:         fmt.Sprintf("成功转换 %d/%d 张幻灯片", convertedCount, totalSlides),
+		Message:         fmt.Sprintf("成功转换 %d 张幻灯片", convertedCount),
 		TotalSlides:     totalSlides,
 		ConvertedSlides: convertedCount,
+		HiddenSlides:    len(hidden),
+		SlideSize:       slideSize,
+		AspectRatio:     slideSize.AspectRatio(),
 		Images:          images,
+		SlideErrors:     job.errorList(),
+	}
+	result.NormalizedWidth, result.NormalizedHeight = normalizedSize(options)
+	if len(result.SlideErrors) > 0 {
+		result.Message = fmt.Sprintf("成功转换 %d 张幻灯片，%d 张失败", convertedCount, len(result.SlideErrors))
 	}
 
 	if convertedCount == 0 {
 		result.Error = "没有成功转换任何幻灯片"
 		result.Success = false
 	}
+	success = result.Success
+
+	if success && options.GenerateManifest {
+		c.appendManifest(result, outputPath, filename, options)
+	}
+	timing.Summaries = time.Since(summaryStart)
+	timing.Total = time.Since(start)
+	result.Timing = timing
 
 	c.logger.Infof("PPT转换完成: %s", result.Message)
+	if len(c.fallbacks) > 0 {
+		c.logBackends(filename, images)
+	}
+	c.logTiming(filename, timing)
 	return result, nil
 }
 
-// convertSlide 转换单张幻灯片
-func (c *PPTConverter) convertSlide(slide *presentation.Slide, slideNumber int, outputPath string) (*ImageInfo, error) {
-	// 生成文件名
-	filename := fmt.Sprintf("slide_%03d.%s", slideNumber, strings.ToLower(c.outputFormat))
-	filePath := filepath.Join(outputPath, filename)
+// finishTiming 为超时的部分结果补全耗时，生成汇总文件的时间计入 Summaries
+func (c *PPTConverter) finishTiming(result *ConversionResult, job *renderJob, timing *ConversionTiming, start time.Time, filename string) {
+	job.fillTiming(timing)
+	timing.Total = time.Since(start)
+	timing.Summaries = timing.Total - timing.Parse - timing.Render
+	result.Timing = timing
+	c.logTiming(filename, timing)
+}
 
-	// 将幻灯片转换为图片
-	// 注意: unioffice库可能不直接支持幻灯片转图片
-	// 这里我们使用一个简化的方法，实际项目中可能需要使用其他库或工具
-	
-	// 创建一个占位图片 (实际实现中需要真正的幻灯片转图片逻辑)
-	img, err := c.createPlaceholderImage(slideNumber)
+// partialResult 超时时由已完成的幻灯片组成的部分结果，总览图等汇总文件只包含这些幻灯片
+func (c *PPTConverter) partialResult(job *renderJob, outputPath, filename string, pptData []byte, slideSize SlideSize, err error) *ConversionResult {
+	images := job.completed()
+	convertedCount := slideCount(images)
+	if convertedCount > 0 {
+		images = c.appendSummaries(images, outputPath, filename, pptData, job.options)
+	}
+
+	result := &ConversionResult{
+		Success:         false,
+		Message:         fmt.Sprintf("转换超时，已完成 %d 张幻灯片", convertedCount),
+		TotalSlides:     job.total - len(job.hidden),
+		ConvertedSlides: convertedCount,
+		HiddenSlides:    len(job.hidden),
+		SlideSize:       slideSize,
+		AspectRatio:     slideSize.AspectRatio(),
+		Images:          images,
+		Error:           err.Error(),
+		Partial:         true,
+		SlideErrors:     job.errorList(),
+	}
+	result.NormalizedWidth, result.NormalizedHeight = normalizedSize(job.options)
+	c.logger.Infof("返回部分结果: %s", result.Message)
+	return result
+}
+
+// saveImage 按指定格式保存图片到文件，JPEG使用指定的色度抽样，meta不为空时同时写入元数据
+func (c *PPTConverter) saveImage(img image.Image, filePath string, format string, subsampling jpegenc.Subsampling, meta *imageMetadata) error {
+	return writeImageFile(img, filePath, format, jpegenc.Options{Quality: DefaultJPEGQuality, Subsampling: subsampling}, meta)
+}
+
+// writeImageFile 按指定格式和JPEG选项编码图片并写入文件，meta不为空时同时写入元数据
+func writeImageFile(img image.Image, filePath string, format string, jpegOptions jpegenc.Options, meta *imageMetadata) error {
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case "PNG":
+		err = png.Encode(&buf, img)
+	case "JPEG", "JPG":
+		err = jpegenc.Encode(&buf, img, &jpegOptions)
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return err
+	}
+
+	data := buf.Bytes()
+	if meta != nil {
+		if data, err = addImageMetadata(data, meta); err != nil {
+			return fmt.Errorf("写入元数据失败: %v", err)
+		}
+	}
+
+	return os.WriteFile(filePath, data, 0644)
+}
+
+// resolveOptions 用转换器默认值补全请求选项
+func (c *PPTConverter) resolveOptions(options ConversionOptions) (ConversionOptions, error) {
+	if len(options.Resolutions) > 0 {
+		if err := CheckResolutions(options.Resolutions); err != nil {
+			return options, err
+		}
+		largest := LargestResolution(options.Resolutions)
+		options.Width, options.Height = largest.Width, largest.Height
+	}
+	if options.Width <= 0 || options.Height <= 0 {
+		options.Width = c.width
+		options.Height = c.height
+	}
+	if options.OutputFormat == "" {
+		options.OutputFormat = c.outputFormat
+	}
+	pixelWidth, pixelHeight := options.Width, options.Height
+	if options.Border != nil {
+		pixelWidth += 2 * options.Border.Width
+		pixelHeight += 2 * options.Border.Width
+	}
+	if err := c.checkPixels(pixelWidth, pixelHeight); err != nil {
+		return options, err
+	}
+	options.OutputFormat = strings.ToUpper(options.OutputFormat)
+	if !isSupportedFormat(options.OutputFormat) {
+		return options, fmt.Errorf("%w: %s", ErrUnsupportedFormat, options.OutputFormat)
+	}
+	if len(options.SlideFormats) > 0 {
+		slideFormats := make(map[int]string, len(options.SlideFormats))
+		for slideNumber, format := range options.SlideFormats {
+			format = strings.ToUpper(format)
+			if !isSupportedFormat(format) {
+				return options, fmt.Errorf("%w: %s (幻灯片 %d)", ErrUnsupportedFormat, format, slideNumber)
+			}
+			slideFormats[slideNumber] = format
+		}
+		options.SlideFormats = slideFormats
+	}
+	switch options.Rotate {
+	case 0, 90, 180, 270:
+	default:
+		return options, fmt.Errorf("无效的旋转角度: %d (只支持 0, 90, 180, 270)", options.Rotate)
+	}
+	if options.TileHeight < 0 {
+		return options, fmt.Errorf("无效的分块高度: %d", options.TileHeight)
+	}
+	if _, err := ParseResampleFilter(options.ResampleFilter); err != nil {
+		return options, err
+	}
+	if err := CheckPNGDepth(options.PNGBitDepth, options.PNGPalette); err != nil {
+		return options, err
+	}
+	if options.ThemeVariant < 0 {
+		return options, fmt.Errorf("无效的主题变体: %d (从1开始，0表示不改变)", options.ThemeVariant)
+	}
+	if options.ConversionID == "" {
+		options.ConversionID = generateSessionID()
+	}
+	options.SlideIndices = normalizeSlideIndices(options.SlideIndices)
+	options.AnimationMode = strings.ToLower(options.AnimationMode)
+	if options.AnimationMode == "" {
+		options.AnimationMode = AnimationFinal
+	}
+	order, err := normalizeOrder(options.Order)
 	if err != nil {
-		return nil, fmt.Errorf("创建图片失败: %v", err)
+		return options, err
 	}
+	options.Order = order
+	return options, nil
+}
 
-	// 调整图片尺寸
-	if c.width > 0 && c.height > 0 {
-		img = imaging.Resize(img, c.width, c.height, imaging.Lanczos)
+// normalizeSlideIndices 去重并按升序排列幻灯片编号
+func normalizeSlideIndices(indices []int) []int {
+	if len(indices) == 0 {
+		return nil
+	}
+
+	seen := make(map[int]bool, len(indices))
+	var result []int
+	for _, index := range indices {
+		if !seen[index] {
+			seen[index] = true
+			result = append(result, index)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+// checkSlideIndices 检查指定的幻灯片编号是否都在 1..totalSlides 范围内
+func checkSlideIndices(indices []int, totalSlides int) error {
+	for _, index := range indices {
+		if index < 1 || index > totalSlides {
+			return fmt.Errorf("%w: %d (共 %d 张)", ErrSlideOutOfRange, index, totalSlides)
+		}
+	}
+	return nil
+}
+
+// imageExtension 输出格式对应的文件扩展名
+func imageExtension(format string) string {
+	switch strings.ToUpper(format) {
+	case "JPEG", "JPG":
+		return "jpg"
+	default:
+		return "png"
 	}
+}
+
+// Backend 转换后端名称，由渲染器决定
+func (c *PPTConverter) Backend() string {
+	return c.renderer.Backend()
+}
+
+// SupportedExtensions 支持的输入文件扩展名 (小写，带点)
+func (c *PPTConverter) SupportedExtensions() []string {
+	return c.renderer.SupportedExtensions()
+}
 
-	// 保存图片
-	if err := c.saveImage(img, filePath); err != nil {
-		return nil, fmt.Errorf("保存图片失败: %v", err)
+// Close 释放渲染器 (包括备用渲染器) 的资源
+func (c *PPTConverter) Close() error {
+	err := c.renderer.Close()
+	for _, renderer := range c.fallbacks {
+		if closeErr := renderer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
 	}
+	return err
+}
 
-	// 获取文件信息
+// imageInfoFor 根据外部工具生成的图片文件创建图片信息，幻灯片编号和构建步骤从文件名中提取
+func (c *PPTConverter) imageInfoFor(filePath string) (ImageInfo, error) {
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("获取文件信息失败: %v", err)
+		return ImageInfo{}, err
 	}
 
-	return &ImageInfo{
-		SlideNumber: slideNumber,
+	filename := filepath.Base(filePath)
+	imageInfo := ImageInfo{
+		SlideNumber: c.extractSlideNumber(filename),
 		Filename:    filename,
 		FilePath:    filePath,
 		FileSize:    fileInfo.Size(),
 		DownloadID:  generateDownloadID(),
-	}, nil
+		Format:      formatFromExtension(filepath.Ext(filename)),
+		BuildIndex:  c.extractBuildIndex(filename),
+	}
+	imageInfo.Width, imageInfo.Height = imageDimensions(filePath)
+	return imageInfo, nil
 }
 
-// createPlaceholderImage 创建占位图片 (实际项目中需要实现真正的幻灯片转图片)
-func (c *PPTConverter) createPlaceholderImage(slideNumber int) (image.Image, error) {
-	// 创建一个简单的占位图片
-	// 实际实现中，这里应该使用真正的PPT转图片逻辑
-	// 可能需要调用外部工具如LibreOffice或使用其他Go库
-	
-	width := c.width
-	height := c.height
-	if width <= 0 {
-		width = 1920
-	}
-	if height <= 0 {
-		height = 1080
+// imageDimensions 只读取文件头获取图片尺寸，失败时返回0
+func imageDimensions(filePath string) (int, int) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, 0
 	}
+	defer file.Close()
 
-	// 创建一个简单的彩色图片作为占位符
-	img := image.NewRGBA(image.Rect(0, 0, width, height))
-	
-	// 填充背景色 (根据幻灯片编号使用不同颜色)
-	colors := []struct{ r, g, b, a uint8 }{
-		{255, 200, 200, 255}, // 浅红色
-		{200, 255, 200, 255}, // 浅绿色
-		{200, 200, 255, 255}, // 浅蓝色
-		{255, 255, 200, 255}, // 浅黄色
-		{255, 200, 255, 255}, // 浅紫色
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0
 	}
-	
-	color := colors[slideNumber%len(colors)]
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			img.Set(x, y, color)
+	return config.Width, config.Height
+}
+
+// extractSlideNumber 从文件名提取幻灯片编号
+func (c *PPTConverter) extractSlideNumber(filename string) int {
+	// 文件名格式: slide_001.png
+	parts := strings.Split(filename, "_")
+	if len(parts) >= 2 {
+		slidePart := strings.Split(parts[1], ".")[0]
+		if num, err := strconv.Atoi(slidePart); err == nil {
+			return num
 		}
 	}
+	return 1
+}
 
-	return img, nil
+// extractBuildIndex 从文件名提取动画构建步骤
+func (c *PPTConverter) extractBuildIndex(filename string) int {
+	// 文件名格式: slide_001_b02.png，没有构建步骤时为0
+	parts := strings.Split(filename, "_")
+	if len(parts) >= 3 {
+		buildPart := strings.TrimPrefix(strings.Split(parts[2], ".")[0], "b")
+		if num, err := strconv.Atoi(buildPart); err == nil {
+			return num
+		}
+	}
+	return 0
 }
 
-// saveImage 保存图片到文件
-func (c *PPTConverter) saveImage(img image.Image, filePath string) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
+// releaseWorkDir 转换结束后删除整个临时目录，设置了 KeepUploadDir 时先把上传文件移动到该目录保留
+func (c *PPTConverter) releaseWorkDir(workDir, tempFile, filename string, options ConversionOptions) {
+	if options.KeepUploadDir != "" {
+		target := filepath.Join(options.KeepUploadDir, filepath.Base(filename))
+		err := os.MkdirAll(options.KeepUploadDir, 0755)
+		if err == nil {
+			err = os.Rename(tempFile, target)
+		}
+		if err != nil {
+			c.logger.Warnf("保留上传文件失败: %v", err)
+		} else {
+			c.logger.Infof("上传文件已保留: %s", target)
+		}
 	}
-	defer file.Close()
 
-	switch c.outputFormat {
-	case "PNG":
-		return png.Encode(file, img)
-	case "JPEG", "JPG":
-		return jpeg.Encode(file, img, &jpeg.Options{Quality: 90})
-	default:
-		return png.Encode(file, img)
+	if err := os.RemoveAll(workDir); err != nil {
+		c.logger.Warnf("删除临时目录失败: %v", err)
 	}
 }
 
-// createTempFile 创建临时文件
-func (c *PPTConverter) createTempFile(data []byte, filename string) (string, error) {
-	// 确保临时目录存在
-	if err := os.MkdirAll(c.tempDir, 0755); err != nil {
-		return "", err
+// createWorkDir 在临时目录下创建本次转换专用的子目录 (<tempDir>/<转换ID>/)，并写入上传文件的副本
+// 转换过程中的脚本和中间文件也放在该目录中，转换结束后由 releaseWorkDir 整体删除
+func (c *PPTConverter) createWorkDir(data []byte, filename string, options ConversionOptions) (string, string, error) {
+	// 只取ID和文件名的最后一段，防止路径穿越
+	workDir := filepath.Join(c.tempDir, filepath.Base(options.ConversionID))
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return "", "", err
 	}
 
-	// 创建临时文件
-	tempFile := filepath.Join(c.tempDir, fmt.Sprintf("temp_%d_%s", time.Now().UnixNano(), filename))
-	
-	file, err := os.Create(tempFile)
-	if err != nil {
-		return "", err
+	tempFile := filepath.Join(workDir, filepath.Base(filename))
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		os.RemoveAll(workDir)
+		return "", "", err
 	}
-	defer file.Close()
+	return workDir, tempFile, nil
+}
 
-	_, err = io.Copy(file, bytes.NewReader(data))
-	return tempFile, err
+// outputPathFor 本次转换的输出目录
+func (c *PPTConverter) outputPathFor(options ConversionOptions) string {
+	if options.OutputSubdir != "" {
+		return filepath.Join(c.outputDir, options.OutputSubdir)
+	}
+	return filepath.Join(c.outputDir, generateSessionID())
 }
 
 // generateSessionID 生成会话ID
@@ -297,7 +837,20 @@ func generateSessionID() string {
 	return fmt.Sprintf("session_%d", time.Now().UnixNano())
 }
 
-// generateDownloadID 生成下载ID
+// generateDownloadID 生成随机的下载ID，不能像时间戳那样被猜出其他转换的文件
 func generateDownloadID() string {
-	return fmt.Sprintf("download_%d", time.Now().UnixNano())
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Sprintf("download_%d", time.Now().UnixNano())
+	}
+	return "download_" + hex.EncodeToString(id[:])
+}
+
+// NamespacedDownloadID 把下载ID放到转换ID之下 (<转换ID>.<下载ID>)，已带有该前缀时原样返回
+func NamespacedDownloadID(conversionID, downloadID string) string {
+	prefix := conversionID + "."
+	if conversionID == "" || strings.HasPrefix(downloadID, prefix) {
+		return downloadID
+	}
+	return prefix + downloadID
 }
@@ -0,0 +1,822 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slideNumbers 图片列表中的幻灯片编号
+func slideNumbers(images []ImageInfo) []int {
+	var numbers []int
+	for _, image := range images {
+		numbers = append(numbers, image.SlideNumber)
+	}
+	return numbers
+}
+
+// outputFiles 输出目录中的文件名，目录不存在时返回nil
+func outputFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestConvertPPTProgress(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		renderer := &MockRenderer{Slides: 4, Stream: stream}
+		c, _ := newTestConverter(t, renderer, Options{})
+
+		var statuses []ConversionStatus
+		result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{}, func(status ConversionStatus) {
+			statuses = append(statuses, status)
+		})
+		if err != nil {
+			t.Fatalf("stream=%v: ConvertPPT 失败: %v", stream, err)
+		}
+		if !result.Success || result.TotalSlides != 4 || result.ConvertedSlides != 4 {
+			t.Fatalf("stream=%v: 结果 %+v", stream, result)
+		}
+
+		if statuses[0].Status != "processing" || statuses[0].Progress != 10 {
+			t.Errorf("stream=%v: 第一个状态为 %+v", stream, statuses[0])
+		}
+		last := statuses[len(statuses)-1]
+		if last.Status != "completed" || last.Progress != 100 || last.ProcessedSlides != 4 || last.TotalSlides != 4 {
+			t.Errorf("stream=%v: 最后一个状态为 %+v", stream, last)
+		}
+
+		processed := 0
+		for i, status := range statuses {
+			if i > 0 && status.Progress < statuses[i-1].Progress {
+				t.Errorf("stream=%v: 进度倒退: %d -> %d", stream, statuses[i-1].Progress, status.Progress)
+			}
+			if status.Status == "processing" && status.ProcessedSlides > 0 {
+				processed++
+				if status.ProcessedSlides != processed || status.Progress > 95 {
+					t.Errorf("stream=%v: 逐张进度为 %+v", stream, status)
+				}
+			}
+		}
+		if processed != 4 {
+			t.Errorf("stream=%v: 逐张进度报告了 %d 次，应为4次", stream, processed)
+		}
+
+		timing := result.Timing
+		if timing == nil || timing.Slides != 4 || timing.Total <= 0 || timing.Render > timing.Total || timing.FirstSlide > timing.Total {
+			t.Errorf("stream=%v: 耗时为 %+v", stream, timing)
+		}
+	}
+}
+
+func TestConvertPPTSlideSelection(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   ConversionOptions
+		rendered  []int
+		numbers   []int
+		filenames []string
+	}{
+		{
+			name:      "全部",
+			rendered:  []int{1, 2, 3, 4, 5},
+			numbers:   []int{1, 2, 3, 4, 5},
+			filenames: []string{"slide_001.png", "slide_002.png", "slide_003.png", "slide_004.png", "slide_005.png"},
+		},
+		{
+			name:      "指定幻灯片",
+			options:   ConversionOptions{SlideIndices: []int{4, 2, 4}},
+			rendered:  []int{2, 4},
+			numbers:   []int{2, 4},
+			filenames: []string{"slide_002.png", "slide_004.png"},
+		},
+		{
+			name:      "编号偏移",
+			options:   ConversionOptions{SlideIndices: []int{1, 5}, NumberOffset: 10},
+			rendered:  []int{1, 5},
+			numbers:   []int{11, 15},
+			filenames: []string{"slide_011.png", "slide_015.png"},
+		},
+		{
+			name:      "逆序",
+			options:   ConversionOptions{SlideIndices: []int{1, 3, 5}, Order: "Descending"},
+			rendered:  []int{1, 3, 5},
+			numbers:   []int{5, 3, 1},
+			filenames: []string{"slide_001.png", "slide_003.png", "slide_005.png"},
+		},
+		{
+			name:      "指定顺序",
+			options:   ConversionOptions{SlideIndices: []int{2, 3, 4}, NumberOffset: 10, OrderIndices: []int{3, 4, 2}},
+			rendered:  []int{2, 3, 4},
+			numbers:   []int{13, 14, 12},
+			filenames: []string{"slide_012.png", "slide_013.png", "slide_014.png"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, stream := range []bool{false, true} {
+				renderer := &MockRenderer{Slides: 5, Stream: stream}
+				c, outputDir := newTestConverter(t, renderer, Options{})
+
+				var ready []int
+				options := test.options
+				options.OutputSubdir = "job"
+				options.OnImageReady = func(image ImageInfo) {
+					ready = append(ready, image.SlideNumber)
+				}
+				result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", options, nil)
+				if err != nil {
+					t.Fatalf("stream=%v: ConvertPPT 失败: %v", stream, err)
+				}
+
+				if got := renderer.renderedSlides(); !reflect.DeepEqual(got, test.rendered) {
+					t.Errorf("stream=%v: 渲染了 %v，应为 %v", stream, got, test.rendered)
+				}
+				if got := slideNumbers(result.Images); !reflect.DeepEqual(got, test.numbers) {
+					t.Errorf("stream=%v: 结果编号为 %v，应为 %v", stream, got, test.numbers)
+				}
+				if !reflect.DeepEqual(ready, test.numbers) {
+					t.Errorf("stream=%v: OnImageReady 顺序为 %v，应为 %v", stream, ready, test.numbers)
+				}
+				// 渲染用的临时子目录和未选中的幻灯片都不能留在输出目录中
+				if got := outputFiles(t, filepath.Join(outputDir, "job")); !reflect.DeepEqual(got, test.filenames) {
+					t.Errorf("stream=%v: 输出目录中有 %v，应为 %v", stream, got, test.filenames)
+				}
+				if result.TotalSlides != 5 || result.ConvertedSlides != len(test.numbers) {
+					t.Errorf("stream=%v: 总数 %d，转换 %d", stream, result.TotalSlides, result.ConvertedSlides)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertPPTSlideOutOfRange(t *testing.T) {
+	renderer := &MockRenderer{Slides: 3}
+	c, outputDir := newTestConverter(t, renderer, Options{})
+
+	_, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{SlideIndices: []int{2, 4}}, nil)
+	if !errors.Is(err, ErrSlideOutOfRange) {
+		t.Fatalf("错误为 %v，应为 ErrSlideOutOfRange", err)
+	}
+	if len(renderer.renderedSlides()) != 0 {
+		t.Errorf("检查编号之前就开始了渲染: %v", renderer.renderedSlides())
+	}
+	if files := outputFiles(t, outputDir); len(files) != 0 {
+		t.Errorf("失败后输出目录中残留 %v", files)
+	}
+}
+
+func TestConvertPPTInvalidOrder(t *testing.T) {
+	for _, options := range []ConversionOptions{
+		{Order: "random"},
+		{OrderIndices: []int{1, 2}},
+		{OrderIndices: []int{3, 2, 1, 1}},
+		{SlideIndices: []int{1, 2}, OrderIndices: []int{2, 1, 3}},
+	} {
+		renderer := &MockRenderer{Slides: 3}
+		c, _ := newTestConverter(t, renderer, Options{})
+		if _, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", options, nil); !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("%+v: 错误为 %v，应为 ErrInvalidOrder", options, err)
+		}
+		if len(renderer.renderedSlides()) != 0 {
+			t.Errorf("%+v: 检查顺序之前就开始了渲染: %v", options, renderer.renderedSlides())
+		}
+	}
+}
+
+func TestConvertPPTFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ConversionOptions
+		formats []string
+	}{
+		{
+			name:    "PNG",
+			options: ConversionOptions{OutputFormat: "png"},
+			formats: []string{"PNG", "PNG", "PNG"},
+		},
+		{
+			name:    "JPEG",
+			options: ConversionOptions{OutputFormat: "JPEG"},
+			formats: []string{"JPEG", "JPEG", "JPEG"},
+		},
+		{
+			name:    "单独指定格式",
+			options: ConversionOptions{OutputFormat: "PNG", SlideFormats: map[int]string{2: "jpeg"}},
+			formats: []string{"PNG", "JPEG", "PNG"},
+		},
+		{
+			// 第3张为噪点图片
+			name:    "AUTO",
+			options: ConversionOptions{OutputFormat: "AUTO"},
+			formats: []string{"PNG", "PNG", "JPEG"},
+		},
+		{
+			name:    "单独指定格式使用编号偏移前的编号",
+			options: ConversionOptions{OutputFormat: "JPEG", SlideFormats: map[int]string{1: "PNG"}, NumberOffset: 5},
+			formats: []string{"PNG", "JPEG", "JPEG"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			renderer := &MockRenderer{Slides: 3, Photos: map[int]bool{3: true}}
+			c, _ := newTestConverter(t, renderer, Options{})
+
+			result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", test.options, nil)
+			if err != nil {
+				t.Fatalf("ConvertPPT 失败: %v", err)
+			}
+
+			var formats []string
+			for _, image := range result.Images {
+				formats = append(formats, image.Format)
+				if formatFromExtension(filepath.Ext(image.Filename)) != image.Format {
+					t.Errorf("%s 的格式为 %s", image.Filename, image.Format)
+				}
+				if _, err := os.Stat(image.FilePath); err != nil {
+					t.Errorf("图片文件不存在: %v", err)
+				}
+			}
+			if !reflect.DeepEqual(formats, test.formats) {
+				t.Errorf("格式为 %v，应为 %v", formats, test.formats)
+			}
+		})
+	}
+}
+
+func TestConvertPPTUnsupportedFormat(t *testing.T) {
+	c, _ := newTestConverter(t, &MockRenderer{Slides: 1}, Options{})
+
+	_, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{OutputFormat: "BMP"}, nil)
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("错误为 %v，应为 ErrUnsupportedFormat", err)
+	}
+
+	_, err = c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{SlideFormats: map[int]string{1: "GIF"}}, nil)
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("错误为 %v，应为 ErrUnsupportedFormat", err)
+	}
+
+	if _, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{ResampleFilter: "bicubic"}, nil); err == nil {
+		t.Fatal("无效的缩放算法没有返回错误")
+	}
+}
+
+func TestConvertPPTSizes(t *testing.T) {
+	type size struct{ width, height int }
+	tests := []struct {
+		name    string
+		pptData []byte
+		options ConversionOptions
+		sizes   []size
+	}{
+		{
+			name:  "转换器默认尺寸",
+			sizes: []size{{160, 90}},
+		},
+		{
+			name:    "指定宽高",
+			options: ConversionOptions{Width: 320, Height: 240},
+			sizes:   []size{{320, 240}},
+		},
+		{
+			name:    "按幻灯片宽高比确定高度",
+			pptData: testPPTX(t, 9144000, 6858000),
+			options: ConversionOptions{Width: 400},
+			sizes:   []size{{400, 300}},
+		},
+		{
+			name:    "纵向幻灯片使用默认尺寸的最长边",
+			pptData: testPPTX(t, 6858000, 12192000),
+			sizes:   []size{{90, 160}},
+		},
+		{
+			name:    "边框",
+			options: ConversionOptions{Border: &BorderOptions{Width: 5}},
+			sizes:   []size{{170, 100}},
+		},
+		{
+			name:    "旋转",
+			options: ConversionOptions{Rotate: 90},
+			sizes:   []size{{90, 160}},
+		},
+		{
+			name:    "多分辨率",
+			options: ConversionOptions{Resolutions: []Resolution{{Width: 80, Height: 45}, {Width: 320, Height: 180, Suffix: "@2x"}}},
+			sizes:   []size{{80, 45}, {320, 180}},
+		},
+		{
+			name: "多分辨率使用最近邻缩放",
+			options: ConversionOptions{
+				Resolutions:    []Resolution{{Width: 80, Height: 45}, {Width: 320, Height: 180, Suffix: "@2x"}},
+				ResampleFilter: ResampleNearest,
+			},
+			sizes: []size{{80, 45}, {320, 180}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, _ := newTestConverter(t, &MockRenderer{Slides: 1}, Options{})
+
+			pptData := test.pptData
+			if pptData == nil {
+				pptData = []byte("ppt")
+			}
+			result, err := c.ConvertPPT(context.Background(), pptData, "deck.pptx", test.options, nil)
+			if err != nil {
+				t.Fatalf("ConvertPPT 失败: %v", err)
+			}
+
+			var sizes []size
+			for _, image := range result.Images {
+				width, height := imageDimensions(image.FilePath)
+				if width != image.Width || height != image.Height {
+					t.Errorf("%s 实际尺寸 %dx%d 与 %dx%d 不一致", image.Filename, width, height, image.Width, image.Height)
+				}
+				sizes = append(sizes, size{image.Width, image.Height})
+			}
+			if !reflect.DeepEqual(sizes, test.sizes) {
+				t.Errorf("尺寸为 %v，应为 %v", sizes, test.sizes)
+			}
+		})
+	}
+}
+
+func TestConvertPPTFilenameCollision(t *testing.T) {
+	// 后缀 _t02 的尺寸与较大尺寸切分出的第2块同名
+	options := ConversionOptions{
+		Resolutions: []Resolution{{Width: 320, Height: 180}, {Width: 160, Height: 90, Suffix: "_t02"}},
+		TileHeight:  100,
+	}
+
+	c, _ := newTestConverter(t, &MockRenderer{Slides: 1}, Options{})
+	result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.pptx", options, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+
+	var names []string
+	for _, image := range result.Images {
+		width, height := imageDimensions(image.FilePath)
+		if width != image.Width || height != image.Height {
+			t.Errorf("%s 实际尺寸 %dx%d 与 %dx%d 不一致，文件被覆盖", image.Filename, width, height, image.Width, image.Height)
+		}
+		names = append(names, image.Filename)
+	}
+	want := []string{"slide_001_t01.png", "slide_001_t02_2.png", "slide_001_t02.png"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("文件名为 %v，应为 %v", names, want)
+	}
+
+	options.StrictFilenames = true
+	c, _ = newTestConverter(t, &MockRenderer{Slides: 1}, Options{})
+	if _, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.pptx", options, nil); !errors.Is(err, ErrFilenameCollision) {
+		t.Errorf("StrictFilenames 时的错误为 %v", err)
+	}
+}
+
+func TestConvertPPTCoverOnly(t *testing.T) {
+	// 第1张幻灯片隐藏，封面为第2张
+	files := map[string][]byte{}
+	var slideIDs, rels strings.Builder
+	for i, show := range []string{"0", "1", "1"} {
+		fmt.Fprintf(&slideIDs, `<p:sldId id="%d" r:id="rId%d"/>`, 256+i, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Target="slides/slide%d.xml"/>`, i+1, i+1)
+		files[fmt.Sprintf("ppt/slides/slide%d.xml", i+1)] = []byte(`<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" show="` + show + `"><p:cSld><p:spTree/></p:cSld></p:sld>`)
+	}
+	files["ppt/presentation.xml"] = []byte(`<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:sldIdLst>` +
+		slideIDs.String() + `</p:sldIdLst></p:presentation>`)
+	files["ppt/_rels/presentation.xml.rels"] = []byte("<Relationships>" + rels.String() + "</Relationships>")
+
+	renderer := &MockRenderer{Slides: 3}
+	c, _ := newTestConverter(t, renderer, Options{})
+	result, err := c.ConvertPPT(context.Background(), zipPackage(t, files), "deck.pptx", ConversionOptions{CoverOnly: true}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+
+	if len(result.Images) != 1 || result.Images[0].SlideNumber != 2 {
+		t.Fatalf("返回的图片为 %+v，应只有第2张幻灯片", result.Images)
+	}
+	if result.TotalSlides != 2 || result.HiddenSlides != 1 || result.ConvertedSlides != 1 {
+		t.Errorf("总数 %d、隐藏 %d、转换 %d，应为 2、1、1", result.TotalSlides, result.HiddenSlides, result.ConvertedSlides)
+	}
+}
+
+func TestConvertPPTSlideSizes(t *testing.T) {
+	type size struct{ width, height int }
+	options := ConversionOptions{
+		SlideSizes: map[int]SizeOverride{
+			2: {Width: 320, Height: 200},
+			3: {DPI: 192},
+			4: {Width: 80},
+		},
+	}
+	want := []size{{160, 90}, {320, 200}, {320, 180}, {80, 45}}
+
+	for _, wholeDeck := range []bool{false, true} {
+		renderer := &MockRenderer{Slides: 4, Stream: true, WholeDeck: wholeDeck}
+		c, _ := newTestConverter(t, renderer, Options{})
+
+		var ready []size
+		options := options
+		options.OnImageReady = func(image ImageInfo) {
+			ready = append(ready, size{image.Width, image.Height})
+		}
+		result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", options, nil)
+		if err != nil {
+			t.Fatalf("ConvertPPT 失败: %v", err)
+		}
+
+		var sizes []size
+		for _, image := range result.Images {
+			width, height := imageDimensions(image.FilePath)
+			if width != image.Width || height != image.Height {
+				t.Errorf("%s 实际尺寸 %dx%d 与 %dx%d 不一致", image.Filename, width, height, image.Width, image.Height)
+			}
+			sizes = append(sizes, size{image.Width, image.Height})
+		}
+		if !reflect.DeepEqual(sizes, want) || !reflect.DeepEqual(ready, want) {
+			t.Errorf("一次导出全部: %v，尺寸为 %v，通知的尺寸为 %v，应为 %v", wholeDeck, sizes, ready, want)
+		}
+		// 不支持单独尺寸的后端对每种尺寸各重新渲染一次
+		if rendered := len(renderer.renderedSlides()); wholeDeck && rendered != 7 || !wholeDeck && rendered != 4 {
+			t.Errorf("一次导出全部: %v，渲染了 %d 张幻灯片", wholeDeck, rendered)
+		}
+	}
+
+	c, _ := newTestConverter(t, &MockRenderer{Slides: 1}, Options{MaxPixels: 100 * 100})
+	_, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{
+		Width: 80, Height: 45, SlideSizes: map[int]SizeOverride{1: {Width: 400, Height: 300}},
+	}, nil)
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("超过像素上限时错误为 %v，应为 ErrImageTooLarge", err)
+	}
+	_, err = c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{
+		Width: 80, Height: 45, SlideSizes: map[int]SizeOverride{1: {}},
+	}, nil)
+	if !errors.Is(err, ErrInvalidSlideSize) {
+		t.Errorf("没有指定尺寸时错误为 %v，应为 ErrInvalidSlideSize", err)
+	}
+}
+
+func TestConvertPPTRendererFails(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		renderer := &MockRenderer{Slides: 5, FailOn: 3, Stream: stream}
+		c, outputDir := newTestConverter(t, renderer, Options{})
+
+		var ready []int
+		_, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{
+			OnImageReady: func(image ImageInfo) {
+				ready = append(ready, image.SlideNumber)
+			},
+		}, nil)
+		if !errors.Is(err, errMockRender) {
+			t.Fatalf("stream=%v: 错误为 %v，应为渲染器返回的错误", stream, err)
+		}
+
+		// 逐张提交时失败之前的幻灯片已经通知，整份失败时一张都不通知
+		want := []int(nil)
+		if stream {
+			want = []int{1, 2}
+		}
+		if !reflect.DeepEqual(ready, want) {
+			t.Errorf("stream=%v: OnImageReady 通知了 %v，应为 %v", stream, ready, want)
+		}
+		if files := outputFiles(t, outputDir); len(files) != 0 {
+			t.Errorf("stream=%v: 失败后输出目录中残留 %v", stream, files)
+		}
+	}
+}
+
+func TestConvertPPTSkipsFailedSlide(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		renderer := &MockRenderer{Slides: 5, FailOn: 3, SkipFailed: true, Stream: stream}
+		c, _ := newTestConverter(t, renderer, Options{})
+
+		var ready []int
+		result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{
+			OnImageReady: func(image ImageInfo) {
+				ready = append(ready, image.SlideNumber)
+			},
+		}, nil)
+		if err != nil {
+			t.Fatalf("stream=%v: ConvertPPT 失败: %v", stream, err)
+		}
+
+		want := []int{1, 2, 4, 5}
+		if got := slideNumbers(result.Images); !reflect.DeepEqual(got, want) {
+			t.Errorf("stream=%v: 结果编号为 %v，应为 %v", stream, got, want)
+		}
+		if !reflect.DeepEqual(ready, want) {
+			t.Errorf("stream=%v: OnImageReady 顺序为 %v，应为 %v", stream, ready, want)
+		}
+		if !result.Success || result.TotalSlides != 5 || result.ConvertedSlides != 4 {
+			t.Errorf("stream=%v: 结果 %+v", stream, result)
+		}
+	}
+}
+
+func TestConvertPPTInvalidImages(t *testing.T) {
+	renderer := &MockRenderer{Slides: 3, Empty: map[int]bool{2: true}}
+	c, outputDir := newTestConverter(t, renderer, Options{MinImageSize: 50})
+
+	result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{OutputSubdir: "out"}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+	if got := slideNumbers(result.Images); !reflect.DeepEqual(got, []int{1, 3}) || result.ConvertedSlides != 2 {
+		t.Errorf("结果编号为 %v (%d 张)", got, result.ConvertedSlides)
+	}
+	if len(result.SlideErrors) != 1 || result.SlideErrors[0].SlideNumber != 2 {
+		t.Errorf("幻灯片错误为 %+v", result.SlideErrors)
+	}
+	if files := outputFiles(t, filepath.Join(outputDir, "out")); !reflect.DeepEqual(files, []string{"slide_001.png", "slide_003.png"}) {
+		t.Errorf("输出目录中的文件为 %v", files)
+	}
+
+	// 全部幻灯片都是空文件时不能报告成功
+	renderer = &MockRenderer{Slides: 2, Empty: map[int]bool{1: true, 2: true}}
+	c, _ = newTestConverter(t, renderer, Options{})
+	result, err = c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+	if result.Success || result.ConvertedSlides != 0 || len(result.SlideErrors) != 2 {
+		t.Errorf("全部为空文件时的结果 %+v", result)
+	}
+
+	// 文件小于 MinImageSize 时同样丢弃
+	renderer = &MockRenderer{Slides: 1}
+	c, _ = newTestConverter(t, renderer, Options{MinImageSize: 1 << 20})
+	result, err = c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{}, nil)
+	if err != nil {
+		t.Fatalf("ConvertPPT 失败: %v", err)
+	}
+	if result.Success || len(result.SlideErrors) != 1 || !strings.Contains(result.SlideErrors[0].Error, ErrInvalidImage.Error()) {
+		t.Errorf("图片过小时的结果 %+v", result)
+	}
+}
+
+func TestConvertPPTMissingSlides(t *testing.T) {
+	// 演示文稿有5张幻灯片，渲染器 (如导出的PDF缺页的LibreOffice) 只报告并输出了前3张
+	var slideIDs, rels strings.Builder
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(&slideIDs, `<p:sldId id="%d" r:id="rId%d"/>`, 255+i, i)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Target="slides/slide%d.xml"/>`, i, i)
+	}
+	pptx := zipPackage(t, map[string][]byte{
+		"ppt/presentation.xml": []byte(`<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:sldIdLst>` +
+			slideIDs.String() + `</p:sldIdLst></p:presentation>`),
+		"ppt/_rels/presentation.xml.rels": []byte("<Relationships>" + rels.String() + "</Relationships>"),
+	})
+
+	for _, stream := range []bool{false, true} {
+		c, _ := newTestConverter(t, &MockRenderer{Slides: 3, Stream: stream}, Options{})
+		result, err := c.ConvertPPT(context.Background(), pptx, "deck.pptx", ConversionOptions{NumberOffset: 10}, nil)
+		if err != nil {
+			t.Fatalf("stream=%v: ConvertPPT 失败: %v", stream, err)
+		}
+		if result.TotalSlides != 5 || result.ConvertedSlides != 3 {
+			t.Errorf("stream=%v: 总数 %d，转换 %d，应为 5 和 3", stream, result.TotalSlides, result.ConvertedSlides)
+		}
+		var missing []int
+		for _, slideError := range result.SlideErrors {
+			if slideError.Error == ErrSlideMissing.Error() {
+				missing = append(missing, slideError.SlideNumber)
+			}
+		}
+		if !reflect.DeepEqual(missing, []int{14, 15}) || len(result.SlideErrors) != 2 {
+			t.Errorf("stream=%v: 幻灯片错误为 %+v，应为缺少第14、15张", stream, result.SlideErrors)
+		}
+		if !strings.Contains(result.Message, "2 张失败") {
+			t.Errorf("stream=%v: 结果消息 %q 没有说明失败的幻灯片", stream, result.Message)
+		}
+	}
+}
+
+func TestConvertPPTNoSlides(t *testing.T) {
+	c, _ := newTestConverter(t, &MockRenderer{}, Options{})
+
+	_, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{}, nil)
+	if !errors.Is(err, ErrNoSlides) {
+		t.Fatalf("错误为 %v，应为 ErrNoSlides", err)
+	}
+}
+
+func TestConvertPPTTooManySlides(t *testing.T) {
+	c, _ := newTestConverter(t, &MockRenderer{Slides: 4}, Options{MaxSlides: 3})
+
+	_, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{}, nil)
+	if !errors.Is(err, ErrTooManySlides) {
+		t.Fatalf("错误为 %v，应为 ErrTooManySlides", err)
+	}
+}
+
+func TestConvertPPTCancelled(t *testing.T) {
+	renderer := &MockRenderer{Slides: 3}
+	c, outputDir := newTestConverter(t, renderer, Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.ConvertPPT(ctx, []byte("ppt"), "deck.ppt", ConversionOptions{}, nil)
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("错误为 %v，应为 ErrCancelled", err)
+	}
+	if files := outputFiles(t, outputDir); len(files) != 0 {
+		t.Errorf("取消后输出目录中残留 %v", files)
+	}
+}
+
+func TestConvertPPTTimeout(t *testing.T) {
+	renderer := &MockRenderer{Slides: 4, HangOn: 3, Stream: true}
+	c, outputDir := newTestConverter(t, renderer, Options{})
+
+	result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", ConversionOptions{Timeout: 50 * time.Millisecond}, nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("错误为 %v，应为 ErrTimeout", err)
+	}
+	if result != nil {
+		t.Errorf("没有设置 ReturnPartial 时返回了结果 %+v", result)
+	}
+	if files := outputFiles(t, outputDir); len(files) != 0 {
+		t.Errorf("超时后输出目录中残留 %v", files)
+	}
+}
+
+func TestConvertPPTTimeoutPartial(t *testing.T) {
+	renderer := &MockRenderer{Slides: 4, HangOn: 3, Stream: true}
+	c, outputDir := newTestConverter(t, renderer, Options{})
+
+	options := ConversionOptions{Timeout: 50 * time.Millisecond, ReturnPartial: true, OutputSubdir: "job"}
+	result, err := c.ConvertPPT(context.Background(), []byte("ppt"), "deck.ppt", options, nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("错误为 %v，应为 ErrTimeout", err)
+	}
+	if result == nil || !result.Partial || result.Success {
+		t.Fatalf("部分结果为 %+v", result)
+	}
+	if result.ConvertedSlides != 2 || result.TotalSlides != 4 || result.Error == "" {
+		t.Errorf("部分结果为 %+v", result)
+	}
+	if got := slideNumbers(result.Images); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("部分结果的幻灯片为 %v", got)
+	}
+	if files := outputFiles(t, filepath.Join(outputDir, "job")); !reflect.DeepEqual(files, []string{"slide_001.png", "slide_002.png"}) {
+		t.Errorf("部分结果的输出文件为 %v", files)
+	}
+}
+
+func TestPPTConverterDelegatesToRenderer(t *testing.T) {
+	renderer := &MockRenderer{}
+	c, _ := newTestConverter(t, renderer, Options{})
+
+	if c.Backend() != "mock" {
+		t.Errorf("Backend() 为 %q", c.Backend())
+	}
+	if !reflect.DeepEqual(c.SupportedExtensions(), powerPointExtensions) {
+		t.Errorf("SupportedExtensions() 为 %v", c.SupportedExtensions())
+	}
+	// 模拟渲染器不启动外部进程
+	if n := c.KillProcesses(); n != 0 {
+		t.Errorf("KillProcesses() 为 %d", n)
+	}
+	if err := c.Close(); err != nil || !renderer.closed {
+		t.Errorf("Close() 没有关闭渲染器: %v", err)
+	}
+}
+
+func TestParseSlideFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		slide    RenderedSlide
+		ok       bool
+	}{
+		{"slide_001.png", RenderedSlide{SlideNumber: 1, BuildIndex: -1}, true},
+		{"slide_1234.jpg", RenderedSlide{SlideNumber: 1234, BuildIndex: -1}, true},
+		{"slide_012_b00.png", RenderedSlide{SlideNumber: 12, BuildIndex: 0}, true},
+		{"slide_012_b03.png", RenderedSlide{SlideNumber: 12, BuildIndex: 3}, true},
+		{"slide_000.png", RenderedSlide{}, false},
+		{"slide_abc.png", RenderedSlide{}, false},
+		{"page-1.png", RenderedSlide{}, false},
+	}
+
+	for _, test := range tests {
+		slide, ok := parseSlideFilename(test.filename)
+		if ok != test.ok || slide != test.slide {
+			t.Errorf("parseSlideFilename(%q) = %+v, %v", test.filename, slide, ok)
+		}
+	}
+}
+
+func TestProbePresentation(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		pptData   []byte
+		size      SlideSize
+		defaulted bool
+	}{
+		{"4:3", testPPTX(t, 9144000, 6858000), SlideSize{Width: 9144000, Height: 6858000}, false},
+		{"没有记录尺寸", testPPTX(t, 0, 0), DefaultSlideSize, true},
+		{"PPT", []byte("ppt"), DefaultSlideSize, true},
+	} {
+		result, err := ProbePresentation(tc.pptData, false)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if result.SlideSize != tc.size || result.SlideSizeDefaulted != tc.defaulted || len(result.Images) != 0 {
+			t.Errorf("%s: 尺寸 %+v，默认 %v", tc.name, result.SlideSize, result.SlideSizeDefaulted)
+		}
+		if want := float64(tc.size.Width) / float64(tc.size.Height); result.AspectRatio != want {
+			t.Errorf("%s: 宽高比 %v，应为 %v", tc.name, result.AspectRatio, want)
+		}
+	}
+
+	if _, err := ProbePresentation([]byte("PK\x03\x04broken"), false); !errors.Is(err, ErrCorruptFile) {
+		t.Errorf("损坏的PPTX错误为 %v", err)
+	}
+}
+
+func TestSlidesWithTitle(t *testing.T) {
+	files := map[string][]byte{}
+	var slideIDs, rels strings.Builder
+	for i, title := range []string{"概述", "附录 A", "", "附录 B"} {
+		fmt.Fprintf(&slideIDs, `<p:sldId id="%d" r:id="rId%d"/>`, 256+i, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Target="slides/slide%d.xml"/>`, i+1, i+1)
+		shape := ""
+		if title != "" {
+			shape = `<p:sp><p:nvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>` + title + `</a:t></a:r></a:p></p:txBody></p:sp>`
+		}
+		files[fmt.Sprintf("ppt/slides/slide%d.xml", i+1)] = []byte(`<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><p:cSld><p:spTree>` + shape + `</p:spTree></p:cSld></p:sld>`)
+	}
+	files["ppt/presentation.xml"] = []byte(`<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:sldIdLst>` +
+		slideIDs.String() + `</p:sldIdLst></p:presentation>`)
+	files["ppt/_rels/presentation.xml.rels"] = []byte("<Relationships>" + rels.String() + "</Relationships>")
+	pptx := zipPackage(t, files)
+
+	for pattern, want := range map[string][]int{
+		"^附录":  {2, 4},
+		"^$":   {3},
+		"不存在的": nil,
+	} {
+		matches, total, err := SlidesWithTitle(pptx, regexp.MustCompile(pattern))
+		if err != nil {
+			t.Fatalf("%q: %v", pattern, err)
+		}
+		if total != 4 || !reflect.DeepEqual(matches, want) {
+			t.Errorf("%q: 匹配 %v (共 %d 张)，应为 %v", pattern, matches, total, want)
+		}
+	}
+
+	if _, _, err := SlidesWithTitle([]byte("ppt"), regexp.MustCompile(".")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("PPT文件的错误为 %v", err)
+	}
+}
+
+func TestResolveOptionsMaxPixels(t *testing.T) {
+	c := &PPTConverter{width: 1920, height: 1080, outputFormat: "PNG"}
+	c.configure(Options{MaxPixels: 1280 * 720})
+
+	tests := []struct {
+		name          string
+		width, height int
+		tooLarge      bool
+	}{
+		{"请求的尺寸在上限内", 1280, 720, false},
+		{"请求的尺寸超过上限", 1281, 720, true},
+		{"未指定尺寸时检查默认尺寸", 0, 0, true},
+	}
+	for _, tt := range tests {
+		_, err := c.resolveOptions(ConversionOptions{Width: tt.width, Height: tt.height})
+		if tooLarge := errors.Is(err, ErrImageTooLarge); tooLarge != tt.tooLarge {
+			t.Errorf("%s: 错误为 %v", tt.name, err)
+		}
+	}
+
+	// 未设置上限时不检查
+	c.configure(Options{})
+	if _, err := c.resolveOptions(ConversionOptions{Width: 20000, Height: 20000}); err != nil {
+		t.Errorf("未设置上限时返回错误: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// pptxSlide 幻灯片XML中用到的部分
+type pptxSlide struct {
+	Show   string `xml:"show,attr"`
+	Shapes []struct {
+		Placeholder struct {
+			Type string `xml:"type,attr"`
+		} `xml:"nvSpPr>nvPr>ph"`
+		Paragraphs []struct {
+			Runs []string `xml:"r>t"`
+		} `xml:"txBody>p"`
+	} `xml:"cSld>spTree>sp"`
+}
+
+// readPPTXSlides 按放映顺序解析PPTX中的所有幻灯片
+// 不是PPTX (如PPT、ODP) 时返回nil
+func readPPTXSlides(pptData []byte) ([]pptxSlide, error) {
+	if !bytes.HasPrefix(pptData, []byte("PK\x03\x04")) {
+		return nil, nil
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(pptData), int64(len(pptData)))
+	if err != nil {
+		return nil, fmt.Errorf("打开PPTX失败: %v", err)
+	}
+	if !isPPTXPackage(reader) {
+		return nil, nil
+	}
+
+	paths, err := pptxSlidePaths(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	slides := make([]pptxSlide, len(paths))
+	for i, slidePath := range paths {
+		if slidePath == "" {
+			continue
+		}
+		if err := readZipXML(reader, slidePath, &slides[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return slides, nil
+}
+
+// CountSlides 只读取 presentation.xml 快速统计PPTX的幻灯片数量 (包括隐藏的幻灯片)
+// 不是PPTX或无法解析时 ok 为false
+func CountSlides(pptData []byte) (count int, ok bool) {
+	if !bytes.HasPrefix(pptData, []byte("PK\x03\x04")) {
+		return 0, false
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(pptData), int64(len(pptData)))
+	if err != nil || !isPPTXPackage(reader) {
+		return 0, false
+	}
+
+	paths, err := pptxSlidePaths(reader)
+	if err != nil {
+		return 0, false
+	}
+	return len(paths), true
+}
+
+// isPPTXPackage 判断ZIP是否为PPTX，ODP和Keynote同样是ZIP格式但没有 ppt/presentation.xml
+func isPPTXPackage(reader *zip.Reader) bool {
+	for _, file := range reader.File {
+		if file.Name == "ppt/presentation.xml" {
+			return true
+		}
+	}
+	return false
+}
+
+// pptxSlidePaths 按放映顺序返回幻灯片XML在ZIP中的路径，找不到关系的幻灯片为空字符串
+func pptxSlidePaths(reader *zip.Reader) ([]string, error) {
+	// 幻灯片顺序由 presentation.xml 中的 sldIdLst 决定，通过关系ID找到对应的幻灯片文件
+	var presentation struct {
+		SlideIDs []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := readZipXML(reader, "ppt/presentation.xml", &presentation); err != nil {
+		return nil, err
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := readZipXML(reader, "ppt/_rels/presentation.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		targets[rel.ID] = path.Join("ppt", rel.Target)
+	}
+
+	paths := make([]string, len(presentation.SlideIDs))
+	for i, slideID := range presentation.SlideIDs {
+		paths[i] = targets[slideID.RelID]
+	}
+	return paths, nil
+}
+
+// readPPTXSlideSize 读取 presentation.xml 中的幻灯片尺寸 (EMU)
+// 不是PPTX (如PPT、ODP) 时返回零值
+func readPPTXSlideSize(pptData []byte) (SlideSize, error) {
+	if !bytes.HasPrefix(pptData, []byte("PK\x03\x04")) {
+		return SlideSize{}, nil
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(pptData), int64(len(pptData)))
+	if err != nil {
+		return SlideSize{}, fmt.Errorf("打开PPTX失败: %v", err)
+	}
+	if !isPPTXPackage(reader) {
+		return SlideSize{}, nil
+	}
+
+	var presentation struct {
+		SlideSize struct {
+			CX int64 `xml:"cx,attr"`
+			CY int64 `xml:"cy,attr"`
+		} `xml:"sldSz"`
+	}
+	if err := readZipXML(reader, "ppt/presentation.xml", &presentation); err != nil {
+		return SlideSize{}, err
+	}
+
+	return SlideSize{Width: presentation.SlideSize.CX, Height: presentation.SlideSize.CY}, nil
+}
+
+// title 幻灯片标题占位符中的文字，多个段落用空格连接，没有标题时返回空字符串
+func (s pptxSlide) title() string {
+	for _, shape := range s.Shapes {
+		if shape.Placeholder.Type != "title" && shape.Placeholder.Type != "ctrTitle" {
+			continue
+		}
+
+		var paragraphs []string
+		for _, paragraph := range shape.Paragraphs {
+			if text := strings.TrimSpace(strings.Join(paragraph.Runs, "")); text != "" {
+				paragraphs = append(paragraphs, text)
+			}
+		}
+		return strings.Join(paragraphs, " ")
+	}
+	return ""
+}
+
+// readZipXML 读取ZIP中的XML文件并解码
+func readZipXML(reader *zip.Reader, name string, v interface{}) error {
+	file, err := reader.Open(name)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %v", name, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %v", name, err)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("解析 %s 失败: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package converter
+
+import (
+	"fmt"
+)
+
+// DefaultSlideSize PowerPoint默认的16:9幻灯片尺寸 (13.333x7.5英寸)，探测时用于没有记录尺寸的演示文稿
+var DefaultSlideSize = SlideSize{Width: 12192000, Height: 6858000}
+
+// AspectRatio 幻灯片的宽高比 (宽/高)，尺寸未知时为0
+func (s SlideSize) AspectRatio() float64 {
+	if s.Width <= 0 || s.Height <= 0 {
+		return 0
+	}
+	return float64(s.Width) / float64(s.Height)
+}
+
+// ProbePresentation 只读取PPTX的 presentation.xml 和幻灯片XML，返回幻灯片数量、尺寸和宽高比，不调用渲染后端、不生成图片
+// 客户端可以在图片生成之前按宽高比预留布局。没有记录尺寸 (缺少 sldSz) 或无法读取结构 (PPT、ODP、Keynote) 时
+// 按 DefaultSlideSize 返回并设置 SlideSizeDefaulted；非PPTX格式的幻灯片数量为0 (未知)
+func ProbePresentation(pptData []byte, includeHidden bool) (*ConversionResult, error) {
+	// 无法打开的ZIP在读取尺寸时报错
+	size, err := readPPTXSlideSize(pptData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptFile, err)
+	}
+
+	result := &ConversionResult{Success: true, Message: "探测完成，未渲染幻灯片"}
+	if total, ok := CountSlides(pptData); ok {
+		result.TotalSlides = total
+		if !includeHidden {
+			hidden, err := hiddenSlides(pptData)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrCorruptFile, err)
+			}
+			result.HiddenSlides = len(hidden)
+			result.TotalSlides -= len(hidden)
+		}
+	}
+
+	if size.Width <= 0 || size.Height <= 0 {
+		size = DefaultSlideSize
+		result.SlideSizeDefaulted = true
+	}
+	result.SlideSize = size
+	result.AspectRatio = size.AspectRatio()
+	return result, nil
+}
@@ -0,0 +1,139 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// processWaitDelay 进程被结束后等待其输出管道关闭的最长时间
+// 外部工具启动的子进程可能继承管道，不设置时 Wait 会一直等到子进程退出
+const processWaitDelay = 5 * time.Second
+
+// processTracker 记录转换器启动的、仍在运行的外部进程，关闭服务时可以强制结束残留的进程
+type processTracker struct {
+	mutex   sync.Mutex
+	running map[*exec.Cmd]bool
+	limiter *processLimiter // 所有渲染器共用的进程数量上限，为nil时不限制
+}
+
+// newProcessTracker 创建进程登记表，启动进程前从 limiter 获取槽位
+func newProcessTracker(limiter *processLimiter) *processTracker {
+	return &processTracker{running: make(map[*exec.Cmd]bool), limiter: limiter}
+}
+
+// processLimiter 限制所有转换同时运行的外部渲染进程数量，与转换数量的上限相互独立
+// 同一转换逐页并行渲染时每个进程分别占用一个槽位
+type processLimiter struct {
+	slots  chan struct{}
+	logger *logrus.Logger
+}
+
+// newProcessLimiter 创建进程数量上限，max 不大于0时返回nil (不限制)
+func newProcessLimiter(max int, logger *logrus.Logger) *processLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &processLimiter{slots: make(chan struct{}, max), logger: logger}
+}
+
+// acquire 获取一个进程槽位，已达上限时等待并在获取后记录等待的时间；ctx 取消时返回 ErrCancelled
+func (l *processLimiter) acquire(ctx context.Context, name string) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	start := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+		l.logger.Infof("外部进程数量达到上限 (%d)，%s 等待 %v 后启动", cap(l.slots), name, time.Since(start).Round(time.Millisecond))
+		return nil
+	case <-ctx.Done():
+		return checkCancelled(ctx)
+	}
+}
+
+// release 归还 acquire 获取的槽位
+func (l *processLimiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
+
+// command 创建外部命令，ctx 取消时结束进程 (非Windows下连同它启动的子进程)
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = processWaitDelay
+	killProcessTree(cmd)
+	return cmd
+}
+
+// run 启动命令并等待结束，运行期间登记进程；未设置 Stdout/Stderr 时两者合并后返回
+// 因 ctx 取消而失败时返回 ErrCancelled
+func (t *processTracker) run(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	var output bytes.Buffer
+	if cmd.Stdout == nil {
+		cmd.Stdout = &output
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = &output
+	}
+
+	if err := t.limiter.acquire(ctx, filepath.Base(cmd.Path)); err != nil {
+		return nil, err
+	}
+	defer t.limiter.release()
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	t.running[cmd] = true
+	t.mutex.Unlock()
+
+	err := cmd.Wait()
+
+	t.mutex.Lock()
+	delete(t.running, cmd)
+	t.mutex.Unlock()
+
+	if err != nil && ctx.Err() != nil {
+		return output.Bytes(), checkCancelled(ctx)
+	}
+	return output.Bytes(), err
+}
+
+// killAll 强制结束所有仍在运行的进程，返回结束的数量
+func (t *processTracker) killAll() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	killed := 0
+	for cmd := range t.running {
+		if cmd.Cancel() == nil {
+			killed++
+		}
+	}
+	return killed
+}
+
+// checkCancelled ctx 已取消时返回 ErrCancelled
+func checkCancelled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrCancelled, err)
+	}
+	return nil
+}
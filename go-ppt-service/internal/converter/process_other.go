@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package converter
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessTree 让命令在独立的进程组中运行，取消时结束整个进程组
+// soffice 会再启动 soffice.bin，只结束直接启动的进程时后者会残留
+func killProcessTree(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
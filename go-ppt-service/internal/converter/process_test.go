@@ -0,0 +1,54 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestProcessLimiter(t *testing.T) {
+	if newProcessLimiter(0, nil) != nil {
+		t.Fatal("上限为0时应不限制")
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	limiter := newProcessLimiter(1, logger)
+	if err := limiter.acquire(context.Background(), "soffice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 槽位已满时不启动进程，等待到 ctx 取消
+	tracker := newProcessTracker(limiter)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	cmd := exec.Command("pdftoppm-not-started")
+	if _, err := tracker.run(ctx, cmd); !errors.Is(err, ErrCancelled) {
+		t.Errorf("等待槽位时取消的错误为 %v", err)
+	}
+	if cmd.Process != nil {
+		t.Error("没有槽位时启动了进程")
+	}
+
+	// 归还后等待的调用方获得槽位
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- limiter.acquire(context.Background(), "pdftoppm")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	limiter.release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("归还槽位后等待的调用方没有获得槽位")
+	}
+	limiter.release()
+}
@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package converter
+
+import "os/exec"
+
+// killProcessTree Windows下取消时只结束直接启动的进程 (exec.CommandContext 的默认行为)
+// PowerPoint是单实例COM服务器，不能随宿主进程一起结束
+func killProcessTree(cmd *exec.Cmd) {}
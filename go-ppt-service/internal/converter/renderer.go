@@ -0,0 +1,541 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Renderer 将演示文稿的幻灯片渲染为图片文件的后端
+// 临时目录、选项解析、幻灯片筛选和编号、后处理与结果汇总都由 PPTConverter 完成，渲染器只负责生成图片
+type Renderer interface {
+	// Render 将 pptPath 中的幻灯片渲染到 opts.OutputPath，返回的图片按幻灯片编号和构建步骤排序
+	// 得到幻灯片总数后必须先调用 opts.Counted；ctx 取消时结束外部进程并返回 ErrCancelled
+	Render(ctx context.Context, pptPath string, opts RenderOptions) ([]RenderedSlide, error)
+	// Backend 转换后端名称 (placeholder, libreoffice, powerpoint)
+	Backend() string
+	// SupportedExtensions 支持的输入文件扩展名 (小写，带点)
+	SupportedExtensions() []string
+	Close() error
+}
+
+// processKiller 启动外部进程的渲染器实现该接口，关闭服务时强制结束仍在运行的进程
+type processKiller interface {
+	KillProcesses() int
+}
+
+// RenderedSlide 渲染器生成的一张图片
+type RenderedSlide struct {
+	// SlideNumber 演示文稿中的原始编号 (从1开始，隐藏的幻灯片也占用编号，不含编号偏移)
+	SlideNumber int
+	// BuildIndex 动画构建步骤，-1表示整张幻灯片
+	BuildIndex int
+	FilePath   string
+	// Placeholder 图片不是幻灯片的真实渲染结果 (占位渲染器生成)
+	Placeholder bool
+}
+
+// RenderOptions 一次渲染的参数，由 PPTConverter 填写
+type RenderOptions struct {
+	// ConversionOptions 已补全默认值的转换选项
+	ConversionOptions
+	// WorkDir 本次转换的临时目录，中间文件 (PDF、脚本等) 放在这里
+	WorkDir string
+	// OutputPath 图片写入的目录，PPTConverter 处理时会按最终编号移动到输出目录
+	OutputPath string
+	// Format 渲染使用的格式 (PNG或JPEG)，最终格式由后处理决定
+	Format string
+	// Hidden 需要跳过的隐藏幻灯片，IncludeHidden 时为空
+	Hidden map[int]bool
+
+	job *renderJob
+}
+
+// Counted 报告演示文稿的幻灯片总数 (包括隐藏的)，检查数量上限和 SlideIndices 并确定需要渲染的幻灯片
+func (o RenderOptions) Counted(total int) error {
+	return o.job.counted(total)
+}
+
+// Selected 幻灯片是否需要渲染 (没有隐藏且在 SlideIndices 中)，在 Counted 之后调用
+func (o RenderOptions) Selected(slideNumber int) bool {
+	return o.job.selected(slideNumber)
+}
+
+// Report 报告渲染阶段的进度，之后逐张完成的进度从该值开始计算
+func (o RenderOptions) Report(progress int, message string) {
+	o.job.report(progress, message)
+}
+
+// Rendered 一张幻灯片的图片 (all_builds 时为它的全部构建步骤) 写好后调用，交给后处理工作协程处理后按顺序通知 OnImageReady
+// 后处理工作协程都忙时等待，否则立即返回，渲染器可以继续渲染下一张；
+// 可以在多个协程中同时调用；没有通过 Rendered 提交的图片在 Render 返回后统一处理
+func (o RenderOptions) Rendered(slides []RenderedSlide) {
+	o.job.rendered(slides)
+}
+
+// renderJob 一次转换中 PPTConverter 与渲染器共享的状态
+type renderJob struct {
+	converter  *PPTConverter
+	filename   string
+	options    ConversionOptions
+	outputPath string
+	hidden     map[int]bool
+	progress   ProgressCallback
+	backend    string // 渲染器名称，记录在 ImageInfo.Backend 中
+	// deferBad 配置了备用后端时为 true: 失败或空白的幻灯片暂不通知 OnImageReady，等备用后端重新渲染后再通知
+	deferBad bool
+	// probed 从PPTX读取的幻灯片总数 (包括隐藏的)，无法读取时为0
+	probed int
+
+	mutex   sync.Mutex
+	total   int
+	plan    []int // 需要渲染的幻灯片，按 Order/OrderIndices 排列
+	planned map[int]bool
+	ready   *imageReadyQueue
+	results map[int][]ImageInfo
+	base    int
+	// submitted 已提交后处理的幻灯片，pending 为其中尚未处理完的
+	submitted map[int]bool
+	pending   sync.WaitGroup
+	// deferred 暂缓通知的幻灯片，见 deferBad
+	deferred map[int]bool
+	// slideErrors 未通过检查的幻灯片 (原始编号) 及原因
+	slideErrors map[int]error
+	// sizes 单独指定了尺寸的幻灯片实际渲染的尺寸，见 wrongSize
+	sizes map[int]image.Point
+
+	started    time.Time     // 开始渲染的时刻
+	firstSlide time.Duration // 开始渲染到第一张幻灯片提交的耗时
+	encode     time.Duration // 后处理的累计耗时
+}
+
+// counted 见 RenderOptions.Counted
+func (j *renderJob) counted(total int) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.planned != nil {
+		return nil
+	}
+	// 渲染器只能从导出结果推算数量 (如PDF页数)，少于演示文稿中的数量时按演示文稿计算，缺少的幻灯片记为失败
+	if j.probed > total {
+		j.converter.logger.Warnf("%s: %s 只报告了 %d 张幻灯片，演示文稿中有 %d 张", j.filename, j.backend, total, j.probed)
+		total = j.probed
+	}
+	if total == 0 || len(j.hidden) >= total {
+		return ErrNoSlides
+	}
+	if err := j.converter.checkSlideCount(total); err != nil {
+		return err
+	}
+	if err := checkSlideIndices(j.options.SlideIndices, total); err != nil {
+		return err
+	}
+
+	indices := make(map[int]bool, len(j.options.SlideIndices))
+	for _, index := range j.options.SlideIndices {
+		indices[index] = true
+	}
+
+	j.total = total
+	j.planned = make(map[int]bool)
+	for slide := 1; slide <= total; slide++ {
+		if !j.hidden[slide] && (len(indices) == 0 || indices[slide]) {
+			j.plan = append(j.plan, slide)
+			j.planned[slide] = true
+		}
+	}
+	plan, err := orderPlan(j.plan, j.options)
+	if err != nil {
+		j.planned = nil
+		j.plan = nil
+		return err
+	}
+	j.plan = plan
+	j.ready = newImageReadyQueue(j.options.OnImageReady, j.plan)
+	return nil
+}
+
+// selected 见 RenderOptions.Selected
+func (j *renderJob) selected(slideNumber int) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.planned[slideNumber]
+}
+
+// report 见 RenderOptions.Report
+func (j *renderJob) report(progress int, message string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.base = progress
+	if j.progress != nil {
+		j.progress(ConversionStatus{
+			Status:      "processing",
+			Progress:    progress,
+			Message:     message,
+			TotalSlides: j.total - len(j.hidden),
+		})
+	}
+}
+
+// rendered 见 RenderOptions.Rendered，不需要的幻灯片直接删除
+func (j *renderJob) rendered(slides []RenderedSlide) {
+	if len(slides) == 0 {
+		return
+	}
+	slideNumber := slides[0].SlideNumber
+	if !j.selected(slideNumber) {
+		for _, slide := range slides {
+			os.Remove(slide.FilePath)
+		}
+		return
+	}
+
+	j.mutex.Lock()
+	if j.firstSlide == 0 {
+		j.firstSlide = time.Since(j.started)
+	}
+	j.submitted[slideNumber] = true
+	j.mutex.Unlock()
+
+	j.converter.encoder.submit(&j.pending, func() {
+		j.encodeSlide(slideNumber, slides)
+	})
+}
+
+// encodeSlide 在后处理工作协程中完成一张幻灯片的后处理，记录结果和进度并通知 OnImageReady
+func (j *renderJob) encodeSlide(slideNumber int, slides []RenderedSlide) {
+	start := time.Now()
+	placed := j.place(slides)
+	j.recordSize(slideNumber, placed)
+	images := j.converter.finishImages(placed, j.filename, j.options)
+	elapsed := time.Since(start)
+	// 尺寸不符的幻灯片重新渲染后再通知
+	wrongSize := j.wrongSize(slideNumber)
+
+	// 结果和进度在同一次加锁中更新，多个工作协程同时完成时进度也不会倒退
+	j.mutex.Lock()
+	j.results[slideNumber] = images
+	j.encode += elapsed
+	done := len(j.results)
+	deferred := j.deferBad && needsFallback(images) || wrongSize
+	if deferred {
+		j.deferred[slideNumber] = true
+	}
+	if j.progress != nil {
+		j.progress(ConversionStatus{
+			Status:          "processing",
+			Progress:        j.base + done*(95-j.base)/len(j.plan),
+			Message:         fmt.Sprintf("已渲染 %d/%d 张幻灯片", done, len(j.plan)),
+			TotalSlides:     j.total - len(j.hidden),
+			ProcessedSlides: done,
+		})
+	}
+	j.mutex.Unlock()
+
+	if deferred {
+		return
+	}
+	// 失败 (没有图片) 的幻灯片也要登记，否则排在后面的幻灯片无法通知
+	j.ready.done(slideNumber, images)
+}
+
+// place 将渲染好的图片按加上编号偏移后的编号移动到输出目录
+func (j *renderJob) place(slides []RenderedSlide) []ImageInfo {
+	images := make([]ImageInfo, 0, len(slides))
+	for _, slide := range slides {
+		if err := checkRenderedImage(slide.FilePath, j.converter.minImageSize); err != nil {
+			j.converter.logger.Warnf("第 %d 张幻灯片的渲染结果无效，按渲染失败处理: %v", slide.SlideNumber, err)
+			os.Remove(slide.FilePath)
+			j.mutex.Lock()
+			j.slideErrors[slide.SlideNumber] = err
+			j.mutex.Unlock()
+			continue
+		}
+
+		ext := strings.TrimPrefix(filepath.Ext(slide.FilePath), ".")
+		filePath := filepath.Join(j.outputPath, slideFilename(slide.SlideNumber+j.options.NumberOffset, slide.BuildIndex, ext))
+		if err := os.Rename(slide.FilePath, filePath); err != nil {
+			j.converter.logger.Warnf("移动第 %d 张幻灯片的图片失败: %v", slide.SlideNumber, err)
+			os.Remove(slide.FilePath)
+			continue
+		}
+
+		image, err := j.converter.imageInfoFor(filePath)
+		if err != nil {
+			j.converter.logger.Warnf("获取文件信息失败: %s", filePath)
+			continue
+		}
+		image.Placeholder = slide.Placeholder
+		image.Backend = j.backend
+		images = append(images, image)
+	}
+	return images
+}
+
+// wait 等待已提交的幻灯片全部完成后处理，Render 返回后、读取结果之前调用
+func (j *renderJob) wait() {
+	j.pending.Wait()
+}
+
+// finish Render 返回后处理尚未提交的图片并等待全部完成，之后由 completed 取得全部图片
+func (j *renderJob) finish(slides []RenderedSlide) error {
+	j.mutex.Lock()
+	counted := j.planned != nil
+	j.mutex.Unlock()
+	if !counted {
+		return fmt.Errorf("渲染器没有报告幻灯片数量")
+	}
+
+	for len(slides) > 0 {
+		n := 1
+		for n < len(slides) && slides[n].SlideNumber == slides[0].SlideNumber {
+			n++
+		}
+		j.mutex.Lock()
+		submitted := j.submitted[slides[0].SlideNumber]
+		j.mutex.Unlock()
+		if !submitted {
+			j.rendered(slides[:n])
+		}
+		slides = slides[n:]
+	}
+	j.wait()
+
+	for _, slideNumber := range j.plan {
+		j.mutex.Lock()
+		_, ok := j.results[slideNumber]
+		if !ok && j.deferBad {
+			j.deferred[slideNumber] = true
+		}
+		j.mutex.Unlock()
+		if !ok && !j.deferBad {
+			// 没有渲染出来的幻灯片也要登记，OnImageReady 才能通知完后面的幻灯片
+			j.ready.done(slideNumber, nil)
+		}
+	}
+	return nil
+}
+
+// retrySlides 需要由备用后端重新渲染的幻灯片；渲染器没有报告幻灯片数量时 counted 为 false，需要重新渲染全部幻灯片
+func (j *renderJob) retrySlides() (slides []int, counted bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.planned == nil {
+		return nil, false
+	}
+	for _, slideNumber := range j.plan {
+		if needsFallback(j.results[slideNumber]) {
+			slides = append(slides, slideNumber)
+		}
+	}
+	return slides, true
+}
+
+// subJob 用另一个渲染器或另一组选项重新渲染部分幻灯片的任务，结果由 merge 合并回主任务
+func (j *renderJob) subJob(options ConversionOptions, outputPath, backend string) *renderJob {
+	return &renderJob{
+		converter:  j.converter,
+		filename:   j.filename,
+		options:    options,
+		outputPath: outputPath,
+		hidden:     j.hidden,
+		backend:    backend,
+		results:    make(map[int][]ImageInfo),
+		submitted:  make(map[int]bool),
+		deferred:   make(map[int]bool),
+		started:    j.started,
+
+		slideErrors: make(map[int]error),
+		sizes:       make(map[int]image.Point),
+	}
+}
+
+// merge 用备用后端的结果替换失败或空白的幻灯片: 原来没有图片，或原来空白而新结果不空白时替换
+// force 为 true 时 (按指定尺寸重新渲染) 只要有新结果就替换
+func (j *renderJob) merge(sub *renderJob, force bool) error {
+	j.mutex.Lock()
+	counted := j.planned != nil
+	j.mutex.Unlock()
+	if !counted {
+		if err := j.counted(sub.total); err != nil {
+			return err
+		}
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for _, slideNumber := range sub.plan {
+		images := sub.results[slideNumber]
+		current := j.results[slideNumber]
+		if !j.planned[slideNumber] || len(images) == 0 {
+			continue
+		}
+		if !force && len(current) > 0 && (!needsFallback(current) || needsFallback(images)) {
+			continue
+		}
+
+		for _, image := range current {
+			os.Remove(image.FilePath)
+		}
+		delete(j.slideErrors, slideNumber)
+		moved := make([]ImageInfo, 0, len(images))
+		for _, image := range images {
+			filePath := filepath.Join(j.outputPath, image.Filename)
+			if err := os.Rename(image.FilePath, filePath); err != nil {
+				j.converter.logger.Warnf("移动第 %d 张幻灯片的图片失败: %v", slideNumber, err)
+				continue
+			}
+			image.FilePath = filePath
+			moved = append(moved, image)
+		}
+		j.results[slideNumber] = moved
+		if size, ok := sub.sizes[slideNumber]; ok {
+			j.sizes[slideNumber] = size
+		}
+	}
+	j.encode += sub.encode
+	return nil
+}
+
+// errorList 未通过检查的幻灯片，按编号排列
+func (j *renderJob) errorList() []SlideError {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	var slideErrors []SlideError
+	for slideNumber, err := range j.slideErrors {
+		slideErrors = append(slideErrors, SlideError{
+			SlideNumber: slideNumber + j.options.NumberOffset,
+			Error:       err.Error(),
+		})
+	}
+	sort.Slice(slideErrors, func(i, k int) bool {
+		return slideErrors[i].SlideNumber < slideErrors[k].SlideNumber
+	})
+	return slideErrors
+}
+
+// recordMissing 为没有任何图片、也没有记录原因的幻灯片记录 ErrSlideMissing，在全部渲染 (包括备用后端) 结束后调用
+func (j *renderJob) recordMissing() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	var missing []int
+	for _, slideNumber := range j.plan {
+		if _, failed := j.slideErrors[slideNumber]; len(j.results[slideNumber]) == 0 && !failed {
+			j.slideErrors[slideNumber] = ErrSlideMissing
+			missing = append(missing, slideNumber+j.options.NumberOffset)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Ints(missing)
+		j.converter.logger.Warnf("%s: 输出了 %d/%d 张幻灯片，缺少 %v", j.filename, len(j.plan)-len(missing), len(j.plan), missing)
+	}
+}
+
+// notifyDeferred 通知暂缓的幻灯片，在备用后端全部尝试过之后调用
+func (j *renderJob) notifyDeferred() {
+	j.mutex.Lock()
+	var slides []int
+	for _, slideNumber := range j.plan {
+		if j.deferred[slideNumber] {
+			slides = append(slides, slideNumber)
+		}
+	}
+	j.deferred = make(map[int]bool)
+	j.mutex.Unlock()
+
+	for _, slideNumber := range slides {
+		j.mutex.Lock()
+		images := j.results[slideNumber]
+		j.mutex.Unlock()
+		j.ready.done(slideNumber, images)
+	}
+}
+
+// completed 已经完成后处理的图片，按 plan 的顺序排列；用于超时后返回部分结果
+func (j *renderJob) completed() []ImageInfo {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	var images []ImageInfo
+	for _, slideNumber := range j.plan {
+		images = append(images, j.results[slideNumber]...)
+	}
+	return images
+}
+
+// fillTiming 填写渲染阶段的耗时
+func (j *renderJob) fillTiming(timing *ConversionTiming) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	timing.FirstSlide = j.firstSlide
+	timing.Encode = j.encode
+	timing.Slides = len(j.results)
+}
+
+// scanRenderedSlides 扫描目录中按 slideFilename 规则命名的图片，按幻灯片编号和构建步骤排序
+func scanRenderedSlides(dir, format string) ([]RenderedSlide, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, slideFileGlob(imageExtension(format))))
+	if err != nil {
+		return nil, err
+	}
+
+	slides := make([]RenderedSlide, 0, len(matches))
+	for _, match := range matches {
+		slide, ok := parseSlideFilename(filepath.Base(match))
+		if !ok {
+			continue
+		}
+		slide.FilePath = match
+		slides = append(slides, slide)
+	}
+
+	// 编号超过3位时文件名顺序与编号顺序不一致
+	sort.Slice(slides, func(i, k int) bool {
+		if slides[i].SlideNumber != slides[k].SlideNumber {
+			return slides[i].SlideNumber < slides[k].SlideNumber
+		}
+		return slides[i].BuildIndex < slides[k].BuildIndex
+	})
+	return slides, nil
+}
+
+// parseSlideFilename 从 slide_001.png 或 slide_001_b02.png 形式的文件名中提取编号和构建步骤
+func parseSlideFilename(filename string) (RenderedSlide, bool) {
+	stem := strings.TrimPrefix(strings.TrimSuffix(filename, filepath.Ext(filename)), slideFilePrefix)
+	number, build, hasBuild := strings.Cut(stem, "_b")
+
+	slide := RenderedSlide{BuildIndex: -1}
+	var err error
+	if slide.SlideNumber, err = strconv.Atoi(number); err != nil || slide.SlideNumber <= 0 {
+		return RenderedSlide{}, false
+	}
+	if hasBuild {
+		if slide.BuildIndex, err = strconv.Atoi(build); err != nil {
+			return RenderedSlide{}, false
+		}
+	}
+	return slide, true
+}
+
+// renderedSlideCount 不同幻灯片的数量 (all_builds 时一张幻灯片有多张图片)
+func renderedSlideCount(slides []RenderedSlide) int {
+	seen := make(map[int]bool, len(slides))
+	for _, slide := range slides {
+		seen[slide.SlideNumber] = true
+	}
+	return len(seen)
+}
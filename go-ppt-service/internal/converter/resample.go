@@ -0,0 +1,47 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// 缩放算法名称，从高质量到高速度排列
+const (
+	ResampleLanczos    = "lanczos"
+	ResampleCatmullRom = "catmullrom"
+	ResampleLinear     = "linear"
+	ResampleBox        = "box"
+	ResampleNearest    = "nearest"
+)
+
+// resampleFilters 缩放算法名称到 imaging 滤波器的映射
+var resampleFilters = map[string]imaging.ResampleFilter{
+	ResampleLanczos:    imaging.Lanczos,
+	ResampleCatmullRom: imaging.CatmullRom,
+	ResampleLinear:     imaging.Linear,
+	ResampleBox:        imaging.Box,
+	ResampleNearest:    imaging.NearestNeighbor,
+}
+
+// ParseResampleFilter 解析缩放算法名称 (不区分大小写)，为空时为 lanczos
+func ParseResampleFilter(name string) (imaging.ResampleFilter, error) {
+	if name == "" {
+		return imaging.Lanczos, nil
+	}
+	filter, ok := resampleFilters[strings.ToLower(name)]
+	if !ok {
+		return imaging.ResampleFilter{}, fmt.Errorf("无效的缩放算法: %q (只支持 lanczos, catmullrom, linear, box, nearest)", name)
+	}
+	return filter, nil
+}
+
+// resampleFilter 选项中的缩放算法，resolveOptions 已检查过名称
+func (o ConversionOptions) resampleFilter() imaging.ResampleFilter {
+	filter, err := ParseResampleFilter(o.ResampleFilter)
+	if err != nil {
+		return imaging.Lanczos
+	}
+	return filter
+}
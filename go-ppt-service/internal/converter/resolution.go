@@ -0,0 +1,183 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Resolution 多分辨率输出中的一个尺寸 (不含边框)
+type Resolution struct {
+	Width  int
+	Height int
+	// Suffix 加在文件名扩展名之前的后缀，如 @2x
+	Suffix string
+}
+
+// resolutionSuffixPattern 后缀允许的字符，不能包含路径分隔符
+var resolutionSuffixPattern = regexp.MustCompile(`^[A-Za-z0-9@._-]*$`)
+
+// CheckResolutions 检查多分辨率选项：尺寸为正数，后缀只含字母、数字和 @._- 且互不相同
+func CheckResolutions(resolutions []Resolution) error {
+	suffixes := make(map[string]bool, len(resolutions))
+	for _, resolution := range resolutions {
+		if resolution.Width <= 0 || resolution.Height <= 0 {
+			return fmt.Errorf("无效的分辨率: %dx%d", resolution.Width, resolution.Height)
+		}
+		if !resolutionSuffixPattern.MatchString(resolution.Suffix) {
+			return fmt.Errorf("无效的分辨率后缀: %q", resolution.Suffix)
+		}
+		if suffixes[resolution.Suffix] {
+			return fmt.Errorf("重复的分辨率后缀: %q", resolution.Suffix)
+		}
+		suffixes[resolution.Suffix] = true
+	}
+	return nil
+}
+
+// LargestResolution 返回像素最多的尺寸，幻灯片以该尺寸渲染，其余尺寸由它缩小得到
+func LargestResolution(resolutions []Resolution) Resolution {
+	var largest Resolution
+	for _, resolution := range resolutions {
+		if resolution.Width*resolution.Height > largest.Width*largest.Height {
+			largest = resolution
+		}
+	}
+	return largest
+}
+
+// summaryImages 返回用于总览图和HTML页面的图片，多分辨率输出时只保留最大尺寸
+func summaryImages(images []ImageInfo, options ConversionOptions) []ImageInfo {
+	if len(options.Resolutions) == 0 {
+		return images
+	}
+
+	suffix := LargestResolution(options.Resolutions).Suffix
+	var result []ImageInfo
+	for _, image := range images {
+		if image.Resolution == suffix {
+			result = append(result, image)
+		}
+	}
+	return result
+}
+
+// splitResolutions 把以最大尺寸渲染的幻灯片图片按 Resolutions 输出为多张，每个尺寸一条 ImageInfo
+// 最大尺寸直接重命名，其余尺寸按 ResampleFilter 缩小，文件名在扩展名之前加上后缀
+// 总览图等非幻灯片图片 (编号为0) 不处理，缩小失败时保留原图并记录日志
+func (c *PPTConverter) splitResolutions(images []ImageInfo, sourceFile string, options ConversionOptions) []ImageInfo {
+	if len(options.Resolutions) == 0 {
+		return images
+	}
+
+	result := make([]ImageInfo, 0, len(images)*len(options.Resolutions))
+	for _, info := range images {
+		if info.SlideNumber <= 0 {
+			result = append(result, info)
+			continue
+		}
+
+		var meta *imageMetadata
+		if options.EmbedMetadata {
+			meta = &imageMetadata{SourceFile: sourceFile, SlideNumber: info.SlideNumber}
+		}
+
+		variants, err := c.resizeVariants(info, options, meta)
+		if err != nil {
+			c.logger.Warnf("生成第 %d 张幻灯片的多分辨率图片失败: %v", info.SlideNumber, err)
+			result = append(result, info)
+			continue
+		}
+		result = append(result, variants...)
+	}
+	return result
+}
+
+// resizeVariants 按各尺寸相对最大尺寸的比例缩放单张图片，边框、裁剪和旋转后的尺寸同比缩放
+// 先把原图重命名为最大尺寸的文件名 (缩小的图片可能使用原文件名)，出错时删除已写入的文件并恢复原图
+func (c *PPTConverter) resizeVariants(info ImageInfo, options ConversionOptions, meta *imageMetadata) ([]ImageInfo, error) {
+	img, err := imaging.Open(info.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取图片失败: %v", err)
+	}
+	filter := options.resampleFilter()
+
+	largest := LargestResolution(options.Resolutions)
+	scaleX := float64(info.Width) / float64(largest.Width)
+	scaleY := float64(info.Height) / float64(largest.Height)
+	if options.Rotate == 90 || options.Rotate == 270 {
+		scaleX = float64(info.Width) / float64(largest.Height)
+		scaleY = float64(info.Height) / float64(largest.Width)
+	}
+
+	ext := filepath.Ext(info.Filename)
+	base := strings.TrimSuffix(info.Filename, ext)
+	dir := filepath.Dir(info.FilePath)
+
+	// variant 第 i 个尺寸的图片信息，尺寸和文件大小在写入后填写；与原图不同名时先占用文件名
+	variant := func(i int) ImageInfo {
+		result := info
+		result.Filename = base + options.Resolutions[i].Suffix + ext
+		if result.Filename != info.Filename {
+			result.Filename = c.outputFilename(dir, result.Filename, options)
+		}
+		result.FilePath = filepath.Join(dir, result.Filename)
+		result.Resolution = options.Resolutions[i].Suffix
+		return result
+	}
+
+	variants := make([]ImageInfo, len(options.Resolutions))
+	largestIndex := slices.Index(options.Resolutions, largest)
+	variants[largestIndex] = variant(largestIndex)
+	if err := os.Rename(info.FilePath, variants[largestIndex].FilePath); err != nil {
+		if variants[largestIndex].FilePath != info.FilePath {
+			os.Remove(variants[largestIndex].FilePath)
+		}
+		return nil, fmt.Errorf("重命名 %s 失败: %v", info.Filename, err)
+	}
+
+	// rollback 删除已写入的缩小图片并恢复原图
+	rollback := func() {
+		for i, written := range variants {
+			if i != largestIndex && written.FilePath != "" {
+				os.Remove(written.FilePath)
+			}
+		}
+		os.Rename(variants[largestIndex].FilePath, info.FilePath)
+	}
+
+	for i, resolution := range options.Resolutions {
+		if i == largestIndex {
+			continue
+		}
+
+		width, height := float64(resolution.Width), float64(resolution.Height)
+		if options.Rotate == 90 || options.Rotate == 270 {
+			width, height = height, width
+		}
+		variants[i] = variant(i)
+		variants[i].Width = max(1, int(math.Round(width*scaleX)))
+		variants[i].Height = max(1, int(math.Round(height*scaleY)))
+
+		resized := imaging.Resize(img, variants[i].Width, variants[i].Height, filter)
+		if err := c.saveImage(resized, variants[i].FilePath, info.Format, options.JPEGSubsampling, meta); err != nil {
+			rollback()
+			return nil, fmt.Errorf("保存 %s 失败: %v", variants[i].Filename, err)
+		}
+
+		fileInfo, err := os.Stat(variants[i].FilePath)
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("获取文件信息失败: %v", err)
+		}
+		variants[i].FileSize = fileInfo.Size()
+		variants[i].DownloadID = generateDownloadID()
+	}
+	return variants, nil
+}
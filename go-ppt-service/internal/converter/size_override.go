@@ -0,0 +1,197 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// MaxSlideDPI 单张幻灯片可以指定的最大DPI
+	MaxSlideDPI = 1200
+	// screenDPI 无法识别幻灯片尺寸时，把全局输出尺寸视为该DPI换算 SizeOverride.DPI
+	screenDPI = 96
+)
+
+// SizeOverride 单张幻灯片的输出尺寸，Width/Height 优先于 DPI
+// 只指定宽或高时另一边按全局输出尺寸的宽高比计算
+type SizeOverride struct {
+	Width  int
+	Height int
+	// DPI 按幻灯片的实际尺寸 (英寸) 换算像素；无法识别幻灯片尺寸 (如PPT) 时把全局输出尺寸视为96 DPI换算
+	DPI int
+}
+
+// CheckSizeOverride 检查单张幻灯片的尺寸选项: 编号从1开始，宽高和DPI不能为负数且至少指定一项
+func CheckSizeOverride(slideNumber int, override SizeOverride) error {
+	if slideNumber < 1 {
+		return fmt.Errorf("%w: 无效的幻灯片编号 %d (从1开始)", ErrInvalidSlideSize, slideNumber)
+	}
+	if override.Width < 0 || override.Height < 0 {
+		return fmt.Errorf("%w: 幻灯片 %d 的尺寸 %dx%d", ErrInvalidSlideSize, slideNumber, override.Width, override.Height)
+	}
+	if override.DPI < 0 || override.DPI > MaxSlideDPI {
+		return fmt.Errorf("%w: 幻灯片 %d 的DPI %d (1-%d)", ErrInvalidSlideSize, slideNumber, override.DPI, MaxSlideDPI)
+	}
+	if override.Width == 0 && override.Height == 0 && override.DPI == 0 {
+		return fmt.Errorf("%w: 幻灯片 %d 没有指定宽高或DPI", ErrInvalidSlideSize, slideNumber)
+	}
+	return nil
+}
+
+// resolveSlideSizes 把 SlideSizes 换算为确定的宽高并检查像素上限，在 resolveOptions 确定全局尺寸之后调用
+func (c *PPTConverter) resolveSlideSizes(options ConversionOptions, slideSize SlideSize) (ConversionOptions, error) {
+	if len(options.SlideSizes) == 0 {
+		return options, nil
+	}
+	if len(options.Resolutions) > 0 {
+		return options, fmt.Errorf("%w: 不能与多分辨率输出同时使用", ErrInvalidSlideSize)
+	}
+	if options.NormalizeSize {
+		return options, fmt.Errorf("%w: 不能与统一尺寸同时使用", ErrInvalidSlideSize)
+	}
+
+	sizes := make(map[int]SizeOverride, len(options.SlideSizes))
+	for slideNumber, override := range options.SlideSizes {
+		if err := CheckSizeOverride(slideNumber, override); err != nil {
+			return options, err
+		}
+
+		width, height := override.Width, override.Height
+		switch {
+		case width > 0 && height > 0:
+		case width > 0:
+			height = scaleEdge(width, int64(options.Height), int64(options.Width))
+		case height > 0:
+			width = scaleEdge(height, int64(options.Width), int64(options.Height))
+		case slideSize.Width > 0 && slideSize.Height > 0:
+			width = scaleEdge(override.DPI, slideSize.Width, EMUPerInch)
+			height = scaleEdge(override.DPI, slideSize.Height, EMUPerInch)
+		default:
+			width = scaleEdge(options.Width, int64(override.DPI), screenDPI)
+			height = scaleEdge(options.Height, int64(override.DPI), screenDPI)
+		}
+
+		pixelWidth, pixelHeight := width, height
+		if options.Border != nil {
+			pixelWidth += 2 * options.Border.Width
+			pixelHeight += 2 * options.Border.Width
+		}
+		if err := c.checkPixels(pixelWidth, pixelHeight); err != nil {
+			return options, fmt.Errorf("幻灯片 %d: %w", slideNumber, err)
+		}
+		sizes[slideNumber] = SizeOverride{Width: width, Height: height}
+	}
+	options.SlideSizes = sizes
+	return options, nil
+}
+
+// SizeFor 幻灯片 (原始编号) 的渲染尺寸，单独指定了尺寸时使用指定的尺寸
+// 渲染器按幻灯片分别渲染时使用它；一次导出全部幻灯片的渲染器使用 Width/Height，
+// 尺寸不符的幻灯片由 PPTConverter 按指定尺寸重新渲染
+func (o RenderOptions) SizeFor(slideNumber int) (width, height int) {
+	if size, ok := o.SlideSizes[slideNumber]; ok {
+		return size.Width, size.Height
+	}
+	return o.Width, o.Height
+}
+
+// recordSize 记录单独指定了尺寸的幻灯片实际渲染的尺寸，在后处理之前调用
+func (j *renderJob) recordSize(slideNumber int, images []ImageInfo) {
+	if _, ok := j.options.SlideSizes[slideNumber]; !ok || len(images) == 0 {
+		return
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.sizes[slideNumber] = image.Pt(images[0].Width, images[0].Height)
+}
+
+// wrongSize 幻灯片的渲染结果是否与单独指定的尺寸不符，需要按指定尺寸重新渲染
+func (j *renderJob) wrongSize(slideNumber int) bool {
+	size, ok := j.options.SlideSizes[slideNumber]
+	if !ok {
+		return false
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	rendered, ok := j.sizes[slideNumber]
+	return ok && rendered != image.Pt(size.Width, size.Height)
+}
+
+// renderSlideSizes 按单独指定的尺寸重新渲染尺寸不符的幻灯片 (渲染器一次导出全部幻灯片时)，替换原来的结果
+// 每种尺寸渲染一次，失败时保留原尺寸的图片并记录日志
+func (c *PPTConverter) renderSlideSizes(ctx context.Context, job *renderJob, pptPath, workDir string) {
+	groups := make(map[SizeOverride][]int)
+	var order []SizeOverride
+	job.mutex.Lock()
+	plan := job.plan
+	job.mutex.Unlock()
+	for _, slideNumber := range plan {
+		job.mutex.Lock()
+		rendered := len(job.results[slideNumber]) > 0
+		job.mutex.Unlock()
+		if !rendered || !job.wrongSize(slideNumber) {
+			continue
+		}
+		size := job.options.SlideSizes[slideNumber]
+		if _, ok := groups[size]; !ok {
+			order = append(order, size)
+		}
+		groups[size] = append(groups[size], slideNumber)
+	}
+
+	for i, size := range order {
+		if ctx.Err() != nil {
+			return
+		}
+		slides := groups[size]
+		c.logger.Infof("%s 没有按指定尺寸渲染幻灯片 %v，以 %dx%d 重新渲染", job.backend, slides, size.Width, size.Height)
+		if err := c.renderSize(ctx, job, size, slides, pptPath, workDir, i); err != nil {
+			c.logger.Warnf("以 %dx%d 重新渲染幻灯片 %v 失败，保留原尺寸: %v", size.Width, size.Height, slides, err)
+		}
+	}
+}
+
+// renderSize 用主后端以 size 渲染 slides，结果替换主任务中的图片
+func (c *PPTConverter) renderSize(ctx context.Context, job *renderJob, size SizeOverride, slides []int, pptPath, workDir string, attempt int) error {
+	options := job.options
+	options.Width, options.Height = size.Width, size.Height
+	options.SlideSizes = nil
+	options.SlideIndices = slides
+	options.OnImageReady = nil
+	options.Order = OrderAscending
+	options.OrderIndices = nil
+	options.notesPDFPath = ""
+
+	outputPath := filepath.Join(job.outputPath, fmt.Sprintf(".size%d", attempt+1))
+	renderPath := filepath.Join(outputPath, ".render")
+	if err := os.MkdirAll(renderPath, 0755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %v", err)
+	}
+	defer os.RemoveAll(outputPath)
+	workDir = filepath.Join(workDir, fmt.Sprintf("size%d", attempt+1))
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("创建临时目录失败: %v", err)
+	}
+
+	sub := job.subJob(options, outputPath, c.renderer.Backend())
+	// 返回 (包括出错) 之前等待已提交的幻灯片处理完，之后才删除输出目录
+	defer sub.wait()
+	rendered, err := c.renderer.Render(ctx, pptPath, RenderOptions{
+		ConversionOptions: options,
+		WorkDir:           workDir,
+		OutputPath:        renderPath,
+		Format:            options.renderFormat(),
+		Hidden:            job.hidden,
+		job:               sub,
+	})
+	if err != nil {
+		return err
+	}
+	if err := sub.finish(rendered); err != nil {
+		return err
+	}
+	return job.merge(sub, true)
+}
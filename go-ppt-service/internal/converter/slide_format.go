@@ -0,0 +1,31 @@
+package converter
+
+import "ppt-to-images-service/internal/jpegenc"
+
+// isSupportedFormat 是否为支持的输出格式 (需为大写)
+func isSupportedFormat(format string) bool {
+	switch format {
+	case "PNG", "JPEG", "JPG", AutoFormat:
+		return true
+	default:
+		return false
+	}
+}
+
+// slideFormat 幻灯片的输出格式，slideNumber 为演示文稿内的原始编号 (不含编号偏移)
+func (o ConversionOptions) slideFormat(slideNumber int) string {
+	if format, ok := o.SlideFormats[slideNumber]; ok {
+		return format
+	}
+	return o.OutputFormat
+}
+
+// renderFormat 外部工具渲染时使用的格式
+// AUTO格式、单独指定了格式或JPEG不使用4:2:0抽样 (外部工具无法控制) 时先统一渲染为无损的PNG，
+// 再由 applyImageFormats 逐张转存
+func (o ConversionOptions) renderFormat() string {
+	if o.OutputFormat == AutoFormat || len(o.SlideFormats) > 0 || o.JPEGSubsampling != jpegenc.Subsampling420 {
+		return "PNG"
+	}
+	return o.OutputFormat
+}
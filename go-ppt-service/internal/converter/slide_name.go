@@ -0,0 +1,40 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 输出图片文件名规则，各平台转换器共用: slide_001.png，动画构建步骤为 slide_001_b02.png
+const (
+	slideFilePrefix   = "slide_"
+	slideNumberDigits = 3
+	buildIndexDigits  = 2
+)
+
+// slideFileStem 幻灯片图片不含扩展名的文件名，build 小于0表示没有构建步骤
+func slideFileStem(slideNumber, build int) string {
+	stem := fmt.Sprintf("%s%0*d", slideFilePrefix, slideNumberDigits, slideNumber)
+	if build >= 0 {
+		stem += fmt.Sprintf("_b%0*d", buildIndexDigits, build)
+	}
+	return stem
+}
+
+// slideFilename 幻灯片图片的文件名
+func slideFilename(slideNumber, build int, ext string) string {
+	return slideFileStem(slideNumber, build) + "." + ext
+}
+
+// slideFileGlob 匹配指定扩展名的所有幻灯片图片的文件名模式
+func slideFileGlob(ext string) string {
+	return slideFilePrefix + "*." + ext
+}
+
+// dotNetSlideNameFormats 按 slideFileStem 的规则生成 .NET 格式字符串，传给PowerShell脚本生成文件名
+// slide 中 {0} 为幻灯片编号，build 中 {0} 为幻灯片编号、{1} 为构建步骤，扩展名由脚本追加
+func dotNetSlideNameFormats() (slide, build string) {
+	slide = slideFilePrefix + "{0:D" + strconv.Itoa(slideNumberDigits) + "}"
+	build = slide + "_b{1:D" + strconv.Itoa(buildIndexDigits) + "}"
+	return slide, build
+}
@@ -0,0 +1,82 @@
+package converter
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+// dotNetFormatItem .NET 复合格式字符串中的 {索引:D位数} 格式项
+var dotNetFormatItem = regexp.MustCompile(`\{(\d+):D(\d+)\}`)
+
+// formatDotNet 按PowerShell的 -f 运算符展开 dotNetSlideNameFormats 生成的格式字符串 (只支持 D 格式项)
+func formatDotNet(t *testing.T, format string, args ...int) string {
+	t.Helper()
+
+	return dotNetFormatItem.ReplaceAllStringFunc(format, func(item string) string {
+		match := dotNetFormatItem.FindStringSubmatch(item)
+		index, _ := strconv.Atoi(match[1])
+		digits, _ := strconv.Atoi(match[2])
+		if index >= len(args) {
+			t.Fatalf("格式 %q 引用了第 %d 个参数", format, index)
+		}
+		return fmt.Sprintf("%0*d", digits, args[index])
+	})
+}
+
+func TestSlideFilename(t *testing.T) {
+	slideFormat, buildFormat := dotNetSlideNameFormats()
+	if slideFormat != "slide_{0:D3}" || buildFormat != "slide_{0:D3}_b{1:D2}" {
+		t.Fatalf(".NET格式为 %q 和 %q", slideFormat, buildFormat)
+	}
+
+	tests := []struct {
+		index        int // PowerPoint中的幻灯片编号
+		build        int // 构建步骤，-1表示没有
+		numberOffset int
+		powerShell   string // PowerShell脚本导出的文件名
+		want         string // 应用 number_offset 后的文件名
+	}{
+		{1, -1, 0, "slide_001.png", "slide_001.png"},
+		{12, -1, 0, "slide_012.png", "slide_012.png"},
+		{999, -1, 0, "slide_999.png", "slide_999.png"},
+		{1000, -1, 0, "slide_1000.png", "slide_1000.png"}, // 超过3位时不截断
+		{1, 0, 0, "slide_001_b00.png", "slide_001_b00.png"},
+		{3, 2, 0, "slide_003_b02.png", "slide_003_b02.png"},
+		{7, 12, 0, "slide_007_b12.png", "slide_007_b12.png"},
+		{5, 123, 0, "slide_005_b123.png", "slide_005_b123.png"},
+		{1, -1, 100, "slide_001.png", "slide_101.png"},
+		{2, 1, 9, "slide_002_b01.png", "slide_011_b01.png"},
+		{1, -1, 999, "slide_001.png", "slide_1000.png"},
+	}
+
+	for _, tt := range tests {
+		var exported string
+		if tt.build < 0 {
+			exported = formatDotNet(t, slideFormat, tt.index) + ".png"
+		} else {
+			exported = formatDotNet(t, buildFormat, tt.index, tt.build) + ".png"
+		}
+		if exported != tt.powerShell {
+			t.Errorf("幻灯片 %d 构建步骤 %d: PowerShell导出 %s，应为 %s", tt.index, tt.build, exported, tt.powerShell)
+		}
+		if got := slideFilename(tt.index, tt.build, "png"); got != exported {
+			t.Errorf("幻灯片 %d 构建步骤 %d: slideFilename 为 %s，与PowerShell导出的 %s 不一致", tt.index, tt.build, got, exported)
+		}
+		if matched, _ := filepath.Match(slideFileGlob("png"), exported); !matched {
+			t.Errorf("%s 不匹配 %s", exported, slideFileGlob("png"))
+		}
+
+		// 扫描导出目录时从文件名读回编号和构建步骤，再按 number_offset 重新命名
+		slide, ok := parseSlideFilename(exported)
+		if !ok || slide.SlideNumber != tt.index || slide.BuildIndex != tt.build {
+			t.Errorf("%s: 读取的编号为 %d，构建步骤为 %d (%v)", exported, slide.SlideNumber, slide.BuildIndex, ok)
+			continue
+		}
+		if got := slideFilename(slide.SlideNumber+tt.numberOffset, slide.BuildIndex, "png"); got != tt.want {
+			t.Errorf("%s 偏移 %d: 文件名为 %s，应为 %s", exported, tt.numberOffset, got, tt.want)
+		}
+	}
+}
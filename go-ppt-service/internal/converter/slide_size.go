@@ -0,0 +1,50 @@
+package converter
+
+// EMUPerInch 每英寸的EMU数，PPTX中的尺寸以EMU为单位
+const EMUPerInch = 914400
+
+// SlideSize 演示文稿的幻灯片尺寸 (EMU)，例如16:9为 12192000x6858000，4:3为 9144000x6858000
+type SlideSize struct {
+	Width  int64 `json:"width_emu"`
+	Height int64 `json:"height_emu"`
+}
+
+// fitSlideSize 客户端没有同时指定宽高时，按幻灯片的实际宽高比确定输出尺寸，避免拉伸非16:9的幻灯片
+// 最长边为客户端指定的宽或高，都未指定时为默认尺寸的最长边；无法识别幻灯片尺寸 (如PPT) 时保持原选项
+func (c *PPTConverter) fitSlideSize(pptData []byte, options ConversionOptions) (ConversionOptions, SlideSize) {
+	size, err := readPPTXSlideSize(pptData)
+	if err != nil {
+		c.logger.Warnf("读取幻灯片尺寸失败: %v", err)
+		return options, SlideSize{}
+	}
+	if size.Width <= 0 || size.Height <= 0 {
+		return options, SlideSize{}
+	}
+	if options.Width > 0 && options.Height > 0 {
+		return options, size
+	}
+
+	longest := max(options.Width, options.Height)
+	if longest <= 0 {
+		longest = max(c.width, c.height)
+	}
+
+	if size.Width >= size.Height {
+		options.Width = longest
+		options.Height = scaleEdge(longest, size.Height, size.Width)
+	} else {
+		options.Height = longest
+		options.Width = scaleEdge(longest, size.Width, size.Height)
+	}
+	c.logger.Debugf("幻灯片尺寸 %dx%d EMU，输出尺寸 %dx%d", size.Width, size.Height, options.Width, options.Height)
+	return options, size
+}
+
+// scaleEdge 按 num/den 的比例缩放边长，四舍五入且至少为1
+func scaleEdge(edge int, num, den int64) int {
+	scaled := int((int64(edge)*num + den/2) / den)
+	if scaled < 1 {
+		scaled = 1
+	}
+	return scaled
+}
@@ -0,0 +1,88 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// slideSourceHashes 按放映顺序计算每张幻灯片源XML的SHA-256 (十六进制)
+// 哈希覆盖幻灯片XML及其关系文件，直接读取压缩包中的原始字节，与渲染结果无关，
+// 因此未修改的幻灯片每次得到相同的值；不是PPTX (如PPT、ODP) 时返回nil
+func slideSourceHashes(pptData []byte) ([]string, error) {
+	if !bytes.HasPrefix(pptData, []byte("PK\x03\x04")) {
+		return nil, nil
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(pptData), int64(len(pptData)))
+	if err != nil {
+		return nil, fmt.Errorf("打开PPTX失败: %v", err)
+	}
+	if !isPPTXPackage(reader) {
+		return nil, nil
+	}
+
+	paths, err := pptxSlidePaths(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(paths))
+	for i, slidePath := range paths {
+		if slidePath == "" {
+			continue
+		}
+
+		sum := sha256.New()
+		relsPath := path.Join(path.Dir(slidePath), "_rels", path.Base(slidePath)+".rels")
+		for _, name := range []string{slidePath, relsPath} {
+			if err := hashZipFile(reader, name, sum); err != nil {
+				return nil, err
+			}
+		}
+		hashes[i] = hex.EncodeToString(sum.Sum(nil))
+	}
+	return hashes, nil
+}
+
+// hashZipFile 把ZIP中文件的内容写入哈希，文件不存在时只写入分隔符
+// 不写入文件名，幻灯片调整顺序 (文件被重命名) 时哈希不变
+func hashZipFile(reader *zip.Reader, name string, sum hash.Hash) error {
+	file, err := reader.Open(name)
+	if err == nil {
+		_, err = io.Copy(sum, file)
+		file.Close()
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("读取 %s 失败: %v", name, err)
+	}
+	sum.Write([]byte{0})
+	return nil
+}
+
+// withSourceHashes 读取每张幻灯片的源哈希并记录在选项中，失败时只记录日志
+func (c *PPTConverter) withSourceHashes(pptData []byte, options ConversionOptions) ConversionOptions {
+	hashes, err := slideSourceHashes(pptData)
+	if err != nil {
+		c.logger.Warnf("计算幻灯片源哈希失败: %v", err)
+	}
+	options.sourceHashes = hashes
+	return options
+}
+
+// setSourceHashes 按原始幻灯片编号 (去掉编号偏移) 写入 SourceHash，总览图等编号为0的图片跳过
+func setSourceHashes(images []ImageInfo, options ConversionOptions) {
+	for i := range images {
+		index := images[i].SlideNumber - options.NumberOffset - 1
+		if images[i].SlideNumber > 0 && index >= 0 && index < len(options.sourceHashes) {
+			images[i].SourceHash = options.sourceHashes[index]
+		}
+	}
+}
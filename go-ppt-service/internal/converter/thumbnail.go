@@ -0,0 +1,102 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // 注册JPEG解码，用于读取缩略图尺寸
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const (
+	// thumbnailRelType 包关系中指向预览缩略图的关系类型
+	thumbnailRelType = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail"
+	// odpThumbnailPath ODP内嵌缩略图的固定位置
+	odpThumbnailPath = "Thumbnails/thumbnail.png"
+	// maxThumbnailSize 内嵌缩略图的大小上限，超过时视为文件损坏
+	maxThumbnailSize = 16 << 20
+)
+
+// Thumbnail 演示文稿内嵌的预览缩略图
+type Thumbnail struct {
+	Data   []byte
+	Format string // JPEG、PNG，或无法解码时的大写扩展名 (如 WMF)
+	Width  int    // 无法解码时为0
+	Height int
+}
+
+// ExtractThumbnail 读取PPTX (通常为 docProps/thumbnail.jpeg) 或ODP中内嵌的预览缩略图，不进行任何渲染
+// 没有内嵌缩略图 (包括PPT等非ZIP文件) 时返回 ErrNoThumbnail
+func ExtractThumbnail(pptData []byte) (Thumbnail, error) {
+	if !bytes.HasPrefix(pptData, []byte("PK\x03\x04")) {
+		return Thumbnail{}, ErrNoThumbnail
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(pptData), int64(len(pptData)))
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("%w: %v", ErrCorruptFile, err)
+	}
+
+	name := pptxThumbnailPath(reader)
+	if name == "" {
+		name = odpThumbnailPath
+	}
+
+	file, err := reader.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Thumbnail{}, ErrNoThumbnail
+	}
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("读取 %s 失败: %v", name, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxThumbnailSize+1))
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("读取 %s 失败: %v", name, err)
+	}
+	if len(data) == 0 {
+		return Thumbnail{}, ErrNoThumbnail
+	}
+	if len(data) > maxThumbnailSize {
+		return Thumbnail{}, fmt.Errorf("%w: 缩略图 %s 过大", ErrCorruptFile, name)
+	}
+
+	thumbnail := Thumbnail{
+		Data:   data,
+		Format: strings.ToUpper(strings.TrimPrefix(path.Ext(name), ".")),
+	}
+	// Office 早期版本可能写入WMF缩略图，无法解码时按原样返回
+	if config, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		thumbnail.Format = strings.ToUpper(format)
+		thumbnail.Width = config.Width
+		thumbnail.Height = config.Height
+	}
+	return thumbnail, nil
+}
+
+// pptxThumbnailPath 从包关系 (_rels/.rels) 中找到缩略图在ZIP中的路径，没有时返回空字符串
+func pptxThumbnailPath(reader *zip.Reader) string {
+	var rels struct {
+		Relationships []struct {
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := readZipXML(reader, "_rels/.rels", &rels); err != nil {
+		return ""
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.Type == thumbnailRelType {
+			// 包关系的目标相对于包的根目录，可能带有开头的 /
+			return path.Clean(strings.TrimPrefix(rel.Target, "/"))
+		}
+	}
+	return ""
+}
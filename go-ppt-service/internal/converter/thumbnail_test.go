@@ -0,0 +1,66 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// zipPackage 生成包含指定文件的ZIP
+func zipPackage(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractThumbnail(t *testing.T) {
+	var thumbnail bytes.Buffer
+	if err := jpeg.Encode(&thumbnail, image.NewRGBA(image.Rect(0, 0, 256, 144)), nil); err != nil {
+		t.Fatal(err)
+	}
+	rels := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail" Target="/docProps/thumbnail.jpeg"/>
+</Relationships>`)
+
+	got, err := ExtractThumbnail(zipPackage(t, map[string][]byte{
+		"_rels/.rels":             rels,
+		"ppt/presentation.xml":    []byte("<p:presentation/>"),
+		"docProps/thumbnail.jpeg": thumbnail.Bytes(),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != "JPEG" || got.Width != 256 || got.Height != 144 || !bytes.Equal(got.Data, thumbnail.Bytes()) {
+		t.Errorf("缩略图为 %s %dx%d (%d 字节)", got.Format, got.Width, got.Height, len(got.Data))
+	}
+}
+
+func TestExtractThumbnailMissing(t *testing.T) {
+	tests := map[string][]byte{
+		"PPTX": zipPackage(t, map[string][]byte{"ppt/presentation.xml": []byte("<p:presentation/>")}),
+		"PPT":  {0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1},
+	}
+	for name, data := range tests {
+		if _, err := ExtractThumbnail(data); !errors.Is(err, ErrNoThumbnail) {
+			t.Errorf("%s: 错误为 %v，应为 ErrNoThumbnail", name, err)
+		}
+	}
+}
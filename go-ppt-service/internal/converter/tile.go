@@ -0,0 +1,105 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// splitTiles 把高度超过 TileHeight 的幻灯片图片按从上到下切分为多张，最后一块可能较矮
+// 切分后删除原图，每块作为一条 ImageInfo 返回，编号不变，TileIndex 从1开始
+// 总览图等非幻灯片图片 (编号为0) 不切分，切分失败时保留原图并记录日志
+func (c *PPTConverter) splitTiles(images []ImageInfo, sourceFile string, options ConversionOptions) []ImageInfo {
+	if options.TileHeight <= 0 {
+		return images
+	}
+
+	result := make([]ImageInfo, 0, len(images))
+	for _, info := range images {
+		if info.SlideNumber <= 0 || info.Height <= options.TileHeight {
+			result = append(result, info)
+			continue
+		}
+
+		var meta *imageMetadata
+		if options.EmbedMetadata {
+			meta = &imageMetadata{SourceFile: sourceFile, SlideNumber: info.SlideNumber}
+		}
+
+		tiles, err := c.splitImage(info, options, meta)
+		if err != nil {
+			c.logger.Warnf("切分第 %d 张幻灯片失败: %v", info.SlideNumber, err)
+			result = append(result, info)
+			continue
+		}
+
+		os.Remove(info.FilePath)
+		c.logger.Infof("第 %d 张幻灯片高度 %d 超过 %d，切分为 %d 张", info.SlideNumber, info.Height, options.TileHeight, len(tiles))
+		result = append(result, tiles...)
+	}
+	return result
+}
+
+// splitImage 切分单张图片，文件名在原文件名后加 _t01 形式的序号，出错时删除已写入的分块
+func (c *PPTConverter) splitImage(info ImageInfo, options ConversionOptions, meta *imageMetadata) ([]ImageInfo, error) {
+	tileHeight := options.TileHeight
+	img, err := imaging.Open(info.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取图片失败: %v", err)
+	}
+
+	bounds := img.Bounds()
+	ext := filepath.Ext(info.Filename)
+	base := strings.TrimSuffix(info.Filename, ext)
+	dir := filepath.Dir(info.FilePath)
+	format := formatFromExtension(ext)
+
+	var tiles []ImageInfo
+	for top, index := bounds.Min.Y, 1; top < bounds.Max.Y; top, index = top+tileHeight, index+1 {
+		bottom := min(top+tileHeight, bounds.Max.Y)
+		tile := imaging.Crop(img, image.Rect(bounds.Min.X, top, bounds.Max.X, bottom))
+
+		filename := c.outputFilename(dir, fmt.Sprintf("%s_t%02d%s", base, index, ext), options)
+		filePath := filepath.Join(dir, filename)
+		if err := c.saveImage(tile, filePath, format, options.JPEGSubsampling, meta); err != nil {
+			os.Remove(filePath)
+			removeTiles(tiles)
+			return nil, fmt.Errorf("保存分块 %s 失败: %v", filename, err)
+		}
+
+		fileInfo, err := os.Stat(filePath)
+		if err != nil {
+			removeTiles(tiles)
+			return nil, fmt.Errorf("获取文件信息失败: %v", err)
+		}
+
+		tiles = append(tiles, ImageInfo{
+			SlideNumber: info.SlideNumber,
+			Filename:    filename,
+			FilePath:    filePath,
+			FileSize:    fileInfo.Size(),
+			DownloadID:  generateDownloadID(),
+			Format:      format,
+			BuildIndex:  info.BuildIndex,
+			TileIndex:   index,
+			Width:       tile.Bounds().Dx(),
+			Height:      tile.Bounds().Dy(),
+
+			LowConfidence: info.LowConfidence,
+			Crop:          info.Crop,
+			SourceHash:    info.SourceHash,
+		})
+	}
+	return tiles, nil
+}
+
+// removeTiles 删除已写入的分块文件
+func removeTiles(tiles []ImageInfo) {
+	for _, tile := range tiles {
+		os.Remove(tile.FilePath)
+	}
+}
@@ -0,0 +1,48 @@
+package converter
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ConversionTiming 一次转换各阶段的耗时，用于判断时间主要花在外部进程启动、渲染还是编码上
+type ConversionTiming struct {
+	// Parse 渲染之前的准备: 读取幻灯片尺寸和源哈希、解析选项、写入临时文件
+	Parse time.Duration `json:"parse"`
+	// Render 渲染器的总耗时，包括外部进程的启动；逐张提交的幻灯片的后处理与之重叠
+	Render time.Duration `json:"render"`
+	// FirstSlide 从开始渲染到第一张幻灯片渲染完成，主要是外部进程启动和文档加载
+	FirstSlide time.Duration `json:"first_slide"`
+	// Encode 所有幻灯片后处理 (叠加、编码、多分辨率、压缩等) 的累计耗时，并行处理时可能超过 Total
+	Encode time.Duration `json:"encode"`
+	// Summaries 生成总览图、HTML页面和清单的耗时
+	Summaries time.Duration `json:"summaries"`
+	Total     time.Duration `json:"total"`
+	// Slides 完成后处理的幻灯片数量
+	Slides int `json:"slides"`
+}
+
+// PerSlide 平均每张幻灯片的渲染耗时，没有幻灯片时为0
+func (t ConversionTiming) PerSlide() time.Duration {
+	if t.Slides == 0 {
+		return 0
+	}
+	return t.Render / time.Duration(t.Slides)
+}
+
+// logTiming 在转换结束时记录各阶段耗时
+func (c *PPTConverter) logTiming(filename string, timing *ConversionTiming) {
+	c.logger.WithFields(logrus.Fields{
+		"file":        filename,
+		"backend":     c.renderer.Backend(),
+		"slides":      timing.Slides,
+		"parse":       timing.Parse.Round(time.Millisecond),
+		"render":      timing.Render.Round(time.Millisecond),
+		"first_slide": timing.FirstSlide.Round(time.Millisecond),
+		"per_slide":   timing.PerSlide().Round(time.Millisecond),
+		"encode":      timing.Encode.Round(time.Millisecond),
+		"summaries":   timing.Summaries.Round(time.Millisecond),
+		"total":       timing.Total.Round(time.Millisecond),
+	}).Info("转换耗时")
+}
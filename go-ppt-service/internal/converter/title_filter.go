@@ -0,0 +1,32 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// SlidesWithTitle 返回标题与 pattern 匹配的幻灯片编号，以及幻灯片总数
+// 标题取自幻灯片的标题占位符 (title、ctrTitle)，没有标题的幻灯片按空字符串匹配
+func SlidesWithTitle(pptData []byte, pattern *regexp.Regexp) ([]int, int, error) {
+	if !bytes.HasPrefix(pptData, []byte("PK\x03\x04")) {
+		return nil, 0, fmt.Errorf("%w: 按标题筛选只支持PPTX文件", ErrUnsupportedFormat)
+	}
+
+	slides, err := readPPTXSlides(pptData)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrCorruptFile, err)
+	}
+	if slides == nil {
+		return nil, 0, fmt.Errorf("%w: 按标题筛选只支持PPTX文件", ErrUnsupportedFormat)
+	}
+
+	var matches []int
+	for i, slide := range slides {
+		if pattern.MatchString(slide.title()) {
+			matches = append(matches, i+1)
+		}
+	}
+
+	return matches, len(slides), nil
+}
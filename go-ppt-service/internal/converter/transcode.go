@@ -0,0 +1,90 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+
+	"ppt-to-images-service/internal/jpegenc"
+)
+
+// DefaultJPEGQuality 未指定质量时JPEG的编码质量
+const DefaultJPEGQuality = 90
+
+// TranscodeImages 把已渲染的图片重新编码为指定格式，不重新渲染幻灯片
+// 新文件写入原输出目录下的 transcode_<扩展名>[_q<质量>] 子目录，原图片保持不变；
+// 只处理PNG和JPEG图片 (包括总览图)，index.html 等其他文件被跳过
+// quality 只对JPEG有效，0表示使用 DefaultJPEGQuality；progress 不为空时每处理一张调用一次
+func TranscodeImages(images []ImageInfo, format string, quality int, progress func(done, total int)) ([]ImageInfo, error) {
+	format = strings.ToUpper(format)
+	if format != "PNG" && format != "JPEG" && format != "JPG" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	if quality <= 0 {
+		quality = DefaultJPEGQuality
+	}
+
+	var sources []ImageInfo
+	for _, image := range images {
+		if image.Format == "PNG" || image.Format == "JPEG" {
+			sources = append(sources, image)
+		}
+	}
+
+	ext := imageExtension(format)
+	subdir := "transcode_" + ext
+	if ext == "jpg" {
+		subdir = fmt.Sprintf("%s_q%d", subdir, quality)
+	}
+
+	var results []ImageInfo
+	for i, source := range sources {
+		img, err := imaging.Open(source.FilePath)
+		if err != nil {
+			return results, fmt.Errorf("读取图片 %s 失败: %v", source.Filename, err)
+		}
+
+		outputPath := filepath.Join(filepath.Dir(source.FilePath), subdir)
+		if err := os.MkdirAll(outputPath, 0755); err != nil {
+			return results, fmt.Errorf("创建输出目录失败: %v", err)
+		}
+
+		filename := strings.TrimSuffix(source.Filename, filepath.Ext(source.Filename)) + "." + ext
+		filePath := filepath.Join(outputPath, filename)
+		if err := writeImageFile(img, filePath, format, jpegenc.Options{Quality: quality}, nil); err != nil {
+			os.Remove(filePath)
+			return results, fmt.Errorf("保存图片 %s 失败: %v", filename, err)
+		}
+
+		fileInfo, err := os.Stat(filePath)
+		if err != nil {
+			return results, fmt.Errorf("获取文件信息失败: %v", err)
+		}
+
+		results = append(results, ImageInfo{
+			SlideNumber: source.SlideNumber,
+			Filename:    filename,
+			FilePath:    filePath,
+			FileSize:    fileInfo.Size(),
+			DownloadID:  generateDownloadID(),
+			Format:      formatFromExtension(ext),
+			BuildIndex:  source.BuildIndex,
+			TileIndex:   source.TileIndex,
+			Width:       source.Width,
+			Height:      source.Height,
+
+			LowConfidence: source.LowConfidence,
+			Crop:          source.Crop,
+			SourceHash:    source.SourceHash,
+		})
+
+		if progress != nil {
+			progress(i+1, len(sources))
+		}
+	}
+
+	return results, nil
+}
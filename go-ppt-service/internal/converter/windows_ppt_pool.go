@@ -0,0 +1,514 @@
+//go:build windows
+// +build windows
+
+package converter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// powerPointReadyMarker 宿主进程启动完成标记
+	powerPointReadyMarker = "__READY__"
+	// powerPointDoneMarker 单次转换结束标记，后跟 OK 或 ERR <消息>
+	powerPointDoneMarker = "__DONE__"
+	// powerPointWarnMarker 转换仍可继续的问题 (如无法应用主题变体)，后跟消息，以Warn级别记录
+	powerPointWarnMarker = "__WARN__"
+	// powerPointExitTimeout 关闭时等待宿主进程自行退出的时间
+	powerPointExitTimeout = 5 * time.Second
+)
+
+// powerPointExportFunction 导出单张幻灯片的PowerShell函数，一次性脚本和宿主脚本共用
+// PowerPoint的Export总是导出动画播放完成后的样子，first和all_builds模式通过临时隐藏
+// 尚未进入 (或显示尚未退出) 的形状模拟某一构建步骤。按段落构建的文本只能整体显示或隐藏
+const powerPointExportFunction = `
+# 应用演示文稿每个设计的第 variant 个主题变体 (从1开始)，0表示不改变；无法应用时输出警告并继续
+function Apply-ThemeVariant($presentation, $variant) {
+    if ($variant -le 0) {
+        return
+    }
+    foreach ($design in $presentation.Designs) {
+        try {
+            $variants = $design.SlideMaster.Theme.ThemeVariants
+            if ($variant -gt $variants.Count) {
+                [Console]::Out.WriteLine("__WARN__ 设计 $($design.Name) 只有 $($variants.Count) 个主题变体，忽略 theme_variant=$variant")
+                continue
+            }
+            $variants.Item($variant).Apply()
+        }
+        catch {
+            [Console]::Out.WriteLine("__WARN__ 设计 $($design.Name) 无法应用主题变体 ${variant}: $($_.Exception.Message)")
+        }
+    }
+}
+
+# 把备注页导出为PDF (ppFixedFormatTypePDF, ppPrintOutputNotesPages)，path 为空时不导出；失败时输出警告，由服务器自行生成备注页
+function Export-NotesPages($presentation, $path, $includeHidden) {
+    if (-not $path) {
+        return
+    }
+    $hidden = 0
+    if ($includeHidden) {
+        $hidden = -1
+    }
+    try {
+        $presentation.ExportAsFixedFormat($path, 2, 2, 0, 1, 5, $hidden)
+    }
+    catch {
+        [Console]::Out.WriteLine("__WARN__ 导出备注页失败: $($_.Exception.Message)")
+    }
+}
+
+# 判断动画效果类型: exit、entrance，其他 (强调、路径) 返回 $null
+function Get-EffectKind($effect) {
+    if ($effect.Exit -eq -1) {
+        return "exit"
+    }
+    # 进入效果都包含一个将visibility设置为可见的行为 (msoAnimTypeSet / msoAnimVisibility)
+    for ($k = 1; $k -le $effect.Behaviors.Count; $k++) {
+        $behavior = $effect.Behaviors.Item($k)
+        if ($behavior.Type -eq 8 -and $behavior.SetEffect.Property -eq 8) {
+            return "entrance"
+        }
+    }
+    return $null
+}
+
+# 导出一张幻灯片，mode 为 final、first 或 all_builds
+# slideName、buildName 为服务端生成的文件名格式，{0} 为幻灯片编号，{1} 为构建步骤
+function Export-Slide($slide, $index, $outputDir, $slideName, $buildName, $extension, $filter, $width, $height, $mode) {
+    if ($mode -ne "first" -and $mode -ne "all_builds") {
+        $slide.Export((Join-Path $outputDir (($slideName -f $index) + "." + $extension)), $filter, $width, $height)
+        return
+    }
+
+    # 单击触发的效果开始新的构建步骤，步骤0为幻灯片开始时自动播放的效果
+    $effects = @()
+    $original = @{}
+    $steps = 0
+    $sequence = $slide.TimeLine.MainSequence
+    for ($j = 1; $j -le $sequence.Count; $j++) {
+        $effect = $sequence.Item($j)
+        if ($effect.Timing.TriggerType -eq 1) {
+            $steps++
+        }
+        $kind = Get-EffectKind $effect
+        if ($kind -ne $null) {
+            $shape = $effect.Shape
+            $original[$shape.Id] = $shape.Visible
+            $effects += [pscustomobject]@{ Shape = $shape; Step = $steps; Entrance = ($kind -eq "entrance") }
+        }
+    }
+
+    $last = $steps
+    if ($mode -eq "first") {
+        $last = 0
+    }
+
+    try {
+        for ($build = 0; $build -le $last; $build++) {
+            # 已播放的效果决定形状是否可见，否则取第一个未播放效果之前的状态
+            $visible = @{}
+            foreach ($item in $effects) {
+                $id = $item.Shape.Id
+                if ($item.Step -le $build) {
+                    $visible[$id] = $item.Entrance
+                } elseif (-not $visible.ContainsKey($id)) {
+                    $visible[$id] = -not $item.Entrance
+                }
+            }
+            foreach ($item in $effects) {
+                if ($visible[$item.Shape.Id]) {
+                    $item.Shape.Visible = -1
+                } else {
+                    $item.Shape.Visible = 0
+                }
+            }
+
+            if ($mode -eq "first") {
+                $name = ($slideName -f $index) + "." + $extension
+            } else {
+                $name = ($buildName -f $index, $build) + "." + $extension
+            }
+            $slide.Export((Join-Path $outputDir $name), $filter, $width, $height)
+        }
+    }
+    finally {
+        foreach ($item in $effects) {
+            $item.Shape.Visible = $original[$item.Shape.Id]
+        }
+    }
+}
+`
+
+// powerPointHostScript 常驻PowerShell宿主脚本
+// 启动后创建一次PowerPoint COM对象，然后逐行读取JSON转换请求，处理完成后输出结束标记
+const powerPointHostScript = powerPointExportFunction + `
+[Console]::InputEncoding = [System.Text.Encoding]::UTF8
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+
+try {
+    $ppt = New-Object -ComObject PowerPoint.Application
+}
+catch {
+    [Console]::Out.WriteLine("__DONE__ ERR 创建PowerPoint对象失败: $($_.Exception.Message)")
+    exit 1
+}
+
+[Console]::Out.WriteLine("__READY__")
+
+while ($true) {
+    $line = [Console]::In.ReadLine()
+    if ($line -eq $null -or $line -eq "EXIT") {
+        break
+    }
+
+    $presentation = $null
+    try {
+        $req = $line | ConvertFrom-Json
+
+        # 以只读、无窗口方式打开演示文稿
+        $presentation = $ppt.Presentations.Open($req.input, $true, $false, $false)
+        Apply-ThemeVariant $presentation $req.theme_variant
+        $count = $presentation.Slides.Count
+
+        for ($i = 1; $i -le $count; $i++) {
+            Export-Slide $presentation.Slides($i) $i $req.output $req.slide_name $req.build_name $req.extension $req.filter $req.width $req.height $req.mode
+            [Console]::Out.WriteLine("第 $i 张幻灯片导出完成")
+        }
+        Export-NotesPages $presentation $req.notes_pdf $req.include_hidden
+
+        [Console]::Out.WriteLine("__DONE__ OK $count")
+    }
+    catch {
+        [Console]::Out.WriteLine("__DONE__ ERR $($_.Exception.Message)")
+    }
+    finally {
+        if ($presentation -ne $null) {
+            $presentation.Close()
+            [System.Runtime.Interopservices.Marshal]::ReleaseComObject($presentation) | Out-Null
+        }
+    }
+}
+
+$ppt.Quit()
+[System.Runtime.Interopservices.Marshal]::ReleaseComObject($ppt) | Out-Null
+`
+
+// powerPointRequest 发送给宿主进程的转换请求
+type powerPointRequest struct {
+	Input     string `json:"input"`
+	Output    string `json:"output"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Filter    string `json:"filter"`         // 导出过滤器 (PNG, JPG)
+	Extension string `json:"extension"`      // 输出文件扩展名
+	SlideName string `json:"slide_name"`     // 文件名格式 (.NET)，{0} 为幻灯片编号
+	BuildName string `json:"build_name"`     // 构建步骤的文件名格式 (.NET)，{1} 为构建步骤
+	Mode      string `json:"mode"`           // 动画渲染方式 (final, first, all_builds)
+	Variant   int    `json:"theme_variant"`  // 应用的主题变体 (从1开始)，0表示不改变
+	NotesPDF  string `json:"notes_pdf"`      // 备注页PDF的路径，为空时不导出
+	Hidden    bool   `json:"include_hidden"` // 备注页中包括隐藏的幻灯片
+}
+
+// powerPointInstance 常驻的PowerPoint宿主进程
+type powerPointInstance struct {
+	id     int
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *io.PipeWriter // 把宿主进程的错误输出写入日志，进程结束后关闭
+	uses   int
+}
+
+// powerPointPool PowerPoint实例池
+// 注意: PowerPoint是单实例COM服务器，所有宿主进程共享同一个POWERPNT进程，
+// 池的作用是避免每次转换重新启动PowerPoint，并限制同时进行的转换数量
+type powerPointPool struct {
+	size       int
+	maxUses    int
+	scriptFile string
+	logger     *logrus.Logger
+
+	idle      chan *powerPointInstance
+	slots     chan struct{}
+	done      chan struct{}
+	mutex     sync.Mutex
+	instances map[int]*powerPointInstance
+	nextID    int
+	closed    bool
+}
+
+// newPowerPointPool 创建PowerPoint实例池，实例在首次使用时按需启动
+func newPowerPointPool(size, maxUses int, tempDir string, logger *logrus.Logger) (*powerPointPool, error) {
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return nil, err
+	}
+
+	scriptFile := filepath.Join(tempDir, fmt.Sprintf("ppt_host_%d.ps1", time.Now().UnixNano()))
+	// 写入UTF-8 BOM，保证Windows PowerShell正确识别脚本中的中文
+	script := append([]byte{0xEF, 0xBB, 0xBF}, []byte(powerPointHostScript)...)
+	if err := os.WriteFile(scriptFile, script, 0644); err != nil {
+		return nil, fmt.Errorf("创建PowerPoint宿主脚本失败: %v", err)
+	}
+
+	return &powerPointPool{
+		size:       size,
+		maxUses:    maxUses,
+		scriptFile: scriptFile,
+		logger:     logger,
+		idle:       make(chan *powerPointInstance, size),
+		slots:      make(chan struct{}, size),
+		done:       make(chan struct{}),
+		instances:  make(map[int]*powerPointInstance),
+	}, nil
+}
+
+// convert 从池中取出实例执行一次转换，完成后归还
+// ctx 取消时结束正在转换的宿主进程，该实例随后被回收
+func (p *powerPointPool) convert(ctx context.Context, req powerPointRequest) error {
+	inst, err := p.acquire(ctx)
+	if err != nil {
+		if cancelled := checkCancelled(ctx); cancelled != nil {
+			return cancelled
+		}
+		err = fmt.Errorf("%w: %v", ErrBackendUnavailable, err)
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, func() { inst.cmd.Process.Kill() })
+	err = inst.convert(req, p.logger)
+	stop()
+	p.release(inst, err == nil)
+	if err != nil {
+		if cancelled := checkCancelled(ctx); cancelled != nil {
+			return cancelled
+		}
+	}
+	return err
+}
+
+// acquire 取出空闲实例，没有空闲实例且未达上限时启动新实例，否则等待
+func (p *powerPointPool) acquire(ctx context.Context) (*powerPointInstance, error) {
+	if p.isClosed() {
+		return nil, fmt.Errorf("PowerPoint实例池已关闭")
+	}
+
+	select {
+	case inst := <-p.idle:
+		return inst, nil
+	default:
+	}
+
+	select {
+	case <-p.done:
+		return nil, fmt.Errorf("PowerPoint实例池已关闭")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case inst := <-p.idle:
+		return inst, nil
+	case p.slots <- struct{}{}:
+		inst, err := p.start()
+		if err != nil {
+			<-p.slots
+			return nil, err
+		}
+		return inst, nil
+	}
+}
+
+// release 归还实例，出错或达到最大使用次数的实例会被回收
+func (p *powerPointPool) release(inst *powerPointInstance, healthy bool) {
+	inst.uses++
+
+	if !healthy || (p.maxUses > 0 && inst.uses >= p.maxUses) || p.isClosed() {
+		p.logger.Debugf("回收PowerPoint实例 #%d (已使用 %d 次)", inst.id, inst.uses)
+		p.destroy(inst)
+		return
+	}
+
+	p.idle <- inst
+}
+
+// start 启动新的PowerPoint宿主进程并等待其就绪
+func (p *powerPointPool) start() (*powerPointInstance, error) {
+	p.mutex.Lock()
+	p.nextID++
+	id := p.nextID
+	p.mutex.Unlock()
+
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", p.scriptFile)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	// WriterLevel 为每个实例创建管道和读取协程，实例结束时由 stop 关闭
+	stderr := p.logger.WriterLevel(logrus.WarnLevel)
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		stderr.Close()
+		return nil, fmt.Errorf("启动PowerPoint宿主进程失败: %v", err)
+	}
+
+	inst := &powerPointInstance{
+		id:     id,
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+		stderr: stderr,
+	}
+
+	p.mutex.Lock()
+	p.instances[id] = inst
+	p.mutex.Unlock()
+
+	if err := inst.waitReady(); err != nil {
+		p.mutex.Lock()
+		delete(p.instances, id)
+		p.mutex.Unlock()
+		inst.stop()
+		return nil, err
+	}
+
+	p.logger.Infof("PowerPoint实例 #%d 已启动", id)
+	return inst, nil
+}
+
+// destroy 结束实例进程并释放其占用的名额
+func (p *powerPointPool) destroy(inst *powerPointInstance) {
+	p.mutex.Lock()
+	_, exists := p.instances[inst.id]
+	delete(p.instances, inst.id)
+	p.mutex.Unlock()
+
+	if !exists {
+		return
+	}
+
+	inst.stop()
+	<-p.slots
+}
+
+// close 关闭实例池并结束所有宿主进程 (包括正在使用中的实例)
+func (p *powerPointPool) close() error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	instances := make([]*powerPointInstance, 0, len(p.instances))
+	for _, inst := range p.instances {
+		instances = append(instances, inst)
+	}
+	p.instances = make(map[int]*powerPointInstance)
+	p.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	for _, inst := range instances {
+		wg.Add(1)
+		go func(inst *powerPointInstance) {
+			defer wg.Done()
+			inst.stop()
+		}(inst)
+	}
+	wg.Wait()
+
+	p.logger.Infof("PowerPoint实例池已关闭，结束 %d 个实例", len(instances))
+	return os.Remove(p.scriptFile)
+}
+
+// isClosed 实例池是否已关闭
+func (p *powerPointPool) isClosed() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.closed
+}
+
+// waitReady 等待宿主进程输出就绪标记
+func (inst *powerPointInstance) waitReady() error {
+	line, err := inst.stdout.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("PowerPoint宿主进程启动失败: %v", err)
+	}
+
+	line = strings.TrimSpace(line)
+	if line != powerPointReadyMarker {
+		return fmt.Errorf("PowerPoint宿主进程启动失败: %s", line)
+	}
+	return nil
+}
+
+// convert 发送转换请求并等待结束标记
+func (inst *powerPointInstance) convert(req powerPointRequest, logger *logrus.Logger) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(inst.stdin, "%s\n", data); err != nil {
+		return fmt.Errorf("发送转换请求失败: %v", err)
+	}
+
+	for {
+		line, err := inst.stdout.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("读取PowerPoint宿主输出失败: %v", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if message, ok := strings.CutPrefix(line, powerPointWarnMarker); ok {
+			logger.Warnf("PowerPoint实例 #%d: %s", inst.id, strings.TrimSpace(message))
+			continue
+		}
+		if !strings.HasPrefix(line, powerPointDoneMarker) {
+			logger.Debugf("PowerPoint实例 #%d: %s", inst.id, line)
+			continue
+		}
+
+		result := strings.TrimSpace(strings.TrimPrefix(line, powerPointDoneMarker))
+		if strings.HasPrefix(result, "OK") {
+			return nil
+		}
+		return fmt.Errorf("PowerPoint转换失败: %s", strings.TrimSpace(strings.TrimPrefix(result, "ERR")))
+	}
+}
+
+// stop 通知宿主进程退出，超时后强制结束
+func (inst *powerPointInstance) stop() {
+	fmt.Fprintln(inst.stdin, "EXIT")
+	inst.stdin.Close()
+
+	done := make(chan struct{})
+	go func() {
+		inst.cmd.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(powerPointExitTimeout):
+		inst.cmd.Process.Kill()
+		<-done
+	}
+	// Wait 返回时错误输出已全部复制，关闭管道结束日志的读取协程
+	inst.stderr.Close()
+}
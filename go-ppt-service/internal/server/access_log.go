@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryAccessLog 记录每次一元调用的方法、客户端、耗时和状态码的拦截器
+func UnaryAccessLog(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logAccess(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamAccessLog 记录每次流式调用的方法、客户端、耗时和状态码的拦截器，耗时为整个流的持续时间
+func StreamAccessLog(logger *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		logAccess(stream.Context(), logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// logAccess 按状态码选择日志级别记录一次调用
+func logAccess(ctx context.Context, logger *logrus.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	client := "unknown"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client = p.Addr.String()
+	}
+	tenant := tenantFromContext(ctx)
+	if tenant == "" {
+		tenant = "-"
+	}
+
+	message := ""
+	if err != nil {
+		message = ": " + status.Convert(err).Message()
+	}
+	logger.Logf(accessLogLevel(code), "gRPC %s 客户端 %s 租户 %s 耗时 %v 状态 %s%s",
+		method, client, tenant, time.Since(start).Round(time.Millisecond), code, message)
+}
+
+// accessLogLevel 状态码对应的日志级别: 成功为Info，客户端请求导致的错误为Warn，服务器错误为Error
+func accessLogLevel(code codes.Code) logrus.Level {
+	switch code {
+	case codes.OK:
+		return logrus.InfoLevel
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unimplemented, codes.Unavailable:
+		return logrus.ErrorLevel
+	default:
+		return logrus.WarnLevel
+	}
+}
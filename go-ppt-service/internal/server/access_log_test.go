@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAccessLogLevel(t *testing.T) {
+	for code, level := range map[codes.Code]logrus.Level{
+		codes.OK:               logrus.InfoLevel,
+		codes.InvalidArgument:  logrus.WarnLevel,
+		codes.NotFound:         logrus.WarnLevel,
+		codes.DeadlineExceeded: logrus.WarnLevel,
+		codes.Internal:         logrus.ErrorLevel,
+		codes.Unavailable:      logrus.ErrorLevel,
+	} {
+		if got := accessLogLevel(code); got != level {
+			t.Errorf("%s 的日志级别为 %v，应为 %v", code, got, level)
+		}
+	}
+}
+
+func TestUnaryAccessLogPassesThrough(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	interceptor := UnaryAccessLog(logger)
+	info := &grpc.UnaryServerInfo{FullMethod: "/ppt.PPTToImagesService/GetServerInfo"}
+
+	resp, err := interceptor(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "resp", nil
+	})
+	if resp != "resp" || err != nil {
+		t.Errorf("拦截器返回 %v, %v", resp, err)
+	}
+
+	failure := status.Error(codes.NotFound, "不存在")
+	_, err = interceptor(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("拦截器改变了错误: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// 压缩包格式
+const (
+	archiveZip   = "zip"
+	archiveTarGz = "tar.gz"
+)
+
+// archiveContentTypes 压缩包格式对应的内容类型
+var archiveContentTypes = map[string]string{
+	archiveZip:   "application/zip",
+	archiveTarGz: "application/gzip",
+}
+
+// DownloadArchive 把转换的所有输出文件 (与 ListImages 相同) 打包后分块发送
+// 压缩包边生成边发送，不在内存或磁盘中保存完整的压缩包，因此 DownloadInfo.file_size 为0
+func (s *GRPCServer) DownloadArchive(req *proto.DownloadArchiveRequest, stream proto.PPTToImagesService_DownloadArchiveServer) error {
+	format := strings.ToLower(req.Format)
+	if format == "" {
+		format = archiveZip
+	}
+	contentType, ok := archiveContentTypes[format]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "不支持的压缩包格式: %s (只支持 zip, tar.gz)", req.Format)
+	}
+
+	images, err := s.sessionImages(stream.Context(), req.ConversionId)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return status.Errorf(codes.FailedPrecondition, "转换没有输出文件: %s", req.ConversionId)
+	}
+
+	sender := newContextSender(stream.Context(), s.downloadIdleTimeout)
+	send := func(resp *proto.DownloadResponse) error {
+		return sender.send(func() error {
+			return stream.Send(resp)
+		})
+	}
+
+	if err := send(&proto.DownloadResponse{
+		Response: &proto.DownloadResponse_Info{Info: &proto.DownloadInfo{
+			Filename:    req.ConversionId + "." + format,
+			ContentType: contentType,
+		}},
+	}); err != nil {
+		return err
+	}
+
+	// 打包在单独的协程中写入管道，发送失败时关闭读取端使其停止
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeArchive(writer, format, images))
+	}()
+	defer reader.Close()
+
+	buffer := make([]byte, 64*1024)
+	for {
+		n, err := io.ReadFull(reader, buffer)
+		if n > 0 {
+			if err := send(&proto.DownloadResponse{
+				Response: &proto.DownloadResponse_Chunk{Chunk: buffer[:n]},
+			}); err != nil {
+				reader.CloseWithError(err)
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			s.logger.Errorf("打包转换 %s 的输出失败: %v", req.ConversionId, err)
+			return status.Errorf(codes.Internal, "打包失败: %v", err)
+		}
+	}
+
+	s.logger.Infof("已发送转换 %s 的 %s 压缩包 (%d 个文件)", req.ConversionId, format, len(images))
+	// 压缩包包含全部图片，全部记为已下载
+	for _, image := range images {
+		s.markDownloaded(image.DownloadID)
+	}
+	return nil
+}
+
+// writeArchive 按格式把图片依次写入压缩包，重名的文件只保留第一个
+func writeArchive(w io.Writer, format string, images []converter.ImageInfo) error {
+	seen := make(map[string]bool, len(images))
+	var unique []converter.ImageInfo
+	for _, image := range images {
+		if !seen[image.Filename] {
+			seen[image.Filename] = true
+			unique = append(unique, image)
+		}
+	}
+
+	if format == archiveTarGz {
+		return writeTarGz(w, unique)
+	}
+	return writeZip(w, unique)
+}
+
+// writeZip 写入zip压缩包，PNG和JPEG已经压缩过，直接存储以节省CPU
+func writeZip(w io.Writer, images []converter.ImageInfo) error {
+	archive := zip.NewWriter(w)
+	for _, image := range images {
+		err := addArchiveFile(image, func(info os.FileInfo) (io.Writer, error) {
+			header, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return nil, err
+			}
+			header.Name = image.Filename
+			header.Method = zip.Deflate
+			if image.Format == "PNG" || image.Format == "JPEG" {
+				header.Method = zip.Store
+			}
+			return archive.CreateHeader(header)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// writeTarGz 写入gzip压缩的tar包
+func writeTarGz(w io.Writer, images []converter.ImageInfo) error {
+	compressed := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressed)
+	for _, image := range images {
+		err := addArchiveFile(image, func(info os.FileInfo) (io.Writer, error) {
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return nil, err
+			}
+			header.Name = image.Filename
+			return archive, archive.WriteHeader(header)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+// addArchiveFile 打开图片文件，由 create 写入条目头后复制文件内容
+func addArchiveFile(image converter.ImageInfo, create func(info os.FileInfo) (io.Writer, error)) error {
+	file, err := os.Open(image.FilePath)
+	if err != nil {
+		return fmt.Errorf("打开 %s 失败: %v", image.Filename, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("读取 %s 的文件信息失败: %v", image.Filename, err)
+	}
+
+	dst, err := create(info)
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", image.Filename, err)
+	}
+	if _, err := io.Copy(dst, file); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", image.Filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"ppt-to-images-service/internal/converter"
+)
+
+func TestWriteArchive(t *testing.T) {
+	dir := t.TempDir()
+	contents := map[string]string{
+		"slide_001.png": "png data",
+		"slide_002.jpg": "jpeg data",
+		"index.html":    "<html></html>",
+	}
+	var images []converter.ImageInfo
+	for _, name := range []string{"slide_001.png", "slide_002.jpg", "index.html", "slide_001.png"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		images = append(images, converter.ImageInfo{Filename: name, FilePath: path})
+	}
+
+	for _, format := range []string{archiveZip, archiveTarGz} {
+		var buf bytes.Buffer
+		if err := writeArchive(&buf, format, images); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		got := make(map[string]string)
+		if format == archiveZip {
+			reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, file := range reader.File {
+				rc, err := file.Open()
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, _ := io.ReadAll(rc)
+				rc.Close()
+				got[file.Name] = string(data)
+			}
+		} else {
+			compressed, err := gzip.NewReader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader := tar.NewReader(compressed)
+			for {
+				header, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, _ := io.ReadAll(reader)
+				got[header.Name] = string(data)
+			}
+		}
+
+		// 重名的文件只保留一个
+		if !reflect.DeepEqual(got, contents) {
+			t.Errorf("%s 压缩包内容为 %v", format, got)
+		}
+	}
+}
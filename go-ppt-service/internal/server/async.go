@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+const (
+	// defaultQueueSize 默认异步任务队列长度
+	defaultQueueSize = 100
+	// defaultWorkers 默认处理异步任务的协程数量
+	defaultWorkers = 2
+)
+
+// conversionJob 排队等待处理的异步转换任务
+type conversionJob struct {
+	session  *ConversionSession
+	req      *proto.ConvertPPTRequest
+	priority jobPriority
+	enqueued time.Time // 进入队列的时间，用于老化
+}
+
+// jobPriorities 请求中的优先级对应的队列优先级
+var jobPriorities = map[proto.Priority]jobPriority{
+	proto.Priority_HIGH:   priorityHigh,
+	proto.Priority_NORMAL: priorityNormal,
+	proto.Priority_LOW:    priorityLow,
+}
+
+// SubmitConversion 异步提交转换任务，立即返回转换ID
+// 客户端随后通过 GetConversionStatus 轮询状态 (或通过 WatchConversion 订阅)，完成后通过 ListImages 获取图片列表
+func (s *GRPCServer) SubmitConversion(ctx context.Context, req *proto.ConvertPPTRequest) (*proto.SubmitConversionResponse, error) {
+	// source_url 在提交时同步下载，下载失败时直接返回错误
+	if err := s.resolveSource(ctx, req); err != nil {
+		return nil, err
+	}
+	if err := s.validateConvertRequest(req); err != nil {
+		return nil, err
+	}
+
+	session := s.createSession()
+	session.Tenant = tenantFromContext(ctx)
+	session.setStatus(converter.ConversionStatus{
+		Status:  "queued",
+		Message: "等待处理...",
+	})
+
+	job := &conversionJob{session: session, req: req, priority: jobPriorities[req.Priority]}
+	if err := s.pool.Submit(job); err != nil {
+		s.removeSession(session.ID)
+		if err == errPoolClosed {
+			return nil, status.Errorf(codes.Unavailable, "服务正在关闭，不再接受新的转换任务")
+		}
+		return nil, status.Errorf(codes.ResourceExhausted, "转换队列已满 (%d)，请稍后重试", s.pool.QueueSize())
+	}
+
+	s.logger.Infof("转换任务已排队: %s (ID: %s, 优先级: %s)", req.Filename, session.ID, req.Priority)
+
+	session.Mutex.RLock()
+	defer session.Mutex.RUnlock()
+
+	return &proto.SubmitConversionResponse{
+		ConversionId: session.ID,
+		Status:       s.convertStatusToProto(session.Status),
+	}, nil
+}
+
+// ListImages 列出转换完成后生成的图片
+func (s *GRPCServer) ListImages(ctx context.Context, req *proto.ListImagesRequest) (*proto.ListImagesResponse, error) {
+	images, err := s.sessionImages(ctx, req.ConversionId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &proto.ListImagesResponse{}
+	for _, image := range images {
+		response.Images = append(response.Images, s.convertImageInfoToProto(image))
+	}
+
+	return response, nil
+}
+
+// sessionImages 已结束转换的所有输出文件，会话不存在时返回 NotFound，尚未完成时返回 FailedPrecondition
+func (s *GRPCServer) sessionImages(ctx context.Context, conversionID string) ([]converter.ImageInfo, error) {
+	session, err := s.findSession(ctx, conversionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.Mutex.RLock()
+	defer session.Mutex.RUnlock()
+
+	if session.Result == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "转换尚未完成: %s (%s)", conversionID, session.Status.Status)
+	}
+	return session.Result.Images, nil
+}
+
+// processJob 执行排队的转换任务，结果保存在会话中供后续查询
+func (s *GRPCServer) processJob(job *conversionJob) {
+	defer s.recoverJob(job)
+	s.logger.Infof("开始处理排队的转换任务: %s (ID: %s)", job.req.Filename, job.session.ID)
+
+	// 异步任务没有等待结果的客户端，只随服务关闭取消
+	s.runConversion(context.Background(), job.session, job.req, job.session.setStatus, nil)
+
+	s.logger.Infof("排队的转换任务完成: %s (ID: %s)", job.req.Filename, job.session.ID)
+}
+
+// cancelJob 服务关闭时将尚未开始的任务标记为失败
+func (s *GRPCServer) cancelJob(job *conversionJob) {
+	s.logger.Warnf("服务关闭，取消排队的转换任务: %s (ID: %s)", job.req.Filename, job.session.ID)
+
+	job.session.Mutex.Lock()
+	job.session.Status = converter.ConversionStatus{
+		Status:  "failed",
+		Message: "服务关闭，任务已取消",
+	}
+	job.session.Result = &converter.ConversionResult{
+		Success: false,
+		Message: "服务关闭，任务已取消",
+		Error:   "服务关闭，任务已取消",
+	}
+	now := time.Now()
+	job.session.EndTime = &now
+	job.session.closeSubscribers()
+	job.session.Mutex.Unlock()
+
+	s.notifyConversion(job.session, job.req.Filename)
+}
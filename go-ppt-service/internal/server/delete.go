@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// DeleteConversion 删除已结束转换的输出目录、下载ID和会话
+// 与后台清理共用 cleanupMutex，两者不会同时删除同一目录
+func (s *GRPCServer) DeleteConversion(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	s.cleanupMutex.Lock()
+	defer s.cleanupMutex.Unlock()
+
+	s.conversionsMutex.Lock()
+	session, exists := s.conversions[req.ConversionId]
+	if !exists || session.Tenant != tenantFromContext(ctx) {
+		s.conversionsMutex.Unlock()
+		return nil, status.Errorf(codes.NotFound, "转换会话不存在: %s", req.ConversionId)
+	}
+
+	session.Mutex.RLock()
+	ended := session.EndTime != nil
+	state := session.Status.Status
+	result := session.Result
+	session.Mutex.RUnlock()
+
+	if !ended {
+		s.conversionsMutex.Unlock()
+		return nil, status.Errorf(codes.FailedPrecondition, "转换尚未结束: %s (%s)", req.ConversionId, state)
+	}
+	delete(s.conversions, req.ConversionId)
+	s.conversionsMutex.Unlock()
+
+	deleted, err := s.removeConversionOutput(req.ConversionId, result)
+	if err != nil {
+		s.logger.Warnf("删除输出目录失败: %v", err)
+		return nil, status.Errorf(codes.Internal, "删除输出目录失败: %v", err)
+	}
+
+	s.logger.Infof("已删除转换: %s (%d 张图片)", req.ConversionId, deleted)
+	return &proto.DeleteResponse{DeletedImages: int32(deleted)}, nil
+}
+
+// removeConversionOutput 删除转换的输出目录 (以及保留的上传文件) 和下载ID，返回删除前仍存在的图片数量
+// 调用方需持有 cleanupMutex
+func (s *GRPCServer) removeConversionOutput(conversionID string, result *converter.ConversionResult) (int, error) {
+	// 所有图片位于同一输出目录，按图片路径找到需要删除的目录
+	dirs := make(map[string]bool)
+	deleted := 0
+	s.downloadsMutex.Lock()
+	delete(s.downloadTrackers, conversionID)
+	if result != nil {
+		for _, image := range result.Images {
+			delete(s.downloads, image.DownloadID)
+			dirs[filepath.Dir(image.FilePath)] = true
+			if _, err := os.Stat(image.FilePath); err == nil {
+				deleted++
+			}
+		}
+	}
+	s.downloadsMutex.Unlock()
+	if s.keepUploads {
+		dirs[filepath.Join(s.outputDir, uploadsDirName, conversionID)] = true
+	}
+
+	root := filepath.Clean(s.outputDir)
+	for dir := range dirs {
+		// 只删除输出目录下的子目录
+		if rel, err := filepath.Rel(root, dir); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if err := s.removeOutputDir(dir); err != nil {
+			return deleted, err
+		}
+	}
+	// Transcode 生成的图片位于输出目录的子目录中，随目录一起删除
+	s.pruneDownloads()
+	return deleted, nil
+}
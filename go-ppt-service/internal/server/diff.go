@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/disintegration/imaging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+const (
+	// 比较时默认的渲染尺寸，足以区分文字改动，又比全高清快
+	defaultDiffWidth  = 1280
+	defaultDiffHeight = 720
+)
+
+// DiffPresentations 分别渲染两个版本，按幻灯片编号比较图片内容
+func (s *GRPCServer) DiffPresentations(ctx context.Context, req *proto.DiffRequest) (*proto.DiffResponse, error) {
+	width, height := req.Width, req.Height
+	if width <= 0 || height <= 0 {
+		width, height = defaultDiffWidth, defaultDiffHeight
+	}
+
+	oldReq := &proto.ConvertPPTRequest{Filename: req.OldFilename, PptData: req.OldData, Width: width, Height: height, OutputFormat: "PNG"}
+	newReq := &proto.ConvertPPTRequest{Filename: req.NewFilename, PptData: req.NewData, Width: width, Height: height, OutputFormat: "PNG"}
+	for _, r := range []*proto.ConvertPPTRequest{oldReq, newReq} {
+		if err := s.validateConvertRequest(r); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.Infof("开始比较演示文稿: %s -> %s", req.OldFilename, req.NewFilename)
+
+	oldHashes, err := s.slideHashes(ctx, oldReq)
+	if err != nil {
+		return nil, err
+	}
+	newHashes, err := s.slideHashes(ctx, newReq)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &proto.DiffResponse{
+		OldSlides: int32(len(oldHashes)),
+		NewSlides: int32(len(newHashes)),
+	}
+	for number, newHash := range newHashes {
+		oldHash, exists := oldHashes[number]
+		switch {
+		case !exists:
+			response.Added = append(response.Added, int32(number))
+		case oldHash != newHash:
+			response.Changed = append(response.Changed, int32(number))
+		default:
+			response.Unchanged = append(response.Unchanged, int32(number))
+		}
+	}
+	for number := range oldHashes {
+		if _, exists := newHashes[number]; !exists {
+			response.Removed = append(response.Removed, int32(number))
+		}
+	}
+	for _, numbers := range [][]int32{response.Changed, response.Added, response.Removed, response.Unchanged} {
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	}
+
+	s.logger.Infof("比较完成: 修改 %d, 新增 %d, 删除 %d", len(response.Changed), len(response.Added), len(response.Removed))
+	return response, nil
+}
+
+// slideHashes 渲染演示文稿并计算每张幻灯片像素数据的哈希，渲染结果用完即删除
+func (s *GRPCServer) slideHashes(ctx context.Context, req *proto.ConvertPPTRequest) (map[int][32]byte, error) {
+	// 比较需要完整的渲染结果，超时时不保留部分输出
+	options := s.conversionOptionsFromRequest(req)
+	options.ReturnPartial = false
+	result, err := s.convert(ctx, req.PptData, req.Filename, options, nil)
+	if err != nil {
+		return nil, status.Errorf(conversionErrorCode(err), "渲染 %s 失败: %v", req.Filename, err)
+	}
+	if len(result.Images) > 0 {
+		dir := filepath.Dir(result.Images[0].FilePath)
+		defer func() {
+			s.cleanupMutex.Lock()
+			defer s.cleanupMutex.Unlock()
+			if err := s.removeOutputDir(dir); err != nil {
+				s.logger.Warnf("删除比较用的渲染结果失败: %v", err)
+			}
+		}()
+	}
+
+	hashes := make(map[int][32]byte, len(result.Images))
+	for _, image := range result.Images {
+		hash, err := imageHash(image)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "读取第 %d 张幻灯片失败: %v", image.SlideNumber, err)
+		}
+		hashes[image.SlideNumber] = hash
+	}
+	return hashes, nil
+}
+
+// imageHash 对解码后的像素计算哈希，不受图片编码细节影响
+func imageHash(info converter.ImageInfo) ([32]byte, error) {
+	img, err := imaging.Open(info.FilePath)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("打开图片失败: %v", err)
+	}
+	return sha256.Sum256(imaging.Clone(img).Pix), nil
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	"os"
+)
+
+// writeProbePattern 检查目录是否可写时创建的探测文件名
+const writeProbePattern = ".write-probe-*"
+
+// PrepareDirs 创建输出目录和临时目录，并确认两者都可写
+// 只读的目录在启动时报错，而不是等到第一次转换才以难以理解的错误失败
+func PrepareDirs(outputDir, tempDir string) error {
+	if err := prepareDir(outputDir); err != nil {
+		return fmt.Errorf("输出目录不可用: %v", err)
+	}
+	if err := prepareDir(tempDir); err != nil {
+		return fmt.Errorf("临时目录不可用: %v", err)
+	}
+	return nil
+}
+
+// prepareDir 创建目录，并写入、删除一个探测文件确认可写
+func prepareDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	probe, err := os.CreateTemp(dir, writeProbePattern)
+	if err != nil {
+		return fmt.Errorf("%s 不可写: %v", dir, err)
+	}
+	_, writeErr := probe.Write([]byte("ok"))
+	closeErr := probe.Close()
+	os.Remove(probe.Name())
+
+	if writeErr != nil {
+		return fmt.Errorf("%s 不可写: %v", dir, writeErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("%s 不可写: %v", dir, closeErr)
+	}
+	return nil
+}
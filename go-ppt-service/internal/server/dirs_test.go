@@ -0,0 +1,100 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// dirEntries 目录中的文件名
+func dirEntries(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestPrepareDirs(t *testing.T) {
+	root := t.TempDir()
+	outputDir := filepath.Join(root, "output", "nested")
+	tempDir := filepath.Join(root, "temp")
+
+	if err := PrepareDirs(outputDir, tempDir); err != nil {
+		t.Fatal(err)
+	}
+	// 目录已创建，探测文件已删除
+	for _, dir := range []string{outputDir, tempDir} {
+		if names := dirEntries(t, dir); len(names) != 0 {
+			t.Errorf("%s 中残留了 %v", dir, names)
+		}
+	}
+}
+
+func TestPrepareDirsReadOnly(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root不受目录权限限制")
+	}
+
+	tests := []struct {
+		name     string
+		readOnly string // 设为只读的目录: output 或 temp
+		message  string
+	}{
+		{"输出目录", "output", "输出目录不可用"},
+		{"临时目录", "temp", "临时目录不可用"},
+	}
+
+	for _, tt := range tests {
+		root := t.TempDir()
+		dirs := map[string]string{"output": filepath.Join(root, "output"), "temp": filepath.Join(root, "temp")}
+		for _, dir := range dirs {
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		readOnly := dirs[tt.readOnly]
+		if err := os.Chmod(readOnly, 0500); err != nil {
+			t.Fatal(err)
+		}
+		// 恢复权限，t.TempDir 才能删除目录
+		defer os.Chmod(readOnly, 0755)
+
+		err := PrepareDirs(dirs["output"], dirs["temp"])
+		if err == nil {
+			t.Fatalf("%s只读时应返回错误", tt.name)
+		}
+		if !strings.Contains(err.Error(), tt.message) || !strings.Contains(err.Error(), readOnly) {
+			t.Errorf("%s只读时的错误为 %q，应说明哪个目录不可写", tt.name, err)
+		}
+		for _, dir := range dirs {
+			if names := dirEntries(t, dir); len(names) != 0 {
+				t.Errorf("%s 中残留了 %v", dir, names)
+			}
+		}
+	}
+}
+
+func TestPrepareDirsNotDirectory(t *testing.T) {
+	// 路径已被普通文件占用时无法创建目录 (root也一样)
+	root := t.TempDir()
+	file := filepath.Join(root, "output")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := PrepareDirs(file, filepath.Join(root, "temp"))
+	if err == nil || !strings.Contains(err.Error(), "输出目录不可用") {
+		t.Errorf("输出路径是文件时的错误为 %v", err)
+	}
+	if names := dirEntries(t, root); len(names) != 1 {
+		t.Errorf("失败后 %s 中有 %v，不应创建其他文件", root, names)
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"ppt-to-images-service/internal/converter"
+)
+
+// downloadTracker 启用下载后清理时一个转换的下载记录，与下载ID一起登记在服务器上，不依赖会话是否仍然存在
+// 图片边渲染边发送，转换结束前完成的下载先记入 downloaded；转换成功结束后只等待其余的图片
+type downloadTracker struct {
+	result     *converter.ConversionResult // 转换结束后的结果，转换进行中为nil
+	pending    map[string]bool             // 尚未完整下载的图片 (下载ID)
+	downloaded map[string]bool             // 转换结束前已完整下载的图片 (下载ID)
+}
+
+// trackDownloads 转换结束时调用，调用方需持有 session.Mutex 且尚未设置 EndTime
+// 成功时记录尚未下载的图片，全部下载后由 markDownloaded 删除输出目录；失败时丢弃记录，输出由 -output-ttl 清理
+// 返回 true 表示图片在转换结束前已全部下载，调用方释放会话锁后应调用 removeDownloadedOutput
+func (s *GRPCServer) trackDownloads(conversionID string, result *converter.ConversionResult, success bool) bool {
+	if !s.cleanupAfterDownload {
+		return false
+	}
+
+	s.downloadsMutex.Lock()
+	defer s.downloadsMutex.Unlock()
+
+	tracker := s.downloadTrackers[conversionID]
+	delete(s.downloadTrackers, conversionID)
+	if !success || result == nil || len(result.Images) == 0 {
+		return false
+	}
+
+	pending := make(map[string]bool, len(result.Images))
+	for _, image := range result.Images {
+		if tracker == nil || !tracker.downloaded[image.DownloadID] {
+			pending[image.DownloadID] = true
+		}
+	}
+	if len(pending) == 0 {
+		return true
+	}
+	if s.downloadTrackers == nil {
+		s.downloadTrackers = make(map[string]*downloadTracker)
+	}
+	s.downloadTrackers[conversionID] = &downloadTracker{result: result, pending: pending}
+	return false
+}
+
+// markDownloaded 记录下载ID对应的图片已完整下载；所属转换的图片全部下载后删除其输出目录和下载ID，保留会话
+// 同一张图片多次下载只计一次；没有全部下载的输出由 -output-ttl 清理
+func (s *GRPCServer) markDownloaded(downloadID string) {
+	if !s.cleanupAfterDownload {
+		return
+	}
+
+	s.downloadsMutex.RLock()
+	entry, exists := s.downloads[downloadID]
+	s.downloadsMutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	// 转换是否仍在进行，决定下载先记入 downloaded 还是直接从 pending 中移除
+	s.conversionsMutex.RLock()
+	session := s.conversions[entry.conversionID]
+	s.conversionsMutex.RUnlock()
+	running := false
+	if session != nil {
+		session.Mutex.RLock()
+		running = session.EndTime == nil
+		session.Mutex.RUnlock()
+	}
+
+	s.downloadsMutex.Lock()
+	tracker := s.downloadTrackers[entry.conversionID]
+	if tracker == nil {
+		if running {
+			if s.downloadTrackers == nil {
+				s.downloadTrackers = make(map[string]*downloadTracker)
+			}
+			s.downloadTrackers[entry.conversionID] = &downloadTracker{downloaded: map[string]bool{downloadID: true}}
+		}
+		s.downloadsMutex.Unlock()
+		return
+	}
+	if tracker.result == nil {
+		tracker.downloaded[downloadID] = true
+		s.downloadsMutex.Unlock()
+		return
+	}
+	delete(tracker.pending, downloadID)
+	if len(tracker.pending) > 0 {
+		s.downloadsMutex.Unlock()
+		return
+	}
+	delete(s.downloadTrackers, entry.conversionID)
+	s.downloadsMutex.Unlock()
+
+	s.removeDownloadedOutput(entry.conversionID, tracker.result)
+}
+
+// removeDownloadedOutput 删除图片已全部下载的转换的输出目录和下载ID
+func (s *GRPCServer) removeDownloadedOutput(conversionID string, result *converter.ConversionResult) {
+	s.cleanupMutex.Lock()
+	defer s.cleanupMutex.Unlock()
+
+	// 等待 cleanupMutex 期间输出可能已被 DeleteConversion 或后台清理删除，下载ID随之移除
+	s.downloadsMutex.RLock()
+	registered := false
+	for _, image := range result.Images {
+		if _, exists := s.downloads[image.DownloadID]; exists {
+			registered = true
+			break
+		}
+	}
+	s.downloadsMutex.RUnlock()
+	if !registered {
+		return
+	}
+
+	deleted, err := s.removeConversionOutput(conversionID, result)
+	if err != nil {
+		s.logger.Warnf("删除已下载的输出目录失败 (ID: %s): %v", conversionID, err)
+		return
+	}
+	s.logger.Infof("转换 %s 的 %d 张图片已全部下载，已删除输出目录", conversionID, deleted)
+}
+
+// replace RetrySlide 替换图片后更新等待下载的图片，调用方需持有 downloadsMutex
+func (t *downloadTracker) replace(result *converter.ConversionResult, old, images []converter.ImageInfo) {
+	t.result = result
+	for _, image := range old {
+		delete(t.pending, image.DownloadID)
+	}
+	for _, image := range images {
+		t.pending[image.DownloadID] = true
+	}
+}
+
+// registered 记录中的图片是否仍全部登记了下载ID
+func (t *downloadTracker) registered(downloads map[string]downloadEntry) bool {
+	for _, ids := range []map[string]bool{t.pending, t.downloaded} {
+		for downloadID := range ids {
+			if _, exists := downloads[downloadID]; !exists {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/proto"
+)
+
+// convertForCleanup 在启用下载后清理的服务器上用 ConvertPPT 转换一个两页的演示文稿，返回转换结果和输出目录
+func convertForCleanup(t *testing.T, s *GRPCServer, stream *recordingStream) (*proto.ConversionResult, string) {
+	t.Helper()
+
+	if err := s.ConvertPPT(&proto.ConvertPPTRequest{Filename: "deck.pptx", PptData: selfTestDeck}, stream); err != nil {
+		t.Fatal(err)
+	}
+	result := stream.result()
+	if result == nil || !result.Success || len(result.Images) != 2 {
+		t.Fatalf("转换结果为 %+v", result)
+	}
+	// pngConverter 把图片写入会话的输出子目录
+	return result, filepath.Join(s.outputDir, s.outputSubdir(s.conversions[onlySessionID(t, s)]))
+}
+
+func newDownloadCleanupServer(t *testing.T) *GRPCServer {
+	s := newTranscodeServer(t)
+	s.cleanupAfterDownload = true
+	return s
+}
+
+func downloadForTest(s *GRPCServer, downloadID string) error {
+	return s.DownloadImage(&proto.DownloadRequest{DownloadId: downloadID}, &selfTestDownloadStream{ctx: context.Background()})
+}
+
+func TestCleanupAfterDownload(t *testing.T) {
+	s := newDownloadCleanupServer(t)
+	result, dir := convertForCleanup(t, s, &recordingStream{ctx: context.Background()})
+	images := result.Images
+
+	// 重复下载同一张图片只计一次
+	for i := 0; i < 2; i++ {
+		if err := downloadForTest(s, images[0].DownloadId); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("还有图片没有下载时输出目录已被删除: %v", err)
+	}
+
+	if err := downloadForTest(s, images[1].DownloadId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("全部下载后输出目录仍然存在: %v", err)
+	}
+	if err := downloadForTest(s, images[0].DownloadId); status.Code(err) != codes.NotFound {
+		t.Errorf("清理后下载的错误为 %v，应为 NotFound", err)
+	}
+	if len(s.downloadTrackers) != 0 {
+		t.Errorf("清理后仍有下载记录: %v", s.downloadTrackers)
+	}
+	// 会话保留，仍可查询状态
+	if _, err := s.GetConversionStatus(context.Background(), &proto.StatusRequest{ConversionId: onlySessionID(t, s)}); err != nil {
+		t.Errorf("清理后查询状态失败: %v", err)
+	}
+}
+
+func TestCleanupAfterDownloadDuringConversion(t *testing.T) {
+	s := newDownloadCleanupServer(t)
+
+	// 客户端收到图片信息就立即下载，下载在转换结束之前完成
+	stream := &recordingStream{ctx: context.Background()}
+	stream.onImage = func(info *proto.ImageInfo) {
+		if err := downloadForTest(s, info.DownloadId); err != nil {
+			t.Errorf("转换过程中下载失败: %v", err)
+		}
+	}
+	_, dir := convertForCleanup(t, s, stream)
+
+	// 结果中的图片在转换结束前已全部下载，结束时即删除输出目录
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("全部下载后输出目录仍然存在: %v", err)
+	}
+	if len(s.downloadTrackers) != 0 {
+		t.Errorf("清理后仍有下载记录: %v", s.downloadTrackers)
+	}
+}
+
+func TestCleanupAfterPartialDownloadFallsBackToTTL(t *testing.T) {
+	s := newDownloadCleanupServer(t)
+	result, dir := convertForCleanup(t, s, &recordingStream{ctx: context.Background()})
+	images := result.Images
+
+	if err := downloadForTest(s, images[0].DownloadId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("只下载了部分图片时输出目录已被删除: %v", err)
+	}
+
+	// 没有全部下载的输出由后台清理按保留时间删除
+	s.prune(time.Now().Add(time.Hour))
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("超过保留时间后输出目录仍然存在: %v", err)
+	}
+	if err := downloadForTest(s, images[1].DownloadId); status.Code(err) != codes.NotFound {
+		t.Errorf("清理后下载的错误为 %v，应为 NotFound", err)
+	}
+	if len(s.downloadTrackers) != 0 {
+		t.Errorf("清理后仍有下载记录: %v", s.downloadTrackers)
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/proto"
+)
+
+// defaultFetchTimeout 下载 source_url 的默认超时时间
+const defaultFetchTimeout = 30 * time.Second
+
+// maxFetchRedirects 下载 source_url 时最多跟随的重定向次数
+const maxFetchRedirects = 5
+
+// ParseFetchHosts 解析逗号分隔的主机名列表，忽略空项并统一为小写
+func ParseFetchHosts(value string) []string {
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// fetchHostAllowed 主机名是否在允许列表中，"*.example.com" 匹配 example.com 的所有子域名
+func (s *GRPCServer) fetchHostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range s.fetchHosts {
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSourceURL 校验 source_url 的格式和主机
+func (s *GRPCServer) checkSourceURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "无效的source_url: %s", rawURL)
+	}
+	if !s.fetchHostAllowed(u.Hostname()) {
+		return nil, status.Errorf(codes.InvalidArgument, "不允许从该主机下载: %s (见 -allowed-fetch-hosts)", u.Hostname())
+	}
+	return u, nil
+}
+
+// resolveSource 请求指定了 source_url 时下载演示文稿并填入 ppt_data，未指定时不做任何处理
+// 下载受 -max-upload-size 和 -fetch-timeout 限制，重定向的目标主机同样需要在允许列表中
+func (s *GRPCServer) resolveSource(ctx context.Context, req *proto.ConvertPPTRequest) error {
+	if req.SourceUrl == "" {
+		return nil
+	}
+	if len(req.PptData) > 0 {
+		return status.Error(codes.InvalidArgument, "ppt_data 和 source_url 只能指定一个")
+	}
+
+	u, err := s.checkSourceURL(req.SourceUrl)
+	if err != nil {
+		return err
+	}
+	// 没有指定文件名时使用URL路径的最后一段，没有扩展名时下载后再按内容确定
+	urlFilename := req.Filename == ""
+	if urlFilename {
+		req.Filename = path.Base(u.Path)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.fetchTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "无效的source_url: %v", err)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(next *http.Request, via []*http.Request) error {
+			if len(via) >= maxFetchRedirects {
+				return fmt.Errorf("重定向次数过多")
+			}
+			if _, err := s.checkSourceURL(next.URL.String()); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	s.logger.Infof("开始下载演示文稿: %s", u.Redacted())
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return s.fetchError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		code := codes.Unavailable
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			code = codes.InvalidArgument
+		}
+		return status.Errorf(code, "下载演示文稿失败: HTTP %d", resp.StatusCode)
+	}
+
+	// 多读一个字节以判断是否超过上限
+	body := io.Reader(resp.Body)
+	if s.maxUploadSize > 0 {
+		if resp.ContentLength > s.maxUploadSize {
+			return status.Errorf(codes.InvalidArgument, "文件大小 %d 字节超过上限 %d 字节", resp.ContentLength, s.maxUploadSize)
+		}
+		body = io.LimitReader(resp.Body, s.maxUploadSize+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return s.fetchError(err)
+	}
+	if s.maxUploadSize > 0 && int64(len(data)) > s.maxUploadSize {
+		return status.Errorf(codes.InvalidArgument, "文件大小超过上限 %d 字节", s.maxUploadSize)
+	}
+
+	if urlFilename {
+		filename, err := sourceFilename(req.Filename, resp.Header.Get("Content-Type"), data)
+		if err != nil {
+			return err
+		}
+		req.Filename = filename
+	}
+
+	s.logger.Infof("演示文稿下载完成: %s (%d 字节)", u.Redacted(), len(data))
+	req.PptData = data
+	return nil
+}
+
+// presentationContentTypes 演示文稿的 Content-Type 对应的扩展名
+var presentationContentTypes = map[string]string{
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+	"application/vnd.ms-powerpoint":                                             ".ppt",
+	"application/vnd.oasis.opendocument.presentation":                           ".odp",
+	"application/vnd.apple.keynote":                                             ".key",
+}
+
+// sourceFilename 从 source_url 取得的文件名，没有扩展名 (如 https://host/ 或 /download?id=1) 时
+// 按响应的 Content-Type，再按文件头补上扩展名；都无法确定时返回 InvalidArgument
+func sourceFilename(base, contentType string, data []byte) (string, error) {
+	if base == "/" || base == "." {
+		base = "presentation"
+	}
+	if path.Ext(base) != "" {
+		return base, nil
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if ext, ok := presentationContentTypes[mediaType]; ok {
+			return base + ext, nil
+		}
+	}
+	switch {
+	case bytes.HasPrefix(data, pptMagic):
+		return base + ".ppt", nil
+	case bytes.HasPrefix(data, pptxMagic) && len(data) >= 30 && bytes.HasPrefix(data[30:], odpMimetype):
+		return base + ".odp", nil
+	case bytes.HasPrefix(data, pptxMagic):
+		return base + ".pptx", nil
+	}
+	return "", status.Errorf(codes.InvalidArgument, "无法从source_url确定文件类型 (Content-Type: %q)，请通过 filename 指定带扩展名的文件名", contentType)
+}
+
+// fetchError 下载错误对应的gRPC状态: 超时为 DeadlineExceeded，重定向到不允许的主机为 InvalidArgument，其余为 Unavailable
+func (s *GRPCServer) fetchError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err) {
+		return status.Errorf(codes.DeadlineExceeded, "下载演示文稿超时 (%v)", s.fetchTimeout)
+	}
+	// CheckRedirect 返回的状态错误被包装在 url.Error 中
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Err != nil {
+		if st, ok := status.FromError(urlErr.Err); ok {
+			return st.Err()
+		}
+	}
+	return status.Errorf(codes.Unavailable, "下载演示文稿失败: %v", err)
+}
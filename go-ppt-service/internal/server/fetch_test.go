@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/proto"
+)
+
+func TestSourceFilename(t *testing.T) {
+	tests := []struct {
+		base        string
+		contentType string
+		data        []byte
+		want        string // 为空表示应返回 InvalidArgument
+	}{
+		{"deck.pptx", "application/octet-stream", nil, "deck.pptx"},
+		{"/", "application/vnd.openxmlformats-officedocument.presentationml.presentation", nil, "presentation.pptx"},
+		{".", "application/vnd.ms-powerpoint; charset=binary", nil, "presentation.ppt"},
+		{"download", "application/octet-stream", pptMagic, "download.ppt"},
+		{"download", "", append(append([]byte{}, pptxMagic...), make([]byte, 26)...), "download.pptx"},
+		{"download", "", append(append(append([]byte{}, pptxMagic...), make([]byte, 26)...), odpMimetype...), "download.odp"},
+		{"/", "text/html", []byte("<html>"), ""},
+	}
+
+	for _, tt := range tests {
+		got, err := sourceFilename(tt.base, tt.contentType, tt.data)
+		if tt.want == "" {
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("%q (%s): 文件名 %q，错误 %v，应返回 InvalidArgument", tt.base, tt.contentType, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q (%s): 文件名 %q，错误 %v，应为 %q", tt.base, tt.contentType, got, err, tt.want)
+		}
+	}
+}
+
+func TestResolveSourceWithoutFilename(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(selfTestDeck)
+	}))
+	defer server.Close()
+
+	s := &GRPCServer{logger: logrus.New(), fetchHosts: []string{"127.0.0.1"}, fetchTimeout: 5 * time.Second}
+	s.logger.SetOutput(io.Discard)
+
+	// URL路径为根目录时按文件头确定扩展名
+	req := &proto.ConvertPPTRequest{SourceUrl: server.URL + "/"}
+	if err := s.resolveSource(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Filename != "presentation.pptx" || len(req.PptData) != len(selfTestDeck) {
+		t.Errorf("文件名为 %q，数据 %d 字节", req.Filename, len(req.PptData))
+	}
+}
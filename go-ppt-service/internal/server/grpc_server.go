@@ -2,135 +2,393 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/internal/jpegenc"
 	"ppt-to-images-service/proto"
 )
 
+// 未配置 Config.DefaultWidth 等时，请求不指定尺寸和格式使用的默认值
+const (
+	defaultOutputWidth  = 1920
+	defaultOutputHeight = 1080
+	defaultOutputFormat = "PNG"
+)
+
 // GRPCServer gRPC服务器
 type GRPCServer struct {
 	proto.UnimplementedPPTToImagesServiceServer
-	converter    *converter.PPTConverter
+	converter            converter.Converter
 	logger       *logrus.Logger
 	conversions  map[string]*ConversionSession
 	conversionsMutex sync.RWMutex
+	downloads            map[string]downloadEntry // 下载ID -> 文件及其所属的转换
+	downloadsMutex       sync.RWMutex
+	downloadTrackers     map[string]*downloadTracker // 启用下载后清理时各转换的下载记录，由 downloadsMutex 保护
 	outputDir    string
 	tempDir      string
+	maxUploadSize        int64
+	maxPixels            int64
+	pool                 *WorkerPool // 异步转换任务工作池
+	webhook              *webhookNotifier
+	convSlots            chan struct{} // 限制同时执行的转换数量，为nil时不限制
+	outputLayout         string        // 输出子目录模板，为空时由转换器生成目录名
+	keepUploads          bool          // 保留上传的文件，便于排查失败的转换
+	memory               *memoryBudget // 转换的内存预算，为nil时不限制
+	quota                *outputQuota  // 输出目录的总大小配额，为nil时不限制
+	outputTTL            time.Duration // 输出目录保留时间，0表示不清理
+	janitorStop          chan struct{} // 关闭时停止清理过期输出，为nil时未启用清理
+	cleanupMutex         sync.Mutex    // 后台清理与 DeleteConversion 互斥删除输出目录
+	fetchHosts           []string      // 允许下载 source_url 的主机，为空时不允许从URL读取
+	fetchTimeout         time.Duration // 下载 source_url 的超时时间
+	colorProfile         bool          // 是否配置了ICC配置文件，未配置时拒绝 embed_color_profile
+	maxSlides            int           // 单个演示文稿的最大幻灯片数量，0表示不限制
+	maxInlineSize        int64         // 一次转换内联返回的图片数据总大小上限
+	conversionTimeout    time.Duration // 单次转换的时间上限，0表示不限制
+	downloadIdleTimeout  time.Duration // 下载时客户端读取一块数据的最长等待时间，0表示不限制
+	cleanupAfterDownload bool          // 转换的图片全部下载后立即删除输出目录
+	defaultWidth         int           // 请求不指定尺寸时的输出宽度
+	defaultHeight        int           // 请求不指定尺寸时的输出高度
+	defaultFormat        string        // 请求不指定格式时的输出格式
+
+	ctx    context.Context    // 所有转换共用的根上下文，关闭服务时取消
+	cancel context.CancelFunc // 取消所有进行中的转换
+	active *activeConversions // 进行中的转换，关闭服务时等待它们结束
+}
+
+// downloadEntry 下载ID登记的文件及其所属的转换
+type downloadEntry struct {
+	path         string
+	conversionID string
+	tenant       string // 发起转换的请求携带的租户，未携带时为空
 }
 
 // ConversionSession 转换会话
 type ConversionSession struct {
 	ID        string
+	Tenant    string // 请求携带的租户，用于输出目录模板
 	Status    converter.ConversionStatus
 	Result    *converter.ConversionResult
 	StartTime time.Time
 	EndTime   *time.Time
 	Mutex     sync.RWMutex
+
+	subscribers []chan converter.ConversionStatus // WatchConversion 的订阅者
+	options     *converter.ConversionOptions      // 转换使用的选项，保留上传文件时记录，供 RetrySlide 使用
+}
+
+// Config 服务器配置
+type Config struct {
+	OutputDir            string            // 输出目录
+	TempDir              string            // 临时目录
+	MaxUploadSize        int64             // 上传文件大小上限 (字节)，0表示不限制
+	QueueSize            int               // 异步转换队列长度
+	Workers              int               // 处理异步转换任务的协程数量
+	QueueAging           time.Duration     // 排队任务每等待该时间有效优先级提高一级，0使用默认值30秒
+	WebhookURL           string            // 转换结束时POST事件的地址，为空时不发送
+	WebhookSecret        string            // webhook签名密钥，为空时不签名
+	MaxConversions       int               // 同时执行的转换数量上限，0表示不限制
+	OutputLayout         string            // 输出子目录模板，支持 {tenant}、{date}、{conversion_id}
+	OutputTTL            time.Duration     // 输出目录保留时间，超过后由后台清理，0表示不清理
+	KeepUploads          bool              // 转换结束后把上传的文件保留在 <OutputDir>/uploads/<转换ID>/ 中
+	MaxMemory            int64             // 同时进行的转换估算内存占用上限 (字节)，0表示不限制
+	AllowedFetchHosts    []string          // 允许下载 source_url 的主机名，支持 *.example.com，为空时不允许
+	FetchTimeout         time.Duration     // 下载 source_url 的超时时间，0使用默认值30秒
+	MaxInlineSize        int64             // 一次转换内联返回的图片数据总大小上限 (字节)，0使用默认值4MB
+	ConversionTimeout    time.Duration     // 单次转换的时间上限，请求的 timeout_seconds 不能超过该值，0表示不限制
+	MaxOutputBytes       int64             // 输出目录的总大小上限 (字节)，预计超过时拒绝新的转换，0表示不限制
+	DownloadIdleTimeout  time.Duration     // 下载时客户端读取一块数据的最长等待时间，超过后中止下载并关闭文件，0表示不限制
+	CleanupAfterDownload bool              // 成功转换的图片全部下载后立即删除输出目录，没有全部下载的由 OutputTTL 清理
+	DefaultWidth         int               // 请求不指定尺寸时的输出宽度，与 DefaultHeight 任一为0时使用1920x1080
+	DefaultHeight        int               // 请求不指定尺寸时的输出高度
+	DefaultFormat        string            // 请求不指定格式时的输出格式 (PNG, JPEG, AUTO)，为空时为PNG
+	Converter            converter.Options // 转换器选项
 }
 
 // NewGRPCServer 创建新的gRPC服务器
-func NewGRPCServer(outputDir, tempDir string, logger *logrus.Logger) *GRPCServer {
-	// 确保目录存在
-	os.MkdirAll(outputDir, 0755)
-	os.MkdirAll(tempDir, 0755)
+func NewGRPCServer(config Config, logger *logrus.Logger) *GRPCServer {
+	outputDir := config.OutputDir
+	tempDir := config.TempDir
+
+	// 确保目录存在且可写，main 中已在启动时检查过，这里只记录错误
+	if err := PrepareDirs(outputDir, tempDir); err != nil {
+		logger.Errorf("%v", err)
+	}
+
+	defaultWidth, defaultHeight := config.DefaultWidth, config.DefaultHeight
+	if defaultWidth <= 0 || defaultHeight <= 0 {
+		defaultWidth, defaultHeight = defaultOutputWidth, defaultOutputHeight
+	}
+	defaultFormat := strings.ToUpper(config.DefaultFormat)
+	if defaultFormat == "" {
+		defaultFormat = defaultOutputFormat
+	}
 
-	var pptConverter *converter.PPTConverter
-	
 	// 根据操作系统选择转换器
-	if runtime.GOOS == "windows" {
-		pptConverter = converter.NewWindowsPPTConverter(
-			outputDir,
-			tempDir,
-			1920,  // 默认宽度
-			1080,  // 默认高度
-			"PNG", // 默认格式
-			logger,
-		)
-	} else {
-		// 非Windows平台使用基础转换器
-		pptConverter = converter.NewPPTConverter(
-			outputDir,
-			tempDir,
-			1920,  // 默认宽度
-			1080,  // 默认高度
-			"PNG", // 默认格式
-			logger,
-		)
-	}
-
-	return &GRPCServer{
+	pptConverter := converter.NewPlatformConverter(
+		outputDir,
+		tempDir,
+		defaultWidth,
+		defaultHeight,
+		defaultFormat,
+		config.Converter,
+		logger,
+	)
+
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+
+	s := &GRPCServer{
 		converter:   pptConverter,
 		logger:      logger,
 		conversions: make(map[string]*ConversionSession),
+		downloads:   make(map[string]downloadEntry),
 		outputDir:   outputDir,
 		tempDir:     tempDir,
+
+		maxUploadSize: config.MaxUploadSize,
+		maxPixels:     config.Converter.MaxPixels,
+		colorProfile:  config.Converter.ICCProfile != "",
+		maxSlides:     config.Converter.MaxSlides,
+		defaultWidth:  defaultWidth,
+		defaultHeight: defaultHeight,
+		defaultFormat: defaultFormat,
+		webhook:       newWebhookNotifier(config.WebhookURL, config.WebhookSecret, logger),
+	}
+
+	workers := config.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if config.MaxConversions > 0 {
+		s.convSlots = make(chan struct{}, config.MaxConversions)
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.active = newActiveConversions()
+	s.pool = NewWorkerPool(workers, queueSize, config.QueueAging, s.processJob, s.cancelJob, logger)
+
+	s.outputLayout = config.OutputLayout
+	s.keepUploads = config.KeepUploads
+	s.memory = newMemoryBudget(config.MaxMemory)
+	s.quota = newOutputQuota(config.MaxOutputBytes, outputDir)
+	s.outputTTL = config.OutputTTL
+	s.fetchHosts = config.AllowedFetchHosts
+	s.fetchTimeout = config.FetchTimeout
+	s.maxInlineSize = config.MaxInlineSize
+	s.conversionTimeout = config.ConversionTimeout
+	s.downloadIdleTimeout = config.DownloadIdleTimeout
+	s.cleanupAfterDownload = config.CleanupAfterDownload
+	if s.maxInlineSize <= 0 {
+		s.maxInlineSize = defaultMaxInlineSize
+	}
+	if s.fetchTimeout <= 0 {
+		s.fetchTimeout = defaultFetchTimeout
+	}
+	if config.OutputTTL > 0 {
+		s.janitorStop = make(chan struct{})
+		go s.runJanitor(config.OutputTTL, s.janitorStop)
 	}
+
+	return s
+}
+
+// outputSize 请求的输出尺寸，宽高任一未指定 (为0) 时与转换器一样使用服务器的默认尺寸
+func (s *GRPCServer) outputSize(width, height int) (int, int) {
+	if width <= 0 || height <= 0 {
+		return s.defaultWidth, s.defaultHeight
+	}
+	return width, height
+}
+
+// Close 释放服务器持有的资源 (如常驻的转换进程)
+func (s *GRPCServer) Close() error {
+	if s.janitorStop != nil {
+		close(s.janitorStop)
+	}
+	return s.converter.Close()
 }
 
 // ConvertPPT 转换PPT文件 (流式响应)
 func (s *GRPCServer) ConvertPPT(req *proto.ConvertPPTRequest, stream proto.PPTToImagesService_ConvertPPTServer) error {
-	// 生成转换ID
-	conversionID := generateConversionID()
-	
-	s.logger.Infof("开始处理转换请求: %s (ID: %s)", req.Filename, conversionID)
+	// 客户端不读取响应时发送不会无限阻塞转换
+	stream = convertPPTStream{stream, newContextSender(stream.Context(), 0)}
 
-	// 创建转换会话
-	session := &ConversionSession{
-		ID:        conversionID,
-		StartTime: time.Now(),
-		Status: converter.ConversionStatus{
-			Status:  "processing",
-			Progress: 0,
-			Message: "开始处理...",
-		},
+	if err := s.resolveSource(stream.Context(), req); err != nil {
+		return err
 	}
 
-	s.conversionsMutex.Lock()
-	s.conversions[conversionID] = session
-	s.conversionsMutex.Unlock()
+	// 校验请求
+	if err := s.validateConvertRequest(req); err != nil {
+		return err
+	}
+
+	// 创建转换会话
+	session := s.createSession()
+	session.Tenant = tenantFromContext(stream.Context())
+	conversionID := session.ID
+
+	s.logger.Infof("开始处理转换请求: %s (ID: %s)", req.Filename, conversionID)
 
 	// 清理函数
-	defer func() {
-		s.conversionsMutex.Lock()
-		delete(s.conversions, conversionID)
-		s.conversionsMutex.Unlock()
-	}()
+	defer s.finishSession(conversionID)
 
 	// 发送初始状态
 	if err := s.sendStatusUpdate(stream, session); err != nil {
+		// 未开始转换的会话不会结束，后台清理不会移除它
+		s.removeSession(conversionID)
 		return err
 	}
 
+	// 进度回调可能来自并行渲染的多个协程，与图片信息的发送需要互斥
+	var sendMutex sync.Mutex
+
 	// 创建进度回调
 	progressCallback := func(status converter.ConversionStatus) {
-		session.Mutex.Lock()
-		session.Status = status
-		session.Mutex.Unlock()
+		session.setStatus(status)
 
 		// 发送状态更新
+		sendMutex.Lock()
+		defer sendMutex.Unlock()
 		if err := s.sendStatusUpdate(stream, session); err != nil {
 			s.logger.Errorf("发送状态更新失败: %v", err)
 		}
 	}
 
+	// 每张幻灯片写好后立即登记并发送图片信息，客户端不必等整个转换结束就能开始下载
+	sent := make(map[string]bool)
+	inline := s.newInlineBudget(req)
+	imageReady := func(image converter.ImageInfo) {
+		images := []converter.ImageInfo{image}
+		s.registerImages(session.ID, session.Tenant, images)
+		image = images[0]
+
+		info := s.convertImageInfoToProto(image)
+		info.Data = inline.read(image)
+
+		sendMutex.Lock()
+		defer sendMutex.Unlock()
+		if err := stream.Send(&proto.ConvertPPTResponse{
+			Response: &proto.ConvertPPTResponse_ImageInfo{
+				ImageInfo: info,
+			},
+		}); err != nil {
+			s.logger.Errorf("发送图片信息失败: %v", err)
+			return
+		}
+		sent[image.DownloadID] = true
+	}
+
 	// 执行转换
-	result, err := s.converter.ConvertPPT(
-		req.PptData,
-		req.Filename,
-		progressCallback,
-	)
+	convErr := s.runConversion(stream.Context(), session, req, progressCallback, imageReady)
+
+	// 发送最终结果
+	if err := s.sendFinalResult(stream, session, sent, inline); err != nil {
+		return err
+	}
+
+	// 失败时以对应的状态码结束流，客户端可据此决定是否重试
+	if convErr != nil {
+		return status.Error(conversionErrorCode(convErr), convErr.Error())
+	}
+
+	s.logger.Infof("转换完成: %s (ID: %s)", req.Filename, conversionID)
+	return nil
+}
+
+// createSession 创建并登记新的转换会话
+func (s *GRPCServer) createSession() *ConversionSession {
+	session := &ConversionSession{
+		ID:        generateConversionID(),
+		StartTime: time.Now(),
+		Status: converter.ConversionStatus{
+			Status:   "processing",
+			Progress: 0,
+			Message:  "开始处理...",
+		},
+	}
+
+	s.conversionsMutex.Lock()
+	s.conversions[session.ID] = session
+	s.conversionsMutex.Unlock()
+
+	return session
+}
+
+// findSession 查找请求携带的租户发起的转换会话
+// 会话属于其他租户时与不存在返回相同的 NotFound，不透露其他租户的转换
+func (s *GRPCServer) findSession(ctx context.Context, conversionID string) (*ConversionSession, error) {
+	s.conversionsMutex.RLock()
+	session, exists := s.conversions[conversionID]
+	s.conversionsMutex.RUnlock()
+
+	if !exists || session.Tenant != tenantFromContext(ctx) {
+		return nil, status.Errorf(codes.NotFound, "转换会话不存在: %s", conversionID)
+	}
+	return session, nil
+}
+
+// removeSession 移除转换会话
+func (s *GRPCServer) removeSession(conversionID string) {
+	s.conversionsMutex.Lock()
+	delete(s.conversions, conversionID)
+	s.conversionsMutex.Unlock()
+}
+
+// finishSession 同步转换的请求结束时调用
+// 设置了 -output-ttl 时保留已结束的会话，供 Transcode、RetrySlide 使用，由后台清理或 DeleteConversion 移除；
+// 未设置时没有其他途径移除会话，立即移除，避免每次转换都占用内存
+func (s *GRPCServer) finishSession(conversionID string) {
+	if s.outputTTL <= 0 {
+		s.removeSession(conversionID)
+	}
+}
+
+// runConversion 执行转换并把结果记录到会话中，gRPC与HTTP接口共用
+// imageReady 不为空时每张幻灯片图片完成后立即调用；ctx 为发起请求的上下文，客户端断开或超过截止时间时取消转换
+// 返回转换器的错误，供调用方映射为状态码
+func (s *GRPCServer) runConversion(ctx context.Context, session *ConversionSession, req *proto.ConvertPPTRequest, progressCallback converter.ProgressCallback, imageReady converter.ImageReadyCallback) error {
+	options := s.conversionOptionsFromRequest(req)
+	options.OnImageReady = imageReady
+	options.OutputSubdir = s.outputSubdir(session)
+	options.ConversionID = session.ID
+	if s.keepUploads {
+		// 保留的文件与输出放在同一目录树下，随 -output-ttl 一起清理
+		options.KeepUploadDir = filepath.Join(s.outputDir, uploadsDirName, session.ID)
+	}
+
+	var result *converter.ConversionResult
+	var err error
+	if req.Probe {
+		// 探测只读取演示文稿的结构，不占用转换槽位和内存预算
+		result, err = converter.ProbePresentation(req.PptData, req.IncludeHidden)
+	} else {
+		result, err = s.convertFiltered(ctx, req, options, progressCallback)
+	}
+	if result != nil {
+		// 超时且请求了部分结果时 result 与 err 同时返回
+		s.registerImages(session.ID, session.Tenant, result.Images)
+		if result.ManifestDownloadID != "" {
+			result.ManifestDownloadID = converter.NamespacedDownloadID(session.ID, result.ManifestDownloadID)
+		}
+	}
 
 	// 更新会话结果
 	session.Mutex.Lock()
@@ -140,9 +398,12 @@ func (s *GRPCServer) ConvertPPT(req *proto.ConvertPPTRequest, stream proto.PPTTo
 			Progress: 100,
 			Message: fmt.Sprintf("转换失败: %v", err),
 		}
-		session.Result = &converter.ConversionResult{
-			Success: false,
-			Error:   err.Error(),
+		session.Result = result
+		if session.Result == nil {
+			session.Result = &converter.ConversionResult{
+				Success: false,
+				Error:   err.Error(),
+			}
 		}
 	} else {
 		session.Status = converter.ConversionStatus{
@@ -152,27 +413,126 @@ func (s *GRPCServer) ConvertPPT(req *proto.ConvertPPTRequest, stream proto.PPTTo
 		}
 		session.Result = result
 	}
+	downloaded := s.trackDownloads(session.ID, session.Result, err == nil)
+	if options.KeepUploadDir != "" {
+		options.OnImageReady = nil
+		session.options = &options
+	}
 	now := time.Now()
 	session.EndTime = &now
+	session.closeSubscribers()
 	session.Mutex.Unlock()
 
-	// 发送最终结果
-	if err := s.sendFinalResult(stream, session); err != nil {
-		return err
+	if downloaded {
+		s.removeDownloadedOutput(session.ID, result)
 	}
+	s.notifyConversion(session, req.Filename)
 
-	s.logger.Infof("转换完成: %s (ID: %s)", req.Filename, conversionID)
-	return nil
+	return err
+}
+
+// convert 在并发上限和内存预算内调用转换器，所有转换 (包括比较演示文稿) 都经过这里
+// ctx 结束 (客户端断开、超过截止时间) 或服务关闭时都会取消转换，释放占用的转换槽位
+func (s *GRPCServer) convert(ctx context.Context, pptData []byte, filename string, options converter.ConversionOptions, progressCallback converter.ProgressCallback) (*converter.ConversionResult, error) {
+	if !s.active.begin() {
+		return nil, fmt.Errorf("%w: 服务正在关闭", converter.ErrCancelled)
+	}
+	defer s.active.end()
+
+	// 预算不足时阻塞，转换结束后归还
+	width, height := options.Width, options.Height
+	if len(options.Resolutions) > 0 {
+		largest := converter.LargestResolution(options.Resolutions)
+		width, height = largest.Width, largest.Height
+	}
+	width, height = s.outputSize(width, height)
+	// 单独指定了更大尺寸的幻灯片按其中最大的估算 (按DPI指定的尺寸要由转换器换算，不计入)
+	for _, size := range options.SlideSizes {
+		if int64(size.Width)*int64(size.Height) > int64(width)*int64(height) {
+			width, height = size.Width, size.Height
+		}
+	}
+	memory := estimateMemory(int64(len(pptData)), width, height)
+	if err := s.memory.acquire(memory); err != nil {
+		return nil, err
+	}
+	defer s.memory.release(memory)
+
+	// 按估算的输出大小预留配额，结束后按实际写入的大小记录
+	estimated := options
+	estimated.Width, estimated.Height = s.outputSize(options.Width, options.Height)
+	if estimated.OutputFormat == "" {
+		estimated.OutputFormat = s.defaultFormat
+	}
+	estimate := estimateOutput(pptData, estimated)
+	if err := s.reserveOutput(estimate); err != nil {
+		return nil, err
+	}
+	var result *converter.ConversionResult
+	defer func() {
+		var written int64
+		if result != nil {
+			written = imagesSize(result.Images)
+		}
+		if options.KeepUploadDir != "" {
+			written += int64(len(pptData))
+		}
+		s.quota.commit(estimate, written)
+	}()
+
+	// 请求的超时时间不能超过服务器的上限
+	if s.conversionTimeout > 0 && (options.Timeout <= 0 || options.Timeout > s.conversionTimeout) {
+		options.Timeout = s.conversionTimeout
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(s.ctx, cancel)()
+
+	if s.convSlots != nil {
+		select {
+		case s.convSlots <- struct{}{}:
+			defer func() { <-s.convSlots }()
+		case <-ctx.Done():
+			if s.ctx.Err() != nil {
+				return nil, fmt.Errorf("%w: 服务正在关闭", converter.ErrCancelled)
+			}
+			return nil, fmt.Errorf("%w: 等待转换时请求已结束: %v", converter.ErrCancelled, ctx.Err())
+		}
+	}
+	var err error
+	result, err = s.converter.ConvertPPT(ctx, pptData, filename, options, progressCallback)
+	return result, err
+}
+
+// conversionErrorCode 转换器错误类型对应的gRPC状态码
+func conversionErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, converter.ErrUnsupportedFormat), errors.Is(err, converter.ErrCorruptFile),
+		errors.Is(err, converter.ErrImageTooLarge), errors.Is(err, converter.ErrNoMatchingSlides),
+		errors.Is(err, converter.ErrTooManySlides), errors.Is(err, converter.ErrInvalidOrder),
+		errors.Is(err, converter.ErrInvalidSlideSize), errors.Is(err, converter.ErrFilenameCollision):
+		return codes.InvalidArgument
+	case errors.Is(err, converter.ErrSlideOutOfRange):
+		return codes.OutOfRange
+	case errors.Is(err, converter.ErrNoSlides):
+		return codes.FailedPrecondition
+	case errors.Is(err, converter.ErrBackendUnavailable), errors.Is(err, converter.ErrCancelled):
+		return codes.Unavailable
+	case errors.Is(err, errMemoryBudget), errors.Is(err, errOutputQuota):
+		return codes.ResourceExhausted
+	case errors.Is(err, converter.ErrTimeout):
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
 }
 
 // GetConversionStatus 获取转换状态
 func (s *GRPCServer) GetConversionStatus(ctx context.Context, req *proto.StatusRequest) (*proto.StatusResponse, error) {
-	s.conversionsMutex.RLock()
-	session, exists := s.conversions[req.ConversionId]
-	s.conversionsMutex.RUnlock()
-
-	if !exists {
-		return nil, status.Errorf(codes.NotFound, "转换会话不存在: %s", req.ConversionId)
+	session, err := s.findSession(ctx, req.ConversionId)
+	if err != nil {
+		return nil, err
 	}
 
 	session.Mutex.RLock()
@@ -191,12 +551,22 @@ func (s *GRPCServer) GetConversionStatus(ctx context.Context, req *proto.StatusR
 
 // DownloadImage 下载图片 (流式响应)
 func (s *GRPCServer) DownloadImage(req *proto.DownloadRequest, stream proto.PPTToImagesService_DownloadImageServer) error {
+	stream = downloadStream{stream, newContextSender(stream.Context(), s.downloadIdleTimeout)}
+
 	// 查找对应的图片文件
-	imagePath, err := s.findImageByDownloadID(req.DownloadId)
+	imagePath, err := s.findImageByDownloadID(req.DownloadId, req.ConversionId, tenantFromContext(stream.Context()))
 	if err != nil {
 		return status.Errorf(codes.NotFound, "图片文件不存在: %s", req.DownloadId)
 	}
 
+	// 发送到文件末尾才算下载完成 (断点续传时为最后一段)，在关闭文件之后记录
+	complete := false
+	defer func() {
+		if complete {
+			s.markDownloaded(req.DownloadId)
+		}
+	}()
+
 	// 打开文件
 	file, err := os.Open(imagePath)
 	if err != nil {
@@ -210,11 +580,26 @@ func (s *GRPCServer) DownloadImage(req *proto.DownloadRequest, stream proto.PPTT
 		return status.Errorf(codes.Internal, "无法获取文件信息: %v", err)
 	}
 
+	// 断点续传: 从指定偏移开始发送
+	if req.Offset < 0 || req.Offset > fileInfo.Size() {
+		return status.Errorf(codes.OutOfRange, "无效的偏移: %d (文件大小 %d)", req.Offset, fileInfo.Size())
+	}
+
+	// 校验和覆盖整个文件，续传时客户端用它校验拼接后的结果
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return status.Errorf(codes.Internal, "读取文件失败: %v", err)
+	}
+	if _, err := file.Seek(req.Offset, io.SeekStart); err != nil {
+		return status.Errorf(codes.Internal, "定位文件失败: %v", err)
+	}
+
 	// 发送文件信息
 	info := &proto.DownloadInfo{
 		Filename:    fileInfo.Name(),
 		FileSize:    fileInfo.Size(),
 		ContentType: s.getContentType(filepath.Ext(imagePath)),
+		Sha256:      hex.EncodeToString(hash.Sum(nil)),
 	}
 
 	if err := stream.Send(&proto.DownloadResponse{
@@ -241,9 +626,131 @@ func (s *GRPCServer) DownloadImage(req *proto.DownloadRequest, stream proto.PPTT
 		}
 	}
 
+	complete = true
 	return nil
 }
 
+// stampPositions 编号位置枚举到转换器选项的映射
+var stampPositions = map[proto.StampPosition]string{
+	proto.StampPosition_BOTTOM_RIGHT: converter.StampBottomRight,
+	proto.StampPosition_BOTTOM_LEFT:  converter.StampBottomLeft,
+	proto.StampPosition_TOP_RIGHT:    converter.StampTopRight,
+	proto.StampPosition_TOP_LEFT:     converter.StampTopLeft,
+}
+
+// animationModes 动画渲染方式枚举到转换器选项的映射
+var animationModes = map[proto.AnimationMode]string{
+	proto.AnimationMode_FINAL:      converter.AnimationFinal,
+	proto.AnimationMode_FIRST:      converter.AnimationFirst,
+	proto.AnimationMode_ALL_BUILDS: converter.AnimationAllBuilds,
+}
+
+// conversionOptionsFromRequest 从请求中提取转换选项
+func (s *GRPCServer) conversionOptionsFromRequest(req *proto.ConvertPPTRequest) converter.ConversionOptions {
+	options := converter.ConversionOptions{
+		Width:              int(req.Width),
+		Height:             int(req.Height),
+		OutputFormat:       req.OutputFormat,
+		EmbedMetadata:      req.EmbedMetadata,
+		IncludeHidden:      req.IncludeHidden,
+		NumberOffset:       int(req.NumberOffset),
+		AnimationMode:      animationModes[req.AnimationMode],
+		Optimize:           req.Optimize,
+		HTMLBundle:         req.HtmlBundle,
+		PDFImageOnly:       req.PdfImageOnly,
+		NotesPDF:           req.NotesPdf,
+		StrictFilenames:    req.StrictFilenames,
+		CoverOnly:          req.CoverOnly,
+		NormalizeSize:      req.NormalizeSize,
+		ExtractBackground:  req.ExtractBackground,
+		Rotate:             int(req.Rotate),
+		EmbedColorProfile:  req.EmbedColorProfile,
+		TileHeight:         int(req.TileHeight),
+		GenerateManifest:   req.GenerateManifest,
+		Timeout:            time.Duration(req.TimeoutSeconds) * time.Second,
+		ReturnPartial:      req.ReturnPartial,
+		ResampleFilter:     req.ResampleFilter,
+		Order:              req.Order,
+		RenderMediaPosters: req.RenderMediaPosters,
+		PNGBitDepth:        int(req.PngBitDepth),
+		PNGPalette:         req.PngPalette,
+		ThemeVariant:       int(req.ThemeVariant),
+	}
+
+	for _, index := range req.SlideIndices {
+		options.SlideIndices = append(options.SlideIndices, int(index))
+	}
+	for _, index := range req.OrderIndices {
+		options.OrderIndices = append(options.OrderIndices, int(index))
+	}
+
+	if len(req.SlideFormats) > 0 {
+		options.SlideFormats = make(map[int]string, len(req.SlideFormats))
+		for _, override := range req.SlideFormats {
+			options.SlideFormats[int(override.Slide)] = override.Format
+		}
+	}
+
+	if len(req.SlideSizes) > 0 {
+		options.SlideSizes = make(map[int]converter.SizeOverride, len(req.SlideSizes))
+		for _, override := range req.SlideSizes {
+			options.SlideSizes[int(override.Slide)] = converter.SizeOverride{
+				Width:  int(override.Width),
+				Height: int(override.Height),
+				DPI:    int(override.Dpi),
+			}
+		}
+	}
+
+	for _, font := range req.Fonts {
+		options.Fonts = append(options.Fonts, converter.FontFile{
+			Filename: font.Filename,
+			Data:     font.Data,
+		})
+	}
+
+	if req.StampSlideNumber {
+		options.Stamp = &converter.StampOptions{
+			Position: stampPositions[req.StampPosition],
+			FontSize: float64(req.StampFontSize),
+		}
+		if req.StampColor != "" {
+			if stampColor, err := parseHexColor(req.StampColor); err == nil {
+				options.Stamp.Color = stampColor
+			}
+		}
+	}
+
+	if req.BorderWidth > 0 {
+		options.Border = &converter.BorderOptions{
+			Width: int(req.BorderWidth),
+		}
+		if req.BorderColor != "" {
+			if borderColor, err := parseHexColor(req.BorderColor); err == nil {
+				options.Border.Color = borderColor
+			}
+		}
+	}
+	if req.AutoCrop {
+		options.AutoCrop = &converter.AutoCropOptions{Tolerance: int(req.AutoCropTolerance)}
+	}
+	// 已在 validateConvertRequest 中校验
+	options.JPEGSubsampling, _ = jpegenc.ParseSubsampling(req.JpegSubsampling)
+	options.Resolutions = resolutionsFromRequest(req)
+
+	if sheet := req.ContactSheet; sheet != nil {
+		options.ContactSheet = &converter.ContactSheetOptions{
+			Columns:    int(sheet.Columns),
+			Rows:       int(sheet.Rows),
+			CellWidth:  int(sheet.CellWidth),
+			CellHeight: int(sheet.CellHeight),
+			Gutter:     int(sheet.Gutter),
+		}
+	}
+
+	return options
+}
+
 // sendStatusUpdate 发送状态更新
 func (s *GRPCServer) sendStatusUpdate(stream proto.PPTToImagesService_ConvertPPTServer, session *ConversionSession) error {
 	session.Mutex.RLock()
@@ -257,8 +764,9 @@ func (s *GRPCServer) sendStatusUpdate(stream proto.PPTToImagesService_ConvertPPT
 	})
 }
 
-// sendFinalResult 发送最终结果
-func (s *GRPCServer) sendFinalResult(stream proto.PPTToImagesService_ConvertPPTServer, session *ConversionSession) error {
+// sendFinalResult 发送尚未发送过的图片信息 (如总览图)，最后发送结果作为流的结束
+// sent 为转换过程中已发送的下载ID，inline 不为空时在额度内附带图片数据
+func (s *GRPCServer) sendFinalResult(stream proto.PPTToImagesService_ConvertPPTServer, session *ConversionSession, sent map[string]bool, inline *inlineBudget) error {
 	session.Mutex.RLock()
 	result := session.Result
 	session.Mutex.RUnlock()
@@ -269,9 +777,14 @@ func (s *GRPCServer) sendFinalResult(stream proto.PPTToImagesService_ConvertPPTS
 
 	// 发送每个图片信息
 	for _, image := range result.Images {
+		if sent[image.DownloadID] {
+			continue
+		}
+		info := s.convertImageInfoToProto(image)
+		info.Data = inline.read(image)
 		if err := stream.Send(&proto.ConvertPPTResponse{
 			Response: &proto.ConvertPPTResponse_ImageInfo{
-				ImageInfo: s.convertImageInfoToProto(image),
+				ImageInfo: info,
 			},
 		}); err != nil {
 			return err
@@ -304,12 +817,26 @@ func (s *GRPCServer) convertResultToProto(result *converter.ConversionResult) *p
 		Message:         result.Message,
 		TotalSlides:     int32(result.TotalSlides),
 		ConvertedSlides: int32(result.ConvertedSlides),
+		HiddenSlides:       int32(result.HiddenSlides),
+		SlideWidthEmu:      result.SlideSize.Width,
+		SlideHeightEmu:     result.SlideSize.Height,
+		AspectRatio:        result.AspectRatio,
+		SlideSizeDefaulted: result.SlideSizeDefaulted,
 		Error:           result.Error,
+		ManifestDownloadId: result.ManifestDownloadID,
+		Partial:            result.Partial,
+		Timing:             timingToProto(result.Timing),
+		NormalizedWidth:    int32(result.NormalizedWidth),
+		NormalizedHeight:   int32(result.NormalizedHeight),
 	}
 
 	for _, image := range result.Images {
 		protoResult.Images = append(protoResult.Images, s.convertImageInfoToProto(image))
 	}
+	protoResult.SlideErrors = slideErrorsToProto(result.SlideErrors)
+	for _, number := range result.MatchedSlides {
+		protoResult.MatchedSlides = append(protoResult.MatchedSlides, int32(number))
+	}
 
 	return protoResult
 }
@@ -321,14 +848,104 @@ func (s *GRPCServer) convertImageInfoToProto(image converter.ImageInfo) *proto.I
 		Filename:    image.Filename,
 		FileSize:    image.FileSize,
 		DownloadId:  image.DownloadID,
+		Format:      image.Format,
+		BuildIndex:  int32(image.BuildIndex),
+		TileIndex:   int32(image.TileIndex),
+		Width:       int32(image.Width),
+		Height:      int32(image.Height),
+
+		LowConfidence: image.LowConfidence,
+		Crop:          cropBoxToProto(image.Crop),
+		SourceHash:    image.SourceHash,
+		Resolution:    image.Resolution,
+		Placeholder:   image.Placeholder,
+		Backend:       image.Backend,
+		Background:    image.Background,
+	}
+}
+
+// resolutionsFromRequest 转换请求中的多分辨率尺寸，未设置时为nil
+func resolutionsFromRequest(req *proto.ConvertPPTRequest) []converter.Resolution {
+	var resolutions []converter.Resolution
+	for _, resolution := range req.Resolutions {
+		resolutions = append(resolutions, converter.Resolution{
+			Width:  int(resolution.Width),
+			Height: int(resolution.Height),
+			Suffix: resolution.Suffix,
+		})
+	}
+	return resolutions
+}
+
+// cropBoxToProto 转换自动裁剪区域到protobuf，未裁剪时为nil
+func cropBoxToProto(crop *converter.CropBox) *proto.CropBox {
+	if crop == nil {
+		return nil
+	}
+	return &proto.CropBox{
+		X:      int32(crop.X),
+		Y:      int32(crop.Y),
+		Width:  int32(crop.Width),
+		Height: int32(crop.Height),
+	}
+}
+
+// timingToProto 转换各阶段耗时到protobuf
+func timingToProto(timing *converter.ConversionTiming) *proto.ConversionTiming {
+	if timing == nil {
+		return nil
+	}
+	return &proto.ConversionTiming{
+		ParseMs:      timing.Parse.Milliseconds(),
+		RenderMs:     timing.Render.Milliseconds(),
+		FirstSlideMs: timing.FirstSlide.Milliseconds(),
+		EncodeMs:     timing.Encode.Milliseconds(),
+		SummariesMs:  timing.Summaries.Milliseconds(),
+		TotalMs:      timing.Total.Milliseconds(),
+		Slides:       int32(timing.Slides),
+	}
+}
+
+// slideErrorsToProto 转换幻灯片错误到protobuf
+func slideErrorsToProto(slideErrors []converter.SlideError) []*proto.SlideError {
+	var result []*proto.SlideError
+	for _, slideError := range slideErrors {
+		result = append(result, &proto.SlideError{
+			SlideNumber: int32(slideError.SlideNumber),
+			Error:       slideError.Error,
+		})
+	}
+	return result
+}
+
+// registerImages 登记下载ID与文件路径的对应关系
+// 下载ID改写为 <转换ID>.<随机ID> 的形式 (直接修改 images)，同一张图片多次登记得到相同的ID
+func (s *GRPCServer) registerImages(conversionID, tenant string, images []converter.ImageInfo) {
+	s.downloadsMutex.Lock()
+	defer s.downloadsMutex.Unlock()
+
+	for i := range images {
+		images[i].DownloadID = converter.NamespacedDownloadID(conversionID, images[i].DownloadID)
+		s.downloads[images[i].DownloadID] = downloadEntry{
+			path:         images[i].FilePath,
+			conversionID: conversionID,
+			tenant:       tenant,
+		}
 	}
 }
 
 // findImageByDownloadID 根据下载ID查找图片文件
-func (s *GRPCServer) findImageByDownloadID(downloadID string) (string, error) {
-	// 在实际实现中，这里应该维护一个下载ID到文件路径的映射
-	// 为了简化，我们遍历输出目录查找文件
-	return filepath.Glob(filepath.Join(s.outputDir, "**", "*"))
+// 下载ID必须属于 tenant 发起的转换 (转换未携带租户时请求也不能携带)，conversionID 不为空时还必须属于该转换；
+// 不属于时与不存在返回相同的错误，不透露其他转换的信息
+func (s *GRPCServer) findImageByDownloadID(downloadID, conversionID, tenant string) (string, error) {
+	s.downloadsMutex.RLock()
+	entry, exists := s.downloads[downloadID]
+	s.downloadsMutex.RUnlock()
+
+	if !exists || (conversionID != "" && entry.conversionID != conversionID) || entry.tenant != tenant {
+		return "", fmt.Errorf("下载ID不存在: %s", downloadID)
+	}
+	return entry.path, nil
 }
 
 // getContentType 根据文件扩展名获取内容类型
@@ -336,18 +953,28 @@ func (s *GRPCServer) getContentType(ext string) string {
 	switch ext {
 	case ".png":
 		return "image/png"
+	case ".html":
+		return "text/html; charset=utf-8"
 	case ".jpg", ".jpeg":
 		return "image/jpeg"
 	case ".gif":
 		return "image/gif"
 	case ".webp":
 		return "image/webp"
+	case ".json":
+		return "application/json"
+	case ".pdf":
+		return "application/pdf"
 	default:
 		return "application/octet-stream"
 	}
 }
 
-// generateConversionID 生成转换ID
+// generateConversionID 生成随机的转换ID，与下载ID一样不能被猜测
 func generateConversionID() string {
-	return fmt.Sprintf("conv_%d", time.Now().UnixNano())
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Sprintf("conv_%d", time.Now().UnixNano())
+	}
+	return "conv_" + hex.EncodeToString(id[:])
 }
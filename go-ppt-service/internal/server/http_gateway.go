@@ -0,0 +1,586 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+const (
+	// multipartMemory 解析multipart表单时保存在内存中的上限，超出部分写入临时文件
+	multipartMemory = 32 << 20
+	// multipartOverhead 请求体大小限制中为表单字段和边界预留的空间
+	multipartOverhead = 1 << 20
+)
+
+// HTTPGateway HTTP网关，为不方便使用gRPC的客户端 (curl、Postman等) 提供转换和下载接口
+type HTTPGateway struct {
+	server *GRPCServer
+	logger *logrus.Logger
+}
+
+// httpImageInfo HTTP接口返回的图片信息
+type httpImageInfo struct {
+	SlideNumber int    `json:"slide_number"`
+	Filename    string `json:"filename"`
+	FileSize    int64  `json:"file_size"`
+	DownloadID  string `json:"download_id"`
+	DownloadURL string `json:"download_url"`
+	Format      string `json:"format"`
+	BuildIndex  int    `json:"build_index"`
+	TileIndex   int    `json:"tile_index"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+
+	LowConfidence bool               `json:"low_confidence"`
+	Crop          *converter.CropBox `json:"crop,omitempty"`
+	Data          []byte             `json:"data,omitempty"`
+	SourceHash    string             `json:"source_hash"`
+	Resolution    string             `json:"resolution,omitempty"`
+	Placeholder   bool               `json:"placeholder,omitempty"`
+	Background    bool               `json:"background,omitempty"`
+	Backend       string             `json:"backend,omitempty"`
+}
+
+// httpConvertResponse HTTP转换接口的响应
+type httpConvertResponse struct {
+	ConversionID    string          `json:"conversion_id"`
+	Success         bool            `json:"success"`
+	Message         string          `json:"message"`
+	TotalSlides     int             `json:"total_slides"`
+	ConvertedSlides int             `json:"converted_slides"`
+	HiddenSlides    int             `json:"hidden_slides"`
+	SlideWidthEmu   int64           `json:"slide_width_emu"`
+	SlideHeightEmu  int64           `json:"slide_height_emu"`
+	Images          []httpImageInfo `json:"images"`
+	Error           string          `json:"error,omitempty"`
+	// ManifestDownloadID/ManifestURL 请求设置 generate_manifest 时 manifest.json 的下载ID和下载地址
+	ManifestDownloadID string `json:"manifest_download_id,omitempty"`
+	ManifestURL        string `json:"manifest_url,omitempty"`
+	// Partial 转换超时后按 return_partial 返回的部分结果
+	Partial bool `json:"partial,omitempty"`
+	// Timing 各阶段的耗时 (毫秒)，字段与gRPC的 ConversionTiming 相同
+	Timing *proto.ConversionTiming `json:"timing,omitempty"`
+	// SlideErrors 渲染结果无效而被丢弃的幻灯片
+	SlideErrors []converter.SlideError `json:"slide_errors,omitempty"`
+}
+
+// NewHTTPGateway 创建HTTP网关
+func NewHTTPGateway(server *GRPCServer, logger *logrus.Logger) *HTTPGateway {
+	return &HTTPGateway{
+		server: server,
+		logger: logger,
+	}
+}
+
+// Handler 返回HTTP路由
+func (g *HTTPGateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/convert", g.handleConvert)
+	mux.HandleFunc("/download/", g.handleDownload)
+	return mux
+}
+
+// handleConvert POST /convert
+// multipart表单字段 file 为PPT文件，查询参数 width、height、format、embed_metadata、slides、include_hidden、number_offset、animation_mode、optimize、html_bundle 与gRPC请求字段含义相同
+func (g *HTTPGateway) handleConvert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "只支持POST请求")
+		return
+	}
+
+	// 与gRPC接口使用相同的上传大小限制
+	if g.server.maxUploadSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, g.server.maxUploadSize+multipartOverhead)
+	}
+
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "上传文件超过大小上限")
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, "解析表单失败: "+err.Error())
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "缺少文件字段 file")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "读取上传文件失败: "+err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	width, err := parseIntParam(query.Get("width"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的width参数")
+		return
+	}
+	height, err := parseIntParam(query.Get("height"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的height参数")
+		return
+	}
+	embedMetadata, _ := strconv.ParseBool(query.Get("embed_metadata"))
+	includeHidden, _ := strconv.ParseBool(query.Get("include_hidden"))
+	probe, _ := strconv.ParseBool(query.Get("probe"))
+	optimize, _ := strconv.ParseBool(query.Get("optimize"))
+	htmlBundle, _ := strconv.ParseBool(query.Get("html_bundle"))
+	pdfImageOnly, _ := strconv.ParseBool(query.Get("pdf_image_only"))
+	notesPDF, _ := strconv.ParseBool(query.Get("notes_pdf"))
+	strictFilenames, _ := strconv.ParseBool(query.Get("strict_filenames"))
+	coverOnly, _ := strconv.ParseBool(query.Get("cover_only"))
+	normalizeSize, _ := strconv.ParseBool(query.Get("normalize_size"))
+	extractBackground, _ := strconv.ParseBool(query.Get("extract_background"))
+	generateManifest, _ := strconv.ParseBool(query.Get("generate_manifest"))
+	allowEmptyFilter, _ := strconv.ParseBool(query.Get("allow_empty_filter"))
+	embedColorProfile, _ := strconv.ParseBool(query.Get("embed_color_profile"))
+	autoCrop, _ := strconv.ParseBool(query.Get("auto_crop"))
+	autoCropTolerance, err := parseIntParam(query.Get("auto_crop_tolerance"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的auto_crop_tolerance参数")
+		return
+	}
+	numberOffset, err := parseIntParam(query.Get("number_offset"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的number_offset参数")
+		return
+	}
+	borderWidth, err := parseIntParam(query.Get("border_width"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的border_width参数")
+		return
+	}
+	rotate, err := parseIntParam(query.Get("rotate"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的rotate参数")
+		return
+	}
+	tileHeight, err := parseIntParam(query.Get("tile_height"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的tile_height参数")
+		return
+	}
+	timeoutSeconds, err := parseIntParam(query.Get("timeout_seconds"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的timeout_seconds参数")
+		return
+	}
+	returnPartial, _ := strconv.ParseBool(query.Get("return_partial"))
+	renderMediaPosters, _ := strconv.ParseBool(query.Get("render_media_posters"))
+	pngPalette, _ := strconv.ParseBool(query.Get("png_palette"))
+	pngBitDepth, err := parseIntParam(query.Get("png_bit_depth"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的png_bit_depth参数")
+		return
+	}
+	themeVariant, err := parseIntParam(query.Get("theme_variant"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的theme_variant参数")
+		return
+	}
+	inlineImages, _ := strconv.ParseBool(query.Get("inline_images"))
+	var inlineMaxBytes int64
+	if value := query.Get("inline_max_bytes"); value != "" {
+		inlineMaxBytes, err = strconv.ParseInt(value, 10, 64)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的inline_max_bytes参数")
+		return
+	}
+	animationMode, ok := proto.AnimationMode_value[strings.ToUpper(query.Get("animation_mode"))]
+	if !ok && query.Get("animation_mode") != "" {
+		writeJSONError(w, http.StatusBadRequest, "无效的animation_mode参数")
+		return
+	}
+	slideIndices, err := parseSlideIndices(query.Get("slides"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的slides参数")
+		return
+	}
+	orderIndices, err := parseSlideIndices(query.Get("order_indices"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的order_indices参数")
+		return
+	}
+	slideFormats, err := parseSlideFormats(query.Get("slide_formats"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的slide_formats参数")
+		return
+	}
+	slideSizes, err := parseSlideSizes(query.Get("slide_sizes"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的slide_sizes参数")
+		return
+	}
+	resolutions, err := parseResolutions(query.Get("resolutions"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "无效的resolutions参数")
+		return
+	}
+
+	req := &proto.ConvertPPTRequest{
+		Filename:           header.Filename,
+		PptData:            data,
+		Width:              width,
+		Height:             height,
+		OutputFormat:       query.Get("format"),
+		EmbedMetadata:      embedMetadata,
+		SlideIndices:       slideIndices,
+		SlideFormats:       slideFormats,
+		SlideSizes:         slideSizes,
+		IncludeHidden:      includeHidden,
+		NumberOffset:       numberOffset,
+		AnimationMode:      proto.AnimationMode(animationMode),
+		Optimize:           optimize,
+		HtmlBundle:         htmlBundle,
+		PdfImageOnly:       pdfImageOnly,
+		NotesPdf:           notesPDF,
+		StrictFilenames:    strictFilenames,
+		CoverOnly:          coverOnly,
+		NormalizeSize:      normalizeSize,
+		ExtractBackground:  extractBackground,
+		BorderWidth:        borderWidth,
+		BorderColor:        query.Get("border_color"),
+		Rotate:             rotate,
+		LayoutFilter:       query.Get("layout_filter"),
+		TitlePattern:       query.Get("title_pattern"),
+		AllowEmptyFilter:   allowEmptyFilter,
+		EmbedColorProfile:  embedColorProfile,
+		TileHeight:         tileHeight,
+		AutoCrop:           autoCrop,
+		AutoCropTolerance:  autoCropTolerance,
+		InlineImages:       inlineImages,
+		InlineMaxBytes:     inlineMaxBytes,
+		JpegSubsampling:    query.Get("jpeg_subsampling"),
+		Resolutions:        resolutions,
+		GenerateManifest:   generateManifest,
+		TimeoutSeconds:     timeoutSeconds,
+		ReturnPartial:      returnPartial,
+		ResampleFilter:     query.Get("resample_filter"),
+		Order:              query.Get("order"),
+		OrderIndices:       orderIndices,
+		RenderMediaPosters: renderMediaPosters,
+		PngBitDepth:        pngBitDepth,
+		PngPalette:         pngPalette,
+		ThemeVariant:       themeVariant,
+		Probe:              probe,
+	}
+
+	if err := g.server.validateConvertRequest(req); err != nil {
+		writeStatusError(w, err)
+		return
+	}
+
+	session := g.server.createSession()
+	session.Tenant = r.Header.Get("X-Tenant-ID")
+	defer g.server.finishSession(session.ID)
+
+	g.logger.Infof("开始处理HTTP转换请求: %s (ID: %s)", req.Filename, session.ID)
+
+	convErr := g.server.runConversion(r.Context(), session, req, session.setStatus, nil)
+
+	session.Mutex.RLock()
+	result := session.Result
+	session.Mutex.RUnlock()
+
+	response := httpConvertResponse{
+		ConversionID:    session.ID,
+		Success:         result.Success,
+		Message:         result.Message,
+		TotalSlides:     result.TotalSlides,
+		ConvertedSlides: result.ConvertedSlides,
+		HiddenSlides:    result.HiddenSlides,
+		SlideWidthEmu:   result.SlideSize.Width,
+		SlideHeightEmu:  result.SlideSize.Height,
+		Images:          []httpImageInfo{},
+		Error:           result.Error,
+		Partial:         result.Partial,
+		Timing:          timingToProto(result.Timing),
+		SlideErrors:     result.SlideErrors,
+	}
+	if result.ManifestDownloadID != "" {
+		response.ManifestDownloadID = result.ManifestDownloadID
+		response.ManifestURL = "/download/" + result.ManifestDownloadID
+	}
+	inline := g.server.newInlineBudget(req)
+	for _, image := range result.Images {
+		response.Images = append(response.Images, httpImageInfo{
+			SlideNumber: image.SlideNumber,
+			Filename:    image.Filename,
+			FileSize:    image.FileSize,
+			DownloadID:  image.DownloadID,
+			DownloadURL: "/download/" + image.DownloadID,
+			Format:      image.Format,
+			BuildIndex:  image.BuildIndex,
+			TileIndex:   image.TileIndex,
+			Width:       image.Width,
+			Height:      image.Height,
+
+			LowConfidence: image.LowConfidence,
+			Crop:          image.Crop,
+			Data:          inline.read(image),
+			SourceHash:    image.SourceHash,
+			Resolution:    image.Resolution,
+			Placeholder:   image.Placeholder,
+			Background:    image.Background,
+			Backend:       image.Backend,
+		})
+	}
+
+	code := http.StatusOK
+	switch {
+	case convErr != nil:
+		code = httpStatusFromCode(conversionErrorCode(convErr))
+	case !result.Success:
+		code = http.StatusInternalServerError
+	}
+	writeJSON(w, code, response)
+}
+
+// handleDownload GET /download/{download_id}
+func (g *HTTPGateway) handleDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "只支持GET请求")
+		return
+	}
+
+	downloadID := strings.TrimPrefix(r.URL.Path, "/download/")
+	// 与gRPC相同，可以用 conversion_id 参数和 X-Tenant-ID 请求头限制只下载自己的转换的文件
+	imagePath, err := g.server.findImageByDownloadID(downloadID, r.URL.Query().Get("conversion_id"), r.Header.Get("X-Tenant-ID"))
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "图片文件不存在: "+downloadID)
+		return
+	}
+
+	file, err := os.Open(imagePath)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "无法打开文件: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "无法获取文件信息: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", g.server.getContentType(filepath.Ext(imagePath)))
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+fileInfo.Name()+"\"")
+	http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), file)
+	file.Close()
+
+	// 只有不带 Range 的完整GET请求算作下载完成
+	if r.Method == http.MethodGet && r.Header.Get("Range") == "" && r.Context().Err() == nil {
+		g.server.markDownloaded(downloadID)
+	}
+}
+
+// parseIntParam 解析int32整数查询参数，空字符串视为0，超出int32范围时返回错误
+func parseIntParam(value string) (int32, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return parseInt32(value)
+}
+
+// parseInt32 解析int32整数，超出范围时返回错误而不是在转换时溢出
+func parseInt32(value string) (int32, error) {
+	n, err := strconv.ParseInt(value, 10, 32)
+	return int32(n), err
+}
+
+// parseSlideIndices 解析逗号分隔的幻灯片编号列表，如 "1,5,9"
+func parseSlideIndices(value string) ([]int32, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var indices []int32
+	for _, part := range strings.Split(value, ",") {
+		index, err := parseInt32(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		indices = append(indices, index)
+	}
+	return indices, nil
+}
+
+// parseSlideFormats 解析逗号分隔的 编号:格式 列表，如 "1:png,2:jpeg"
+func parseSlideFormats(value string) ([]*proto.SlideFormatOverride, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var overrides []*proto.SlideFormatOverride
+	for _, part := range strings.Split(value, ",") {
+		slide, format, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("缺少格式: %s", part)
+		}
+		index, err := parseInt32(slide)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, &proto.SlideFormatOverride{
+			Slide:  index,
+			Format: format,
+		})
+	}
+	return overrides, nil
+}
+
+// parseSlideSizes 解析逗号分隔的 编号:尺寸 列表，尺寸为 宽x高、只有一边 (800x、x600) 或 DPI (300dpi)，
+// 如 "2:1920x1080,3:300dpi"
+func parseSlideSizes(value string) ([]*proto.SlideSizeOverride, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var overrides []*proto.SlideSizeOverride
+	for _, part := range strings.Split(value, ",") {
+		slide, size, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("缺少尺寸: %s", part)
+		}
+		index, err := parseInt32(slide)
+		if err != nil {
+			return nil, err
+		}
+		override := &proto.SlideSizeOverride{Slide: index}
+		size = strings.ToLower(size)
+		if dpi, ok := strings.CutSuffix(size, "dpi"); ok {
+			value, err := parseInt32(dpi)
+			if err != nil {
+				return nil, err
+			}
+			override.Dpi = value
+		} else {
+			width, height, ok := strings.Cut(size, "x")
+			if !ok || width == "" && height == "" {
+				return nil, fmt.Errorf("无效的尺寸: %s", size)
+			}
+			if width != "" {
+				value, err := parseInt32(width)
+				if err != nil {
+					return nil, err
+				}
+				override.Width = value
+			}
+			if height != "" {
+				value, err := parseInt32(height)
+				if err != nil {
+					return nil, err
+				}
+				override.Height = value
+			}
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+// parseResolutions 解析 "1280x720,2560x1440@2x" 形式的多分辨率参数，高度之后的部分为文件名后缀
+func parseResolutions(value string) ([]*proto.Resolution, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var resolutions []*proto.Resolution
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		widthText, rest, ok := strings.Cut(part, "x")
+		if !ok {
+			return nil, fmt.Errorf("缺少高度: %s", part)
+		}
+		end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if end < 0 {
+			end = len(rest)
+		}
+		width, err := parseInt32(widthText)
+		if err != nil {
+			return nil, err
+		}
+		height, err := parseInt32(rest[:end])
+		if err != nil {
+			return nil, err
+		}
+		resolutions = append(resolutions, &proto.Resolution{
+			Width:  width,
+			Height: height,
+			Suffix: rest[end:],
+		})
+	}
+	return resolutions, nil
+}
+
+// writeJSON 写入JSON响应
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeJSONError 写入JSON格式的错误响应
+func writeJSONError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
+
+// writeStatusError 将gRPC状态错误转换为对应的HTTP状态码
+func writeStatusError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	writeJSONError(w, httpStatusFromCode(st.Code()), st.Message())
+}
+
+// httpStatusFromCode gRPC状态码到HTTP状态码的映射
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
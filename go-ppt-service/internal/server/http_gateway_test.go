@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleConvertIntOverflow(t *testing.T) {
+	s := newTranscodeServer(t)
+	handler := NewHTTPGateway(s, s.logger).Handler()
+
+	// 超出int32范围的值不能在转换时溢出为其他数值 (4294967297 转换为int32后为1)
+	for _, tt := range []struct {
+		query string
+		param string
+	}{
+		{"width=4294967297", "width"},
+		{"height=-2147483649", "height"},
+		{"timeout_seconds=4294967297", "timeout_seconds"},
+		{"slides=4294967297", "slides"},
+		{"slide_sizes=1:4294967297x", "slide_sizes"},
+		{"resolutions=4294967297x720", "resolutions"},
+	} {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("file", "deck.pptx")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(selfTestDeck)
+		form.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/convert?"+tt.query, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		response, _ := io.ReadAll(recorder.Body)
+		if recorder.Code != http.StatusBadRequest || !strings.Contains(string(response), tt.param) {
+			t.Errorf("%s: 状态为 %d，响应为 %s，应返回400", tt.query, recorder.Code, response)
+		}
+	}
+	if len(s.conversions) != 0 {
+		t.Errorf("参数无效时创建了 %d 个会话", len(s.conversions))
+	}
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"context"
+
+	"ppt-to-images-service/proto"
+)
+
+// GetServerInfo 返回当前使用的转换后端、支持的输入文件扩展名和异步队列中各优先级的任务数量
+func (s *GRPCServer) GetServerInfo(ctx context.Context, req *proto.ServerInfoRequest) (*proto.ServerInfoResponse, error) {
+	depths := s.pool.Depths()
+	return &proto.ServerInfoResponse{
+		Backend:             s.converter.Backend(),
+		SupportedExtensions: s.converter.SupportedExtensions(),
+		QueueDepths: &proto.QueueDepths{
+			High:   int32(depths.High),
+			Normal: int32(depths.Normal),
+			Low:    int32(depths.Low),
+		},
+	}, nil
+}
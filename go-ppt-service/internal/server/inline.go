@@ -0,0 +1,42 @@
+package server
+
+import (
+	"os"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// defaultMaxInlineSize 一次转换内联返回的图片数据总大小的默认上限
+const defaultMaxInlineSize = 4 << 20
+
+// inlineBudget 一次转换中还能内联返回的图片字节数，按图片发送的顺序扣减
+// 为nil时不内联，客户端通过下载ID下载
+type inlineBudget struct {
+	remaining int64
+}
+
+// newInlineBudget 按请求的 inline_images 和 inline_max_bytes 创建额度，不能超过服务器上限
+func (s *GRPCServer) newInlineBudget(req *proto.ConvertPPTRequest) *inlineBudget {
+	if !req.InlineImages {
+		return nil
+	}
+	limit := s.maxInlineSize
+	if req.InlineMaxBytes > 0 && req.InlineMaxBytes < limit {
+		limit = req.InlineMaxBytes
+	}
+	return &inlineBudget{remaining: limit}
+}
+
+// read 读取图片数据并扣减额度，超出剩余额度或读取失败时返回nil，该图片改为通过下载ID获取
+func (b *inlineBudget) read(image converter.ImageInfo) []byte {
+	if b == nil || image.FileSize > b.remaining {
+		return nil
+	}
+	data, err := os.ReadFile(image.FilePath)
+	if err != nil || int64(len(data)) > b.remaining {
+		return nil
+	}
+	b.remaining -= int64(len(data))
+	return data
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxJanitorInterval 清理输出目录的最长间隔
+const maxJanitorInterval = 10 * time.Minute
+
+// runJanitor 定期删除超过 ttl 的转换输出目录，以及对应的下载ID和已结束的会话，直到 stop 关闭
+func (s *GRPCServer) runJanitor(ttl time.Duration, stop <-chan struct{}) {
+	interval := ttl / 2
+	if interval > maxJanitorInterval {
+		interval = maxJanitorInterval
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.prune(time.Now().Add(-ttl))
+		}
+	}
+}
+
+// prune 删除修改时间早于 cutoff 的转换输出目录
+// 输出目录可能按模板分为多级 (如 {tenant}/{date}/{conversion_id})，只删除最底层的目录，
+// 再删除因此变空的上级目录；进行中的转换的目录 (渲染较慢时修改时间可能早于 cutoff) 不删除
+func (s *GRPCServer) prune(cutoff time.Time) {
+	root := filepath.Clean(s.outputDir)
+	running := s.runningOutputDirs(root)
+
+	var expired []string
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() || path == root {
+			return nil
+		}
+		if running[path] {
+			return filepath.SkipDir
+		}
+		if !isLeafDir(path) {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) {
+			expired = append(expired, path)
+		}
+		return filepath.SkipDir
+	})
+
+	s.cleanupMutex.Lock()
+	for _, dir := range expired {
+		if err := s.removeOutputDir(dir); err != nil {
+			s.logger.Warnf("删除过期输出目录失败: %v", err)
+			continue
+		}
+		s.logger.Debugf("删除过期输出目录: %s", dir)
+	}
+	s.cleanupMutex.Unlock()
+
+	if len(expired) > 0 {
+		s.logger.Infof("清理了 %d 个过期的输出目录", len(expired))
+	}
+
+	s.pruneDownloads()
+
+	s.conversionsMutex.Lock()
+	for id, session := range s.conversions {
+		session.Mutex.RLock()
+		ended := session.EndTime != nil && session.EndTime.Before(cutoff)
+		session.Mutex.RUnlock()
+		if ended {
+			delete(s.conversions, id)
+		}
+	}
+	s.conversionsMutex.Unlock()
+}
+
+// runningOutputDirs 尚未结束的转换的输出目录和保留上传文件的目录
+func (s *GRPCServer) runningOutputDirs(root string) map[string]bool {
+	dirs := make(map[string]bool)
+
+	s.conversionsMutex.RLock()
+	defer s.conversionsMutex.RUnlock()
+
+	for _, session := range s.conversions {
+		session.Mutex.RLock()
+		if session.EndTime == nil {
+			dirs[filepath.Join(root, s.outputSubdir(session))] = true
+			dirs[filepath.Join(root, uploadsDirName, session.ID)] = true
+		}
+		session.Mutex.RUnlock()
+	}
+	return dirs
+}
+
+// pruneDownloads 移除文件已不存在的下载ID，以及图片已被删除的转换的下载记录
+func (s *GRPCServer) pruneDownloads() {
+	s.downloadsMutex.Lock()
+	defer s.downloadsMutex.Unlock()
+
+	for downloadID, entry := range s.downloads {
+		if _, err := os.Stat(entry.path); os.IsNotExist(err) {
+			delete(s.downloads, downloadID)
+		}
+	}
+	for conversionID, tracker := range s.downloadTrackers {
+		if !tracker.registered(s.downloads) {
+			delete(s.downloadTrackers, conversionID)
+		}
+	}
+}
+
+// removeOutputDir 删除输出目录，再删除因此变空的上级目录，调用方需持有 cleanupMutex
+func (s *GRPCServer) removeOutputDir(dir string) error {
+	var size int64
+	if s.quota != nil {
+		size = dirSize(dir)
+	}
+	err := os.RemoveAll(dir)
+	// 删除失败时可能已删除了部分文件，按删除后剩余的大小计算
+	if s.quota != nil {
+		s.quota.remove(size - dirSize(dir))
+	}
+	if err != nil {
+		return err
+	}
+
+	// 上级目录不为空时 os.Remove 会失败，停止向上删除
+	root := filepath.Clean(s.outputDir)
+	for parent := filepath.Dir(dir); parent != root && len(parent) > len(root); parent = filepath.Dir(parent) {
+		if os.Remove(parent) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// isLeafDir 目录中是否没有子目录
+func isLeafDir(path string) bool {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			return false
+		}
+	}
+	return true
+}
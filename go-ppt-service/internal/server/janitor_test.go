@@ -0,0 +1,40 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneSkipsRunningConversions(t *testing.T) {
+	for _, layout := range []string{"", "{tenant}/{date}/{conversion_id}"} {
+		s := newTranscodeServer(t)
+		s.outputLayout = layout
+
+		running := s.createSession()
+		ended := s.createSession()
+		endTime := time.Now()
+		ended.EndTime = &endTime
+
+		// 进行中的转换还在渲染，输出目录下只有渲染用的子目录
+		runningDir := filepath.Join(s.outputDir, s.outputSubdir(running))
+		endedDir := filepath.Join(s.outputDir, s.outputSubdir(ended))
+		for _, dir := range []string{filepath.Join(runningDir, ".render"), endedDir} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		s.prune(time.Now().Add(time.Hour))
+		if _, err := os.Stat(filepath.Join(runningDir, ".render")); err != nil {
+			t.Errorf("模板 %q: 进行中的转换的目录被删除: %v", layout, err)
+		}
+		if _, err := os.Stat(endedDir); !os.IsNotExist(err) {
+			t.Errorf("模板 %q: 已结束的转换的目录仍然存在: %v", layout, err)
+		}
+		if _, exists := s.conversions[running.ID]; !exists {
+			t.Errorf("模板 %q: 进行中的会话被移除", layout)
+		}
+	}
+}
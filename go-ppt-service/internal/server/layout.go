@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// tenantMetadataKey 请求中携带租户的gRPC元数据键 (HTTP网关使用 X-Tenant-ID 请求头)
+	tenantMetadataKey = "x-tenant-id"
+	// defaultTenant 请求未携带租户时使用的目录名
+	defaultTenant = "default"
+	// uploadsDirName 输出目录下保留上传文件的子目录
+	uploadsDirName = "uploads"
+)
+
+// unsafePathChars 租户名中不能出现在路径里的字符
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// ValidateOutputLayout 检查输出目录模板
+// 模板必须包含 {conversion_id} 以保证每次转换的目录不同，并且只能是输出目录下的相对路径
+func ValidateOutputLayout(layout string) error {
+	if layout == "" {
+		return nil
+	}
+	if !strings.Contains(layout, "{conversion_id}") {
+		return fmt.Errorf("输出目录模板必须包含 {conversion_id}: %s", layout)
+	}
+	if filepath.IsAbs(layout) || strings.HasPrefix(layout, "/") {
+		return fmt.Errorf("输出目录模板必须是相对路径: %s", layout)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(layout), "/") {
+		if part == ".." {
+			return fmt.Errorf("输出目录模板不能包含 ..: %s", layout)
+		}
+	}
+	return nil
+}
+
+// expandOutputLayout 替换模板中的 {tenant}、{date}、{conversion_id} 占位符
+func expandOutputLayout(layout, tenant, conversionID string, now time.Time) string {
+	replacer := strings.NewReplacer(
+		"{tenant}", sanitizeTenant(tenant),
+		"{date}", now.Format("2006-01-02"),
+		"{conversion_id}", conversionID,
+	)
+	return filepath.FromSlash(replacer.Replace(layout))
+}
+
+// outputSubdir 会话的输出子目录
+// 未配置模板时与转换器的默认目录名一样为 session_<时间戳>，时间戳取自会话的开始时间，后台清理据此找到进行中的转换的目录
+func (s *GRPCServer) outputSubdir(session *ConversionSession) string {
+	if s.outputLayout == "" {
+		return fmt.Sprintf("session_%d", session.StartTime.UnixNano())
+	}
+	return expandOutputLayout(s.outputLayout, session.Tenant, session.ID, session.StartTime)
+}
+
+// sanitizeTenant 将租户名转换为可以安全用作目录名的形式
+func sanitizeTenant(tenant string) string {
+	tenant = unsafePathChars.ReplaceAllString(strings.TrimSpace(tenant), "_")
+	if tenant == "" || strings.Trim(tenant, ".") == "" {
+		return defaultTenant
+	}
+	return tenant
+}
+
+// tenantFromContext 从gRPC请求元数据中读取租户
+func tenantFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(tenantMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// renderBuffersPerConversion 估算内存时每个转换同时存在的整幅图片缓冲数量
+	// (渲染、缩放、叠加和编码各需要一份RGBA图像)
+	renderBuffersPerConversion = 4
+	// defaultRenderPixels 调用方没有给出尺寸时按内置的默认尺寸估算
+	defaultRenderPixels = defaultOutputWidth * defaultOutputHeight
+)
+
+// errMemoryBudget 单个转换的内存估算超过了整个预算，无论等待多久都无法执行
+var errMemoryBudget = errors.New("转换所需内存超过服务器内存预算")
+
+// memoryBudget 按估算的内存占用限制同时进行的转换，预算不足时等待其他转换释放
+type memoryBudget struct {
+	limit int64
+	used  int64
+	mutex sync.Mutex
+	cond  *sync.Cond
+}
+
+// newMemoryBudget 创建内存预算，limit 不大于0时返回nil (不限制)
+func newMemoryBudget(limit int64) *memoryBudget {
+	if limit <= 0 {
+		return nil
+	}
+	budget := &memoryBudget{limit: limit}
+	budget.cond = sync.NewCond(&budget.mutex)
+	return budget
+}
+
+// estimateMemory 估算一次转换的内存占用: 上传数据及其临时副本，加上渲染图片的缓冲
+func estimateMemory(uploadSize int64, width, height int) int64 {
+	pixels := int64(width) * int64(height)
+	if pixels <= 0 {
+		pixels = defaultRenderPixels
+	}
+	return uploadSize*2 + pixels*4*renderBuffersPerConversion
+}
+
+// check 检查单个转换的估算是否可能满足，用于在排队之前尽早拒绝
+func (b *memoryBudget) check(size int64) error {
+	if b != nil && size > b.limit {
+		return fmt.Errorf("%w: 需要约 %d 字节，预算 %d 字节", errMemoryBudget, size, b.limit)
+	}
+	return nil
+}
+
+// acquire 占用 size 字节的预算，预算不足时阻塞直到其他转换释放
+func (b *memoryBudget) acquire(size int64) error {
+	if b == nil {
+		return nil
+	}
+	if err := b.check(size); err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for b.used+size > b.limit {
+		b.cond.Wait()
+	}
+	b.used += size
+	return nil
+}
+
+// release 归还 acquire 占用的预算
+func (b *memoryBudget) release(size int64) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	b.used -= size
+	b.mutex.Unlock()
+	b.cond.Broadcast()
+}
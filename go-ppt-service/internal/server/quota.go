@@ -0,0 +1,163 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ppt-to-images-service/internal/converter"
+)
+
+const (
+	// pngBytesPerPixel 估算PNG幻灯片大小时每像素的字节数
+	// 幻灯片以大面积纯色和文字为主，实际通常只有0.1-0.5字节，按1字节估算偏保守
+	pngBytesPerPixel = 1.0
+	// jpegBytesPerPixel 估算JPEG幻灯片大小时每像素的字节数
+	jpegBytesPerPixel = 0.25
+	// defaultEstimatedSlides 无法统计幻灯片数量 (非PPTX) 时按该数量估算
+	defaultEstimatedSlides = 30
+)
+
+// errOutputQuota 输出目录的总大小将超过配额
+var errOutputQuota = errors.New("输出目录空间不足")
+
+// outputQuota 限制输出目录的总大小
+// used 为已写入的文件 (启动时扫描一次，之后随转换完成和目录删除增减)，reserved 为进行中转换的预估输出
+type outputQuota struct {
+	limit    int64
+	used     int64
+	reserved int64
+	mutex    sync.Mutex
+}
+
+// newOutputQuota 创建输出配额并统计 dir 中已有文件的大小，limit 不大于0时返回nil (不限制)
+func newOutputQuota(limit int64, dir string) *outputQuota {
+	if limit <= 0 {
+		return nil
+	}
+	return &outputQuota{limit: limit, used: dirSize(dir)}
+}
+
+// estimateOutput 估算一次转换写入输出目录的字节数:
+// 幻灯片数量 (PPTX读取 presentation.xml，指定 slide_indices 时取两者的较小值，否则按 defaultEstimatedSlides)
+// 乘以每张幻灯片所有输出尺寸的像素数，再乘以按格式估算的每像素字节数；保留上传文件时加上文件大小
+// 总览图、HTML页面、动画的多个构建步骤不计入
+func estimateOutput(pptData []byte, options converter.ConversionOptions) int64 {
+	slides, ok := converter.CountSlides(pptData)
+	if !ok {
+		slides = defaultEstimatedSlides
+	}
+	if len(options.SlideIndices) > 0 && (len(options.SlideIndices) < slides || !ok) {
+		slides = len(options.SlideIndices)
+	}
+
+	var pixels int64
+	for _, resolution := range options.Resolutions {
+		pixels += int64(resolution.Width) * int64(resolution.Height)
+	}
+	if pixels == 0 {
+		pixels = int64(options.Width) * int64(options.Height)
+	}
+	if pixels <= 0 {
+		pixels = defaultRenderPixels
+	}
+
+	bytesPerPixel := pngBytesPerPixel
+	if format := strings.ToUpper(options.OutputFormat); format == "JPEG" || format == "JPG" {
+		bytesPerPixel = jpegBytesPerPixel
+	}
+
+	size := int64(float64(pixels)*bytesPerPixel) * int64(slides)
+	if options.KeepUploadDir != "" {
+		size += int64(len(pptData))
+	}
+	return size
+}
+
+// reserve 为进行中的转换预留 size 字节，已用、已预留与 size 之和超过配额时返回 errOutputQuota
+func (q *outputQuota) reserve(size int64) error {
+	if q == nil {
+		return nil
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.used+q.reserved+size > q.limit {
+		return fmt.Errorf("%w: 预计写入 %d 字节，已使用 %d 字节 (另有 %d 字节预留)，配额 %d 字节",
+			errOutputQuota, size, q.used, q.reserved, q.limit)
+	}
+	q.reserved += size
+	return nil
+}
+
+// commit 转换结束后释放 reserved 字节的预留，并记录实际写入的 written 字节
+func (q *outputQuota) commit(reserved, written int64) {
+	if q == nil {
+		return
+	}
+
+	q.mutex.Lock()
+	q.reserved -= reserved
+	q.used += written
+	q.mutex.Unlock()
+}
+
+// add 记录不经过预留直接写入的文件 (如 Transcode 生成的图片)
+func (q *outputQuota) add(size int64) {
+	q.commit(0, size)
+}
+
+// remove 记录被删除的文件
+func (q *outputQuota) remove(size int64) {
+	if q == nil {
+		return
+	}
+
+	q.mutex.Lock()
+	q.used -= size
+	if q.used < 0 {
+		q.used = 0
+	}
+	q.mutex.Unlock()
+}
+
+// reserveOutput 为一次转换预留输出配额
+// 配额不足且设置了 -output-ttl 时先立即清理已过期的输出 (不必等待下一次定期清理)，再重试一次
+func (s *GRPCServer) reserveOutput(size int64) error {
+	err := s.quota.reserve(size)
+	if err == nil || s.outputTTL <= 0 {
+		return err
+	}
+
+	s.logger.Infof("输出目录配额不足，提前清理过期的输出: %v", err)
+	s.prune(time.Now().Add(-s.outputTTL))
+	return s.quota.reserve(size)
+}
+
+// dirSize 目录中所有文件的总大小，读取失败的文件不计入
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// imagesSize 图片文件的总大小
+func imagesSize(images []converter.ImageInfo) int64 {
+	var size int64
+	for _, image := range images {
+		size += image.FileSize
+	}
+	return size
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"ppt-to-images-service/internal/converter"
+)
+
+func TestOutputQuota(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "existing.png"), make([]byte, 400), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 启动时统计已有的文件
+	quota := newOutputQuota(1000, dir)
+	if quota.used != 400 {
+		t.Fatalf("已使用 %d 字节，应为400", quota.used)
+	}
+
+	if err := quota.reserve(500); err != nil {
+		t.Fatal(err)
+	}
+	// 预留的空间同样计入
+	if err := quota.reserve(200); !errors.Is(err, errOutputQuota) {
+		t.Errorf("超过配额时错误为 %v", err)
+	}
+
+	// 实际写入比预估少，剩余的预留归还
+	quota.commit(500, 300)
+	if err := quota.reserve(300); err != nil {
+		t.Errorf("提交后仍无法预留: %v", err)
+	}
+	quota.commit(300, 0)
+
+	// 删除输出目录后释放空间
+	s := &GRPCServer{outputDir: dir, quota: quota, logger: logrus.New()}
+	s.logger.SetOutput(io.Discard)
+	session := filepath.Join(dir, "session_1")
+	if err := os.MkdirAll(session, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(session, "slide_001.png"), make([]byte, 300), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.removeOutputDir(session); err != nil {
+		t.Fatal(err)
+	}
+	if quota.used != 400 {
+		t.Errorf("删除后已使用 %d 字节，应为400", quota.used)
+	}
+}
+
+func TestEstimateOutput(t *testing.T) {
+	png := estimateOutput(nil, converter.ConversionOptions{Width: 100, Height: 100})
+	if png != 100*100*defaultEstimatedSlides {
+		t.Errorf("PNG估算为 %d", png)
+	}
+
+	jpeg := estimateOutput(nil, converter.ConversionOptions{Width: 100, Height: 100, OutputFormat: "jpeg", SlideIndices: []int{1, 2}})
+	if jpeg != 100*100/4*2 {
+		t.Errorf("指定两张幻灯片的JPEG估算为 %d", jpeg)
+	}
+}
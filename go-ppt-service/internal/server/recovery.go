@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+)
+
+// UnaryRecovery 捕获一元调用处理过程中的panic，记录堆栈后返回 Internal，避免畸形文件导致整个服务退出
+func UnaryRecovery(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoveredError(logger, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery 捕获流式调用处理过程中的panic，记录堆栈后返回 Internal
+func StreamRecovery(logger *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoveredError(logger, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+// recoveredError 记录panic的值和堆栈，返回给客户端的错误不包含堆栈
+func recoveredError(logger *logrus.Logger, method string, r interface{}) error {
+	logger.Errorf("处理 %s 时发生panic: %v\n%s", method, r, debug.Stack())
+	return status.Errorf(codes.Internal, "服务器内部错误: %v", r)
+}
+
+// recoverJob 捕获异步转换任务中的panic，把会话标记为失败；任务在工作池的协程中执行，不经过拦截器
+// 在 processJob 中 defer 调用
+func (s *GRPCServer) recoverJob(job *conversionJob) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	s.logger.Errorf("转换任务 %s (%s) 发生panic: %v\n%s", job.session.ID, job.req.Filename, r, debug.Stack())
+
+	message := fmt.Sprintf("转换失败: 服务器内部错误: %v", r)
+	job.session.Mutex.Lock()
+	if job.session.EndTime != nil {
+		job.session.Mutex.Unlock()
+		return
+	}
+	job.session.Status = converter.ConversionStatus{
+		Status:   "failed",
+		Progress: 100,
+		Message:  message,
+	}
+	job.session.Result = &converter.ConversionResult{
+		Success: false,
+		Message: message,
+		Error:   fmt.Sprint(r),
+	}
+	now := time.Now()
+	job.session.EndTime = &now
+	job.session.closeSubscribers()
+	job.session.Mutex.Unlock()
+
+	s.notifyConversion(job.session, job.req.Filename)
+}
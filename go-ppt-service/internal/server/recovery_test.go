@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoveryInterceptors(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	unary := UnaryRecovery(logger)
+	info := &grpc.UnaryServerInfo{FullMethod: "/ppt.PPTToImagesService/ConvertPPTSimple"}
+
+	_, err := unary(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		var slides []int
+		return slides[3], nil
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("panic后返回 %v，应为 Internal", err)
+	}
+
+	// 发生panic后后续调用照常处理
+	resp, err := unary(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "resp", nil
+	})
+	if resp != "resp" || err != nil {
+		t.Errorf("panic之后的调用返回 %v, %v", resp, err)
+	}
+
+	stream := StreamRecovery(logger)
+	err = stream(nil, nil, &grpc.StreamServerInfo{FullMethod: "/ppt.PPTToImagesService/ConvertPPT"}, func(srv interface{}, stream grpc.ServerStream) error {
+		panic("畸形文件")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("流式调用panic后返回 %v，应为 Internal", err)
+	}
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// RetrySlide 用保留的上传文件重新渲染已结束转换中的单张幻灯片，替换会话中该幻灯片的图片并移除它的 SlideError
+// 新图片写入原输出目录下的 retry_<编号> 子目录并登记新的下载ID，原图片的文件和下载ID被删除
+func (s *GRPCServer) RetrySlide(ctx context.Context, req *proto.RetrySlideRequest) (*proto.ImageInfo, error) {
+	session, err := s.findSession(ctx, req.ConversionId)
+	if err != nil {
+		return nil, err
+	}
+
+	session.Mutex.RLock()
+	ended := session.EndTime != nil
+	state := session.Status.Status
+	result := session.Result
+	stored := session.options
+	session.Mutex.RUnlock()
+
+	if !ended || result == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "转换尚未结束: %s (%s)", req.ConversionId, state)
+	}
+
+	sourcePath, err := s.keptUpload(session.ID)
+	if err != nil || stored == nil {
+		return nil, status.Errorf(codes.NotFound, "转换 %s 的源文件已不存在 (需要 -keep-uploads，且未被清理)", req.ConversionId)
+	}
+
+	slideNumber := int(req.SlideNumber)
+	original := slideNumber - stored.NumberOffset
+	if original < 1 || (result.TotalSlides > 0 && original > result.TotalSlides) {
+		return nil, status.Errorf(codes.OutOfRange, "幻灯片编号 %d 超出范围 (共 %d 张)", slideNumber, result.TotalSlides)
+	}
+
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "读取源文件失败: %v", err)
+	}
+	filename := filepath.Base(sourcePath)
+
+	options := *stored
+	if req.Options != nil {
+		req.Options.Filename = filename
+		req.Options.PptData = data
+		if err := s.validateConvertRequest(req.Options); err != nil {
+			return nil, err
+		}
+		options = s.conversionOptionsFromRequest(req.Options)
+		options.Timeout = stored.Timeout
+	}
+
+	// 只渲染这一张幻灯片，编号与原转换保持一致，不生成汇总文件
+	options.SlideIndices = []int{original}
+	options.NumberOffset = stored.NumberOffset
+	options.Order = ""
+	options.OrderIndices = nil
+	options.ContactSheet = nil
+	options.HTMLBundle = false
+	options.PDFImageOnly = false
+	options.NotesPDF = false
+	options.ExtractBackground = false
+	options.CoverOnly = false
+	options.GenerateManifest = false
+	options.ReturnPartial = false
+	options.OnImageReady = nil
+	options.KeepUploadDir = ""
+	options.ConversionID = fmt.Sprintf("%s_retry_%d", session.ID, slideNumber)
+	options.OutputSubdir = filepath.Join(s.retryBaseDir(session, result), fmt.Sprintf("retry_%d", slideNumber))
+
+	s.logger.Infof("重新渲染幻灯片 %d (ID: %s)", slideNumber, session.ID)
+	retried, err := s.convert(ctx, data, filename, options, nil)
+	if err != nil {
+		s.logger.Errorf("重新渲染幻灯片 %d 失败 (ID: %s): %v", slideNumber, session.ID, err)
+		return nil, status.Error(conversionErrorCode(err), err.Error())
+	}
+	if len(retried.Images) == 0 {
+		message := retried.Error
+		for _, slideError := range retried.SlideErrors {
+			message = slideError.Error
+		}
+		return nil, status.Errorf(codes.Internal, "重新渲染幻灯片 %d 失败: %s", slideNumber, message)
+	}
+
+	s.registerImages(session.ID, session.Tenant, retried.Images)
+	s.replaceSlideImages(session, slideNumber, retried.Images)
+
+	s.logger.Infof("幻灯片 %d 重新渲染完成 (ID: %s)", slideNumber, session.ID)
+	return s.convertImageInfoToProto(retried.Images[0]), nil
+}
+
+// keptUpload -keep-uploads 为转换保留的上传文件
+func (s *GRPCServer) keptUpload(conversionID string) (string, error) {
+	dir := filepath.Join(s.outputDir, uploadsDirName, filepath.Base(conversionID))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			return filepath.Join(dir, entry.Name()), nil
+		}
+	}
+	return "", fmt.Errorf("%s 中没有保留的上传文件", dir)
+}
+
+// retryBaseDir 重新渲染的图片所在子目录的上级目录 (相对输出目录): 原图片所在的目录，
+// 没有图片时使用会话的输出子目录
+func (s *GRPCServer) retryBaseDir(session *ConversionSession, result *converter.ConversionResult) string {
+	root := filepath.Clean(s.outputDir)
+	for _, image := range result.Images {
+		if image.SlideNumber == 0 {
+			continue
+		}
+		if rel, err := filepath.Rel(root, filepath.Dir(image.FilePath)); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return s.outputSubdir(session)
+}
+
+// replaceSlideImages 用重新渲染的图片替换会话结果中该幻灯片的图片，删除原图片的文件和下载ID，并移除该幻灯片的错误
+// 原来没有图片时按幻灯片编号插入到汇总文件 (总览图、HTML页面、manifest.json) 之前；背景图片不重新生成，保留原来的文件
+func (s *GRPCServer) replaceSlideImages(session *ConversionSession, slideNumber int, images []converter.ImageInfo) {
+	session.Mutex.Lock()
+	defer session.Mutex.Unlock()
+
+	// 复制结果，已返回给其他调用方的结果不受影响
+	result := *session.Result
+	var kept, old []converter.ImageInfo
+	insert := -1
+	for _, image := range result.Images {
+		if image.SlideNumber == slideNumber && !image.Background {
+			if insert < 0 {
+				insert = len(kept)
+			}
+			old = append(old, image)
+			continue
+		}
+		kept = append(kept, image)
+	}
+	if insert < 0 {
+		insert = len(kept)
+		for i, image := range kept {
+			if image.SlideNumber == 0 || image.SlideNumber > slideNumber || image.Background {
+				insert = i
+				break
+			}
+		}
+		result.ConvertedSlides++
+	}
+	result.Images = append(append(append([]converter.ImageInfo{}, kept[:insert]...), images...), kept[insert:]...)
+
+	var slideErrors []converter.SlideError
+	for _, slideError := range result.SlideErrors {
+		if slideError.SlideNumber != slideNumber {
+			slideErrors = append(slideErrors, slideError)
+		}
+	}
+	result.SlideErrors = slideErrors
+	session.Result = &result
+
+	// 再次重试时新图片与上次重试的图片路径相同，这些文件不能删除
+	paths := make(map[string]bool, len(images))
+	downloadIDs := make(map[string]bool, len(images))
+	for _, image := range images {
+		paths[image.FilePath] = true
+		downloadIDs[image.DownloadID] = true
+	}
+	s.downloadsMutex.Lock()
+	for _, image := range old {
+		if !downloadIDs[image.DownloadID] {
+			delete(s.downloads, image.DownloadID)
+		}
+	}
+	if tracker := s.downloadTrackers[session.ID]; tracker != nil && tracker.result != nil {
+		tracker.replace(&result, old, images)
+	}
+	s.downloadsMutex.Unlock()
+	for _, image := range old {
+		if !paths[image.FilePath] && os.Remove(image.FilePath) == nil {
+			s.quota.remove(image.FileSize)
+		}
+	}
+}
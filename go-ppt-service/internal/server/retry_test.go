@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// retryConverter 把请求的幻灯片写为空白文件的转换器
+type retryConverter struct {
+	outputDir string
+	calls     int
+}
+
+func (c *retryConverter) ConvertPPT(ctx context.Context, pptData []byte, filename string, options converter.ConversionOptions, progressCallback converter.ProgressCallback) (*converter.ConversionResult, error) {
+	c.calls++
+	dir := filepath.Join(c.outputDir, options.OutputSubdir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	result := &converter.ConversionResult{Success: true}
+	for _, index := range options.SlideIndices {
+		number := index + options.NumberOffset
+		path := filepath.Join(dir, fmt.Sprintf("slide_%03d.png", number))
+		if err := os.WriteFile(path, []byte("png"), 0644); err != nil {
+			return nil, err
+		}
+		result.Images = append(result.Images, converter.ImageInfo{
+			SlideNumber: number,
+			FilePath:    path,
+			DownloadID:  fmt.Sprintf("download_%d", c.calls),
+		})
+	}
+	return result, nil
+}
+
+func (c *retryConverter) Backend() string               { return "test" }
+func (c *retryConverter) SupportedExtensions() []string { return []string{".pptx"} }
+func (c *retryConverter) KillProcesses() int            { return 0 }
+func (c *retryConverter) Close() error                  { return nil }
+
+func TestRetrySlide(t *testing.T) {
+	root := t.TempDir()
+	s := &GRPCServer{
+		converter:   &retryConverter{outputDir: root},
+		logger:      logrus.New(),
+		conversions: make(map[string]*ConversionSession),
+		downloads:   make(map[string]downloadEntry),
+		outputDir:   root,
+		keepUploads: true,
+		ctx:         context.Background(),
+		active:      newActiveConversions(),
+	}
+	s.logger.SetOutput(io.Discard)
+
+	uploads := filepath.Join(root, uploadsDirName, "conv_1")
+	if err := os.MkdirAll(uploads, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(uploads, "deck.pptx"), []byte("ppt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first := filepath.Join(root, "conv_1", "slide_001.png")
+	if err := os.MkdirAll(filepath.Dir(first), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(first, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第2张幻灯片渲染失败
+	images := []converter.ImageInfo{{SlideNumber: 1, FilePath: first, DownloadID: "download_0"}}
+	s.registerImages("conv_1", "", images)
+	now := time.Now()
+	session := &ConversionSession{
+		ID:      "conv_1",
+		EndTime: &now,
+		Result: &converter.ConversionResult{
+			TotalSlides:     3,
+			ConvertedSlides: 1,
+			Images:          images,
+			SlideErrors:     []converter.SlideError{{SlideNumber: 2, Error: "空白"}},
+		},
+		options: &converter.ConversionOptions{},
+	}
+	s.conversions[session.ID] = session
+
+	info, err := s.RetrySlide(context.Background(), &proto.RetrySlideRequest{ConversionId: "conv_1", SlideNumber: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.SlideNumber != 2 {
+		t.Errorf("返回的图片为 %+v", info)
+	}
+	result := session.Result
+	if len(result.Images) != 2 || result.Images[1].SlideNumber != 2 || len(result.SlideErrors) != 0 || result.ConvertedSlides != 2 {
+		t.Fatalf("重试后的结果: %+v", result)
+	}
+	if dir := filepath.Dir(result.Images[1].FilePath); dir != filepath.Join(root, "conv_1", "retry_2") {
+		t.Errorf("图片写入了 %s", dir)
+	}
+	if _, err := s.findImageByDownloadID(info.DownloadId, "conv_1", ""); err != nil {
+		t.Errorf("新图片没有登记下载ID: %v", err)
+	}
+
+	// 重试已有图片的幻灯片时删除原图片和下载ID
+	if _, err := s.RetrySlide(context.Background(), &proto.RetrySlideRequest{ConversionId: "conv_1", SlideNumber: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("原图片没有被删除: %v", err)
+	}
+	if _, err := s.findImageByDownloadID(images[0].DownloadID, "", ""); err == nil {
+		t.Error("原图片的下载ID仍然有效")
+	}
+
+	_, err = s.RetrySlide(context.Background(), &proto.RetrySlideRequest{ConversionId: "conv_1", SlideNumber: 4})
+	if status.Code(err) != codes.OutOfRange {
+		t.Errorf("编号超出范围时错误为 %v", err)
+	}
+
+	// 没有保留的上传文件时返回 NotFound
+	if err := os.RemoveAll(uploads); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.RetrySlide(context.Background(), &proto.RetrySlideRequest{ConversionId: "conv_1", SlideNumber: 2})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("源文件不存在时错误为 %v", err)
+	}
+}
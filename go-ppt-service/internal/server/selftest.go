@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// selfTestDeck 自检使用的单页演示文稿: 一个带文字的色块标题和一个文本框
+//
+//go:embed selftest/sample.pptx
+var selfTestDeck []byte
+
+// SelfTestStep 自检的一个步骤，Err 为空表示通过
+type SelfTestStep struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// SelfTest 用内置的示例演示文稿走一遍完整流程: 转换 (渲染和编码)、检查图片、登记下载ID、
+// 通过 DownloadImage 下载并与文件比对，最后删除输出。返回执行过的步骤，某一步失败时不再继续
+// 用于部署后验证渲染后端和输出目录是否可用，不需要客户端
+func (s *GRPCServer) SelfTest(ctx context.Context) []SelfTestStep {
+	var steps []SelfTestStep
+	run := func(name string, fn func() error) bool {
+		start := time.Now()
+		err := fn()
+		steps = append(steps, SelfTestStep{Name: name, Duration: time.Since(start), Err: err})
+		return err == nil
+	}
+
+	session := s.createSession()
+	defer s.removeSession(session.ID)
+
+	req := &proto.ConvertPPTRequest{
+		Filename:     "selftest.pptx",
+		PptData:      selfTestDeck,
+		Width:        640,
+		Height:       360,
+		OutputFormat: "PNG",
+	}
+	ok := run("转换", func() error {
+		if err := s.runConversion(ctx, session, req, nil, nil); err != nil {
+			return err
+		}
+		result := s.sessionResult(session)
+		if result.ConvertedSlides != result.TotalSlides || result.ConvertedSlides == 0 {
+			return fmt.Errorf("转换了 %d/%d 张幻灯片: %s", result.ConvertedSlides, result.TotalSlides, result.Error)
+		}
+		return nil
+	})
+	if !ok {
+		return steps
+	}
+	defer func() {
+		run("清理", func() error {
+			_, err := s.DeleteConversion(ctx, &proto.DeleteRequest{ConversionId: session.ID})
+			return err
+		})
+	}()
+
+	result := s.sessionResult(session)
+	ok = run("检查图片", func() error {
+		for _, image := range result.Images {
+			if err := checkSelfTestImage(image.FilePath, image.Width, image.Height); err != nil {
+				return fmt.Errorf("%s: %v", image.Filename, err)
+			}
+			if image.Backend != "" {
+				s.logger.Infof("自检: %s 由 %s 渲染 (%dx%d)", image.Filename, image.Backend, image.Width, image.Height)
+			}
+		}
+		return nil
+	})
+	if !ok {
+		return steps
+	}
+
+	run("下载", func() error {
+		for _, image := range result.Images {
+			// 启用下载后清理时最后一次下载会删除输出目录，先读取文件
+			data, err := os.ReadFile(image.FilePath)
+			if err != nil {
+				return fmt.Errorf("读取 %s 失败: %v", image.Filename, err)
+			}
+			stream := &selfTestDownloadStream{ctx: ctx}
+			if err := s.DownloadImage(&proto.DownloadRequest{DownloadId: image.DownloadID, ConversionId: session.ID}, stream); err != nil {
+				return fmt.Errorf("%s: %v", image.Filename, err)
+			}
+			if !bytes.Equal(stream.data.Bytes(), data) {
+				return fmt.Errorf("%s: 下载了 %d 字节，与文件 (%d 字节) 不一致", image.Filename, stream.data.Len(), len(data))
+			}
+		}
+		return nil
+	})
+	return steps
+}
+
+// sessionResult 会话的转换结果，没有结果时返回空结果
+func (s *GRPCServer) sessionResult(session *ConversionSession) *converter.ConversionResult {
+	session.Mutex.RLock()
+	defer session.Mutex.RUnlock()
+	if session.Result == nil {
+		return &converter.ConversionResult{}
+	}
+	return session.Result
+}
+
+// checkSelfTestImage 检查图片能够解码且尺寸与记录的一致
+func checkSelfTestImage(path string, width, height int) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return fmt.Errorf("无法解码: %v", err)
+	}
+	if config.Width != width || config.Height != height {
+		return fmt.Errorf("实际尺寸 %dx%d 与记录的 %dx%d 不一致", config.Width, config.Height, width, height)
+	}
+	return nil
+}
+
+// selfTestDownloadStream 把 DownloadImage 发送的数据块收集到内存中
+type selfTestDownloadStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	data bytes.Buffer
+}
+
+func (s *selfTestDownloadStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *selfTestDownloadStream) Send(resp *proto.DownloadResponse) error {
+	if chunk, ok := resp.Response.(*proto.DownloadResponse_Chunk); ok {
+		s.data.Write(chunk.Chunk)
+	}
+	return nil
+}
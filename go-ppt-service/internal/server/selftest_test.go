@@ -0,0 +1,17 @@
+package server
+
+import (
+	"testing"
+
+	"ppt-to-images-service/internal/converter"
+)
+
+func TestSelfTestDeck(t *testing.T) {
+	if slides, ok := converter.CountSlides(selfTestDeck); !ok || slides != 1 {
+		t.Fatalf("内置演示文稿的幻灯片数为 %d (%v)，应为1", slides, ok)
+	}
+	// 幻灯片通过关系链找到版式，说明包结构完整
+	if slides, total, err := converter.SlidesWithLayout(selfTestDeck, "blank"); err != nil || total != 1 || len(slides) != 1 {
+		t.Errorf("按版式查找幻灯片: %v (共 %d 张)，错误 %v", slides, total, err)
+	}
+}
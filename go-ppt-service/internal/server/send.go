@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/proto"
+)
+
+// contextSender 在请求上下文结束时放弃阻塞的发送
+// 客户端不读取数据时 Send 会因流控一直阻塞，占用转换协程和转换槽位；
+// 超过截止时间或客户端断开后立即返回错误，之后的发送也不再尝试
+type contextSender struct {
+	ctx   context.Context
+	idle  time.Duration // 单条消息的最长发送时间，0表示只受 ctx 限制
+	mutex sync.Mutex
+	err   error // 放弃发送后的错误，之后的发送直接返回
+}
+
+// newContextSender 创建遵守 ctx 截止时间的发送器，idle 大于0时单条消息发送超过该时间也放弃
+// 下载没有截止时间时，停止读取的客户端会一直占用协程和打开的文件，idle 用于回收这类连接
+func newContextSender(ctx context.Context, idle time.Duration) *contextSender {
+	return &contextSender{ctx: ctx, idle: idle}
+}
+
+// send 调用 fn 发送一条消息，同一时间只有一条在发送
+// ctx 先结束时不等待 fn 返回: 被放弃的发送在处理函数返回、gRPC关闭流后结束
+func (c *contextSender) send(fn func() error) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	if err := c.ctx.Err(); err != nil {
+		c.err = status.FromContextError(err).Err()
+		return c.err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	var idle <-chan time.Time
+	if c.idle > 0 {
+		timer := time.NewTimer(c.idle)
+		defer timer.Stop()
+		idle = timer.C
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-c.ctx.Done():
+		c.err = status.FromContextError(c.ctx.Err()).Err()
+		return c.err
+	case <-idle:
+		c.err = status.Errorf(codes.DeadlineExceeded, "客户端 %v 内没有读取数据，放弃发送", c.idle)
+		return c.err
+	}
+}
+
+// convertPPTStream ConvertPPT 的响应流，Send 遵守请求的截止时间
+type convertPPTStream struct {
+	proto.PPTToImagesService_ConvertPPTServer
+	sender *contextSender
+}
+
+// Send 发送响应，请求结束后立即返回错误
+func (s convertPPTStream) Send(resp *proto.ConvertPPTResponse) error {
+	return s.sender.send(func() error {
+		return s.PPTToImagesService_ConvertPPTServer.Send(resp)
+	})
+}
+
+// downloadStream DownloadImage 的响应流，Send 遵守请求的截止时间
+type downloadStream struct {
+	proto.PPTToImagesService_DownloadImageServer
+	sender *contextSender
+}
+
+// Send 发送响应，请求结束后立即返回错误
+func (s downloadStream) Send(resp *proto.DownloadResponse) error {
+	return s.sender.send(func() error {
+		return s.PPTToImagesService_DownloadImageServer.Send(resp)
+	})
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// stalledDownloadStream 模拟不读取数据的客户端: 第一条消息之后的 Send 一直阻塞，直到 release 被关闭
+type stalledDownloadStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	sent    int
+	release chan struct{}
+}
+
+func (s *stalledDownloadStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *stalledDownloadStream) Send(*proto.DownloadResponse) error {
+	s.sent++
+	if s.sent > 1 {
+		<-s.release
+		return errors.New("流已关闭")
+	}
+	return nil
+}
+
+func TestContextSenderDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+
+	sender := newContextSender(ctx, 0)
+	start := time.Now()
+	err := sender.send(func() error {
+		<-release
+		return nil
+	})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("错误为 %v，应为 DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("超过截止时间 %v 后才返回", elapsed)
+	}
+
+	// 放弃之后的发送直接返回错误，不再调用
+	called := false
+	if err := sender.send(func() error { called = true; return nil }); status.Code(err) != codes.DeadlineExceeded || called {
+		t.Errorf("放弃后的发送: 错误 %v，调用 %v", err, called)
+	}
+}
+
+func TestDownloadImageStalledClient(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "slide_001.png")
+	if err := os.WriteFile(imagePath, make([]byte, 256*1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &GRPCServer{downloads: map[string]downloadEntry{"image": {path: imagePath}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stream := &stalledDownloadStream{ctx: ctx, release: make(chan struct{})}
+	defer close(stream.release)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.DownloadImage(&proto.DownloadRequest{DownloadId: "image"}, stream)
+	}()
+
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.DeadlineExceeded {
+			t.Errorf("错误为 %v，应为 DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("客户端不读取数据时 DownloadImage 没有在截止时间后返回")
+	}
+}
+
+func TestDownloadImageIdleTimeout(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "slide_001.png")
+	if err := os.WriteFile(imagePath, make([]byte, 256*1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &GRPCServer{
+		downloads:           map[string]downloadEntry{"image": {path: imagePath}},
+		downloadIdleTimeout: 50 * time.Millisecond,
+	}
+
+	// 调用没有截止时间，只靠空闲超时结束
+	stream := &stalledDownloadStream{ctx: context.Background(), release: make(chan struct{})}
+	defer close(stream.release)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.DownloadImage(&proto.DownloadRequest{DownloadId: "image"}, stream)
+	}()
+
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.DeadlineExceeded {
+			t.Errorf("错误为 %v，应为 DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("客户端停止读取时 DownloadImage 没有在空闲超时后返回")
+	}
+}
+
+func TestFindImageByDownloadIDNamespace(t *testing.T) {
+	s := &GRPCServer{downloads: make(map[string]downloadEntry)}
+	images := []converter.ImageInfo{{FilePath: "a.png", DownloadID: "download_1"}}
+	s.registerImages("conv_a", "tenant_a", images)
+	s.registerImages("conv_a", "tenant_a", images)
+	if images[0].DownloadID != "conv_a.download_1" {
+		t.Fatalf("下载ID为 %s，应为 conv_a.download_1", images[0].DownloadID)
+	}
+	untenanted := []converter.ImageInfo{{FilePath: "c.png", DownloadID: "download_1"}}
+	s.registerImages("conv_c", "", untenanted)
+
+	for _, tc := range []struct {
+		downloadID, conversionID, tenant string
+		ok                               bool
+	}{
+		{images[0].DownloadID, "", "tenant_a", true},
+		{images[0].DownloadID, "conv_a", "tenant_a", true},
+		{images[0].DownloadID, "", "", false}, // 省略租户不能绕过检查
+		{images[0].DownloadID, "conv_a", "", false},
+		{images[0].DownloadID, "conv_b", "tenant_a", false},
+		{images[0].DownloadID, "", "tenant_b", false},
+		{untenanted[0].DownloadID, "", "", true},
+		{untenanted[0].DownloadID, "", "tenant_a", false},
+	} {
+		path, err := s.findImageByDownloadID(tc.downloadID, tc.conversionID, tc.tenant)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s 转换 %q 租户 %q: 路径 %q，错误 %v", tc.downloadID, tc.conversionID, tc.tenant, path, err)
+		}
+	}
+}
+
+func TestFindSessionTenant(t *testing.T) {
+	s := &GRPCServer{conversions: make(map[string]*ConversionSession)}
+	session := s.createSession()
+	session.Tenant = "tenant_a"
+
+	other := s.createSession()
+	if !strings.HasPrefix(session.ID, "conv_") || len(session.ID) != len("conv_")+32 || other.ID == session.ID {
+		t.Errorf("转换ID为 %s 和 %s，应为随机生成的不同ID", session.ID, other.ID)
+	}
+
+	for _, tc := range []struct {
+		tenant string
+		ok     bool
+	}{
+		{"tenant_a", true},
+		{"", false},
+		{"tenant_b", false},
+	} {
+		ctx := context.Background()
+		if tc.tenant != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(tenantMetadataKey, tc.tenant))
+		}
+		_, err := s.GetConversionStatus(ctx, &proto.StatusRequest{ConversionId: session.ID})
+		if tc.ok && err != nil || !tc.ok && status.Code(err) != codes.NotFound {
+			t.Errorf("租户 %q 查询状态的错误为 %v", tc.tenant, err)
+		}
+		_, err = s.DeleteConversion(ctx, &proto.DeleteRequest{ConversionId: session.ID})
+		if !tc.ok && status.Code(err) != codes.NotFound {
+			t.Errorf("租户 %q 删除的错误为 %v，应为 NotFound", tc.tenant, err)
+		}
+	}
+}
+
+// infoDownloadStream 记录 DownloadImage 发送的文件信息
+type infoDownloadStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	info *proto.DownloadInfo
+}
+
+func (s *infoDownloadStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *infoDownloadStream) Send(resp *proto.DownloadResponse) error {
+	if info, ok := resp.Response.(*proto.DownloadResponse_Info); ok {
+		s.info = info.Info
+	}
+	return nil
+}
+
+func TestDownloadContentType(t *testing.T) {
+	dir := t.TempDir()
+	s := &GRPCServer{downloads: make(map[string]downloadEntry), logger: logrus.New()}
+	s.logger.SetOutput(io.Discard)
+	handler := NewHTTPGateway(s, s.logger).Handler()
+
+	for _, tt := range []struct {
+		filename    string
+		contentType string
+	}{
+		{"slide_001.png", "image/png"},
+		{"notes.pdf", "application/pdf"},
+		{"manifest.json", "application/json"},
+	} {
+		if err := os.WriteFile(filepath.Join(dir, tt.filename), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		images := []converter.ImageInfo{{FilePath: filepath.Join(dir, tt.filename), DownloadID: tt.filename}}
+		s.registerImages("conv", "", images)
+
+		stream := &infoDownloadStream{ctx: context.Background()}
+		if err := s.DownloadImage(&proto.DownloadRequest{DownloadId: images[0].DownloadID}, stream); err != nil {
+			t.Fatalf("%s: %v", tt.filename, err)
+		}
+		if stream.info.ContentType != tt.contentType {
+			t.Errorf("%s: gRPC下载的类型为 %s，应为 %s", tt.filename, stream.info.ContentType, tt.contentType)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/download/"+images[0].DownloadID, nil))
+		if got := recorder.Header().Get("Content-Type"); recorder.Code != http.StatusOK || got != tt.contentType {
+			t.Errorf("%s: HTTP下载的状态为 %d，类型为 %s，应为 %s", tt.filename, recorder.Code, got, tt.contentType)
+		}
+	}
+}
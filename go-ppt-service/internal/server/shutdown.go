@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// activeConversions 进行中的转换数量，关闭服务时等待它们结束
+type activeConversions struct {
+	mutex  sync.Mutex
+	count  int
+	idle   chan struct{} // 没有进行中的转换时已关闭
+	closed bool          // 关闭后不再开始新的转换
+}
+
+// newActiveConversions 创建计数器
+func newActiveConversions() *activeConversions {
+	idle := make(chan struct{})
+	close(idle)
+	return &activeConversions{idle: idle}
+}
+
+// begin 登记一个开始的转换，服务正在关闭时返回false
+func (a *activeConversions) begin() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.closed {
+		return false
+	}
+	if a.count == 0 {
+		a.idle = make(chan struct{})
+	}
+	a.count++
+	return true
+}
+
+// end 登记一个结束的转换
+func (a *activeConversions) end() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.count--
+	if a.count == 0 {
+		close(a.idle)
+	}
+}
+
+// close 不再接受新的转换，返回仍在进行的数量和它们全部结束时关闭的通道
+func (a *activeConversions) close() (int, <-chan struct{}) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.closed = true
+	return a.count, a.idle
+}
+
+// Shutdown 关闭服务: 拒绝新的转换，取消排队的异步任务，并取消所有进行中的转换 (结束外部进程)
+// 在 ctx 到期前等待这些转换退出，超时后强制结束残留的外部进程；之后再调用 grpc.Server.GracefulStop
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	interrupted, idle := s.active.close()
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.pool.Abort()
+		<-idle
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		killed := s.converter.KillProcesses()
+		s.logger.Warnf("等待转换结束超时，强制结束 %d 个外部进程", killed)
+		err = ctx.Err()
+	}
+
+	s.logger.Infof("服务关闭，中断了 %d 个进行中的转换", interrupted)
+	return err
+}
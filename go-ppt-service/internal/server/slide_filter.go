@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// convertFiltered 按 layout_filter 和 title_pattern 确定要转换的幻灯片后执行转换，匹配的编号记录在结果的 MatchedSlides 中
+// 同时指定了多个条件或 slide_indices 时只转换全部都包含的幻灯片
+func (s *GRPCServer) convertFiltered(ctx context.Context, req *proto.ConvertPPTRequest, options converter.ConversionOptions, progressCallback converter.ProgressCallback) (*converter.ConversionResult, error) {
+	if req.LayoutFilter == "" && req.TitlePattern == "" {
+		return s.convert(ctx, req.PptData, req.Filename, options, progressCallback)
+	}
+
+	var matches []int
+	var filters []string
+	total := 0
+	if req.LayoutFilter != "" {
+		layoutMatches, n, err := converter.SlidesWithLayout(req.PptData, req.LayoutFilter)
+		if err != nil {
+			return nil, err
+		}
+		matches, total = layoutMatches, n
+		filters = append(filters, fmt.Sprintf("版式 %q", req.LayoutFilter))
+	}
+	if req.TitlePattern != "" {
+		pattern, err := regexp.Compile(req.TitlePattern)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "无效的标题正则表达式: %v", err)
+		}
+		titleMatches, n, err := converter.SlidesWithTitle(req.PptData, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(filters) > 0 {
+			matches = intersectSlides(matches, titleMatches)
+		} else {
+			matches = titleMatches
+		}
+		total = n
+		filters = append(filters, fmt.Sprintf("标题 %q", req.TitlePattern))
+	}
+	if len(options.SlideIndices) > 0 {
+		matches = intersectSlides(matches, options.SlideIndices)
+	}
+	filter := strings.Join(filters, "、")
+
+	if len(matches) == 0 {
+		if !req.AllowEmptyFilter {
+			return nil, fmt.Errorf("%w: %s", converter.ErrNoMatchingSlides, filter)
+		}
+		s.logger.Infof("没有与%s匹配的幻灯片，返回空结果: %s", filter, req.Filename)
+		return &converter.ConversionResult{
+			Success:     true,
+			Message:     fmt.Sprintf("没有与%s匹配的幻灯片", filter),
+			TotalSlides: total,
+		}, nil
+	}
+
+	s.logger.Infof("%s匹配 %d/%d 张幻灯片: %s", filter, len(matches), total, req.Filename)
+	options.SlideIndices = matches
+	result, err := s.convert(ctx, req.PptData, req.Filename, options, progressCallback)
+	if result != nil {
+		result.MatchedSlides = matches
+	}
+	return result, err
+}
+
+// intersectSlides 返回 matches 中同时包含在 selected 里的幻灯片编号，保持 matches 的顺序
+func intersectSlides(matches, selected []int) []int {
+	set := make(map[int]bool, len(selected))
+	for _, index := range selected {
+		set[index] = true
+	}
+	var both []int
+	for _, index := range matches {
+		if set[index] {
+			both = append(both, index)
+		}
+	}
+	return both
+}
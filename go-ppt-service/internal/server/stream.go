@@ -0,0 +1,162 @@
+package server
+
+import (
+	"io"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// streamChunkSize ConvertAndStream 发送图片数据的分块大小
+const streamChunkSize = 64 * 1024
+
+// ConvertAndStream 在一次双向流调用中接收分块上传的PPT、推送进度，并直接发送所有图片数据
+func (s *GRPCServer) ConvertAndStream(stream proto.PPTToImagesService_ConvertAndStreamServer) error {
+	req, err := s.receiveUpload(stream)
+	if err != nil {
+		return err
+	}
+	if err := s.resolveSource(stream.Context(), req); err != nil {
+		return err
+	}
+
+	if err := s.validateConvertRequest(req); err != nil {
+		return err
+	}
+
+	session := s.createSession()
+	session.Tenant = tenantFromContext(stream.Context())
+	defer s.finishSession(session.ID)
+
+	s.logger.Infof("开始处理单次调用转换请求: %s (ID: %s)", req.Filename, session.ID)
+
+	// 进度回调可能来自并行渲染的多个协程，发送需要互斥；客户端不读取时在请求结束后放弃发送
+	sender := newContextSender(stream.Context(), 0)
+	send := func(resp *proto.ConvertAndStreamResponse) error {
+		return sender.send(func() error {
+			return stream.Send(resp)
+		})
+	}
+
+	convErr := s.runConversion(stream.Context(), session, req, func(progress converter.ConversionStatus) {
+		session.setStatus(progress)
+		if err := send(&proto.ConvertAndStreamResponse{
+			Response: &proto.ConvertAndStreamResponse_Status{Status: s.convertStatusToProto(progress)},
+		}); err != nil {
+			s.logger.Errorf("发送状态更新失败: %v", err)
+		}
+	}, nil)
+
+	session.Mutex.RLock()
+	result := session.Result
+	session.Mutex.RUnlock()
+
+	if convErr == nil {
+		for _, image := range result.Images {
+			if err := s.streamImage(send, image); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := send(&proto.ConvertAndStreamResponse{
+		Response: &proto.ConvertAndStreamResponse_Result{Result: s.convertResultToProto(result)},
+	}); err != nil {
+		return err
+	}
+
+	if convErr != nil {
+		return status.Error(conversionErrorCode(convErr), convErr.Error())
+	}
+
+	s.logger.Infof("单次调用转换完成: %s (ID: %s)", req.Filename, session.ID)
+	return nil
+}
+
+// receiveUpload 接收 header 和所有数据块，拼接为完整的转换请求
+func (s *GRPCServer) receiveUpload(stream proto.PPTToImagesService_ConvertAndStreamServer) (*proto.ConvertPPTRequest, error) {
+	var req *proto.ConvertPPTRequest
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch request := msg.Request.(type) {
+		case *proto.ConvertAndStreamRequest_Header:
+			if req != nil {
+				return nil, status.Error(codes.InvalidArgument, "重复的header消息")
+			}
+			req = request.Header
+		case *proto.ConvertAndStreamRequest_Chunk:
+			if req == nil {
+				return nil, status.Error(codes.InvalidArgument, "第一条消息必须是header")
+			}
+			// 边接收边检查大小，不必等上传完成
+			if s.maxUploadSize > 0 && int64(len(req.PptData)+len(request.Chunk)) > s.maxUploadSize {
+				return nil, status.Errorf(codes.InvalidArgument, "文件大小超过上限 %d 字节", s.maxUploadSize)
+			}
+			req.PptData = append(req.PptData, request.Chunk...)
+		}
+	}
+
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "缺少header消息")
+	}
+	return req, nil
+}
+
+// streamImage 发送一张图片的信息和全部数据块
+func (s *GRPCServer) streamImage(send func(*proto.ConvertAndStreamResponse) error, image converter.ImageInfo) error {
+	file, err := os.Open(image.FilePath)
+	if err != nil {
+		return status.Errorf(codes.Internal, "无法打开文件: %v", err)
+	}
+	defer file.Close()
+
+	if err := send(&proto.ConvertAndStreamResponse{
+		Response: &proto.ConvertAndStreamResponse_ImageInfo{ImageInfo: s.convertImageInfoToProto(image)},
+	}); err != nil {
+		return err
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return status.Errorf(codes.Internal, "无法获取文件信息: %v", err)
+	}
+
+	// 按已发送的字节数判断最后一块，空文件也发送一个标记 last 的空块
+	buffer := make([]byte, streamChunkSize)
+	var sent int64
+	for {
+		n, err := file.Read(buffer)
+		if err != nil && err != io.EOF {
+			return status.Errorf(codes.Internal, "读取文件失败: %v", err)
+		}
+		sent += int64(n)
+		last := err == io.EOF || sent >= fileInfo.Size()
+
+		if n > 0 || last {
+			if err := send(&proto.ConvertAndStreamResponse{
+				Response: &proto.ConvertAndStreamResponse_ImageChunk{ImageChunk: &proto.ImageChunk{
+					SlideNumber: int32(image.SlideNumber),
+					Filename:    image.Filename,
+					Data:        buffer[:n],
+					Last:        last,
+				}},
+			}); err != nil {
+				return err
+			}
+		}
+		if last {
+			return nil
+		}
+	}
+}
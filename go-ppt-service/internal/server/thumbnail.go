@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// GetPresentationThumbnail 返回演示文稿内嵌的预览缩略图，只读取压缩包，不经过转换后端
+func (s *GRPCServer) GetPresentationThumbnail(ctx context.Context, req *proto.ThumbnailRequest) (*proto.ImageData, error) {
+	if len(req.PptData) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "PPT文件数据为空")
+	}
+	if s.maxUploadSize > 0 && int64(len(req.PptData)) > s.maxUploadSize {
+		return nil, status.Errorf(codes.InvalidArgument, "文件大小 %d 字节超过上限 %d 字节", len(req.PptData), s.maxUploadSize)
+	}
+
+	thumbnail, err := converter.ExtractThumbnail(req.PptData)
+	switch {
+	case errors.Is(err, converter.ErrNoThumbnail):
+		return nil, status.Error(codes.NotFound, "演示文稿没有内嵌缩略图")
+	case err != nil:
+		return nil, status.Error(conversionErrorCode(err), err.Error())
+	}
+
+	return &proto.ImageData{
+		Data:   thumbnail.Data,
+		Format: thumbnail.Format,
+		Width:  int32(thumbnail.Width),
+		Height: int32(thumbnail.Height),
+	}, nil
+}
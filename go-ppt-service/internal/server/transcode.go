@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// Transcode 把已完成转换的图片重新编码为其他格式，以与 ConvertPPT 相同的流式消息返回
+// 只读取输出目录中已渲染的图片，不重新渲染幻灯片；新图片登记新的下载ID，原图片不受影响
+func (s *GRPCServer) Transcode(req *proto.TranscodeRequest, stream proto.PPTToImagesService_TranscodeServer) error {
+	format := strings.ToUpper(req.TargetFormat)
+	if format != "PNG" && format != "JPEG" && format != "JPG" {
+		return status.Errorf(codes.InvalidArgument, "不支持的目标格式: %s", req.TargetFormat)
+	}
+	if req.Quality < 0 || req.Quality > 100 {
+		return status.Errorf(codes.InvalidArgument, "无效的JPEG质量: %d (1-100)", req.Quality)
+	}
+
+	session, err := s.findSession(stream.Context(), req.ConversionId)
+	if err != nil {
+		return err
+	}
+
+	session.Mutex.RLock()
+	result := session.Result
+	session.Mutex.RUnlock()
+
+	if result == nil || !result.Success {
+		return status.Errorf(codes.FailedPrecondition, "转换尚未成功完成: %s", req.ConversionId)
+	}
+	for _, image := range result.Images {
+		if _, err := os.Stat(image.FilePath); err != nil {
+			return status.Errorf(codes.FailedPrecondition, "转换的输出已被清理: %s", req.ConversionId)
+		}
+	}
+
+	// 与转换一样登记为进行中的请求，服务关闭时等待其结束
+	if !s.active.begin() {
+		return status.Error(codes.Unavailable, "服务正在关闭")
+	}
+	defer s.active.end()
+
+	s.logger.Infof("开始重新编码为 %s (ID: %s)", format, req.ConversionId)
+
+	// 等待转换槽位时客户端断开或服务关闭则放弃
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	defer context.AfterFunc(s.ctx, cancel)()
+
+	if s.convSlots != nil {
+		select {
+		case s.convSlots <- struct{}{}:
+			defer func() { <-s.convSlots }()
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	images, err := converter.TranscodeImages(result.Images, format, int(req.Quality), func(done, total int) {
+		if err := stream.Send(&proto.ConvertPPTResponse{
+			Response: &proto.ConvertPPTResponse_Status{
+				Status: s.convertStatusToProto(converter.ConversionStatus{
+					Status:          "processing",
+					Progress:        done * 100 / total,
+					Message:         "正在重新编码图片...",
+					TotalSlides:     total,
+					ProcessedSlides: done,
+				}),
+			},
+		}); err != nil {
+			s.logger.Errorf("发送状态更新失败: %v", err)
+		}
+	})
+	s.registerImages(req.ConversionId, session.Tenant, images)
+	s.quota.add(imagesSize(images))
+	if err != nil {
+		s.logger.Errorf("重新编码失败 (ID: %s): %v", req.ConversionId, err)
+		return status.Errorf(codes.Internal, "重新编码失败: %v", err)
+	}
+
+	for _, image := range images {
+		if err := stream.Send(&proto.ConvertPPTResponse{
+			Response: &proto.ConvertPPTResponse_ImageInfo{
+				ImageInfo: s.convertImageInfoToProto(image),
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Infof("重新编码完成: %d 张图片 (ID: %s)", len(images), req.ConversionId)
+	return stream.Send(&proto.ConvertPPTResponse{
+		Response: &proto.ConvertPPTResponse_Result{
+			Result: s.convertResultToProto(&converter.ConversionResult{
+				Success:         true,
+				Message:         "重新编码完成",
+				TotalSlides:     result.TotalSlides,
+				ConvertedSlides: result.ConvertedSlides,
+				HiddenSlides:    result.HiddenSlides,
+				SlideSize:       result.SlideSize,
+				Images:          images,
+			}),
+		},
+	})
+}
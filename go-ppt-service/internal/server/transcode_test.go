@@ -0,0 +1,221 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// pngConverter 把每张幻灯片写为一张可解码的PNG图片的转换器，与真实的转换器一样每写好一张就通知 OnImageReady
+type pngConverter struct {
+	outputDir string
+	slides    int
+}
+
+func (c *pngConverter) ConvertPPT(ctx context.Context, pptData []byte, filename string, options converter.ConversionOptions, progressCallback converter.ProgressCallback) (*converter.ConversionResult, error) {
+	subdir := options.OutputSubdir
+	if subdir == "" {
+		subdir = options.ConversionID
+	}
+	dir := filepath.Join(c.outputDir, subdir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 16, 9))); err != nil {
+		return nil, err
+	}
+
+	result := &converter.ConversionResult{Success: true, TotalSlides: c.slides, ConvertedSlides: c.slides}
+	for number := 1; number <= c.slides; number++ {
+		name := fmt.Sprintf("slide_%03d.png", number)
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+		image := converter.ImageInfo{
+			SlideNumber: number,
+			Filename:    name,
+			FilePath:    path,
+			FileSize:    int64(buf.Len()),
+			DownloadID:  fmt.Sprintf("slide_%d", number),
+			Format:      "PNG",
+		}
+		if options.OnImageReady != nil {
+			options.OnImageReady(image)
+		}
+		result.Images = append(result.Images, image)
+	}
+	return result, nil
+}
+
+func (c *pngConverter) Backend() string               { return "test" }
+func (c *pngConverter) SupportedExtensions() []string { return []string{".pptx"} }
+func (c *pngConverter) KillProcesses() int            { return 0 }
+func (c *pngConverter) Close() error                  { return nil }
+
+// recordingStream 记录 ConvertPPT 和 Transcode 发送的消息，onImage 不为空时收到图片信息即调用
+type recordingStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses []*proto.ConvertPPTResponse
+	onImage   func(info *proto.ImageInfo)
+}
+
+func (s *recordingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *recordingStream) Send(resp *proto.ConvertPPTResponse) error {
+	s.responses = append(s.responses, resp)
+	if info, ok := resp.Response.(*proto.ConvertPPTResponse_ImageInfo); ok && s.onImage != nil {
+		s.onImage(info.ImageInfo)
+	}
+	return nil
+}
+
+// result 流中的最终结果
+func (s *recordingStream) result() *proto.ConversionResult {
+	for _, resp := range s.responses {
+		if result, ok := resp.Response.(*proto.ConvertPPTResponse_Result); ok {
+			return result.Result
+		}
+	}
+	return nil
+}
+
+func newTranscodeServer(t *testing.T) *GRPCServer {
+	root := t.TempDir()
+	s := &GRPCServer{
+		converter:   &pngConverter{outputDir: root, slides: 2},
+		logger:      logrus.New(),
+		conversions: make(map[string]*ConversionSession),
+		downloads:   make(map[string]downloadEntry),
+		outputDir:   root,
+		outputTTL:   time.Hour,
+		ctx:         context.Background(),
+		active:      newActiveConversions(),
+	}
+	s.logger.SetOutput(io.Discard)
+	return s
+}
+
+// onlySessionID 服务器中唯一的转换会话的ID
+func onlySessionID(t *testing.T, s *GRPCServer) string {
+	t.Helper()
+
+	if len(s.conversions) != 1 {
+		t.Fatalf("服务器中有 %d 个会话，应为1个", len(s.conversions))
+	}
+	for id := range s.conversions {
+		return id
+	}
+	return ""
+}
+
+func TestTranscodeAfterConvertPPT(t *testing.T) {
+	s := newTranscodeServer(t)
+
+	convertStream := &recordingStream{ctx: context.Background()}
+	if err := s.ConvertPPT(&proto.ConvertPPTRequest{Filename: "deck.pptx", PptData: selfTestDeck}, convertStream); err != nil {
+		t.Fatal(err)
+	}
+	converted := convertStream.result()
+	if converted == nil || !converted.Success || len(converted.Images) != 2 {
+		t.Fatalf("转换结果为 %+v", converted)
+	}
+
+	// 流式转换结束后会话仍然保留，可以重新编码
+	conversionID := onlySessionID(t, s)
+	stream := &recordingStream{ctx: context.Background()}
+	if err := s.Transcode(&proto.TranscodeRequest{ConversionId: conversionID, TargetFormat: "JPEG", Quality: 80}, stream); err != nil {
+		t.Fatal(err)
+	}
+	result := stream.result()
+	if result == nil || !result.Success || len(result.Images) != 2 {
+		t.Fatalf("重新编码的结果为 %+v", result)
+	}
+	for _, info := range result.Images {
+		path, err := s.findImageByDownloadID(info.DownloadId, conversionID, "")
+		if err != nil {
+			t.Fatalf("新图片没有登记下载ID: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err != nil || format != "jpeg" {
+			t.Errorf("%s 的格式为 %q (%v)，应为jpeg", path, format, err)
+		}
+	}
+
+	// 删除转换后会话不再存在
+	if _, err := s.DeleteConversion(context.Background(), &proto.DeleteRequest{ConversionId: conversionID}); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Transcode(&proto.TranscodeRequest{ConversionId: conversionID, TargetFormat: "JPEG"}, &recordingStream{ctx: context.Background()})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("删除后重新编码的错误为 %v，应为 NotFound", err)
+	}
+}
+
+func TestConvertPPTWithoutTTLRemovesSession(t *testing.T) {
+	s := newTranscodeServer(t)
+	s.outputTTL = 0
+
+	// 没有后台清理时请求结束即移除会话，图片仍可下载
+	stream := &recordingStream{ctx: context.Background()}
+	if err := s.ConvertPPT(&proto.ConvertPPTRequest{Filename: "deck.pptx", PptData: selfTestDeck}, stream); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.conversions) != 0 {
+		t.Errorf("未设置保留时间时仍保留了 %d 个会话", len(s.conversions))
+	}
+	if _, err := s.findImageByDownloadID(stream.result().Images[0].DownloadId, "", ""); err != nil {
+		t.Errorf("移除会话后无法下载: %v", err)
+	}
+}
+
+func TestTranscodeWaitingForSlotCancelled(t *testing.T) {
+	s := newTranscodeServer(t)
+
+	convertStream := &recordingStream{ctx: context.Background()}
+	if err := s.ConvertPPT(&proto.ConvertPPTRequest{Filename: "deck.pptx", PptData: selfTestDeck}, convertStream); err != nil {
+		t.Fatal(err)
+	}
+
+	conversionID := onlySessionID(t, s)
+
+	// 所有转换槽位都被占用时，客户端断开结束等待
+	s.convSlots = make(chan struct{}, 1)
+	s.convSlots <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := s.Transcode(&proto.TranscodeRequest{ConversionId: conversionID, TargetFormat: "JPEG"}, &recordingStream{ctx: ctx})
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("等待槽位时取消的错误为 %v，应为 Canceled", err)
+	}
+
+	// 服务关闭后不再接受重新编码
+	<-s.convSlots
+	s.active.close()
+	err = s.Transcode(&proto.TranscodeRequest{ConversionId: conversionID, TargetFormat: "JPEG"}, &recordingStream{ctx: context.Background()})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("关闭后重新编码的错误为 %v，应为 Unavailable", err)
+	}
+}
@@ -0,0 +1,415 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/internal/jpegenc"
+	"ppt-to-images-service/proto"
+)
+
+var (
+	// pptxMagic PPTX (ZIP) 文件头
+	pptxMagic = []byte{'P', 'K', 0x03, 0x04}
+	// pptMagic PPT (OLE复合文档) 文件头
+	pptMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	// odpMimetype ODP (ZIP) 的第一个条目是不压缩的 mimetype 文件，从偏移30开始依次为文件名和内容
+	odpMimetype = []byte("mimetypeapplication/vnd.oasis.opendocument.presentation")
+)
+
+// supportedOutputFormats 支持的输出格式
+var supportedOutputFormats = map[string]bool{
+	"PNG":  true,
+	"JPEG": true,
+	"JPG":  true,
+	"AUTO": true,
+}
+
+// ValidateOutputDefaults 检查请求不指定尺寸和格式时使用的默认值: 尺寸为正数且不超过像素上限 (maxPixels 为0时不限制)，格式受支持
+func ValidateOutputDefaults(width, height int, format string, maxPixels int64) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("默认输出尺寸必须为正数: %dx%d", width, height)
+	}
+	if maxPixels > 0 && int64(width)*int64(height) > maxPixels {
+		return fmt.Errorf("默认输出尺寸 %dx%d 超过 %d 像素上限", width, height, maxPixels)
+	}
+	if !supportedOutputFormats[strings.ToUpper(format)] {
+		return fmt.Errorf("不支持的默认输出格式: %s", format)
+	}
+	return nil
+}
+
+// supportedFontExtensions 允许上传的字体文件扩展名
+var supportedFontExtensions = map[string]bool{
+	".ttf": true,
+	".otf": true,
+	".ttc": true,
+}
+
+// validateConvertRequest 校验转换请求，gRPC与HTTP接口共用
+func (s *GRPCServer) validateConvertRequest(req *proto.ConvertPPTRequest) error {
+	if len(req.PptData) == 0 {
+		return status.Error(codes.InvalidArgument, "PPT文件数据为空")
+	}
+
+	if s.maxUploadSize > 0 && int64(len(req.PptData)) > s.maxUploadSize {
+		return status.Errorf(codes.InvalidArgument, "文件大小 %d 字节超过上限 %d 字节", len(req.PptData), s.maxUploadSize)
+	}
+
+	// 字体与PPT文件合计不能超过上传大小上限
+	uploadSize := int64(len(req.PptData))
+	for _, font := range req.Fonts {
+		if !supportedFontExtensions[strings.ToLower(filepath.Ext(font.Filename))] {
+			return status.Errorf(codes.InvalidArgument, "不支持的字体文件: %s", font.Filename)
+		}
+		if len(font.Data) == 0 {
+			return status.Errorf(codes.InvalidArgument, "字体文件为空: %s", font.Filename)
+		}
+		uploadSize += int64(len(font.Data))
+	}
+	if s.maxUploadSize > 0 && uploadSize > s.maxUploadSize {
+		return status.Errorf(codes.InvalidArgument, "文件和字体合计 %d 字节超过上限 %d 字节", uploadSize, s.maxUploadSize)
+	}
+
+	if req.Width < 0 || req.Height < 0 {
+		return status.Errorf(codes.InvalidArgument, "无效的输出尺寸: %dx%d", req.Width, req.Height)
+	}
+
+	// 多分辨率输出时以最大的尺寸渲染，尺寸限制按它检查
+	width, height := int(req.Width), int(req.Height)
+	if resolutions := resolutionsFromRequest(req); len(resolutions) > 0 {
+		if err := converter.CheckResolutions(resolutions); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		largest := converter.LargestResolution(resolutions)
+		width, height = largest.Width, largest.Height
+	}
+	width, height = s.outputSize(width, height)
+
+	if s.maxPixels > 0 && int64(width)*int64(height) > s.maxPixels {
+		return status.Errorf(codes.InvalidArgument, "输出尺寸 %dx%d 超过 %d 像素上限", width, height, s.maxPixels)
+	}
+
+	// 按DPI指定的尺寸要按幻灯片尺寸换算，由转换器检查像素上限
+	if len(req.SlideSizes) > 0 && len(req.Resolutions) > 0 {
+		return status.Error(codes.InvalidArgument, "slide_sizes 不能与 resolutions 同时使用")
+	}
+	if len(req.SlideSizes) > 0 && req.NormalizeSize {
+		return status.Error(codes.InvalidArgument, "slide_sizes 不能与 normalize_size 同时使用")
+	}
+	for _, override := range req.SlideSizes {
+		size := converter.SizeOverride{Width: int(override.Width), Height: int(override.Height), DPI: int(override.Dpi)}
+		if err := converter.CheckSizeOverride(int(override.Slide), size); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if s.maxPixels > 0 && int64(size.Width)*int64(size.Height) > s.maxPixels {
+			return status.Errorf(codes.InvalidArgument, "幻灯片 %d 的输出尺寸 %dx%d 超过 %d 像素上限", override.Slide, size.Width, size.Height, s.maxPixels)
+		}
+	}
+
+	// 单个转换就超过内存预算时直接拒绝，不进入等待
+	if err := s.memory.check(estimateMemory(uploadSize, width, height)); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	if req.OutputFormat != "" && !supportedOutputFormats[strings.ToUpper(req.OutputFormat)] {
+		return status.Errorf(codes.InvalidArgument, "不支持的输出格式: %s", req.OutputFormat)
+	}
+
+	for _, index := range req.SlideIndices {
+		if index < 1 {
+			return status.Errorf(codes.InvalidArgument, "无效的幻灯片编号: %d (从1开始)", index)
+		}
+	}
+
+	for _, override := range req.SlideFormats {
+		if override.Slide < 1 {
+			return status.Errorf(codes.InvalidArgument, "无效的幻灯片编号: %d (从1开始)", override.Slide)
+		}
+		if !supportedOutputFormats[strings.ToUpper(override.Format)] {
+			return status.Errorf(codes.InvalidArgument, "幻灯片 %d 不支持的输出格式: %s", override.Slide, override.Format)
+		}
+	}
+
+	if req.StampFontSize < 0 {
+		return status.Errorf(codes.InvalidArgument, "无效的编号字号: %d", req.StampFontSize)
+	}
+	if req.NumberOffset < 0 {
+		return status.Errorf(codes.InvalidArgument, "编号偏移不能为负数: %d", req.NumberOffset)
+	}
+	if _, ok := jobPriorities[req.Priority]; !ok {
+		return status.Errorf(codes.InvalidArgument, "无效的优先级: %d", req.Priority)
+	}
+	switch req.Rotate {
+	case 0, 90, 180, 270:
+	default:
+		return status.Errorf(codes.InvalidArgument, "无效的旋转角度: %d (只支持 0, 90, 180, 270)", req.Rotate)
+	}
+	if req.BorderWidth < 0 {
+		return status.Errorf(codes.InvalidArgument, "无效的边框宽度: %d", req.BorderWidth)
+	}
+	if req.AutoCropTolerance < 0 || req.AutoCropTolerance > 255 {
+		return status.Errorf(codes.InvalidArgument, "无效的自动裁剪容差: %d (0-255)", req.AutoCropTolerance)
+	}
+	if req.TileHeight < 0 {
+		return status.Errorf(codes.InvalidArgument, "无效的分块高度: %d", req.TileHeight)
+	}
+	if _, err := jpegenc.ParseSubsampling(req.JpegSubsampling); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, err := converter.ParseResampleFilter(req.ResampleFilter); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := converter.CheckPNGDepth(int(req.PngBitDepth), req.PngPalette); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.TitlePattern != "" {
+		if _, err := regexp.Compile(req.TitlePattern); err != nil {
+			return status.Errorf(codes.InvalidArgument, "无效的标题正则表达式: %v", err)
+		}
+	}
+	if req.CoverOnly {
+		switch {
+		case len(req.SlideIndices) > 0:
+			return status.Error(codes.InvalidArgument, "cover_only 不能与 slide_indices 同时使用")
+		case len(req.OrderIndices) > 0:
+			return status.Error(codes.InvalidArgument, "cover_only 不能与 order_indices 同时使用")
+		case req.LayoutFilter != "" || req.TitlePattern != "":
+			return status.Error(codes.InvalidArgument, "cover_only 不能与 layout_filter、title_pattern 同时使用")
+		case req.Probe:
+			return status.Error(codes.InvalidArgument, "cover_only 不能与 probe 同时使用")
+		}
+	}
+	if req.ThemeVariant < 0 {
+		return status.Errorf(codes.InvalidArgument, "无效的主题变体: %d (从1开始，0表示不改变)", req.ThemeVariant)
+	}
+	if req.InlineMaxBytes < 0 {
+		return status.Errorf(codes.InvalidArgument, "无效的内联大小上限: %d", req.InlineMaxBytes)
+	}
+	if req.TimeoutSeconds < 0 {
+		return status.Errorf(codes.InvalidArgument, "无效的转换超时时间: %d", req.TimeoutSeconds)
+	}
+	if req.EmbedColorProfile && !s.colorProfile {
+		return status.Error(codes.FailedPrecondition, "服务器未配置ICC配置文件 (-icc-profile)，无法写入颜色配置")
+	}
+
+	if req.StampColor != "" {
+		if _, err := parseHexColor(req.StampColor); err != nil {
+			return status.Errorf(codes.InvalidArgument, "无效的编号颜色: %s", req.StampColor)
+		}
+	}
+	if req.BorderColor != "" {
+		if _, err := parseHexColor(req.BorderColor); err != nil {
+			return status.Errorf(codes.InvalidArgument, "无效的边框颜色: %s", req.BorderColor)
+		}
+	}
+
+	if err := validatePresentation(req.Filename, req.PptData, s.converter.Backend(), s.converter.SupportedExtensions()); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// PPTX在排队和渲染之前快速统计幻灯片数量，其他格式由转换器在得到页数后检查
+	if s.maxSlides > 0 {
+		if total, ok := converter.CountSlides(req.PptData); ok && total > s.maxSlides {
+			return status.Errorf(codes.InvalidArgument, "演示文稿有 %d 张幻灯片，超过上限 %d", total, s.maxSlides)
+		}
+	}
+
+	return nil
+}
+
+// validatePresentation 检查扩展名是否被当前后端支持，并根据文件头检查文件内容与扩展名是否相符
+func validatePresentation(filename string, data []byte, backend string, supported []string) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !slices.Contains(supported, ext) {
+		return fmt.Errorf("当前转换后端 (%s) 不支持的文件类型: %s (支持 %s)", backend, filename, strings.Join(supported, ", "))
+	}
+
+	switch ext {
+	case ".pptx":
+		// 加密的PPTX不是ZIP，而是包含 EncryptedPackage 的OLE复合文档
+		if isEncryptedPresentation(data) {
+			return errPasswordProtected
+		}
+		if !bytes.HasPrefix(data, pptxMagic) {
+			return fmt.Errorf("不是有效的PPTX文件: %s", filename)
+		}
+	case ".ppt":
+		if !bytes.HasPrefix(data, pptMagic) {
+			return fmt.Errorf("不是有效的PPT文件: %s", filename)
+		}
+		if isEncryptedPresentation(data) {
+			return errPasswordProtected
+		}
+	case ".odp":
+		if !bytes.HasPrefix(data, pptxMagic) || len(data) < 30 || !bytes.HasPrefix(data[30:], odpMimetype) {
+			return fmt.Errorf("不是有效的ODP文件: %s", filename)
+		}
+	case ".key":
+		// Keynote 09及以后的版本是ZIP格式
+		if !bytes.HasPrefix(data, pptxMagic) {
+			return fmt.Errorf("不是有效的Keynote文件: %s", filename)
+		}
+	default:
+		return fmt.Errorf("不支持的文件类型: %s", filename)
+	}
+
+	return nil
+}
+
+// parseHexColor 解析 #RRGGBB 格式的颜色
+func parseHexColor(value string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) != 6 {
+		return color.NRGBA{}, fmt.Errorf("颜色格式应为 #RRGGBB")
+	}
+
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("颜色格式应为 #RRGGBB")
+	}
+
+	return color.NRGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255}, nil
+}
+
+// errPasswordProtected 演示文稿受密码保护
+var errPasswordProtected = errors.New("演示文稿受密码保护，请先移除密码后再转换")
+
+// encryptedStreams 加密演示文稿特有的OLE流
+// 加密的PPTX被包装为OLE复合文档并包含 EncryptedPackage 流；加密的PPT包含 EncryptedSummary 流
+var encryptedStreams = map[string]bool{
+	"EncryptedPackage": true,
+	"EncryptedSummary": true,
+}
+
+const (
+	oleEndOfChain = 0xFFFFFFFE
+	oleFreeSector = 0xFFFFFFFF
+	// oleMaxDirEntries 读取的目录项数量上限，演示文稿的目录通常只有几十项
+	oleMaxDirEntries = 1 << 16
+)
+
+// isEncryptedPresentation 解析OLE复合文档的目录，判断是否包含加密流
+func isEncryptedPresentation(data []byte) bool {
+	names, err := oleStreamNames(data)
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		if encryptedStreams[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// oleStreamNames 列出OLE复合文档中所有目录项的名称
+// 文件来自上传，扇区链可能被构造为循环或指向文件之外: 每条链最多遍历文件中的扇区数，
+// 重复访问同一扇区按循环拒绝，因此FAT和目录的大小都不会超过文件本身
+func oleStreamNames(data []byte) ([]string, error) {
+	if len(data) < 512 || !bytes.HasPrefix(data, pptMagic) {
+		return nil, fmt.Errorf("不是OLE复合文档")
+	}
+
+	sectorShift := binary.LittleEndian.Uint16(data[0x1E:])
+	if sectorShift != 9 && sectorShift != 12 {
+		return nil, fmt.Errorf("无效的扇区大小")
+	}
+	sectorSize := 1 << sectorShift
+	sector := func(n uint32) []byte {
+		offset := (int64(n) + 1) * int64(sectorSize)
+		if offset+int64(sectorSize) > int64(len(data)) {
+			return nil
+		}
+		return data[offset : offset+int64(sectorSize)]
+	}
+	// 文件头之后的扇区数，任何扇区链和FAT扇区列表都不会更长
+	maxSectors := len(data)/sectorSize - 1
+
+	// 收集FAT扇区编号: 文件头中的前109个，其余在DIFAT扇区链中
+	var fatSectors []uint32
+	for i := 0; i < 109; i++ {
+		if n := binary.LittleEndian.Uint32(data[0x4C+i*4:]); n != oleFreeSector {
+			fatSectors = append(fatSectors, n)
+		}
+	}
+	visited := make(map[uint32]bool)
+	for n := binary.LittleEndian.Uint32(data[0x44:]); n != oleEndOfChain && n != oleFreeSector; {
+		if visited[n] {
+			return nil, fmt.Errorf("DIFAT扇区链存在循环")
+		}
+		visited[n] = true
+		difat := sector(n)
+		if difat == nil {
+			return nil, fmt.Errorf("DIFAT扇区越界")
+		}
+		for i := 0; i < sectorSize/4-1; i++ {
+			if fat := binary.LittleEndian.Uint32(difat[i*4:]); fat != oleFreeSector {
+				fatSectors = append(fatSectors, fat)
+			}
+		}
+		if len(fatSectors) > maxSectors {
+			return nil, fmt.Errorf("FAT扇区数量超过文件中的扇区数")
+		}
+		n = binary.LittleEndian.Uint32(difat[sectorSize-4:])
+	}
+	if len(fatSectors) > maxSectors {
+		return nil, fmt.Errorf("FAT扇区数量超过文件中的扇区数")
+	}
+
+	var fat []uint32
+	for _, n := range fatSectors {
+		s := sector(n)
+		if s == nil {
+			return nil, fmt.Errorf("FAT扇区越界")
+		}
+		for i := 0; i < sectorSize; i += 4 {
+			fat = append(fat, binary.LittleEndian.Uint32(s[i:]))
+		}
+	}
+
+	// 沿FAT链读取目录扇区，每个目录项128字节
+	var names []string
+	visited = make(map[uint32]bool)
+	for n := binary.LittleEndian.Uint32(data[0x30:]); n != oleEndOfChain; {
+		if visited[n] {
+			return nil, fmt.Errorf("目录扇区链存在循环")
+		}
+		visited[n] = true
+		s := sector(n)
+		if s == nil || int(n) >= len(fat) {
+			return nil, fmt.Errorf("目录扇区越界")
+		}
+		if len(names)+sectorSize/128 > oleMaxDirEntries {
+			return nil, fmt.Errorf("目录项超过 %d 个", oleMaxDirEntries)
+		}
+		for i := 0; i+128 <= sectorSize; i += 128 {
+			entry := s[i : i+128]
+			nameLen := int(binary.LittleEndian.Uint16(entry[0x40:]))
+			if nameLen < 2 || nameLen > 64 {
+				continue
+			}
+			runes := make([]uint16, 0, nameLen/2-1)
+			for j := 0; j < nameLen-2; j += 2 {
+				runes = append(runes, binary.LittleEndian.Uint16(entry[j:]))
+			}
+			names = append(names, string(utf16.Decode(runes)))
+		}
+		n = fat[n]
+	}
+
+	return names, nil
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// oleFile 生成最小的OLE复合文档: 扇区0为FAT，扇区1为目录，目录中依次为 names 中的项
+func oleFile(names ...string) []byte {
+	const sectorSize = 512
+	data := make([]byte, sectorSize*3)
+	copy(data, pptMagic)
+	binary.LittleEndian.PutUint16(data[0x1E:], 9)
+	binary.LittleEndian.PutUint32(data[0x2C:], 1)
+	binary.LittleEndian.PutUint32(data[0x30:], 1)
+	binary.LittleEndian.PutUint32(data[0x44:], oleEndOfChain)
+	for i := 0; i < 109; i++ {
+		binary.LittleEndian.PutUint32(data[0x4C+i*4:], oleFreeSector)
+	}
+	binary.LittleEndian.PutUint32(data[0x4C:], 0)
+
+	fat := data[sectorSize : 2*sectorSize]
+	for i := 0; i < sectorSize; i += 4 {
+		binary.LittleEndian.PutUint32(fat[i:], oleFreeSector)
+	}
+	binary.LittleEndian.PutUint32(fat[0:], 0xFFFFFFFD)
+	binary.LittleEndian.PutUint32(fat[4:], oleEndOfChain)
+
+	dir := data[2*sectorSize:]
+	for i, name := range names {
+		entry := dir[i*128 : (i+1)*128]
+		runes := utf16.Encode([]rune(name))
+		for j, r := range runes {
+			binary.LittleEndian.PutUint16(entry[j*2:], r)
+		}
+		binary.LittleEndian.PutUint16(entry[0x40:], uint16(len(runes)*2+2))
+	}
+	return data
+}
+
+func TestIsEncryptedPresentation(t *testing.T) {
+	if !isEncryptedPresentation(oleFile("Root Entry", "EncryptionInfo", "EncryptedPackage")) {
+		t.Error("包含 EncryptedPackage 的文件应判断为加密")
+	}
+	if isEncryptedPresentation(oleFile("Root Entry", "PowerPoint Document", "Current User")) {
+		t.Error("普通PPT被判断为加密")
+	}
+
+	// 构造的扇区链: DIFAT指向自身、目录链指向自身、文件被截断，都应返回错误而不是无限读取
+	difatLoop := oleFile("Root Entry")
+	binary.LittleEndian.PutUint32(difatLoop[0x44:], 1)
+	binary.LittleEndian.PutUint32(difatLoop[3*512-4:], 1)
+	dirLoop := oleFile("Root Entry")
+	binary.LittleEndian.PutUint32(dirLoop[512+4:], 1)
+	for name, data := range map[string][]byte{
+		"DIFAT循环": difatLoop,
+		"目录循环":    dirLoop,
+		"截断":      oleFile("Root Entry", "EncryptedPackage")[:700],
+	} {
+		if names, err := oleStreamNames(data); err == nil {
+			t.Errorf("%s: 应返回错误，得到 %q", name, names)
+		}
+	}
+}
+
+func TestValidateOutputDefaults(t *testing.T) {
+	tests := []struct {
+		width, height int
+		format        string
+		maxPixels     int64
+		ok            bool
+	}{
+		{3840, 2160, "jpeg", 7680 * 4320, true},
+		{1920, 1080, "AUTO", 0, true},
+		{0, 1080, "PNG", 0, false},
+		{3840, 2160, "PNG", 1920 * 1080, false},
+		{1920, 1080, "GIF", 0, false},
+	}
+	for _, test := range tests {
+		err := ValidateOutputDefaults(test.width, test.height, test.format, test.maxPixels)
+		if (err == nil) != test.ok {
+			t.Errorf("ValidateOutputDefaults(%d, %d, %q, %d) = %v", test.width, test.height, test.format, test.maxPixels, err)
+		}
+	}
+
+	s := &GRPCServer{defaultWidth: 3840, defaultHeight: 2160}
+	if width, height := s.outputSize(800, 0); width != 3840 || height != 2160 {
+		t.Errorf("只指定宽度时尺寸为 %dx%d，应使用默认尺寸", width, height)
+	}
+	if width, height := s.outputSize(800, 600); width != 800 || height != 600 {
+		t.Errorf("指定的尺寸被改为 %dx%d", width, height)
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"ppt-to-images-service/internal/converter"
+	"ppt-to-images-service/proto"
+)
+
+// watchBufferSize 每个订阅者缓冲的状态更新数量，缓冲区满时丢弃新的中间状态
+const watchBufferSize = 16
+
+// WatchConversion 订阅转换状态，推送状态更新直到转换结束
+// 订阅时转换已经结束则只发送一次最终状态
+func (s *GRPCServer) WatchConversion(req *proto.StatusRequest, stream proto.PPTToImagesService_WatchConversionServer) error {
+	session, err := s.findSession(stream.Context(), req.ConversionId)
+	if err != nil {
+		return err
+	}
+
+	updates, current := session.subscribe()
+	if err := stream.Send(s.convertStatusToProto(current)); err != nil {
+		return err
+	}
+	if updates == nil {
+		return nil
+	}
+	defer session.unsubscribe(updates)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case update, ok := <-updates:
+			if !ok {
+				// 转换结束，发送记录在会话中的最终状态
+				session.Mutex.RLock()
+				final := session.Status
+				session.Mutex.RUnlock()
+				return stream.Send(s.convertStatusToProto(final))
+			}
+			if err := stream.Send(s.convertStatusToProto(update)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// subscribe 订阅会话的状态更新，返回更新通道和当前状态
+// 转换已经结束时返回的通道为nil
+func (session *ConversionSession) subscribe() (chan converter.ConversionStatus, converter.ConversionStatus) {
+	session.Mutex.Lock()
+	defer session.Mutex.Unlock()
+
+	if session.EndTime != nil {
+		return nil, session.Status
+	}
+
+	updates := make(chan converter.ConversionStatus, watchBufferSize)
+	session.subscribers = append(session.subscribers, updates)
+	return updates, session.Status
+}
+
+// unsubscribe 取消订阅，客户端提前断开时调用
+func (session *ConversionSession) unsubscribe(updates chan converter.ConversionStatus) {
+	session.Mutex.Lock()
+	defer session.Mutex.Unlock()
+
+	for i, subscriber := range session.subscribers {
+		if subscriber == updates {
+			session.subscribers = append(session.subscribers[:i], session.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// setStatus 更新会话状态并通知订阅者，用作转换进度回调
+func (session *ConversionSession) setStatus(status converter.ConversionStatus) {
+	session.Mutex.Lock()
+	defer session.Mutex.Unlock()
+
+	session.Status = status
+	for _, subscriber := range session.subscribers {
+		select {
+		case subscriber <- status:
+		default:
+		}
+	}
+}
+
+// closeSubscribers 转换结束后关闭所有订阅通道，调用方需持有 session.Mutex
+func (session *ConversionSession) closeSubscribers() {
+	for _, subscriber := range session.subscribers {
+		close(subscriber)
+	}
+	session.subscribers = nil
+}
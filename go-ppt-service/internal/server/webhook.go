@@ -0,0 +1,140 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// webhookTimeout 单次请求超时时间
+	webhookTimeout = 5 * time.Second
+	// webhookAttempts 最多尝试次数
+	webhookAttempts = 3
+	// webhookSignatureHeader 签名请求头，值为 sha256=<十六进制HMAC>
+	webhookSignatureHeader = "X-PPT-Signature"
+)
+
+// webhookEvent 转换结束时发送的事件
+type webhookEvent struct {
+	Event           string    `json:"event"` // conversion.completed 或 conversion.failed
+	ConversionID    string    `json:"conversion_id"`
+	Filename        string    `json:"filename"`
+	Status          string    `json:"status"`
+	Success         bool      `json:"success"`
+	Message         string    `json:"message"`
+	Error           string    `json:"error,omitempty"`
+	TotalSlides     int       `json:"total_slides"`
+	ConvertedSlides int       `json:"converted_slides"`
+	DownloadIDs     []string  `json:"download_ids"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// webhookNotifier 以POST JSON的方式通知外部系统，失败只记录日志，不影响转换结果
+type webhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+	logger *logrus.Logger
+}
+
+// newWebhookNotifier 创建webhook通知器，url为空时返回nil
+func newWebhookNotifier(url, secret string, logger *logrus.Logger) *webhookNotifier {
+	if url == "" {
+		return nil
+	}
+	return &webhookNotifier{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: webhookTimeout},
+		logger: logger,
+	}
+}
+
+// notifyConversion 根据会话的最终状态发送事件
+func (s *GRPCServer) notifyConversion(session *ConversionSession, filename string) {
+	if s.webhook == nil {
+		return
+	}
+
+	session.Mutex.RLock()
+	event := webhookEvent{
+		Event:        "conversion.failed",
+		ConversionID: session.ID,
+		Filename:     filename,
+		Status:       session.Status.Status,
+		Message:      session.Status.Message,
+		DownloadIDs:  []string{},
+		Timestamp:    time.Now(),
+	}
+	if result := session.Result; result != nil {
+		event.Success = result.Success
+		event.Error = result.Error
+		event.TotalSlides = result.TotalSlides
+		event.ConvertedSlides = result.ConvertedSlides
+		for _, image := range result.Images {
+			event.DownloadIDs = append(event.DownloadIDs, image.DownloadID)
+		}
+	}
+	session.Mutex.RUnlock()
+
+	if event.Success {
+		event.Event = "conversion.completed"
+	}
+
+	go s.webhook.send(event)
+}
+
+// send 发送事件，失败时按1秒、2秒的间隔重试
+func (w *webhookNotifier) send(event webhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		w.logger.Errorf("序列化webhook事件失败: %v", err)
+		return
+	}
+
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err = w.post(body)
+		if err == nil {
+			w.logger.Debugf("webhook已发送: %s (ID: %s)", event.Event, event.ConversionID)
+			return
+		}
+		if attempt < webhookAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+
+	w.logger.Warnf("发送webhook失败 (ID: %s, 已尝试 %d 次): %v", event.ConversionID, webhookAttempts, err)
+}
+
+// post 发送一次请求，非2xx响应视为失败
+func (w *webhookNotifier) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.secret))
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("响应状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
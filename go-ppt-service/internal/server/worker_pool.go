@@ -0,0 +1,190 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// errQueueFull 任务队列已满
+	errQueueFull = errors.New("任务队列已满")
+	// errPoolClosed 工作池已关闭，不再接受新任务
+	errPoolClosed = errors.New("工作池已关闭")
+)
+
+// jobPriority 排队任务的优先级，数值越小越先执行
+type jobPriority int
+
+const (
+	priorityHigh jobPriority = iota
+	priorityNormal
+	priorityLow
+	priorityLevels
+)
+
+// defaultQueueAging 默认的任务老化间隔
+const defaultQueueAging = 30 * time.Second
+
+// QueueDepths 各优先级排队中的任务数量
+type QueueDepths struct {
+	High   int
+	Normal int
+	Low    int
+}
+
+// WorkerPool 固定数量的协程按优先级从队列中取出转换任务并执行
+// 同一优先级按提交顺序执行；任务每等待一个老化间隔，有效优先级提高一级，低优先级任务不会一直被插队
+type WorkerPool struct {
+	queues    [priorityLevels][]*conversionJob
+	queued    int
+	queueSize int
+	aging     time.Duration
+	handler   func(job *conversionJob)
+	cancel    func(job *conversionJob)
+	logger    *logrus.Logger
+	wg        sync.WaitGroup
+	mutex     sync.Mutex
+	ready     *sync.Cond // 有新任务或工作池关闭时通知处理协程
+	closed    bool
+	cancelled chan struct{} // 关闭后表示剩余排队任务不再执行
+	abortOnce sync.Once     // 关闭服务和信号处理可能同时调用 Abort，cancelled 只关闭一次
+}
+
+// NewWorkerPool 创建工作池并启动 workers 个处理协程
+// handler 执行任务；cancel 在关闭时对尚未开始的任务调用；aging 为0时使用默认值
+func NewWorkerPool(workers, queueSize int, aging time.Duration, handler, cancel func(job *conversionJob), logger *logrus.Logger) *WorkerPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if aging <= 0 {
+		aging = defaultQueueAging
+	}
+
+	p := &WorkerPool{
+		queueSize: queueSize,
+		aging:     aging,
+		handler:   handler,
+		cancel:    cancel,
+		logger:    logger,
+		cancelled: make(chan struct{}),
+	}
+	p.ready = sync.NewCond(&p.mutex)
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.run()
+	}
+
+	return p
+}
+
+// Submit 提交任务，队列已满或工作池已关闭时立即返回错误
+func (p *WorkerPool) Submit(job *conversionJob) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return errPoolClosed
+	}
+	if p.queued >= p.queueSize {
+		return errQueueFull
+	}
+
+	job.enqueued = time.Now()
+	p.queues[job.priority] = append(p.queues[job.priority], job)
+	p.queued++
+	p.ready.Signal()
+	return nil
+}
+
+// QueueSize 返回队列容量
+func (p *WorkerPool) QueueSize() int {
+	return p.queueSize
+}
+
+// Depths 返回各优先级排队中的任务数量
+func (p *WorkerPool) Depths() QueueDepths {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return QueueDepths{
+		High:   len(p.queues[priorityHigh]),
+		Normal: len(p.queues[priorityNormal]),
+		Low:    len(p.queues[priorityLow]),
+	}
+}
+
+// Abort 停止接受新任务，取消所有尚未开始的排队任务，并等待正在执行的任务结束
+func (p *WorkerPool) Abort() {
+	p.mutex.Lock()
+	p.closed = true
+	remaining := p.queued
+	p.ready.Broadcast()
+	p.mutex.Unlock()
+
+	if remaining > 0 {
+		p.logger.Warnf("服务关闭，取消 %d 个排队任务", remaining)
+	}
+	p.abortOnce.Do(func() { close(p.cancelled) })
+	p.wg.Wait()
+}
+
+// run 处理协程主循环，队列为空且工作池已关闭时退出
+func (p *WorkerPool) run() {
+	defer p.wg.Done()
+
+	for {
+		job := p.next()
+		if job == nil {
+			return
+		}
+
+		select {
+		case <-p.cancelled:
+			p.cancel(job)
+		default:
+			p.handler(job)
+		}
+	}
+}
+
+// next 等待并取出有效优先级最高的任务，有效优先级相同时取等待最久的
+func (p *WorkerPool) next() *conversionJob {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for p.queued == 0 {
+		if p.closed {
+			return nil
+		}
+		p.ready.Wait()
+	}
+
+	// 每个队列按提交顺序排列，只需比较各队列的第一个任务
+	now := time.Now()
+	best := -1
+	var bestLevel jobPriority
+	for level := range p.queues {
+		if len(p.queues[level]) == 0 {
+			continue
+		}
+		job := p.queues[level][0]
+		effective := job.priority - jobPriority(now.Sub(job.enqueued)/p.aging)
+		if effective < priorityHigh {
+			effective = priorityHigh
+		}
+		if best < 0 || effective < bestLevel ||
+			(effective == bestLevel && job.enqueued.Before(p.queues[best][0].enqueued)) {
+			best, bestLevel = level, effective
+		}
+	}
+
+	job := p.queues[best][0]
+	p.queues[best][0] = nil
+	p.queues[best] = p.queues[best][1:]
+	p.queued--
+	return job
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWorkerPoolAbortCancelsQueuedJobs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var mutex sync.Mutex
+	var handled, cancelled []string
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool := NewWorkerPool(2, 10, 0, func(job *conversionJob) {
+		started <- struct{}{}
+		<-release
+		mutex.Lock()
+		handled = append(handled, job.session.ID)
+		mutex.Unlock()
+	}, func(job *conversionJob) {
+		mutex.Lock()
+		cancelled = append(cancelled, job.session.ID)
+		mutex.Unlock()
+	}, logger)
+
+	// 任务比处理协程多: 两个开始执行，其余排队
+	for i := 1; i <= 6; i++ {
+		job := &conversionJob{session: &ConversionSession{ID: fmt.Sprintf("job_%d", i)}, priority: priorityNormal}
+		if err := pool.Submit(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+	<-started
+	if depths := pool.Depths(); depths.Normal != 4 {
+		t.Fatalf("排队任务为 %+v，应为4个", depths)
+	}
+
+	// 关闭服务与信号处理同时调用 Abort
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.Abort()
+		}()
+	}
+	<-pool.cancelled
+	if err := pool.Submit(&conversionJob{session: &ConversionSession{ID: "late"}}); err != errPoolClosed {
+		t.Errorf("关闭后提交返回 %v，应为 errPoolClosed", err)
+	}
+
+	// Abort 等待正在执行的任务结束
+	close(release)
+	wg.Wait()
+
+	sort.Strings(handled)
+	sort.Strings(cancelled)
+	if fmt.Sprint(handled) != "[job_1 job_2]" {
+		t.Errorf("执行的任务为 %v，应为开始执行的两个", handled)
+	}
+	if fmt.Sprint(cancelled) != "[job_3 job_4 job_5 job_6]" {
+		t.Errorf("取消的任务为 %v，应为排队的四个", cancelled)
+	}
+	if depths := pool.Depths(); depths != (QueueDepths{}) {
+		t.Errorf("Abort 返回后仍有排队任务: %+v", depths)
+	}
+}
+
+func TestWorkerPoolCompletesAllJobs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var mutex sync.Mutex
+	handled := make(map[string]int)
+	var done sync.WaitGroup
+	pool := NewWorkerPool(2, 10, 0, func(job *conversionJob) {
+		defer done.Done()
+		mutex.Lock()
+		handled[job.session.ID]++
+		mutex.Unlock()
+	}, func(job *conversionJob) {
+		t.Errorf("任务 %s 被取消", job.session.ID)
+	}, logger)
+
+	// 任务比处理协程多，全部排队后依次执行
+	done.Add(10)
+	for i := 1; i <= 10; i++ {
+		job := &conversionJob{session: &ConversionSession{ID: fmt.Sprintf("job_%d", i)}, priority: priorityNormal}
+		if err := pool.Submit(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done.Wait()
+
+	// 队列已空，关闭时没有需要取消的任务，立即返回
+	pool.Abort()
+	if len(handled) != 10 {
+		t.Errorf("执行了 %d 个任务，应为10个", len(handled))
+	}
+	for id, count := range handled {
+		if count != 1 {
+			t.Errorf("任务 %s 执行了 %d 次", id, count)
+		}
+	}
+	if depths := pool.Depths(); depths != (QueueDepths{}) {
+		t.Errorf("关闭后仍有排队任务: %+v", depths)
+	}
+}
+
+// runPriorityPool 用一个处理协程执行任务: 第一个任务阻塞到 submit 返回，之后按执行顺序记录其余任务
+func runPriorityPool(t *testing.T, aging time.Duration, submit func(pool *WorkerPool)) []string {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var mutex sync.Mutex
+	var order []string
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool := NewWorkerPool(1, 10, aging, func(job *conversionJob) {
+		if job.session.ID == "blocker" {
+			close(started)
+			<-release
+			return
+		}
+		mutex.Lock()
+		order = append(order, job.session.ID)
+		mutex.Unlock()
+	}, func(job *conversionJob) {}, logger)
+
+	if err := pool.Submit(&conversionJob{session: &ConversionSession{ID: "blocker"}, priority: priorityNormal}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	submit(pool)
+	close(release)
+
+	// 等待排队任务全部执行后再关闭
+	for pool.Depths() != (QueueDepths{}) {
+		time.Sleep(time.Millisecond)
+	}
+	pool.Abort()
+	return order
+}
+
+func submitJob(t *testing.T, pool *WorkerPool, id string, priority jobPriority) {
+	t.Helper()
+
+	if err := pool.Submit(&conversionJob{session: &ConversionSession{ID: id}, priority: priority}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkerPoolHighPriorityFirst(t *testing.T) {
+	order := runPriorityPool(t, time.Hour, func(pool *WorkerPool) {
+		submitJob(t, pool, "low_1", priorityLow)
+		submitJob(t, pool, "normal_1", priorityNormal)
+		submitJob(t, pool, "low_2", priorityLow)
+		submitJob(t, pool, "high_1", priorityHigh)
+		if depths := pool.Depths(); depths != (QueueDepths{High: 1, Normal: 1, Low: 2}) {
+			t.Errorf("排队任务为 %+v", depths)
+		}
+	})
+
+	// 高优先级任务插到先提交的任务之前，同一优先级按提交顺序
+	if fmt.Sprint(order) != "[high_1 normal_1 low_1 low_2]" {
+		t.Errorf("执行顺序为 %v", order)
+	}
+}
+
+func TestWorkerPoolAgingPromotesLowPriority(t *testing.T) {
+	const aging = 50 * time.Millisecond
+	order := runPriorityPool(t, aging, func(pool *WorkerPool) {
+		submitJob(t, pool, "low", priorityLow)
+		// 等待超过两个老化间隔，低优先级任务的有效优先级升到最高
+		time.Sleep(3 * aging)
+		submitJob(t, pool, "high", priorityHigh)
+	})
+
+	// 有效优先级相同时等待更久的先执行
+	if fmt.Sprint(order) != "[low high]" {
+		t.Errorf("执行顺序为 %v，等待超过老化间隔的低优先级任务应先执行", order)
+	}
+}